@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"golang.org/x/term"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
+	"github.com/playmixer/secret-keeper/internal/adapter/logger"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/internal/adapter/storage/file"
+	"github.com/playmixer/secret-keeper/internal/core/config"
+	"github.com/playmixer/secret-keeper/internal/core/uiapi"
+)
+
+// Коды завершения подкоманд для скриптов.
+const (
+	exitError       = 1
+	exitUsage       = 2
+	exitAuth        = 3
+	exitNotFound    = 4
+	exitUnavailable = 5
+)
+
+const (
+	envUser     = "KEEPER_USER"
+	envPassword = "KEEPER_PASSWORD"
+)
+
+var (
+	errUsage     = errors.New("неверные аргументы")
+	errNotFound  = errors.New("запись не найдена")
+	errAmbiguous = errors.New("название подходит нескольким записям, укажите ID")
+	errLocked    = errors.New("клиент заблокирован, разблокируйте его паролем")
+)
+
+// itemTypes типы записей в аргументах подкоманд.
+var itemTypes = map[string]models.DataType{
+	"password": models.PASSWORD,
+	"card":     models.CARD,
+	"text":     models.TEXT,
+	"file":     models.BINARY,
+	"ssh-key":  models.SSH_KEY,
+}
+
+// vault методы клиента, которыми пользуются подкоманды.
+type vault interface {
+	EventAuthorization(login, password string) error
+	EventClose() error
+	EventSync() error
+	EventGetMetaDatas() (*[]models.FileMetaDataItem, error)
+	EventNewCard(eID uint, title, number, cvv, pin, date string) (*models.FileMetaDataItem, error)
+	EventGetCard(id int64) (*models.Card, error)
+	EventEditCard(id int64, title, number, cvv, pin, date string) error
+	EventNewText(eID uint, title, text string) (*models.FileMetaDataItem, error)
+	EventGetText(id int64) (*models.Text, error)
+	EventEditText(id int64, title, text string) error
+	EventNewPassword(eID uint, title, site, login, password, otp string) (*models.FileMetaDataItem, error)
+	EventNewPasswordFrom(eID uint, source, title, site, login, password string) (*models.FileMetaDataItem, error)
+	EventGetPassword(id int64) (*models.Password, error)
+	EventEditPassword(id int64, title, site, login, password, otp string) error
+	EventNewFile(eID uint, title, path string) (*models.FileMetaDataItem, error)
+	EventGetFile(id int64) (*models.Binary, error)
+	EventEditFile(id int64, title, path string) error
+	EventUploadFile(id int64, path string) error
+	EventDelete(id int64) error
+	EventNewSSHKey(eID uint, title, privateKey, comment, passphrase string) (*models.FileMetaDataItem, error)
+	EventGetSSHKey(id int64) (*models.SSHKey, error)
+	EventEditSSHKey(id int64, title, privateKey, comment, passphrase string) error
+	Locked(login string) bool
+	PendingChanges() int
+	Offline() bool
+}
+
+// cli окружение подкоманды: потоки ввода-вывода и общие флаги.
+type cli struct {
+	stdin         io.Reader
+	stdout        io.Writer
+	stderr        io.Writer
+	user          string
+	passwordStdin bool
+	json          bool
+	// tty stdin занят данными, пароль спрашивается на терминале.
+	tty bool
+	// ignoreLock входить, даже если клиент заблокирован в интерфейсе терминала.
+	ignoreLock bool
+}
+
+func newCLI() *cli {
+	return &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+}
+
+// flags набор флагов подкоманды с общими флагами входа и вывода.
+func (c *cli) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.user, "user", os.Getenv(envUser), "логин, по умолчанию из "+envUser)
+	fs.BoolVar(&c.passwordStdin, "password-stdin", false,
+		"прочитать пароль из первой строки stdin, иначе из "+envPassword+" или с терминала")
+	fs.BoolVar(&c.json, "json", false, "вывод в JSON")
+	fs.BoolVar(&c.ignoreLock, "ignore-lock", false, "выполнить, даже если клиент заблокирован в интерфейсе терминала")
+	return fs
+}
+
+// parse разбирает флаги вперемешку с позиционными аргументами и возвращает позиционные.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %w", errUsage, err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// password пароль пользователя из stdin, переменной окружения или с терминала.
+// Если stdin занят (tty), пароль спрашивается на терминале пользователя.
+func (c *cli) password() (string, error) {
+	if c.passwordStdin {
+		return readLine(c.stdin)
+	}
+	if password, ok := os.LookupEnv(envPassword); ok {
+		return password, nil
+	}
+	in := os.Stdin
+	if c.tty {
+		f, err := os.OpenFile(ttyPath, os.O_RDWR, 0)
+		if err != nil {
+			return "", fmt.Errorf("%w: пароль не задан, используйте %s", errUsage, envPassword)
+		}
+		defer func() { _ = f.Close() }()
+		in = f
+	}
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("%w: пароль не задан, используйте %s или -password-stdin", errUsage, envPassword)
+	}
+	fmt.Fprint(c.stderr, "Пароль: ")
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(c.stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed read password: %w", err)
+	}
+	return string(password), nil
+}
+
+func readLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed read stdin: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// connect входит в хранилище пользователя и синхронизирует данные. Без связи с сервером подкоманда
+// работает с локальными данными, если strict - возвращает ошибку синхронизации.
+// done закрывает хранилище, сохраненная сессия остается для входа без сервера.
+func (c *cli) connect(ctx context.Context, strict bool) (v vault, done func(), err error) {
+	if c.user == "" {
+		return nil, nil, fmt.Errorf("%w: логин не задан, используйте -user или %s", errUsage, envUser)
+	}
+
+	cfg, err := config.Init(true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed initialize config: %w", err)
+	}
+	lgr, err := logger.New(
+		logger.SetLevel(cfg.LogLevel),
+		logger.SetLogPath(cfg.LogPath),
+		logger.SetEnableTerminalOutput(false),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed initialize logger: %w", err)
+	}
+	store, err := file.Init(file.SetLogger(lgr))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed initialize store: %w", err)
+	}
+
+	api, err := uiapi.New(
+		ctx,
+		store,
+		lgr,
+		uiapi.SetConfig(cfg.Client),
+		uiapi.SetFileMaxSize(cfg.FileMaxSize),
+		uiapi.SetEnableWorker(false),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed create client api: %w", err)
+	}
+	if !c.ignoreLock && api.Locked(c.user) {
+		return nil, nil, errLocked
+	}
+	password, err := c.password()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := api.EventAuthorization(c.user, password); err != nil {
+		return nil, nil, fmt.Errorf("failed login: %w", err)
+	}
+
+	done = func() {
+		if err := api.EventClose(); err != nil {
+			lgr.Error("failed close", zap.Error(err))
+		}
+		if err := lgr.Sync(); err != nil {
+			lgr.Error("failed sync logger", zap.Error(err))
+		}
+	}
+	if err := c.sync(api, strict); err != nil {
+		done()
+		return nil, nil, err
+	}
+	return api, done, nil
+}
+
+// sync синхронизирует данные, без связи с сервером только предупреждает, если не strict.
+func (c *cli) sync(v vault, strict bool) error {
+	err := v.EventSync()
+	if err == nil {
+		return nil
+	}
+	if strict || !(uiapi.Unreachable(err) || errors.Is(err, uiapi.ErrPendingChanges)) {
+		return fmt.Errorf("failed sync: %w", err)
+	}
+	fmt.Fprintf(c.stderr, "предупреждение: %v, используются локальные данные", err)
+	if n := v.PendingChanges(); n > 0 {
+		fmt.Fprintf(c.stderr, ", изменения (%d) будут отправлены при следующей синхронизации", n)
+	}
+	fmt.Fprintln(c.stderr)
+	return nil
+}
+
+// find запись по ID или названию. Название сравнивается сначала точно, затем без учета регистра.
+func find(v vault, ref string) (*models.FileMetaDataItem, error) {
+	items, err := v.EventGetMetaDatas()
+	if err != nil {
+		return nil, fmt.Errorf("failed get list: %w", err)
+	}
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		for i := range *items {
+			if (*items)[i].ID == id && !(*items)[i].IsDeleted {
+				return &(*items)[i], nil
+			}
+		}
+	}
+	for _, match := range []func(title string) bool{
+		func(title string) bool { return title == ref },
+		func(title string) bool { return strings.EqualFold(title, ref) },
+	} {
+		var found *models.FileMetaDataItem
+		for i := range *items {
+			if (*items)[i].IsDeleted || !match((*items)[i].Title) {
+				continue
+			}
+			if found != nil {
+				return nil, fmt.Errorf("%w: %s", errAmbiguous, ref)
+			}
+			found = &(*items)[i]
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errNotFound, ref)
+}
+
+// typeName тип записи в аргументах подкоманд.
+func typeName(dataType models.DataType) string {
+	for name, t := range itemTypes {
+		if t == dataType {
+			return name
+		}
+	}
+	return strings.ToLower(string(dataType))
+}
+
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed write json: %w", err)
+	}
+	return nil
+}
+
+// exitCode код завершения по ошибке подкоманды.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, errLocked):
+		return exitAuth
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, errNotFound), errors.Is(err, errAmbiguous), errors.Is(err, errReference):
+		return exitNotFound
+	case errors.Is(err, uiapi.ErrPendingChanges), uiapi.Unreachable(err):
+		return exitUnavailable
+	}
+	switch uiapi.Code(err) {
+	case rest.CodeInvalidCredentials, rest.CodeUnauthorized, rest.CodeAccountLocked, rest.CodeLegacyAuthDisabled:
+		return exitAuth
+	}
+	return exitError
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/internal/core/uiapi"
+)
+
+type fakeVault struct {
+	vault
+	passwords map[int64]*models.Password
+	sshKeys   map[int64]*models.SSHKey
+	items     []models.FileMetaDataItem
+	locked    bool
+}
+
+func (v *fakeVault) EventGetMetaDatas() (*[]models.FileMetaDataItem, error) {
+	return &v.items, nil
+}
+
+func (v *fakeVault) EventGetPassword(id int64) (*models.Password, error) {
+	p, ok := v.passwords[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	return p, nil
+}
+
+func (v *fakeVault) EventGetSSHKey(id int64) (*models.SSHKey, error) {
+	k, ok := v.sshKeys[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	return k, nil
+}
+
+func (v *fakeVault) Locked(string) bool {
+	return v.locked
+}
+
+func (v *fakeVault) EventSync() error {
+	return nil
+}
+
+func (v *fakeVault) EventNewPasswordFrom(_ uint, source, title, site, login, password string) (
+	*models.FileMetaDataItem, error,
+) {
+	id := int64(len(v.items) + 1)
+	v.items = append(v.items, models.FileMetaDataItem{ID: id, Title: title, DataType: models.PASSWORD})
+	v.passwords[id] = &models.Password{Title: title, Site: site, Login: login, Password: password, Source: source}
+	return &v.items[len(v.items)-1], nil
+}
+
+func (v *fakeVault) EventEditPassword(id int64, title, site, login, password, otp string) error {
+	p, ok := v.passwords[id]
+	if !ok {
+		return errNotFound
+	}
+	p.Title, p.Site, p.Login, p.Password, p.OTP = title, site, login, password, otp
+	return nil
+}
+
+func (v *fakeVault) EventDelete(id int64) error {
+	for i := range v.items {
+		if v.items[i].ID == id {
+			v.items[i].IsDeleted = true
+			return nil
+		}
+	}
+	return errNotFound
+}
+
+func Test_find(t *testing.T) {
+	v := &fakeVault{items: []models.FileMetaDataItem{
+		{ID: 1, Title: "GitHub", DataType: models.PASSWORD},
+		{ID: 2, Title: "github", DataType: models.TEXT},
+		{ID: 3, Title: "Bank", DataType: models.CARD},
+		{ID: 4, Title: "Old", IsDeleted: true},
+		{ID: 5, Title: "Bank", DataType: models.TEXT},
+	}}
+	tests := []struct {
+		name    string
+		ref     string
+		wantID  int64
+		wantErr error
+	}{
+		{name: "by id", ref: "2", wantID: 2},
+		{name: "exact title first", ref: "github", wantID: 2},
+		{name: "title ignoring case", ref: "GITHUB", wantErr: errAmbiguous},
+		{name: "ambiguous", ref: "Bank", wantErr: errAmbiguous},
+		{name: "deleted", ref: "Old", wantErr: errNotFound},
+		{name: "deleted by id", ref: "4", wantErr: errNotFound},
+		{name: "not found", ref: "none", wantErr: errNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := find(v, tt.ref)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantID, got.ID)
+		})
+	}
+}
+
+func Test_parse(t *testing.T) {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	field := fs.String("field", "", "")
+	asJSON := fs.Bool("json", false, "")
+
+	args, err := parse(fs, []string{"GitHub", "-field", "password", "-json"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"GitHub"}, args)
+	assert.Equal(t, "password", *field)
+	assert.True(t, *asJSON)
+
+	_, err = parse(fs, []string{"-unknown"})
+	assert.ErrorIs(t, err, errUsage)
+}
+
+func Test_exitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "usage", err: errUsage, want: exitUsage},
+		{name: "not found", err: errNotFound, want: exitNotFound},
+		{name: "offline", err: uiapi.ErrOffline, want: exitUnavailable},
+		{name: "not pushed", err: uiapi.ErrPendingChanges, want: exitUnavailable},
+		{name: "other", err: errors.New("any"), want: exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, exitCode(tt.err))
+		})
+	}
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,6 +20,7 @@ import (
 	"github.com/playmixer/secret-keeper/internal/adapter/ui"
 	"github.com/playmixer/secret-keeper/internal/core/config"
 	"github.com/playmixer/secret-keeper/internal/core/uiapi"
+	"github.com/playmixer/secret-keeper/pkg/clipboard"
 )
 
 var (
@@ -26,7 +31,27 @@ var (
 	buildCommit  string = "N/A"
 )
 
+// commands подкоманды клиента, без подкоманды запускается интерфейс терминала.
+var commands = map[string]func(c *cli, ctx context.Context, args []string) error{
+	"login":          (*cli).login,
+	"list":           (*cli).list,
+	"get":            (*cli).get,
+	"add":            (*cli).add,
+	"edit":           (*cli).edit,
+	"rm":             (*cli).remove,
+	"run":            (*cli).run,
+	"sync":           (*cli).syncCommand,
+	"inject":         (*cli).inject,
+	"git-credential": (*cli).gitCredentialHelper,
+	"ssh-agent":      (*cli).sshAgent,
+	"generate":       (*cli).generate,
+	"version":        (*cli).version,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
 	fmt.Println("Build verson: " + buildVersion)
 	fmt.Println("Build date: " + buildDate)
 	fmt.Println("Build commit: " + buildCommit)
@@ -35,6 +60,34 @@ func main() {
 	}
 }
 
+// runCommand выполняет подкоманду и возвращает код завершения.
+func runCommand(name string, args []string) int {
+	c := newCLI()
+	command, ok := commands[name]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for n := range commands {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(c.stderr, "неизвестная команда %s, доступны: %s\n", name, strings.Join(names, ", "))
+		return exitUsage
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	err := command(c, ctx, args)
+	if err == nil || errHelp(err) {
+		return 0
+	}
+	var exitErr *processExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	fmt.Fprintln(c.stderr, "ошибка:", err)
+	return exitCode(err)
+}
+
 func run() error {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
@@ -65,18 +118,30 @@ func run() error {
 		return fmt.Errorf("failed initialize store: %w", err)
 	}
 
+	if cfg.Client.Insecure {
+		fmt.Println("ВНИМАНИЕ: API_INSECURE=1, сертификат сервера не проверяется, подключение можно перехватить")
+	}
+
 	api, err := uiapi.New(
 		ctx,
 		store,
 		lgr,
-		uiapi.SetAPIHost(cfg.Client.APIAddress),
+		uiapi.SetConfig(cfg.Client),
 		uiapi.SetFileMaxSize(cfg.FileMaxSize),
 	)
 	if err != nil {
 		return fmt.Errorf("failed create client api: %w", err)
 	}
 
-	client, err := ui.New(ctx, api, lgr, ui.SetVersion(buildVersion, buildDate, buildCommit))
+	var osc io.Writer
+	if cfg.ClipboardOSC52 {
+		osc = os.Stdout
+	}
+	client, err := ui.New(ctx, api, lgr,
+		ui.SetVersion(buildVersion, buildDate, buildCommit),
+		ui.SetAutoLock(cfg.AutoLock),
+		ui.SetClipboard(clipboard.New(clipboard.SetOSC52(osc)), cfg.ClipboardClear),
+	)
 	if err != nil {
 		return fmt.Errorf("failed initialize client: %w", err)
 	}
@@ -86,6 +151,22 @@ func run() error {
 		zap.String("commit", buildCommit),
 		zap.String("server", cfg.Client.APIAddress),
 	)
+	lockCh := make(chan os.Signal, 1)
+	if signals := lockSignals(); len(signals) > 0 {
+		signal.Notify(lockCh, signals...)
+		defer signal.Stop(lockCh)
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-lockCh:
+				lgr.Info("lock by signal")
+				client.Lock()
+			}
+		}
+	}()
 	go func() {
 		defer cancel()
 		if err := client.Run(nil); err != nil {
@@ -0,0 +1,562 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/pkg/generator"
+	"github.com/playmixer/secret-keeper/pkg/totp"
+)
+
+// tabPadding отступ между колонками табличного вывода.
+const tabPadding = 2
+
+// item запись в выводе подкоманд.
+type item struct {
+	Fields map[string]string `json:"fields,omitempty"`
+	Title  string            `json:"title"`
+	Type   string            `json:"type"`
+	ID     int64             `json:"id"`
+}
+
+// itemFields поля записей каждого типа в порядке вывода.
+var itemFields = map[models.DataType][]string{
+	models.PASSWORD: {"site", "login", "password", "otp", "otp_code"},
+	models.CARD:     {"number", "expiry", "cvv", "pin"},
+	models.TEXT:     {"text"},
+	models.BINARY:   {"filename"},
+	models.SSH_KEY:  {"public_key", "fingerprint", "comment", "private_key", "passphrase"},
+}
+
+// fieldValues значения полей записи, флаги add и edit.
+type fieldValues struct {
+	set      map[string]bool
+	values   map[string]*string
+	generate bool
+}
+
+func newFieldValues(fs *flag.FlagSet) *fieldValues {
+	f := &fieldValues{values: make(map[string]*string)}
+	f.values["title"] = fs.String("title", "", "название")
+	for _, name := range []struct{ name, usage string }{
+		{"site", "сайт пароля"},
+		{"login", "логин пароля"},
+		{"password", "пароль, `-` - прочитать из stdin"},
+		{"otp", "ключ одноразовых кодов пароля: otpauth://totp/... или секрет base32"},
+		{"number", "номер карты"},
+		{"expiry", "срок действия карты"},
+		{"cvv", "CVV карты"},
+		{"pin", "PIN карты"},
+		{"text", "текст, `-` - прочитать из stdin"},
+		{"path", "путь к файлу или к закрытому ключу SSH"},
+		{"private_key", "закрытый ключ SSH, `-` - прочитать из stdin"},
+		{"comment", "комментарий ключа SSH"},
+		{"passphrase", "пароль закрытого ключа SSH, `-` - прочитать из stdin"},
+	} {
+		f.values[name.name] = fs.String(name.name, "", name.usage)
+	}
+	fs.BoolVar(&f.generate, "generate", false, "сгенерировать пароль")
+	return f
+}
+
+// parsed запоминает флаги, заданные в командной строке, и читает значения `-` из stdin.
+// stdin nil - поток уже занят паролем входа.
+func (f *fieldValues) parsed(fs *flag.FlagSet, stdin io.Reader) error {
+	f.set = make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) {
+		if _, ok := f.values[fl.Name]; ok {
+			f.set[fl.Name] = true
+		}
+	})
+	for _, name := range []string{"password", "text", "private_key", "passphrase"} {
+		if *f.values[name] != "-" {
+			continue
+		}
+		if stdin == nil {
+			return fmt.Errorf("%w: stdin можно прочитать только для одного значения или пароля входа", errUsage)
+		}
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return fmt.Errorf("failed read stdin: %w", err)
+		}
+		value := string(data)
+		if name == "password" || name == "passphrase" {
+			value = strings.TrimRight(value, "\r\n")
+		}
+		*f.values[name] = value
+		stdin = nil
+	}
+	if f.generate {
+		if f.set["password"] {
+			return fmt.Errorf("%w: -generate и -password вместе", errUsage)
+		}
+		secret, err := generator.Password(generator.DefaultPasswordOptions)
+		if err != nil {
+			return fmt.Errorf("failed generate password: %w", err)
+		}
+		*f.values["password"] = secret.Value
+		f.set["password"] = true
+	}
+	return nil
+}
+
+// check заданы только поля типа dataType.
+func (f *fieldValues) check(dataType models.DataType) error {
+	allowed := map[string]bool{"title": true}
+	for _, name := range itemFields[dataType] {
+		allowed[name] = true
+	}
+	if dataType == models.BINARY || dataType == models.SSH_KEY {
+		allowed["path"] = true
+	}
+	for name := range f.set {
+		if !allowed[name] {
+			return fmt.Errorf("%w: поле -%s не подходит записи %s", errUsage, name, typeName(dataType))
+		}
+	}
+	return nil
+}
+
+// value новое значение поля или old, если поле не задано.
+func (f *fieldValues) value(name, old string) string {
+	if f.set[name] {
+		return *f.values[name]
+	}
+	return old
+}
+
+// privateKey закрытый ключ SSH из -private_key или файла -path, old - если не задан ни один из них.
+func (f *fieldValues) privateKey(old string) (string, error) {
+	if f.set["private_key"] && f.set["path"] {
+		return "", fmt.Errorf("%w: -private_key и -path вместе", errUsage)
+	}
+	if !f.set["path"] {
+		return f.value("private_key", old), nil
+	}
+	data, err := os.ReadFile(*f.values["path"])
+	if err != nil {
+		return "", fmt.Errorf("failed read private key: %w", err)
+	}
+	return string(data), nil
+}
+
+// getItem запись со значениями полей.
+func getItem(v vault, m *models.FileMetaDataItem) (*item, error) {
+	it := &item{ID: m.ID, Title: m.Title, Type: typeName(m.DataType)}
+	switch m.DataType {
+	case models.PASSWORD:
+		p, err := v.EventGetPassword(m.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed get password: %w", err)
+		}
+		it.Fields = map[string]string{"site": p.Site, "login": p.Login, "password": p.Password, "otp": p.OTP}
+		if p.OTP != "" {
+			key, err := totp.Parse(p.OTP)
+			if err != nil {
+				return nil, fmt.Errorf("failed parse otp key: %w", err)
+			}
+			it.Fields["otp_code"] = key.Code(time.Now())
+		}
+	case models.CARD:
+		c, err := v.EventGetCard(m.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed get card: %w", err)
+		}
+		it.Fields = map[string]string{"number": c.Number, "expiry": c.Expiry, "cvv": c.CVV, "pin": c.PIN}
+	case models.TEXT:
+		t, err := v.EventGetText(m.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed get text: %w", err)
+		}
+		it.Fields = map[string]string{"text": t.Text}
+	case models.BINARY:
+		b, err := v.EventGetFile(m.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed get file: %w", err)
+		}
+		it.Fields = map[string]string{"filename": b.Filename}
+	case models.SSH_KEY:
+		k, err := v.EventGetSSHKey(m.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed get ssh key: %w", err)
+		}
+		it.Fields = map[string]string{
+			"public_key": k.PublicKey, "fingerprint": k.Fingerprint, "comment": k.Comment,
+			"private_key": k.PrivateKey, "passphrase": k.Passphrase,
+		}
+	}
+	return it, nil
+}
+
+// fieldValue значение поля записи, заданной ID или названием.
+func fieldValue(v vault, ref, field string) (string, error) {
+	m, err := find(v, ref)
+	if err != nil {
+		return "", err
+	}
+	it, err := getItem(v, m)
+	if err != nil {
+		return "", err
+	}
+	if field == "title" {
+		return it.Title, nil
+	}
+	value, ok := it.Fields[field]
+	if !ok {
+		return "", fmt.Errorf("%w: у записи %s нет поля %s", errReference, it.Type, field)
+	}
+	return value, nil
+}
+
+// login проверяет логин и пароль и забирает данные с сервера, после этого они доступны и без связи.
+func (c *cli) login(ctx context.Context, args []string) error {
+	fs := c.flags("login")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	v, done, err := c.connect(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer done()
+	items, err := v.EventGetMetaDatas()
+	if err != nil {
+		return fmt.Errorf("failed get list: %w", err)
+	}
+	count := 0
+	for _, m := range *items {
+		if !m.IsDeleted {
+			count++
+		}
+	}
+	if c.json {
+		return c.printJSON(map[string]any{"user": c.user, "items": count, "offline": v.Offline()})
+	}
+	fmt.Fprintf(c.stdout, "вход выполнен, записей: %d\n", count)
+	return nil
+}
+
+func (c *cli) list(ctx context.Context, args []string) error {
+	fs := c.flags("list")
+	kind := fs.String("type", "", "только записи типа password, card, text, file или ssh-key")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	dataType, ok := itemTypes[*kind]
+	if *kind != "" && !ok {
+		return fmt.Errorf("%w: неизвестный тип %s", errUsage, *kind)
+	}
+	v, done, err := c.connect(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer done()
+	items, err := v.EventGetMetaDatas()
+	if err != nil {
+		return fmt.Errorf("failed get list: %w", err)
+	}
+	list := make([]item, 0, len(*items))
+	for _, m := range *items {
+		if m.IsDeleted || (ok && m.DataType != dataType) {
+			continue
+		}
+		list = append(list, item{ID: m.ID, Title: m.Title, Type: typeName(m.DataType)})
+	}
+	if c.json {
+		return c.printJSON(list)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 0, tabPadding, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tTITLE")
+	for _, it := range list {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", it.ID, it.Type, it.Title)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed write list: %w", err)
+	}
+	return nil
+}
+
+func (c *cli) get(ctx context.Context, args []string) error {
+	fs := c.flags("get")
+	field := fs.String("field", "", "вывести только значение поля, например password")
+	out := fs.String("o", "", "каталог, куда сохранить файл записи file")
+	refs, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(refs) != 1 {
+		return fmt.Errorf("%w: get <название|id>", errUsage)
+	}
+	v, done, err := c.connect(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer done()
+	m, err := find(v, refs[0])
+	if err != nil {
+		return err
+	}
+	it, err := getItem(v, m)
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		if m.DataType != models.BINARY {
+			return fmt.Errorf("%w: -o только для записей file", errUsage)
+		}
+		if err := v.EventUploadFile(m.ID, *out); err != nil {
+			return fmt.Errorf("failed save file: %w", err)
+		}
+	}
+	if *field != "" {
+		value, ok := it.Fields[*field]
+		if *field == "title" {
+			value, ok = it.Title, true
+		}
+		if !ok {
+			return fmt.Errorf("%w: у записи %s нет поля %s", errUsage, it.Type, *field)
+		}
+		if c.json {
+			return c.printJSON(map[string]string{*field: value})
+		}
+		fmt.Fprintln(c.stdout, value)
+		return nil
+	}
+	if c.json {
+		return c.printJSON(it)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 0, tabPadding, ' ', 0)
+	fmt.Fprintf(w, "id\t%d\ntype\t%s\ntitle\t%s\n", it.ID, it.Type, it.Title)
+	for _, name := range itemFields[m.DataType] {
+		fmt.Fprintf(w, "%s\t%s\n", name, it.Fields[name])
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed write item: %w", err)
+	}
+	return nil
+}
+
+func (c *cli) add(ctx context.Context, args []string) error {
+	fs := c.flags("add")
+	fields := newFieldValues(fs)
+	kinds, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(kinds) != 1 {
+		return fmt.Errorf("%w: add password|card|text|file|ssh-key", errUsage)
+	}
+	dataType, ok := itemTypes[kinds[0]]
+	if !ok {
+		return fmt.Errorf("%w: неизвестный тип %s", errUsage, kinds[0])
+	}
+	if err := fields.parsed(fs, c.valuesInput()); err != nil {
+		return err
+	}
+	if err := fields.check(dataType); err != nil {
+		return err
+	}
+	title := fields.value("title", "")
+	if title == "" {
+		return fmt.Errorf("%w: не задано название -title", errUsage)
+	}
+
+	v, done, err := c.connect(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer done()
+	var m *models.FileMetaDataItem
+	switch dataType {
+	case models.PASSWORD:
+		m, err = v.EventNewPassword(0, title, fields.value("site", ""), fields.value("login", ""),
+			fields.value("password", ""), fields.value("otp", ""))
+	case models.CARD:
+		m, err = v.EventNewCard(0, title, fields.value("number", ""), fields.value("cvv", ""),
+			fields.value("pin", ""), fields.value("expiry", ""))
+	case models.TEXT:
+		m, err = v.EventNewText(0, title, fields.value("text", ""))
+	case models.BINARY:
+		if !fields.set["path"] {
+			return fmt.Errorf("%w: не задан файл -path", errUsage)
+		}
+		m, err = v.EventNewFile(0, title, fields.value("path", ""))
+	case models.SSH_KEY:
+		var privateKey string
+		if privateKey, err = fields.privateKey(""); err != nil {
+			return err
+		}
+		m, err = v.EventNewSSHKey(0, title, privateKey, fields.value("comment", ""), fields.value("passphrase", ""))
+	}
+	if err != nil {
+		return fmt.Errorf("failed add %s: %w", kinds[0], err)
+	}
+	if err := c.sync(v, false); err != nil {
+		return err
+	}
+	return c.printChanged(m.ID, fields)
+}
+
+func (c *cli) edit(ctx context.Context, args []string) error {
+	fs := c.flags("edit")
+	fields := newFieldValues(fs)
+	refs, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(refs) != 1 {
+		return fmt.Errorf("%w: edit <название|id>", errUsage)
+	}
+	if err := fields.parsed(fs, c.valuesInput()); err != nil {
+		return err
+	}
+
+	v, done, err := c.connect(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer done()
+	m, err := find(v, refs[0])
+	if err != nil {
+		return err
+	}
+	if err := fields.check(m.DataType); err != nil {
+		return err
+	}
+	it, err := getItem(v, m)
+	if err != nil {
+		return err
+	}
+	value := func(name string) string {
+		return fields.value(name, it.Fields[name])
+	}
+	title := fields.value("title", m.Title)
+	switch m.DataType {
+	case models.PASSWORD:
+		err = v.EventEditPassword(m.ID, title, value("site"), value("login"), value("password"), value("otp"))
+	case models.CARD:
+		err = v.EventEditCard(m.ID, title, value("number"), value("cvv"), value("pin"), value("expiry"))
+	case models.TEXT:
+		err = v.EventEditText(m.ID, title, value("text"))
+	case models.BINARY:
+		if !fields.set["path"] {
+			return fmt.Errorf("%w: для записи file нужен новый файл -path", errUsage)
+		}
+		err = v.EventEditFile(m.ID, title, value("path"))
+	case models.SSH_KEY:
+		var privateKey string
+		if privateKey, err = fields.privateKey(it.Fields["private_key"]); err != nil {
+			return err
+		}
+		err = v.EventEditSSHKey(m.ID, title, privateKey, value("comment"), value("passphrase"))
+	}
+	if err != nil {
+		return fmt.Errorf("failed edit %s: %w", it.Type, err)
+	}
+	if err := c.sync(v, false); err != nil {
+		return err
+	}
+	return c.printChanged(m.ID, fields)
+}
+
+// printChanged выводит ID записи и сгенерированный пароль, если он был.
+func (c *cli) printChanged(id int64, fields *fieldValues) error {
+	out := map[string]any{"id": id}
+	if fields.generate {
+		out["password"] = *fields.values["password"]
+	}
+	if c.json {
+		return c.printJSON(out)
+	}
+	fmt.Fprintln(c.stdout, id)
+	if fields.generate {
+		fmt.Fprintln(c.stdout, *fields.values["password"])
+	}
+	return nil
+}
+
+func (c *cli) remove(ctx context.Context, args []string) error {
+	fs := c.flags("rm")
+	refs, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(refs) != 1 {
+		return fmt.Errorf("%w: rm <название|id>", errUsage)
+	}
+	v, done, err := c.connect(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer done()
+	m, err := find(v, refs[0])
+	if err != nil {
+		return err
+	}
+	// удаление одинаково для записей всех типов.
+	if err := v.EventDelete(m.ID); err != nil {
+		return fmt.Errorf("failed delete: %w", err)
+	}
+	if err := c.sync(v, false); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(map[string]any{"id": m.ID})
+	}
+	fmt.Fprintln(c.stdout, m.ID)
+	return nil
+}
+
+// syncCommand синхронизирует данные, ошибка, если сервер недоступен или изменения не отправлены.
+func (c *cli) syncCommand(ctx context.Context, args []string) error {
+	fs := c.flags("sync")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	v, done, err := c.connect(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if c.json {
+		return c.printJSON(map[string]any{"pending": v.PendingChanges()})
+	}
+	fmt.Fprintln(c.stdout, "данные синхронизированы")
+	return nil
+}
+
+func (c *cli) version(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.BoolVar(&c.json, "json", false, "вывод в JSON")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if c.json {
+		return c.printJSON(map[string]string{"version": buildVersion, "date": buildDate, "commit": buildCommit})
+	}
+	fmt.Fprintf(c.stdout, "Build verson: %s\nBuild date: %s\nBuild commit: %s\n", buildVersion, buildDate, buildCommit)
+	return nil
+}
+
+// valuesInput поток для значений полей, nil - stdin занят паролем входа.
+func (c *cli) valuesInput() io.Reader {
+	if c.passwordStdin {
+		return nil
+	}
+	return c.stdin
+}
+
+// errHelp запрошена справка по флагам, это не ошибка.
+func errHelp(err error) bool {
+	return errors.Is(err, flag.ErrHelp)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/playmixer/secret-keeper/pkg/generator"
+)
+
+const (
+	generateModeChars         = "chars"
+	generateModePronounceable = "pronounceable"
+	generateModePassphrase    = "passphrase"
+)
+
+var errGenerateMode = errors.New("unknown generate mode")
+
+// generate подкоманда генерации пароля: пароль пишется в stdout, оценка энтропии в stderr,
+// чтобы вывод можно было сразу передать другой программе.
+func (c *cli) generate(_ context.Context, args []string) error {
+	opts := generator.DefaultPasswordOptions
+	var noLower, noUpper, noDigits, noSymbols bool
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	mode := fs.String("mode", generateModeChars, "chars, pronounceable или passphrase")
+	fs.IntVar(&opts.Length, "length", generator.DefaultLength, "длина пароля")
+	words := fs.Int("words", generator.DefaultWords, "число слов парольной фразы")
+	separator := fs.String("separator", "-", "разделитель слов парольной фразы")
+	fs.BoolVar(&noLower, "no-lower", false, "без строчных букв")
+	fs.BoolVar(&noUpper, "no-upper", false, "без заглавных букв")
+	fs.BoolVar(&noDigits, "no-digits", false, "без цифр")
+	fs.BoolVar(&noSymbols, "no-symbols", false, "без спецсимволов")
+	fs.BoolVar(&opts.ExcludeAmbiguous, "exclude-ambiguous", false, "без похожих символов (l, 1, O, 0)")
+	quiet := fs.Bool("quiet", false, "не выводить оценку энтропии")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	opts.Lower = !noLower
+	opts.Upper = !noUpper
+	opts.Digits = !noDigits
+	opts.Symbols = !noSymbols
+
+	var secret *generator.Secret
+	var err error
+	switch *mode {
+	case generateModeChars:
+		secret, err = generator.Password(opts)
+	case generateModePronounceable:
+		secret, err = generator.Pronounceable(opts.Length)
+	case generateModePassphrase:
+		secret, err = generator.Passphrase(*words, *separator)
+	default:
+		return fmt.Errorf("%w: %w: %s", errUsage, errGenerateMode, *mode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed generate password: %w", err)
+	}
+
+	if _, err := fmt.Fprintln(c.stdout, secret.Value); err != nil {
+		return fmt.Errorf("failed write password: %w", err)
+	}
+	if !*quiet {
+		fmt.Fprintf(c.stderr, "энтропия: %.0f бит, %s\n", secret.Entropy, generator.Strength(secret.Entropy))
+	}
+	return nil
+}
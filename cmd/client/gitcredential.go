@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+)
+
+const (
+	gitActionGet   = "get"
+	gitActionStore = "store"
+	gitActionErase = "erase"
+)
+
+// gitCredential описание учетных данных в протоколе git credential, поля key=value по строке.
+type gitCredential struct {
+	protocol string
+	host     string
+	path     string
+	username string
+	password string
+}
+
+// readGitCredential читает описание до пустой строки или конца ввода, неизвестные ключи пропускаются.
+func readGitCredential(r io.Reader) (*gitCredential, error) {
+	cred := &gitCredential{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: строка без `=`: %s", errUsage, line)
+		}
+		switch key {
+		case "protocol":
+			cred.protocol = value
+		case "host":
+			cred.host = value
+		case "path":
+			cred.path = value
+		case "username":
+			cred.username = value
+		case "password":
+			cred.password = value
+		case "url":
+			u, err := url.Parse(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: url: %w", errUsage, err)
+			}
+			cred.protocol, cred.host, cred.path = u.Scheme, u.Host, strings.TrimPrefix(u.Path, "/")
+			if u.User != nil {
+				cred.username = u.User.Username()
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed read credential: %w", err)
+	}
+	return cred, nil
+}
+
+// site адрес записи пароля для учетных данных.
+func (g *gitCredential) site() string {
+	site := g.host
+	if g.protocol != "" {
+		site = g.protocol + "://" + site
+	}
+	if g.path != "" {
+		site += "/" + g.path
+	}
+	return site
+}
+
+// match подходит ли сайт записи пароля к запросу и насколько точно: чем длиннее совпавший путь, тем выше.
+// Сайт без схемы считается https: запросам по другим протоколам подходят только записи с их схемой,
+// чтобы пароль не ушел по http. Сайт с путем - только запросам с этим путем или вложенным.
+func (g *gitCredential) match(site string) (int, bool) {
+	site = strings.TrimSpace(site)
+	withScheme := strings.Contains(site, "://")
+	if !withScheme {
+		site = "//" + site
+	}
+	u, err := url.Parse(site)
+	if err != nil || u.Host == "" {
+		return 0, false
+	}
+	if !strings.EqualFold(u.Host, g.host) {
+		return 0, false
+	}
+	scheme := u.Scheme
+	if !withScheme {
+		scheme = "https"
+	}
+	if !strings.EqualFold(scheme, g.protocol) {
+		return 0, false
+	}
+	score := 0
+	if withScheme {
+		score++
+	}
+	sitePath := strings.Trim(u.Path, "/")
+	if sitePath == "" {
+		return score, true
+	}
+	path := strings.Trim(g.path, "/")
+	if path != sitePath && !strings.HasPrefix(path, sitePath+"/") &&
+		strings.TrimSuffix(path, ".git") != strings.TrimSuffix(sitePath, ".git") {
+		return 0, false
+	}
+	return score + len(sitePath), true
+}
+
+// gitMatch запись пароля, подходящая к запросу.
+type gitMatch struct {
+	password *models.Password
+	score    int
+	id       int64
+}
+
+// gitMatches записи паролей, подходящие к запросу, с логином username, если он задан. Лучшая первой.
+func gitMatches(v vault, cred *gitCredential) ([]gitMatch, error) {
+	items, err := v.EventGetMetaDatas()
+	if err != nil {
+		return nil, fmt.Errorf("failed get list: %w", err)
+	}
+	var matches []gitMatch
+	for _, m := range *items {
+		if m.IsDeleted || m.DataType != models.PASSWORD {
+			continue
+		}
+		p, err := v.EventGetPassword(m.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed get password: %w", err)
+		}
+		score, ok := cred.match(p.Site)
+		if !ok || (cred.username != "" && p.Login != cred.username) {
+			continue
+		}
+		match := gitMatch{id: m.ID, password: p, score: score}
+		if len(matches) > 0 && score > matches[0].score {
+			matches = append([]gitMatch{match}, matches...)
+			continue
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// gitCredentialHelper помощник git credential: git config credential.helper "/path/client git-credential".
+// Пароли берутся из записей, сайт которых совпадает с хостом и путем репозитория. Пока клиент
+// заблокирован, помощник ничего не отдает, и git спрашивает пароль сам.
+func (c *cli) gitCredentialHelper(ctx context.Context, args []string) error {
+	fs := c.flags("git-credential")
+	actions, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(actions) != 1 {
+		return fmt.Errorf("%w: git-credential get|store|erase", errUsage)
+	}
+	action := actions[0]
+	switch action {
+	case gitActionGet, gitActionStore, gitActionErase:
+	default:
+		// git может передать помощнику неизвестные ему действия, их положено пропускать.
+		return nil
+	}
+	cred, err := readGitCredential(c.stdin)
+	if err != nil {
+		return err
+	}
+	if cred.host == "" {
+		return nil
+	}
+	// stdin занят протоколом git, пароль клиента спрашивается на терминале.
+	c.passwordStdin = false
+	c.tty = true
+
+	v, done, err := c.connect(ctx, false)
+	if errors.Is(err, errLocked) && action == gitActionGet {
+		fmt.Fprintln(c.stderr, "secret-keeper:", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer done()
+	matches, err := gitMatches(v, cred)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case gitActionGet:
+		if len(matches) == 0 {
+			return nil
+		}
+		best := matches[0]
+		fmt.Fprintf(c.stdout, "username=%s\npassword=%s\n", best.password.Login, best.password.Password)
+		return nil
+	case gitActionStore:
+		return c.gitStore(v, cred, matches)
+	default:
+		return c.gitErase(v, cred, matches)
+	}
+}
+
+// gitStore сохраняет учетные данные, которые принял сервер git. Если пароль уже есть в подходящей записи,
+// ничего не меняется. Иначе обновляется запись, созданная помощником, или создается новая: записи
+// пользователя помощник не меняет.
+func (c *cli) gitStore(v vault, cred *gitCredential, matches []gitMatch) error {
+	if cred.username == "" || cred.password == "" {
+		return nil
+	}
+	for _, m := range matches {
+		if m.password.Password == cred.password {
+			return nil
+		}
+	}
+	for _, m := range matches {
+		if m.password.Source != models.PasswordSourceGit {
+			continue
+		}
+		err := v.EventEditPassword(m.id, m.password.Title, m.password.Site, cred.username, cred.password,
+			m.password.OTP)
+		if err != nil {
+			return fmt.Errorf("failed update password: %w", err)
+		}
+		return c.sync(v, false)
+	}
+	_, err := v.EventNewPasswordFrom(0, models.PasswordSourceGit, "git "+cred.host, cred.site(),
+		cred.username, cred.password)
+	if err != nil {
+		return fmt.Errorf("failed add password: %w", err)
+	}
+	return c.sync(v, false)
+}
+
+// gitErase удаляет учетные данные, которые отверг сервер git. Удаляются только записи, созданные
+// помощником, с тем же логином и паролем, что прислал git: записи пользователя и запись, в которой
+// пароль уже сменили, остаются.
+func (c *cli) gitErase(v vault, cred *gitCredential, matches []gitMatch) error {
+	if cred.username == "" || cred.password == "" {
+		return nil
+	}
+	erased := false
+	for _, m := range matches {
+		if m.password.Source != models.PasswordSourceGit || m.password.Password != cred.password {
+			continue
+		}
+		// удаление одинаково для записей всех типов.
+		if err := v.EventDelete(m.id); err != nil {
+			return fmt.Errorf("failed delete password: %w", err)
+		}
+		erased = true
+	}
+	if !erased {
+		return nil
+	}
+	return c.sync(v, false)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+)
+
+func Test_readGitCredential(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    gitCredential
+		wantErr bool
+	}{
+		{
+			name:  "fields",
+			input: "protocol=https\nhost=git.example.com\npath=team/repo.git\nusername=bob\n\nhost=ignored\n",
+			want:  gitCredential{protocol: "https", host: "git.example.com", path: "team/repo.git", username: "bob"},
+		},
+		{
+			name:  "url",
+			input: "url=https://bob@git.example.com:8443/team/repo.git\npassword=secret\n",
+			want: gitCredential{
+				protocol: "https", host: "git.example.com:8443", path: "team/repo.git", username: "bob", password: "secret",
+			},
+		},
+		{
+			name:  "unknown key",
+			input: "capability[]=authtype\nhost=git.example.com",
+			want:  gitCredential{host: "git.example.com"},
+		},
+		{name: "no separator", input: "host\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readGitCredential(strings.NewReader(tt.input))
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errUsage)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, *got)
+		})
+	}
+}
+
+func Test_gitCredential_match(t *testing.T) {
+	cred := &gitCredential{protocol: "https", host: "git.example.com", path: "team/repo.git"}
+	tests := []struct {
+		name      string
+		site      string
+		wantScore int
+		wantOk    bool
+	}{
+		{name: "host", site: "git.example.com", wantScore: 0, wantOk: true},
+		{name: "host case", site: "GIT.example.com", wantScore: 0, wantOk: true},
+		{name: "scheme", site: "https://git.example.com", wantScore: 1, wantOk: true},
+		{name: "path prefix", site: "https://git.example.com/team", wantScore: 5, wantOk: true},
+		{name: "path without .git", site: "git.example.com/team/repo", wantScore: 9, wantOk: true},
+		{name: "other scheme", site: "http://git.example.com", wantOk: false},
+		{name: "other host", site: "example.com", wantOk: false},
+		{name: "other port", site: "git.example.com:8443", wantOk: false},
+		{name: "other path", site: "git.example.com/team2", wantOk: false},
+		{name: "empty", site: "", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, ok := cred.match(tt.site)
+			assert.Equal(t, tt.wantOk, ok)
+			if tt.wantOk {
+				assert.Equal(t, tt.wantScore, score)
+			}
+		})
+	}
+}
+
+func Test_gitMatches(t *testing.T) {
+	v := &fakeVault{
+		items: []models.FileMetaDataItem{
+			{ID: 1, DataType: models.PASSWORD},
+			{ID: 2, DataType: models.PASSWORD},
+			{ID: 3, DataType: models.PASSWORD},
+			{ID: 4, DataType: models.PASSWORD, IsDeleted: true},
+			{ID: 5, DataType: models.TEXT},
+			{ID: 6, DataType: models.PASSWORD},
+		},
+		passwords: map[int64]*models.Password{
+			1: {Site: "git.example.com", Login: "bob", Password: "host"},
+			2: {Site: "https://git.example.com/team", Login: "bob", Password: "team"},
+			3: {Site: "git.example.com", Login: "alice", Password: "alice"},
+			4: {Site: "https://git.example.com/team/repo", Login: "bob", Password: "deleted"},
+			6: {Site: "http://git.example.com", Login: "bob", Password: "http"},
+		},
+	}
+	tests := []struct {
+		name string
+		cred gitCredential
+		want []int64
+	}{
+		{
+			name: "best first",
+			cred: gitCredential{protocol: "https", host: "git.example.com", path: "team/repo.git"},
+			want: []int64{2, 1, 3},
+		},
+		{
+			name: "username",
+			cred: gitCredential{protocol: "https", host: "git.example.com", path: "team/repo.git", username: "alice"},
+			want: []int64{3},
+		},
+		{name: "other host", cred: gitCredential{protocol: "https", host: "example.com"}},
+		{
+			name: "http only explicit",
+			cred: gitCredential{protocol: "http", host: "git.example.com", path: "team/repo.git"},
+			want: []int64{6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := gitMatches(v, &tt.cred)
+			assert.NoError(t, err)
+			var got []int64
+			for _, m := range matches {
+				got = append(got, m.id)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// gitVault хранилище с записью пользователя (1) и записью помощника (2) для одного хоста.
+func gitVault() *fakeVault {
+	return &fakeVault{
+		items: []models.FileMetaDataItem{
+			{ID: 1, DataType: models.PASSWORD},
+			{ID: 2, DataType: models.PASSWORD},
+		},
+		passwords: map[int64]*models.Password{
+			1: {Title: "GitHub", Site: "github.com", Login: "bob", Password: "secret"},
+			2: {
+				Title: "git github.com", Site: "https://github.com/team", Login: "bob", Password: "token",
+				Source: models.PasswordSourceGit,
+			},
+		},
+	}
+}
+
+func Test_cli_gitErase(t *testing.T) {
+	tests := []struct {
+		name        string
+		password    string
+		wantDeleted []int64
+	}{
+		{name: "user item survives", password: "secret"},
+		{name: "helper item", password: "token", wantDeleted: []int64{2}},
+		{name: "other password", password: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := gitVault()
+			cred := &gitCredential{
+				protocol: "https", host: "github.com", path: "team/repo.git", username: "bob", password: tt.password,
+			}
+			matches, err := gitMatches(v, cred)
+			assert.NoError(t, err)
+			assert.NoError(t, newCLI().gitErase(v, cred, matches))
+			var deleted []int64
+			for _, m := range v.items {
+				if m.IsDeleted {
+					deleted = append(deleted, m.ID)
+				}
+			}
+			assert.Equal(t, tt.wantDeleted, deleted)
+		})
+	}
+}
+
+func Test_cli_gitStore(t *testing.T) {
+	tests := []struct {
+		name      string
+		vault     *fakeVault
+		password  string
+		want      map[int64]string
+		wantNewID int64
+	}{
+		{
+			name:     "known password",
+			vault:    gitVault(),
+			password: "secret",
+			want:     map[int64]string{1: "secret", 2: "token"},
+		},
+		{
+			name:     "updates helper item only",
+			vault:    gitVault(),
+			password: "new",
+			want:     map[int64]string{1: "secret", 2: "new"},
+		},
+		{
+			name: "user item untouched",
+			vault: &fakeVault{
+				items:     []models.FileMetaDataItem{{ID: 1, DataType: models.PASSWORD}},
+				passwords: map[int64]*models.Password{1: {Site: "github.com", Login: "bob", Password: "secret"}},
+			},
+			password:  "new",
+			want:      map[int64]string{1: "secret", 2: "new"},
+			wantNewID: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred := &gitCredential{
+				protocol: "https", host: "github.com", path: "team/repo.git", username: "bob", password: tt.password,
+			}
+			matches, err := gitMatches(tt.vault, cred)
+			assert.NoError(t, err)
+			assert.NoError(t, newCLI().gitStore(tt.vault, cred, matches))
+			got := map[int64]string{}
+			for id, p := range tt.vault.passwords {
+				got[id] = p.Password
+			}
+			assert.Equal(t, tt.want, got)
+			if tt.wantNewID != 0 {
+				assert.Equal(t, models.PasswordSourceGit, tt.vault.passwords[tt.wantNewID].Source)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+)
+
+const (
+	// injectFileMode права файла с секретами: только владелец.
+	injectFileMode os.FileMode = 0o600
+
+	defaultWatchInterval = 10 * time.Second
+)
+
+// inject подставляет секреты в шаблон: {{ secret "Название" "поле" }}. Ссылка на отсутствующую запись
+// или поле - ошибка, файл в этом случае не пишется. С -watch файл перезаписывается, когда синхронизация
+// приносит изменения; ошибка при повторной подстановке выводится, прежний файл остается.
+func (c *cli) inject(ctx context.Context, args []string) error {
+	fs := c.flags("inject")
+	input := fs.String("i", "", "файл шаблона")
+	output := fs.String("o", "", "файл результата, пустой - stdout")
+	watch := fs.Bool("watch", false, "перезаписывать файл результата при изменении данных")
+	interval := fs.Duration("interval", defaultWatchInterval, "период синхронизации в режиме -watch")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if *input == "" {
+		return fmt.Errorf("%w: inject -i <шаблон> [-o <файл>] [-watch]", errUsage)
+	}
+	if *watch && (*output == "" || *interval <= 0) {
+		return fmt.Errorf("%w: для -watch нужен файл -o и положительный -interval", errUsage)
+	}
+
+	v, done, err := c.connect(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	last, err := render(v, *input)
+	if err != nil {
+		return err
+	}
+	if err := c.writeOutput(*output, last); err != nil {
+		return err
+	}
+	if !*watch {
+		return nil
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if err := c.sync(v, false); err != nil {
+			fmt.Fprintln(c.stderr, "ошибка:", err)
+			continue
+		}
+		data, err := render(v, *input)
+		if err != nil {
+			fmt.Fprintln(c.stderr, "ошибка:", err)
+			continue
+		}
+		if bytes.Equal(data, last) {
+			continue
+		}
+		if err := c.writeOutput(*output, data); err != nil {
+			fmt.Fprintln(c.stderr, "ошибка:", err)
+			continue
+		}
+		last = data
+		fmt.Fprintf(c.stderr, "%s обновлен\n", *output)
+	}
+}
+
+// render шаблон с подставленными секретами.
+func render(v vault, path string) ([]byte, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed read template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{
+			"secret": func(ref, field string) (string, error) {
+				return fieldValue(v, ref, field)
+			},
+		}).
+		Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed parse template: %w", err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, nil); err != nil {
+		return nil, fmt.Errorf("failed render template: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// writeOutput пишет результат во временный файл рядом и переименовывает его, чтобы читатель
+// не увидел файл наполовину записанным.
+func (c *cli) writeOutput(path string, data []byte) error {
+	if path == "" {
+		if _, err := c.stdout.Write(data); err != nil {
+			return fmt.Errorf("failed write output: %w", err)
+		}
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed create output: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if err := tmp.Chmod(injectFileMode); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed chmod output: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed write output: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed close output: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed replace output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+)
+
+func Test_render(t *testing.T) {
+	v := &fakeVault{
+		items: []models.FileMetaDataItem{{ID: 1, Title: "prod db", DataType: models.PASSWORD}},
+		passwords: map[int64]*models.Password{
+			1: {Title: "prod db", Login: "admin", Password: "s3cret"},
+		},
+	}
+	tests := []struct {
+		name    string
+		tmpl    string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "ok",
+			tmpl: `db: {{ secret "prod db" "login" }}:{{ secret "1" "password" }}`,
+			want: "db: admin:s3cret",
+		},
+		{name: "no item", tmpl: `{{ secret "stage db" "password" }}`, wantErr: errNotFound},
+		{name: "no field", tmpl: `{{ secret "prod db" "pin" }}`, wantErr: errReference},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.yaml.tmpl")
+			assert.NoError(t, os.WriteFile(path, []byte(tt.tmpl), 0o600))
+
+			got, err := render(v, path)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func Test_cli_writeOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("old"), 0o644))
+
+	assert.NoError(t, newCLI().writeOutput(path, []byte("new")))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+	if runtime.GOOS != "windows" {
+		stat, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, injectFileMode, stat.Mode().Perm())
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,43 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// signalExitBase код завершения процесса, убитого сигналом, как в оболочке: 128 + номер сигнала.
+const signalExitBase = 128
+
+// ttyPath терминал пользователя, когда stdin занят, например протоколом git.
+const ttyPath = "/dev/tty"
+
+// lockSignals сигналы, по которым клиент блокируется: SIGTSTP от kill -TSTP или оболочки.
+func lockSignals() []os.Signal {
+	return []os.Signal{syscall.SIGTSTP}
+}
+
+// forwardSignals сигналы, которые run передает дочернему процессу.
+func forwardSignals() []os.Signal {
+	return []os.Signal{
+		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGUSR2,
+	}
+}
+
+// forwardSignal передает сигнал дочернему процессу.
+func forwardSignal(p *os.Process, sig os.Signal) error {
+	if err := p.Signal(sig); err != nil {
+		return fmt.Errorf("failed send signal: %w", err)
+	}
+	return nil
+}
+
+// processExitCode код завершения дочернего процесса.
+func processExitCode(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return signalExitBase + int(status.Signal())
+	}
+	return state.ExitCode()
+}
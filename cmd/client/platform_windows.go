@@ -0,0 +1,28 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// ttyPath терминал пользователя, когда stdin занят, например протоколом git.
+const ttyPath = "CONIN$"
+
+// lockSignals в Windows нет SIGTSTP, клиент блокируется только таймером и клавишами.
+func lockSignals() []os.Signal {
+	return nil
+}
+
+// forwardSignals Ctrl+C консоль сама передает дочернему процессу, run только не завершается раньше него.
+func forwardSignals() []os.Signal {
+	return []os.Signal{os.Interrupt}
+}
+
+// forwardSignal Windows не умеет передать Ctrl+C процессу, его получает вся консоль.
+func forwardSignal(_ *os.Process, _ os.Signal) error {
+	return nil
+}
+
+// processExitCode код завершения дочернего процесса.
+func processExitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
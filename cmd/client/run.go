@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/playmixer/secret-keeper/pkg/redact"
+)
+
+// vaultScheme схема ссылки на поле записи: vault://<название|id>/<поле>.
+const vaultScheme = "vault://"
+
+var errReference = errors.New("неверная ссылка на секрет")
+
+// processExitError дочерний процесс завершился с ненулевым кодом, run завершается с тем же кодом.
+type processExitError struct {
+	code int
+}
+
+func (e *processExitError) Error() string {
+	return "process exited with code " + strconv.Itoa(e.code)
+}
+
+// envFlags повторяемый флаг -env NAME=vault://item/field.
+type envFlags []string
+
+func (e *envFlags) String() string {
+	return strings.Join(*e, ",")
+}
+
+func (e *envFlags) Set(value string) error {
+	*e = append(*e, value)
+	return nil
+}
+
+// reference запись и поле из ссылки vault://<название|id>/<поле>, название можно экранировать как путь URL.
+func reference(ref string) (item, field string, err error) {
+	path, ok := strings.CutPrefix(ref, vaultScheme)
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s", errReference, ref)
+	}
+	slash := strings.LastIndex(path, "/")
+	if slash <= 0 || slash == len(path)-1 {
+		return "", "", fmt.Errorf("%w: %s", errReference, ref)
+	}
+	item, err = url.PathUnescape(path[:slash])
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %s: %w", errReference, ref, err)
+	}
+	return item, path[slash+1:], nil
+}
+
+// resolve значение поля записи по ссылке.
+func resolve(v vault, ref string) (string, error) {
+	name, field, err := reference(ref)
+	if err != nil {
+		return "", err
+	}
+	return fieldValue(v, name, field)
+}
+
+// run запускает команду с секретами в переменных окружения. Секреты попадают только в окружение
+// дочернего процесса и заменяются маской, если процесс выведет их в stdout или stderr.
+// Переменные окружения со значением vault://... тоже заменяются секретами.
+func (c *cli) run(ctx context.Context, args []string) error {
+	var envs envFlags
+	fs := c.flags("run")
+	fs.Var(&envs, "env", "NAME=vault://<название|id>/<поле>, можно повторять")
+	noMask := fs.Bool("no-mask", false, "не маскировать секреты в выводе команды")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	command := fs.Args()
+	if len(command) == 0 {
+		return fmt.Errorf("%w: run -env NAME=vault://item/field -- command [args]", errUsage)
+	}
+
+	refs := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if name, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(value, vaultScheme) {
+			refs[name] = value
+		}
+	}
+	for _, kv := range envs {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("%w: -env %s, нужно NAME=vault://item/field", errUsage, kv)
+		}
+		if _, _, err := reference(value); err != nil {
+			return fmt.Errorf("%w: %w", errUsage, err)
+		}
+		refs[name] = value
+	}
+
+	secrets, err := c.resolveAll(ctx, refs)
+	if err != nil {
+		return err
+	}
+	env := os.Environ()
+	values := make([]string, 0, len(secrets))
+	for name, value := range secrets {
+		env = append(env, name+"="+value)
+		values = append(values, value)
+	}
+	return c.exec(command, env, values, !*noMask)
+}
+
+// resolveAll значения всех ссылок, хранилище закрывается до запуска команды.
+func (c *cli) resolveAll(ctx context.Context, refs map[string]string) (map[string]string, error) {
+	secrets := make(map[string]string, len(refs))
+	if len(refs) == 0 {
+		return secrets, nil
+	}
+	v, done, err := c.connect(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	for name, ref := range refs {
+		value, err := resolve(v, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed resolve %s: %w", name, err)
+		}
+		secrets[name] = value
+	}
+	return secrets, nil
+}
+
+// exec запускает команду, передает ей сигналы и возвращает ее код завершения.
+func (c *cli) exec(command, env, secrets []string, mask bool) error {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = env
+	cmd.Stdin = c.stdin
+	cmd.Stdout = c.stdout
+	cmd.Stderr = c.stderr
+	var outputs []*redact.Writer
+	if mask {
+		stdout := redact.NewWriter(c.stdout, secrets, redact.DefaultMask)
+		stderr := redact.NewWriter(c.stderr, secrets, redact.DefaultMask)
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+		outputs = append(outputs, stdout, stderr)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardSignals()...)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed start %s: %w", command[0], err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case sig := <-signals:
+				if err := forwardSignal(cmd.Process, sig); err != nil {
+					fmt.Fprintf(c.stderr, "failed forward %v: %v\n", sig, err)
+				}
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+	for _, w := range outputs {
+		if err := w.Flush(); err != nil {
+			fmt.Fprintf(c.stderr, "failed write output: %v\n", err)
+		}
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &processExitError{code: processExitCode(exitErr.ProcessState)}
+	}
+	if err != nil {
+		return fmt.Errorf("failed run %s: %w", command[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_reference(t *testing.T) {
+	tests := []struct {
+		name      string
+		ref       string
+		wantItem  string
+		wantField string
+		wantErr   bool
+	}{
+		{name: "ok", ref: "vault://prod-db/password", wantItem: "prod-db", wantField: "password"},
+		{name: "escaped title", ref: "vault://prod%20db/login", wantItem: "prod db", wantField: "login"},
+		{name: "slash in title", ref: "vault://team/prod/password", wantItem: "team/prod", wantField: "password"},
+		{name: "by id", ref: "vault://42/pin", wantItem: "42", wantField: "pin"},
+		{name: "no scheme", ref: "prod-db/password", wantErr: true},
+		{name: "no field", ref: "vault://prod-db", wantErr: true},
+		{name: "empty field", ref: "vault://prod-db/", wantErr: true},
+		{name: "bad escape", ref: "vault://prod%zz/password", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, field, err := reference(tt.ref)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errReference)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantItem, item)
+			assert.Equal(t, tt.wantField, field)
+		})
+	}
+}
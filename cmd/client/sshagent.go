@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/pkg/sshkey"
+)
+
+// agentSocketMode права сокета агента: подключаться может только владелец.
+const agentSocketMode os.FileMode = 0o600
+
+var (
+	errAgentReadOnly   = errors.New("ключи агента хранятся в хранилище, добавьте или удалите запись ssh-key")
+	errAgentLocked     = errors.New("агент заблокирован")
+	errAgentPassphrase = errors.New("неверный пароль агента")
+	errAgentDenied     = errors.New("подпись отклонена")
+	errAgentNoKey      = errors.New("ключ не найден")
+)
+
+// vaultAgent агент ssh-agent с ключами из записей ssh-key. Ключи читаются из хранилища при каждом
+// запросе, поэтому изменения после синхронизации видны сразу. Пока клиент заблокирован в интерфейсе
+// терминала, агент ключей не показывает и не подписывает.
+type vaultAgent struct {
+	v vault
+	// confirm спрашивает разрешение на подпись, nil - подписывать без вопросов.
+	confirm func(title, fingerprint string) bool
+	stderr  io.Writer
+	user    string
+	// passphrase пароль блокировки агента командой ssh-add -x, nil - агент не заблокирован.
+	passphrase []byte
+	mu         sync.Mutex
+}
+
+// agentKey ключ записи ssh-key.
+type agentKey struct {
+	public ssh.PublicKey
+	key    *models.SSHKey
+}
+
+// keys ключи записей ssh-key, записи с неверным ключом пропускаются с предупреждением.
+func (a *vaultAgent) keys() ([]agentKey, error) {
+	if a.passphrase != nil || a.v.Locked(a.user) {
+		return nil, nil
+	}
+	items, err := a.v.EventGetMetaDatas()
+	if err != nil {
+		return nil, fmt.Errorf("failed get list: %w", err)
+	}
+	var keys []agentKey
+	for _, m := range *items {
+		if m.IsDeleted || m.DataType != models.SSH_KEY {
+			continue
+		}
+		key, err := a.v.EventGetSSHKey(m.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed get ssh key: %w", err)
+		}
+		public, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key.PublicKey))
+		if err != nil {
+			fmt.Fprintf(a.stderr, "предупреждение: ключ %q пропущен: %v\n", key.Title, err)
+			continue
+		}
+		keys = append(keys, agentKey{public: public, key: key})
+	}
+	return keys, nil
+}
+
+func (a *vaultAgent) List() ([]*agent.Key, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	keys, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*agent.Key, 0, len(keys))
+	for _, k := range keys {
+		comment := k.key.Comment
+		if comment == "" {
+			comment = k.key.Title
+		}
+		list = append(list, &agent.Key{Format: k.public.Type(), Blob: k.public.Marshal(), Comment: comment})
+	}
+	return list, nil
+}
+
+func (a *vaultAgent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags подписывает data ключом key. С confirm подпись выполняется, только если пользователь
+// разрешил ее на терминале агента.
+func (a *vaultAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (
+	*ssh.Signature, error,
+) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.passphrase != nil {
+		return nil, errAgentLocked
+	}
+	keys, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+	blob := key.Marshal()
+	for _, k := range keys {
+		if !bytes.Equal(k.public.Marshal(), blob) {
+			continue
+		}
+		if a.confirm != nil && !a.confirm(k.key.Title, k.key.Fingerprint) {
+			return nil, errAgentDenied
+		}
+		signer, err := sshkey.Signer(k.key.PrivateKey, k.key.Passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed read private key %q: %w", k.key.Title, err)
+		}
+		return sign(signer, data, flags)
+	}
+	return nil, errAgentNoKey
+}
+
+// sign подпись алгоритмом, который просит клиент: для RSA это rsa-sha2-256 или rsa-sha2-512.
+func sign(signer ssh.Signer, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	algorithm := ""
+	switch {
+	case flags&agent.SignatureFlagRsaSha256 != 0:
+		algorithm = ssh.KeyAlgoRSASHA256
+	case flags&agent.SignatureFlagRsaSha512 != 0:
+		algorithm = ssh.KeyAlgoRSASHA512
+	}
+	var signature *ssh.Signature
+	var err error
+	if algorithmSigner, ok := signer.(ssh.AlgorithmSigner); ok && algorithm != "" {
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, data, algorithm)
+	} else {
+		signature, err = signer.Sign(rand.Reader, data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed sign: %w", err)
+	}
+	return signature, nil
+}
+
+func (a *vaultAgent) Add(agent.AddedKey) error { return errAgentReadOnly }
+
+func (a *vaultAgent) Remove(ssh.PublicKey) error { return errAgentReadOnly }
+
+func (a *vaultAgent) RemoveAll() error { return errAgentReadOnly }
+
+// Lock блокирует агент паролем до Unlock с тем же паролем (ssh-add -x и ssh-add -X).
+func (a *vaultAgent) Lock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.passphrase != nil {
+		return errAgentLocked
+	}
+	a.passphrase = append([]byte{}, passphrase...)
+	return nil
+}
+
+func (a *vaultAgent) Unlock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.passphrase == nil || subtle.ConstantTimeCompare(a.passphrase, passphrase) != 1 {
+		return errAgentPassphrase
+	}
+	a.passphrase = nil
+	return nil
+}
+
+func (a *vaultAgent) Signers() ([]ssh.Signer, error) { return nil, errAgentReadOnly }
+
+func (a *vaultAgent) Extension(string, []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
+
+// sshAgent агент ssh-agent на сокете Unix с ключами записей ssh-key. Агент работает на переднем плане
+// до сигнала завершения и синхронизирует данные каждые -interval, адрес сокета выводится строкой
+// SSH_AUTH_SOCK, как у ssh-agent.
+func (c *cli) sshAgent(ctx context.Context, args []string) error {
+	fs := c.flags("ssh-agent")
+	socket := fs.String("socket", "", "путь сокета, пустой - новый каталог во временном каталоге")
+	confirm := fs.Bool("confirm", false, "спрашивать разрешение на каждую подпись на терминале")
+	interval := fs.Duration("interval", defaultWatchInterval, "период синхронизации")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("%w: -interval должен быть положительным", errUsage)
+	}
+
+	v, done, err := c.connect(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	path, cleanup, err := agentSocket(*socket)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed listen %s: %w", path, err)
+	}
+	defer func() { _ = listener.Close() }()
+	if err := os.Chmod(path, agentSocketMode); err != nil {
+		return fmt.Errorf("failed chmod socket: %w", err)
+	}
+
+	a := &vaultAgent{v: v, user: c.user, stderr: c.stderr}
+	if *confirm {
+		a.confirm = c.confirmSign
+	}
+	if c.json {
+		if err := c.printJSON(map[string]string{"socket": path}); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(c.stdout, "SSH_AUTH_SOCK=%s; export SSH_AUTH_SOCK;\n", path)
+	}
+
+	go c.serveAgent(listener, a)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		a.mu.Lock()
+		err := c.sync(v, false)
+		a.mu.Unlock()
+		if err != nil {
+			fmt.Fprintln(c.stderr, "ошибка:", err)
+		}
+	}
+}
+
+// serveAgent обслуживает подключения к агенту, пока listener не закрыт.
+func (c *cli) serveAgent(listener net.Listener, a *vaultAgent) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				fmt.Fprintln(c.stderr, "ошибка:", err)
+			}
+			return
+		}
+		go func() {
+			defer func() { _ = conn.Close() }()
+			if err := agent.ServeAgent(a, conn); err != nil && !errors.Is(err, io.EOF) {
+				fmt.Fprintln(c.stderr, "ошибка агента:", err)
+			}
+		}()
+	}
+}
+
+// agentSocket путь сокета агента. Пустой path - сокет в новом каталоге, доступном только владельцу,
+// каталог удаляется при выходе. Оставшийся от прошлого запуска сокет по path удаляется.
+func agentSocket(path string) (socket string, cleanup func(), err error) {
+	if path == "" {
+		dir, err := os.MkdirTemp("", "secret-keeper-agent-")
+		if err != nil {
+			return "", nil, fmt.Errorf("failed create socket dir: %w", err)
+		}
+		return filepath.Join(dir, "agent.sock"), func() { _ = os.RemoveAll(dir) }, nil
+	}
+	if stat, err := os.Lstat(path); err == nil {
+		if stat.Mode()&os.ModeSocket == 0 {
+			return "", nil, fmt.Errorf("%w: %s существует и это не сокет", errUsage, path)
+		}
+		if err := os.Remove(path); err != nil {
+			return "", nil, fmt.Errorf("failed remove old socket: %w", err)
+		}
+	}
+	return path, func() { _ = os.Remove(path) }, nil
+}
+
+// confirmSign спрашивает на терминале, разрешить ли подпись ключом.
+func (c *cli) confirmSign(title, fingerprint string) bool {
+	f, err := os.OpenFile(ttyPath, os.O_RDWR, 0)
+	if err != nil {
+		fmt.Fprintln(c.stderr, "ошибка: нет терминала для подтверждения, подпись отклонена")
+		return false
+	}
+	defer func() { _ = f.Close() }()
+	fmt.Fprintf(c.stderr, "Разрешить подпись ключом %q (%s)? [y/N] ", title, fingerprint)
+	answer, err := readLine(f)
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes" || answer == "д" || answer == "да"
+}
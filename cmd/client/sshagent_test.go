@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/pkg/sshkey"
+)
+
+// newAgentClient клиент агента a через канал в памяти.
+func newAgentClient(t *testing.T, a *vaultAgent) agent.ExtendedAgent {
+	t.Helper()
+	server, client := net.Pipe()
+	go func() {
+		_ = agent.ServeAgent(a, server)
+	}()
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return agent.NewClient(client)
+}
+
+func publicKey(t *testing.T, key *sshkey.Key) ssh.PublicKey {
+	t.Helper()
+	public, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key.PublicKey))
+	require.NoError(t, err)
+	return public
+}
+
+func Test_vaultAgent(t *testing.T) {
+	ed, err := sshkey.Generate(sshkey.Ed25519, 0, "ed@host", "secret")
+	require.NoError(t, err)
+	rsaKey, err := sshkey.Generate(sshkey.RSA, sshkey.MinRSABits, "", "")
+	require.NoError(t, err)
+	v := &fakeVault{
+		items: []models.FileMetaDataItem{
+			{ID: 1, Title: "ed", DataType: models.SSH_KEY},
+			{ID: 2, Title: "rsa", DataType: models.SSH_KEY},
+			{ID: 3, Title: "old", DataType: models.SSH_KEY, IsDeleted: true},
+			{ID: 4, Title: "password", DataType: models.PASSWORD},
+		},
+		sshKeys: map[int64]*models.SSHKey{
+			1: {Title: "ed", PrivateKey: ed.PrivateKey, PublicKey: ed.PublicKey, Comment: "ed@host", Passphrase: "secret"},
+			2: {Title: "rsa", PrivateKey: rsaKey.PrivateKey, PublicKey: rsaKey.PublicKey},
+		},
+	}
+	a := &vaultAgent{v: v, user: "user", stderr: io.Discard}
+	client := newAgentClient(t, a)
+	data := []byte("data")
+
+	keys, err := client.List()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "ed@host", keys[0].Comment)
+	assert.Equal(t, "rsa", keys[1].Comment)
+
+	edPublic := publicKey(t, ed)
+	signature, err := client.Sign(edPublic, data)
+	require.NoError(t, err)
+	assert.NoError(t, edPublic.Verify(data, signature))
+
+	rsaPublic := publicKey(t, rsaKey)
+	signature, err = client.SignWithFlags(rsaPublic, data, agent.SignatureFlagRsaSha512)
+	require.NoError(t, err)
+	assert.Equal(t, ssh.KeyAlgoRSASHA512, signature.Format)
+	assert.NoError(t, rsaPublic.Verify(data, signature))
+
+	other, err := sshkey.Generate(sshkey.Ed25519, 0, "", "")
+	require.NoError(t, err)
+	_, err = client.Sign(publicKey(t, other), data)
+	assert.Error(t, err)
+	assert.Error(t, client.RemoveAll())
+
+	t.Run("confirm", func(t *testing.T) {
+		var asked string
+		a.confirm = func(title, _ string) bool {
+			asked = title
+			return false
+		}
+		defer func() { a.confirm = nil }()
+		_, err := client.Sign(edPublic, data)
+		assert.Error(t, err)
+		assert.Equal(t, "ed", asked)
+	})
+
+	t.Run("agent lock", func(t *testing.T) {
+		require.NoError(t, client.Lock([]byte("pass")))
+		keys, err := client.List()
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+		_, err = client.Sign(edPublic, data)
+		assert.Error(t, err)
+		assert.Error(t, client.Unlock([]byte("wrong")))
+		require.NoError(t, client.Unlock([]byte("pass")))
+		_, err = client.Sign(edPublic, data)
+		assert.NoError(t, err)
+	})
+
+	t.Run("client locked", func(t *testing.T) {
+		v.locked = true
+		defer func() { v.locked = false }()
+		keys, err := client.List()
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+		_, err = client.Sign(edPublic, data)
+		assert.Error(t, err)
+	})
+}
@@ -1,16 +1,24 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
+	"regexp"
 
 	"go.uber.org/zap"
 
+	"github.com/playmixer/secret-keeper/internal/adapter/api/grpc"
 	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
 	"github.com/playmixer/secret-keeper/internal/adapter/logger"
 	"github.com/playmixer/secret-keeper/internal/adapter/storage/database"
 	"github.com/playmixer/secret-keeper/internal/core/config"
 	"github.com/playmixer/secret-keeper/internal/core/keeper"
+	"github.com/playmixer/secret-keeper/pkg/passhash"
+	"github.com/playmixer/secret-keeper/pkg/policy"
+	"github.com/playmixer/secret-keeper/pkg/ratelimit"
+	"github.com/playmixer/secret-keeper/pkg/tlsconf"
 )
 
 func main() {
@@ -41,7 +49,49 @@ func run() error {
 		return fmt.Errorf("failed initialize storage: %w", err)
 	}
 
-	keep, err := keeper.New(store, keeper.SetEncryptKey(cfg.EncryptKey))
+	hasher, err := passhash.New(cfg.PasswordHash, passhash.Argon2Params{
+		Memory:  cfg.Argon2Memory,
+		Time:    cfg.Argon2Time,
+		Threads: cfg.Argon2Threads,
+	}, cfg.BcryptCost)
+	if err != nil {
+		return fmt.Errorf("failed initialize password hasher: %w", err)
+	}
+
+	pol, err := newPolicy(cfg)
+	if err != nil {
+		return fmt.Errorf("failed initialize registration policy: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var tlsConfig *tls.Config
+	bindDevice := false
+	if cfg.Rest.SSLEnable {
+		reloader, err := newTLSReloader(cfg.Rest, lgr)
+		if err != nil {
+			return fmt.Errorf("failed initialize tls: %w", err)
+		}
+		go reloader.Watch(ctx, tlsconf.DefaultReloadInterval)
+		tlsConfig = reloader.Config()
+		bindDevice = reloader.ClientAuth()
+	}
+
+	limiter := ratelimit.NewMemory()
+	keep, err := keeper.New(
+		store,
+		keeper.SetEncryptKey(cfg.EncryptKey),
+		keeper.SetLimiter(limiter),
+		keeper.SetLoginLimit(ratelimit.PerMinute(cfg.LoginRateLimit)),
+		keeper.SetLockoutThreshold(cfg.LoginLockoutThreshold),
+		keeper.SetLegacyPasswordAuth(cfg.LegacyPasswordAuth),
+		keeper.SetClientPasswordCheck(cfg.ClientPasswordCheck),
+		keeper.SetPasswordHasher(hasher),
+		keeper.SetPolicy(pol),
+		keeper.SetRegistrationMode(cfg.RegistrationMode),
+		keeper.SetAuditLogger(lgr.Named("audit")),
+	)
 	if err != nil {
 		return fmt.Errorf("failed initialize keeper: %w", err)
 	}
@@ -52,14 +102,91 @@ func run() error {
 		rest.SetSecretKey(cfg.SecretKey),
 		rest.SetLogger(lgr),
 		rest.SetSSLEnable(cfg.Rest.SSLEnable),
+		rest.SetTLSConfig(tlsConfig),
+		rest.SetDeviceBinding(bindDevice),
+		rest.SetLimiter(limiter),
 	)
 	if err != nil {
 		return fmt.Errorf("failed initialize server: %w", err)
 	}
 
-	if err := srv.Run(); err != nil {
-		return fmt.Errorf("failed run server: %w", err)
+	errCh := make(chan error, 2)
+	go func() {
+		if err := srv.Run(); err != nil {
+			errCh <- fmt.Errorf("failed run server: %w", err)
+		}
+	}()
+
+	if cfg.GRPC.Address != "" {
+		grpcSrv, err := grpc.New(
+			keep,
+			grpc.SetConfig(*cfg.GRPC),
+			grpc.SetSecretKey(cfg.SecretKey),
+			grpc.SetLogger(lgr),
+			grpc.SetSSLEnable(cfg.Rest.SSLEnable),
+			grpc.SetTLSConfig(tlsConfig),
+			grpc.SetDeviceBinding(bindDevice),
+		)
+		if err != nil {
+			return fmt.Errorf("failed initialize grpc server: %w", err)
+		}
+		defer grpcSrv.Stop()
+
+		go func() {
+			lgr.Info("grpc server started", zap.String("address", cfg.GRPC.Address))
+			if err := grpcSrv.Run(); err != nil {
+				errCh <- fmt.Errorf("failed run grpc server: %w", err)
+			}
+		}()
 	}
 
-	return nil
+	return <-errCh
+}
+
+// newPolicy правила регистрации из конфига, список запрещенных паролей читается из файла.
+func newPolicy(cfg *config.Config) (*policy.Policy, error) {
+	pol := &policy.Policy{
+		LoginMinLength:      cfg.LoginMinLength,
+		LoginMaxLength:      cfg.LoginMaxLength,
+		PasswordMinLength:   cfg.PasswordMinLength,
+		PasswordMinStrength: cfg.PasswordMinStrength,
+	}
+	if cfg.LoginPattern != "" {
+		pattern, err := regexp.Compile(cfg.LoginPattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed compile login pattern: %w", err)
+		}
+		pol.LoginPattern = pattern
+	}
+	if cfg.PasswordDenylist != "" {
+		denylist, err := policy.LoadDenylist(cfg.PasswordDenylist)
+		if err != nil {
+			return nil, err
+		}
+		pol.SetDenylist(denylist)
+	}
+	return pol, nil
+}
+
+// newTLSReloader сертификат сервера и настройки TLS из конфига REST, общие для REST и gRPC.
+func newTLSReloader(cfg *rest.Config, lgr *zap.Logger) (*tlsconf.Reloader, error) {
+	version, err := tlsconf.ParseVersion(cfg.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+	suites, err := tlsconf.ParseCipherSuites(cfg.TLSCipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	reloader, err := tlsconf.New(tlsconf.Options{
+		CertFile:     cfg.CertFile,
+		KeyFile:      cfg.KeyFile,
+		ClientCAFile: cfg.TLSClientCA,
+		MinVersion:   version,
+		CipherSuites: suites,
+	}, tlsconf.SetLogger(lgr))
+	if err != nil {
+		return nil, fmt.Errorf("failed load tls certificate: %w", err)
+	}
+	return reloader, nil
 }
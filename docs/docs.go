@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/auth/login": {
+        "/admin/invites": {
             "post": {
-                "description": "login user",
+                "description": "выпуск одноразовых кодов приглашения для регистрации. Коды отдаются один раз.\nРучка доступна, только если на сервере задан ADMIN_TOKEN.",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,48 +25,64 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Login user",
+                "summary": "Create invites",
                 "parameters": [
                     {
-                        "description": "auth",
-                        "name": "auth",
+                        "type": "string",
+                        "description": "Bearer ADMIN_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "число и срок действия",
+                        "name": "invites",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/rest.tHandlerLoginRequest"
+                            "$ref": "#/definitions/rest.tHandlerCreateInvitesRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "пользователь успешно аутентифицирован",
+                    "201": {
+                        "description": "коды приглашения",
                         "schema": {
-                            "$ref": "#/definitions/rest.tHandlerLoginResponse"
+                            "$ref": "#/definitions/rest.tHandlerCreateInvitesResponse"
                         }
                     },
                     "400": {
                         "description": "неверный формат запроса",
                         "schema": {
-                            "$ref": "#/definitions/rest.tResultErrorResponse"
+                            "$ref": "#/definitions/rest.TProblem"
                         }
                     },
                     "401": {
-                        "description": "логин или пароль не верный",
+                        "description": "ошибка авторизации",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "429": {
+                        "description": "слишком много запросов",
                         "schema": {
-                            "$ref": "#/definitions/rest.tResultErrorResponse"
+                            "$ref": "#/definitions/rest.TProblem"
                         }
                     },
                     "500": {
-                        "description": "внутренняя ошибка сервера"
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
                     }
                 }
             }
         },
-        "/auth/registration": {
+        "/auth/login": {
             "post": {
-                "description": "registration user",
+                "description": "login user",
                 "consumes": [
                     "application/json"
                 ],
@@ -76,46 +92,81 @@ const docTemplate = `{
                 "tags": [
                     "auth"
                 ],
-                "summary": "Register user",
+                "summary": "Login user",
                 "parameters": [
                     {
-                        "description": "registration",
-                        "name": "registration",
+                        "description": "auth",
+                        "name": "auth",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/rest.tHandlerRegistrationRequest"
+                            "$ref": "#/definitions/rest.tHandlerLoginRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "пользователь успешно зарегистрирован",
+                    "200": {
+                        "description": "пользователь успешно аутентифицирован",
                         "schema": {
-                            "$ref": "#/definitions/rest.tHandlerRegistrationResponse"
+                            "$ref": "#/definitions/rest.tHandlerLoginResponse"
                         }
                     },
                     "400": {
                         "description": "неверный формат запроса",
                         "schema": {
-                            "$ref": "#/definitions/rest.tResultErrorResponse"
+                            "$ref": "#/definitions/rest.TProblem"
                         }
                     },
-                    "409": {
-                        "description": "логин уже занят",
+                    "401": {
+                        "description": "логин или пароль не верный",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "429": {
+                        "description": "слишком много попыток или логин заблокирован",
                         "schema": {
-                            "$ref": "#/definitions/rest.tResultErrorResponse"
+                            "$ref": "#/definitions/rest.TProblem"
                         }
                     },
                     "500": {
-                        "description": "внутренняя ошибка сервера"
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
                     }
                 }
             }
         },
-        "/user/data": {
+        "/auth/policy": {
             "get": {
-                "description": "получить данные пользователя",
+                "description": "режим регистрации и правила для логина и пароля. Пароль, заданный верификатором SRP,\nсервер не видит, поэтому клиент проверяет его по этим правилам сам.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Registration policy",
+                "responses": {
+                    "200": {
+                        "description": "правила регистрации",
+                        "schema": {
+                            "$ref": "#/definitions/rest.tHandlerPolicyResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "слишком много запросов",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/recover": {
+            "post": {
+                "description": "новый пароль по ключу восстановления. Ключ одноразовый: в ответе выдается новый ключ,\nвсе выданные ранее токены отзываются. Доли ключа (схема Шамира) объединяет клиент.",
                 "consumes": [
                     "application/json"
                 ],
@@ -123,35 +174,57 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "auth"
                 ],
-                "summary": "Get Datas",
+                "summary": "Recover access",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "authorization",
-                        "name": "Authorization",
-                        "in": "header",
-                        "required": true
+                        "description": "логин, ключ восстановления и новый пароль",
+                        "name": "recover",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.tHandlerRecoverRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "данные получены",
+                        "description": "пароль изменен",
                         "schema": {
-                            "$ref": "#/definitions/rest.tHalderGetDatasResponse"
+                            "$ref": "#/definitions/rest.tHandlerRecoverResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "неверный формат запроса или недопустимый пароль",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
                         }
                     },
                     "401": {
-                        "description": "ошибка авторизации"
+                        "description": "логин или ключ восстановления не верный",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "429": {
+                        "description": "слишком много попыток или логин заблокирован",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
                     },
                     "500": {
-                        "description": "внутренняя ошибка сервера"
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
                     }
                 }
-            },
+            }
+        },
+        "/auth/registration": {
             "post": {
-                "description": "создать секрет",
+                "description": "registration user",
                 "consumes": [
                     "application/json"
                 ],
@@ -159,52 +232,63 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "auth"
                 ],
-                "summary": "Create Data",
+                "summary": "Register user",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "authorization",
-                        "name": "Authorization",
-                        "in": "header",
-                        "required": true
-                    },
-                    {
-                        "description": "данные",
-                        "name": "data",
+                        "description": "registration",
+                        "name": "registration",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/rest.THandlerNewDataRequest"
+                            "$ref": "#/definitions/rest.tHandlerRegistrationRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "данные добавлены",
+                        "description": "пользователь успешно зарегистрирован",
                         "schema": {
-                            "$ref": "#/definitions/rest.THandlerNewDataResponse"
+                            "$ref": "#/definitions/rest.tHandlerRegistrationResponse"
                         }
                     },
                     "400": {
-                        "description": "ошибка запроса",
+                        "description": "неверный формат запроса",
                         "schema": {
-                            "$ref": "#/definitions/rest.tResultErrorResponse"
+                            "$ref": "#/definitions/rest.TProblem"
                         }
                     },
-                    "401": {
-                        "description": "ошибка авторизации"
+                    "403": {
+                        "description": "регистрация закрыта или приглашение не верное",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "409": {
+                        "description": "логин уже занят",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "429": {
+                        "description": "слишком много запросов",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
                     },
                     "500": {
-                        "description": "внутренняя ошибка сервера"
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
                     }
                 }
             }
         },
-        "/user/data/{id}": {
-            "get": {
-                "description": "получить данные",
+        "/auth/srp/init": {
+            "post": {
+                "description": "начало входа по SRP-6a (RFC 5054, группа 2048 бит, SHA-256): пароль серверу не передается.\nДля неизвестного логина ответ неотличим от настоящего, вход не пройдет на втором шаге.",
                 "consumes": [
                     "application/json"
                 ],
@@ -212,54 +296,51 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "auth"
                 ],
-                "summary": "Get Data",
+                "summary": "SRP login, step 1",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "authorization",
-                        "name": "Authorization",
-                        "in": "header",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "логин и открытый ключ клиента A",
+                        "name": "init",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.tHandlerSRPInitRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "данные получены",
+                        "description": "сессия, соль и открытый ключ сервера B",
                         "schema": {
-                            "$ref": "#/definitions/rest.THandlerGetDataResponse"
+                            "$ref": "#/definitions/rest.tHandlerSRPInitResponse"
                         }
                     },
-                    "204": {
-                        "description": "нет данных",
+                    "400": {
+                        "description": "неверный формат запроса",
                         "schema": {
-                            "$ref": "#/definitions/rest.tResultErrorResponse"
+                            "$ref": "#/definitions/rest.TProblem"
                         }
                     },
-                    "400": {
-                        "description": "ошибка запроса",
+                    "429": {
+                        "description": "слишком много попыток или логин заблокирован",
                         "schema": {
-                            "$ref": "#/definitions/rest.tResultErrorResponse"
+                            "$ref": "#/definitions/rest.TProblem"
                         }
                     },
-                    "401": {
-                        "description": "ошибка авторизации"
-                    },
                     "500": {
-                        "description": "внутренняя ошибка сервера"
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
                     }
                 }
-            },
-            "put": {
-                "description": "обновить секрет",
+            }
+        },
+        "/auth/srp/verify": {
+            "post": {
+                "description": "проверка доказательства клиента M1. В ответе токен и доказательство сервера M2:\nклиент проверяет M2, чтобы убедиться, что сервер знает его верификатор.",
                 "consumes": [
                     "application/json"
                 ],
@@ -267,57 +348,57 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "auth"
                 ],
-                "summary": "Update Data",
+                "summary": "SRP login, step 2",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "authorization",
-                        "name": "Authorization",
-                        "in": "header",
-                        "required": true
-                    },
-                    {
-                        "description": "authorization",
-                        "name": "card",
+                        "description": "сессия и доказательство клиента M1",
+                        "name": "verify",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/rest.THandlerUpdDataRequest"
+                            "$ref": "#/definitions/rest.tHandlerSRPVerifyRequest"
                         }
-                    },
-                    {
-                        "type": "string",
-                        "description": "data id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "данные получены",
+                        "description": "пользователь успешно аутентифицирован",
                         "schema": {
-                            "$ref": "#/definitions/rest.THandlerUpdDataResponse"
+                            "$ref": "#/definitions/rest.tHandlerSRPVerifyResponse"
                         }
                     },
                     "400": {
-                        "description": "ошибка запроса",
+                        "description": "неверный формат запроса",
                         "schema": {
-                            "$ref": "#/definitions/rest.tResultErrorResponse"
+                            "$ref": "#/definitions/rest.TProblem"
                         }
                     },
                     "401": {
-                        "description": "ошибка авторизации"
+                        "description": "логин или пароль не верный",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "429": {
+                        "description": "слишком много попыток или логин заблокирован",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
                     },
                     "500": {
-                        "description": "внутренняя ошибка сервера"
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
                     }
                 }
-            },
+            }
+        },
+        "/user": {
             "delete": {
-                "description": "удаляем данные",
+                "description": "безвозвратно удаляет учетную запись и все секреты пользователя, нужно повторно ввести пароль.\nВыданные пользователю токены перестают действовать.",
                 "consumes": [
                     "application/json"
                 ],
@@ -327,7 +408,7 @@ const docTemplate = `{
                 "tags": [
                     "user"
                 ],
-                "summary": "Delete Data",
+                "summary": "Delete account",
                 "parameters": [
                     {
                         "type": "string",
@@ -337,44 +418,58 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "текущий пароль",
+                        "name": "password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.tHandlerDeleteAccountRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "данные удалены",
+                        "description": "учетная запись удалена",
                         "schema": {
-                            "$ref": "#/definitions/rest.THandlerDelDataResponse"
+                            "$ref": "#/definitions/rest.tResultResponse"
                         }
                     },
-                    "204": {
-                        "description": "нет данных",
+                    "400": {
+                        "description": "неверный формат запроса",
                         "schema": {
-                            "$ref": "#/definitions/rest.tResultErrorResponse"
+                            "$ref": "#/definitions/rest.TProblem"
                         }
                     },
-                    "400": {
-                        "description": "ошибка запроса",
+                    "401": {
+                        "description": "ошибка авторизации",
                         "schema": {
-                            "$ref": "#/definitions/rest.tResultErrorResponse"
+                            "$ref": "#/definitions/rest.TProblem"
                         }
                     },
-                    "401": {
-                        "description": "ошибка авторизации"
+                    "403": {
+                        "description": "пароль не верный",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "429": {
+                        "description": "слишком много попыток",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
                     },
                     "500": {
-                        "description": "внутренняя ошибка сервера"
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
                     }
                 }
             }
         },
-        "/user/info": {
-            "get": {
-                "description": "login user",
+        "/user/batch": {
+            "post": {
+                "description": "применить пакет операций создания/обновления/удаления в одной транзакции",
                 "consumes": [
                     "application/json"
                 ],
@@ -384,7 +479,7 @@ const docTemplate = `{
                 "tags": [
                     "user"
                 ],
-                "summary": "Login user",
+                "summary": "Batch Data",
                 "parameters": [
                     {
                         "type": "string",
@@ -392,38 +487,727 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "description": "операции",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.THandlerBatchRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "пользователь успешно аутентифицирован",
+                        "description": "операции применены, результат по каждой операции",
                         "schema": {
-                            "$ref": "#/definitions/rest.tHandlerLoginResponse"
+                            "$ref": "#/definitions/rest.THandlerBatchResponse"
                         }
                     },
-                    "500": {
-                        "description": "внутренняя ошибка сервера"
-                    }
-                }
-            }
-        }
-    },
-    "definitions": {
+                    "400": {
+                        "description": "ошибка запроса",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "ошибка авторизации",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "500": {
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/data": {
+            "get": {
+                "description": "получить мета данные пользователя.\nБез limit возвращается весь список с учетом фильтров, с limit - страница и next_cursor,\nесли есть следующая. Курсор действует только с той же сортировкой, в которой выдан.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get Datas",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "размер страницы, до 1000",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor предыдущей страницы",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "CARD",
+                            "PASSWORD",
+                            "TEXT",
+                            "BINARY",
+                            "SSH_KEY"
+                        ],
+                        "type": "string",
+                        "description": "тип данных",
+                        "name": "data_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "только удаленные (true) или только живые (false)",
+                        "name": "deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "update_dt не раньше, unix время",
+                        "name": "updated_since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "папка",
+                        "name": "folder",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "title",
+                            "update_dt"
+                        ],
+                        "type": "string",
+                        "description": "поле сортировки",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "направление сортировки",
+                        "name": "order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "данные получены",
+                        "schema": {
+                            "$ref": "#/definitions/rest.tHalderGetDatasResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "неверные параметры запроса",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "ошибка авторизации",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "500": {
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "создать секрет",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Create Data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "данные",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.THandlerNewDataRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "данные добавлены",
+                        "schema": {
+                            "$ref": "#/definitions/rest.THandlerNewDataResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "ошибка запроса",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "ошибка авторизации",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "500": {
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/data/{id}": {
+            "get": {
+                "description": "получить данные",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get Data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "данные получены",
+                        "schema": {
+                            "$ref": "#/definitions/rest.THandlerGetDataResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "ошибка запроса",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "ошибка авторизации",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "404": {
+                        "description": "данные не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "500": {
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "обновить секрет",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Update Data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "authorization",
+                        "name": "card",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.THandlerUpdDataRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "data id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "данные получены",
+                        "schema": {
+                            "$ref": "#/definitions/rest.THandlerUpdDataResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "ошибка запроса",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "ошибка авторизации",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "500": {
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "удаляем данные",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Delete Data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "данные удалены",
+                        "schema": {
+                            "$ref": "#/definitions/rest.THandlerDelDataResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "ошибка запроса",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "ошибка авторизации",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "404": {
+                        "description": "данные не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "500": {
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/events": {
+            "get": {
+                "description": "поток событий (Server-Sent Events) об изменении ревизии хранилища пользователя.\nСразу после подключения отправляется текущая ревизия, далее событие ` + "`" + `revision` + "`" + ` при каждом изменении.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "поток событий",
+                        "schema": {
+                            "$ref": "#/definitions/rest.tEventRevision"
+                        }
+                    },
+                    "401": {
+                        "description": "ошибка авторизации",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "500": {
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/export": {
+            "get": {
+                "description": "зашифрованный архив всех секретов и мета данных пользователя (AES-256-GCM, gzip JSON).\nКлюч к архиву отдается один раз в заголовке X-Export-Key и на сервере не сохраняется.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Export data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "архив, ключ в заголовке X-Export-Key",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "ошибка авторизации",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "500": {
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/password": {
+            "post": {
+                "description": "смена пароля. Все выданные ранее токены отзываются, текущему клиенту возвращается новый токен.\nСекреты шифруются ключом сервера, поэтому перешифровывать хранилище не требуется.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Change password",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "текущий и новый пароль",
+                        "name": "password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.tHandlerChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "пароль изменен",
+                        "schema": {
+                            "$ref": "#/definitions/rest.tHandlerChangePasswordResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "неверный формат запроса или недопустимый пароль",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "ошибка авторизации",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "403": {
+                        "description": "текущий пароль не верный",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "429": {
+                        "description": "слишком много попыток",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    },
+                    "500": {
+                        "description": "внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TProblem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.BatchAction": {
+            "type": "string",
+            "enum": [
+                "create",
+                "update",
+                "delete"
+            ],
+            "x-enum-varnames": [
+                "BatchCreate",
+                "BatchUpdate",
+                "BatchDelete"
+            ]
+        },
         "models.DataType": {
             "type": "string",
             "enum": [
                 "CARD",
                 "PASSWORD",
                 "TEXT",
-                "BINARY"
+                "BINARY",
+                "SSH_KEY"
             ],
             "x-enum-varnames": [
                 "CARD",
                 "PASSWORD",
                 "TEXT",
-                "BINARY"
+                "BINARY",
+                "SSH_KEY"
             ]
         },
+        "rest.ErrorCode": {
+            "type": "string",
+            "enum": [
+                "bad_request",
+                "unauthorized",
+                "invalid_credentials",
+                "login_invalid",
+                "recovery_invalid",
+                "password_invalid",
+                "password_incorrect",
+                "login_taken",
+                "secret_not_found",
+                "batch_invalid",
+                "batch_action_unknown",
+                "filter_invalid",
+                "rate_limited",
+                "account_locked",
+                "legacy_auth_disabled",
+                "password_weak",
+                "password_unchecked",
+                "registration_closed",
+                "invite_invalid",
+                "internal_error"
+            ],
+            "x-enum-varnames": [
+                "CodeBadRequest",
+                "CodeUnauthorized",
+                "CodeInvalidCredentials",
+                "CodeLoginInvalid",
+                "CodeRecoveryInvalid",
+                "CodePasswordInvalid",
+                "CodePasswordIncorrect",
+                "CodeLoginTaken",
+                "CodeSecretNotFound",
+                "CodeBatchInvalid",
+                "CodeBatchActionUnknown",
+                "CodeFilterInvalid",
+                "CodeRateLimited",
+                "CodeAccountLocked",
+                "CodeLegacyAuthDisabled",
+                "CodePasswordWeak",
+                "CodePasswordUnchecked",
+                "CodeRegistrationClosed",
+                "CodeInviteInvalid",
+                "CodeInternal"
+            ]
+        },
+        "rest.TBatchOperation": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/models.BatchAction"
+                },
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "data_type": {
+                    "$ref": "#/definitions/models.DataType"
+                },
+                "folder": {
+                    "description": "Folder папка секрета: без поля при обновлении папка не меняется, пустая строка - перенести секрет из папки.",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "description": "Key ключ идемпотентности создания, повтор с тем же ключом не создает второй секрет.",
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "update_dt": {
+                    "type": "integer"
+                }
+            }
+        },
+        "rest.TBatchResult": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/models.BatchAction"
+                },
+                "code": {
+                    "$ref": "#/definitions/rest.ErrorCode"
+                },
+                "data_type": {
+                    "$ref": "#/definitions/models.DataType"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "boolean"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "update_dt": {
+                    "type": "integer"
+                }
+            }
+        },
+        "rest.THandlerBatchRequest": {
+            "type": "object",
+            "properties": {
+                "operations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rest.TBatchOperation"
+                    }
+                }
+            }
+        },
+        "rest.THandlerBatchResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rest.TBatchResult"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
         "rest.THandlerDelDataResponse": {
             "type": "object",
             "properties": {
@@ -461,6 +1245,9 @@ const docTemplate = `{
                 "data_type": {
                     "$ref": "#/definitions/models.DataType"
                 },
+                "folder": {
+                    "type": "string"
+                },
                 "title": {
                     "type": "string"
                 },
@@ -495,6 +1282,10 @@ const docTemplate = `{
                 "data_type": {
                     "$ref": "#/definitions/models.DataType"
                 },
+                "folder": {
+                    "description": "Folder папка секрета: без поля папка не меняется, пустая строка - перенести секрет из папки.",
+                    "type": "string"
+                },
                 "is_deleted": {
                     "type": "boolean"
                 },
@@ -517,6 +1308,38 @@ const docTemplate = `{
                 }
             }
         },
+        "rest.TProblem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "$ref": "#/definitions/rest.ErrorCode"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "retry_after": {
+                    "description": "RetryAfter через сколько секунд можно повторить запрос, дублирует заголовок Retry-After.",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "rest.tEventRevision": {
+            "type": "object",
+            "properties": {
+                "revision": {
+                    "type": "integer"
+                }
+            }
+        },
         "rest.tGetData": {
             "type": "object",
             "properties": {
@@ -555,17 +1378,109 @@ const docTemplate = `{
                 "message": {
                     "type": "string"
                 },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "rest.tHandlerChangePasswordRequest": {
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "type": "string"
+                },
+                "old_password": {
+                    "type": "string"
+                },
+                "salt": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "srp_proof": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "srp_session": {
+                    "type": "string"
+                },
+                "verifier": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "rest.tHandlerChangePasswordResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
                 "status": {
                     "type": "boolean"
                 }
             }
         },
+        "rest.tHandlerCreateInvitesRequest": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "ttl_hours": {
+                    "description": "TTLHours срок действия приглашений в часах.",
+                    "type": "integer"
+                }
+            }
+        },
+        "rest.tHandlerCreateInvitesResponse": {
+            "type": "object",
+            "properties": {
+                "invites": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "rest.tHandlerDeleteAccountRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "srp_proof": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "srp_session": {
+                    "type": "string"
+                }
+            }
+        },
         "rest.tHandlerGetData": {
             "type": "object",
             "properties": {
                 "data_type": {
                     "$ref": "#/definitions/models.DataType"
                 },
+                "folder": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -605,20 +1520,179 @@ const docTemplate = `{
                 }
             }
         },
+        "rest.tHandlerPolicyResponse": {
+            "type": "object",
+            "properties": {
+                "login_max_length": {
+                    "type": "integer"
+                },
+                "login_min_length": {
+                    "type": "integer"
+                },
+                "login_pattern": {
+                    "type": "string"
+                },
+                "password_min_length": {
+                    "type": "integer"
+                },
+                "password_min_strength": {
+                    "type": "integer"
+                },
+                "registration_mode": {
+                    "description": "RegistrationMode open, invite или disabled.",
+                    "type": "string"
+                }
+            }
+        },
+        "rest.tHandlerRecoverRequest": {
+            "type": "object",
+            "properties": {
+                "login": {
+                    "type": "string"
+                },
+                "new_password": {
+                    "type": "string"
+                },
+                "recovery_key": {
+                    "type": "string"
+                },
+                "salt": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "verifier": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "rest.tHandlerRecoverResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "recovery_key": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
         "rest.tHandlerRegistrationRequest": {
             "type": "object",
             "properties": {
+                "invite": {
+                    "description": "Invite код приглашения, нужен в режиме регистрации по приглашениям.",
+                    "type": "string"
+                },
                 "login": {
                     "type": "string"
                 },
                 "password": {
                     "type": "string"
+                },
+                "recovery": {
+                    "description": "Recovery создать ключ восстановления доступа.",
+                    "type": "boolean"
+                },
+                "salt": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "verifier": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 }
             }
         },
         "rest.tHandlerRegistrationResponse": {
             "type": "object",
             "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "recovery_key": {
+                    "description": "RecoveryKey ключ восстановления, отдается только в этом ответе.",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "rest.tHandlerSRPInitRequest": {
+            "type": "object",
+            "properties": {
+                "a": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "login": {
+                    "type": "string"
+                }
+            }
+        },
+        "rest.tHandlerSRPInitResponse": {
+            "type": "object",
+            "properties": {
+                "b": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "salt": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "session": {
+                    "type": "string"
+                }
+            }
+        },
+        "rest.tHandlerSRPVerifyRequest": {
+            "type": "object",
+            "properties": {
+                "m1": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "session": {
+                    "type": "string"
+                }
+            }
+        },
+        "rest.tHandlerSRPVerifyResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "m2": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
                 "message": {
                     "type": "string"
                 },
@@ -644,10 +1718,10 @@ const docTemplate = `{
                 }
             }
         },
-        "rest.tResultErrorResponse": {
+        "rest.tResultResponse": {
             "type": "object",
             "properties": {
-                "error": {
+                "message": {
                     "type": "string"
                 },
                 "status": {
@@ -665,7 +1739,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/api/v0",
 	Schemes:          []string{},
 	Title:            "«GophKeeper»",
-	Description:      "",
+	Description:      "Ошибки отдаются в формате problem details (application/problem+json), поле code - стабильный код ошибки.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
@@ -0,0 +1,6 @@
+package grpc
+
+// Config конфиг gRPC сервера.
+type Config struct {
+	Address string `env:"GRPC_ADDRESS"`
+}
@@ -0,0 +1,255 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/grpc/pb"
+	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
+	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
+	"github.com/playmixer/secret-keeper/pkg/jwt"
+)
+
+type ctxKey string
+
+const (
+	ctxKeyUserID ctxKey = "user_id"
+
+	// claimDevice отпечаток сертификата клиента, которому выдан токен.
+	claimDevice = "device"
+
+	chunkSize            = 64 * 1024
+	maxMessageSize       = 64 * 1024 * 1024
+	defaultMaxUploadSize = 8 * 1024 * 1024
+)
+
+var (
+	errUnauthenticated = status.Error(codes.Unauthenticated, "authorization required")
+
+	publicMethods = map[string]bool{
+		pb.Keeper_Registration_FullMethodName: true,
+		pb.Keeper_Policy_FullMethodName:       true,
+		pb.Keeper_Login_FullMethodName:        true,
+		pb.Keeper_Recover_FullMethodName:      true,
+		pb.Keeper_SRPInit_FullMethodName:      true,
+		pb.Keeper_SRPVerify_FullMethodName:    true,
+	}
+)
+
+// Server - gRPC сервер, использует ту же реализацию Keeper что и REST сервер.
+type Server struct {
+	pb.UnimplementedKeeperServer
+	srv           *gogrpc.Server
+	tlsConfig     *tls.Config
+	log           *zap.Logger
+	keeper        rest.Keeper
+	address       string
+	secretKey     []byte
+	maxUploadSize int
+	sslEnable     bool
+	// bindDevice токен действует только с тем сертификатом клиента, с которым он выдан.
+	bindDevice bool
+}
+
+type option func(*Server)
+
+// SetConfig устанавливаем конфигурации сервера.
+func SetConfig(cfg Config) option {
+	return func(s *Server) {
+		s.address = cfg.Address
+	}
+}
+
+func SetLogger(log *zap.Logger) option {
+	return func(s *Server) {
+		s.log = log
+	}
+}
+
+func SetSecretKey(secret string) option {
+	return func(s *Server) {
+		s.secretKey = []byte(secret)
+	}
+}
+
+func SetSSLEnable(enable bool) option {
+	return func(s *Server) {
+		s.sslEnable = enable
+	}
+}
+
+func SetMaxUploadSize(size int) option {
+	return func(s *Server) {
+		s.maxUploadSize = size
+	}
+}
+
+// SetTLSConfig настройки TLS, вместо чтения сертификата из файлов.
+func SetTLSConfig(cfg *tls.Config) option {
+	return func(s *Server) {
+		s.tlsConfig = cfg
+	}
+}
+
+// SetDeviceBinding привязывает токены к сертификату клиента, как в REST сервере.
+func SetDeviceBinding(enable bool) option {
+	return func(s *Server) {
+		s.bindDevice = enable
+	}
+}
+
+// New создаём gRPC сервер.
+func New(keeper rest.Keeper, options ...option) (*Server, error) {
+	s := &Server{
+		keeper:        keeper,
+		log:           zap.NewNop(),
+		maxUploadSize: defaultMaxUploadSize,
+	}
+
+	for _, opt := range options {
+		opt(s)
+	}
+
+	opts := []gogrpc.ServerOption{
+		// пакет операций может содержать несколько файлов.
+		gogrpc.MaxRecvMsgSize(maxMessageSize),
+		gogrpc.ChainUnaryInterceptor(s.unaryAuthorization),
+		gogrpc.ChainStreamInterceptor(s.streamAuthorization),
+	}
+	switch {
+	case s.sslEnable && s.tlsConfig != nil:
+		opts = append(opts, gogrpc.Creds(credentials.NewTLS(s.tlsConfig)))
+	case s.sslEnable:
+		creds, err := credentials.NewServerTLSFromFile("./cert/gophkeeper.crt", "./cert/gophkeeper.key")
+		if err != nil {
+			return nil, fmt.Errorf("failed load tls credentials: %w", err)
+		}
+		opts = append(opts, gogrpc.Creds(creds))
+	}
+	s.srv = gogrpc.NewServer(opts...)
+	pb.RegisterKeeperServer(s.srv, s)
+
+	return s, nil
+}
+
+// Run - старт сервера.
+func (s *Server) Run() error {
+	listen, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed listen `%s`: %w", s.address, err)
+	}
+	return s.Serve(listen)
+}
+
+// Serve - обслуживание запросов на готовом listener.
+func (s *Server) Serve(listen net.Listener) error {
+	if err := s.srv.Serve(listen); err != nil {
+		return fmt.Errorf("server has failed: %w", err)
+	}
+	return nil
+}
+
+// Stop - остановка сервера, дожидается завершения активных вызовов.
+func (s *Server) Stop() {
+	s.srv.GracefulStop()
+}
+
+func (s *Server) unaryAuthorization(
+	ctx context.Context, req any, info *gogrpc.UnaryServerInfo, handler gogrpc.UnaryHandler,
+) (any, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	ctx, err := s.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+type authorizedStream struct {
+	gogrpc.ServerStream
+	ctx context.Context
+}
+
+func (a *authorizedStream) Context() context.Context {
+	return a.ctx
+}
+
+func (s *Server) streamAuthorization(
+	srv any, ss gogrpc.ServerStream, info *gogrpc.StreamServerInfo, handler gogrpc.StreamHandler,
+) error {
+	if publicMethods[info.FullMethod] {
+		return handler(srv, ss)
+	}
+	ctx, err := s.authorize(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authorizedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authorize проверяет bearer токен из метаданных и кладет user_id в контекст.
+func (s *Server) authorize(ctx context.Context) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, errUnauthenticated
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, errUnauthenticated
+	}
+
+	token := strings.TrimPrefix(values[0], "Bearer ")
+	params, err := jwt.New(s.secretKey).GetParams(token)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+	userID, err := strconv.Atoi(params["user_id"])
+	if err != nil || userID <= 0 {
+		return nil, errUnauthenticated
+	}
+	var epoch int64
+	if v, ok := params["session_epoch"]; ok {
+		if epoch, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, errUnauthenticated
+		}
+	}
+	if s.bindDevice {
+		device := peerDevice(ctx)
+		if device == "" || subtle.ConstantTimeCompare([]byte(params[claimDevice]), []byte(device)) != 1 {
+			s.log.Warn("token used from another device", zap.Int("user_id", userID), zap.String("ip", peerIP(ctx)))
+			return nil, errUnauthenticated
+		}
+	}
+	if err := s.keeper.CheckSession(ctx, uint(userID), epoch); err != nil {
+		if errors.Is(err, keeperr.ErrSessionRevoked) || errors.Is(err, keeperr.ErrNotFound) {
+			return nil, errUnauthenticated
+		}
+		s.log.Error("failed check session", zap.Error(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return context.WithValue(ctx, ctxKeyUserID, uint(userID)), nil
+}
+
+func userIDFromContext(ctx context.Context) (uint, error) {
+	userID, ok := ctx.Value(ctxKeyUserID).(uint)
+	if !ok {
+		return 0, errors.New("user_id not found in context")
+	}
+	return userID, nil
+}
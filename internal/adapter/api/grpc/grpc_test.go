@@ -0,0 +1,391 @@
+package grpc_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/gorm"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/grpc"
+	"github.com/playmixer/secret-keeper/internal/adapter/api/grpc/pb"
+	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/internal/core/keeper"
+	"github.com/playmixer/secret-keeper/internal/mocks/storage/database"
+	"github.com/playmixer/secret-keeper/pkg/export"
+	"github.com/playmixer/secret-keeper/pkg/jwt"
+	"github.com/playmixer/secret-keeper/pkg/srp"
+)
+
+const (
+	secretKey  = "qwe"
+	encryptKey = "RZLMAOIOuljexYLh5S47O9kfVI7O1Ll0"
+	// хеш пароля "user".
+	passwordHash = "$2a$14$M2qLheAVBq/0yqT6NBUleewVIjlhOY4EqzCfEdgg3M0vBvKJA6Ct."
+)
+
+func newClient(t *testing.T, store keeper.Storage) pb.KeeperClient {
+	t.Helper()
+
+	keep, err := keeper.New(store, keeper.SetEncryptKey(encryptKey))
+	require.NoError(t, err)
+
+	server, err := grpc.New(keep,
+		grpc.SetLogger(zap.NewNop()),
+		grpc.SetSecretKey(secretKey),
+		grpc.SetMaxUploadSize(1024*1024))
+	require.NoError(t, err)
+
+	listen := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = server.Serve(listen)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := gogrpc.NewClient("passthrough:///bufnet",
+		gogrpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listen.DialContext(ctx)
+		}),
+		gogrpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return pb.NewKeeperClient(conn)
+}
+
+func authContext(t *testing.T, userID string) context.Context {
+	t.Helper()
+
+	token, err := jwt.New([]byte(secretKey)).Create(map[string]string{"user_id": userID})
+	require.NoError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestServer_Authorization(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{
+			name: "no token",
+			ctx:  context.Background(),
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "bad token",
+			ctx:  metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer bad"),
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "ok",
+			ctx:  authContext(t, "1"),
+			code: codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storeMock := database.NewMockStorage(ctrl)
+
+			expectSession(storeMock)
+			if tt.code == codes.OK {
+				storeMock.EXPECT().
+					GetMetaDatasByUserID(gomock.Any(), uint(1)).
+					Return(&[]models.Secret{{Model: gorm.Model{ID: 1}, Title: "title", DataType: models.TEXT}}, nil).
+					Times(1)
+			}
+			client := newClient(t, storeMock)
+
+			res, err := client.ListSecrets(tt.ctx, &pb.ListSecretsRequest{})
+			assert.Equal(t, tt.code, status.Code(err))
+			if tt.code == codes.OK {
+				assert.Len(t, res.GetItems(), 1)
+				assert.Equal(t, "title", res.GetItems()[0].GetTitle())
+			}
+		})
+	}
+}
+
+func TestServer_Login(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		code     codes.Code
+	}{
+		{
+			name:     "ok",
+			password: "user",
+			code:     codes.OK,
+		},
+		{
+			name:     "wrong password",
+			password: "wrong",
+			code:     codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storeMock := database.NewMockStorage(ctrl)
+
+			expectSession(storeMock)
+			storeMock.EXPECT().
+				GetUserByLogin(gomock.Any(), "user").
+				Return(&models.User{Model: gorm.Model{ID: 1}, Login: "user", PasswordHash: passwordHash}, nil).
+				Times(1)
+			if tt.code == codes.OK {
+				storeMock.EXPECT().SetVerifier(gomock.Any(), uint(1), gomock.Any(), gomock.Any()).Return(nil)
+				storeMock.EXPECT().UpdatePasswordHash(gomock.Any(), uint(1), gomock.Any(), gomock.Any()).Return(nil)
+			}
+			client := newClient(t, storeMock)
+
+			res, err := client.Login(context.Background(), &pb.LoginRequest{Login: "user", Password: tt.password})
+			assert.Equal(t, tt.code, status.Code(err))
+			if tt.code == codes.OK {
+				params, err := jwt.New([]byte(secretKey)).GetParams(res.GetAccessToken())
+				assert.NoError(t, err)
+				assert.Equal(t, "1", params["user_id"])
+			}
+		})
+	}
+}
+
+func TestServer_SRPLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	salt, err := srp.NewSalt()
+	require.NoError(t, err)
+	user := &models.User{
+		Model:       gorm.Model{ID: 1},
+		Login:       "user",
+		SRPSalt:     salt,
+		SRPVerifier: srp.Verifier("user", "user", salt),
+	}
+	storeMock := database.NewMockStorage(ctrl)
+	storeMock.EXPECT().GetUserByLogin(gomock.Any(), "user").Return(user, nil)
+	storeMock.EXPECT().GetUserByID(gomock.Any(), uint(1)).Return(user, nil)
+	client := newClient(t, storeMock)
+
+	srpClient, err := srp.NewClient("user", "user")
+	require.NoError(t, err)
+	challenge, err := client.SRPInit(context.Background(), &pb.SRPInitRequest{Login: "user", A: srpClient.PublicKey()})
+	require.NoError(t, err)
+	proof, err := srpClient.Proof(challenge.GetSalt(), challenge.GetB())
+	require.NoError(t, err)
+
+	res, err := client.SRPVerify(context.Background(), &pb.SRPVerifyRequest{Session: challenge.GetSession(), M1: proof})
+	require.NoError(t, err)
+	assert.NoError(t, srpClient.VerifyServer(res.GetM2()))
+	params, err := jwt.New([]byte(secretKey)).GetParams(res.GetAccessToken())
+	assert.NoError(t, err)
+	assert.Equal(t, "1", params["user_id"])
+
+	_, err = client.SRPVerify(context.Background(), &pb.SRPVerifyRequest{Session: challenge.GetSession(), M1: proof})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServer_Policy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := newClient(t, database.NewMockStorage(ctrl))
+	res, err := client.Policy(context.Background(), &pb.PolicyRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, keeper.RegistrationOpen, res.GetRegistrationMode())
+}
+
+func TestServer_UploadDownloadSecret(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	data := bytes.Repeat([]byte("secret"), 30000)
+	stored := &models.Secret{}
+
+	storeMock := database.NewMockStorage(ctrl)
+
+	expectSession(storeMock)
+	storeMock.EXPECT().
+		NewSecret(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, secret *models.Secret) (*models.Secret, error) {
+			secret.ID = 7
+			*stored = *secret
+			stored.Data = bytes.Clone(secret.Data)
+			return secret, nil
+		}).
+		Times(1)
+	storeMock.EXPECT().
+		GetSecret(gomock.Any(), uint(7), gomock.Any()).
+		DoAndReturn(ownSecret(stored)).
+		Times(2)
+	client := newClient(t, storeMock)
+	ctx := authContext(t, "1")
+
+	upload, err := client.UploadSecret(ctx)
+	require.NoError(t, err)
+	err = upload.Send(&pb.UploadRequest{Payload: &pb.UploadRequest_Header{Header: &pb.UploadHeader{
+		Title:    "big",
+		DataType: string(models.BINARY),
+	}}})
+	require.NoError(t, err)
+	for start := 0; start < len(data); start += 1000 {
+		err = upload.Send(&pb.UploadRequest{Payload: &pb.UploadRequest_Chunk{
+			Chunk: data[start:min(start+1000, len(data))],
+		}})
+		require.NoError(t, err)
+	}
+	meta, err := upload.CloseAndRecv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), meta.GetId())
+
+	download, err := client.DownloadSecret(ctx, &pb.GetSecretRequest{Id: 7})
+	require.NoError(t, err)
+	first, err := download.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "big", first.GetMeta().GetTitle())
+
+	buf := bytes.Buffer{}
+	for {
+		res, err := download.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		buf.Write(res.GetChunk())
+	}
+	assert.Equal(t, data, buf.Bytes())
+
+	// чужой секрет не отдается.
+	_, err = client.GetSecret(authContext(t, "2"), &pb.GetSecretRequest{Id: 7})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// ownSecret отдает сохраненный секрет только владельцу, как database.Storage.
+func ownSecret(stored *models.Secret) func(context.Context, uint, uint) (*models.Secret, error) {
+	return func(_ context.Context, _, userID uint) (*models.Secret, error) {
+		if userID != stored.UserID {
+			return nil, keeperr.ErrNotFound
+		}
+		secret := *stored
+		secret.Data = bytes.Clone(stored.Data)
+		return &secret, nil
+	}
+}
+
+func TestServer_ForeignSecret(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	stored := &models.Secret{}
+	storeMock := database.NewMockStorage(ctrl)
+	expectSession(storeMock)
+	storeMock.EXPECT().
+		NewSecret(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, secret *models.Secret) (*models.Secret, error) {
+			secret.ID = 7
+			*stored = *secret
+			stored.Data = bytes.Clone(secret.Data)
+			return secret, nil
+		}).
+		Times(1)
+	storeMock.EXPECT().
+		GetSecret(gomock.Any(), uint(7), gomock.Any()).
+		DoAndReturn(ownSecret(stored)).
+		AnyTimes()
+	storeMock.EXPECT().
+		UpdSecret(gomock.Any(), gomock.Any(), false).
+		DoAndReturn(func(ctx context.Context, secret *models.Secret, _ bool) (*models.Secret, error) {
+			return ownSecret(stored)(ctx, secret.ID, secret.UserID)
+		}).
+		Times(1)
+	storeMock.EXPECT().
+		DelSecret(gomock.Any(), uint(7), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, id, userID uint) error {
+			_, err := ownSecret(stored)(ctx, id, userID)
+			return err
+		}).
+		Times(1)
+	client := newClient(t, storeMock)
+	_, err := client.CreateSecret(authContext(t, "1"), &pb.CreateSecretRequest{Title: "a", Data: []byte("a")})
+	require.NoError(t, err)
+	ctx := authContext(t, "2")
+
+	_, err = client.UpdateSecret(ctx, &pb.UpdateSecretRequest{Id: 7, Title: "b", Data: []byte("b")})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.DeleteSecret(ctx, &pb.DeleteSecretRequest{Id: 7})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	upload, err := client.UploadSecret(ctx)
+	require.NoError(t, err)
+	err = upload.Send(&pb.UploadRequest{Payload: &pb.UploadRequest_Header{Header: &pb.UploadHeader{
+		Id:       7,
+		Title:    "b",
+		DataType: string(models.BINARY),
+	}}})
+	require.NoError(t, err)
+	_, err = upload.CloseAndRecv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_ExportDeleteAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := &models.User{Model: gorm.Model{ID: 1}, Login: "user", PasswordHash: passwordHash}
+	storeMock := database.NewMockStorage(ctrl)
+	expectSession(storeMock)
+	storeMock.EXPECT().GetUserByID(gomock.Any(), uint(1)).Return(user, nil).Times(3)
+	storeMock.EXPECT().GetMetaDatasByUserID(gomock.Any(), uint(1)).Return(&[]models.Secret{}, nil)
+	storeMock.EXPECT().DeleteUser(gomock.Any(), uint(1), models.Credentials{PasswordHash: passwordHash}).Return(nil)
+	client := newClient(t, storeMock)
+	ctx := authContext(t, "1")
+
+	stream, err := client.Export(ctx, &pb.ExportRequest{})
+	require.NoError(t, err)
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	key := first.GetKey()
+	buf := bytes.Buffer{}
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		buf.Write(res.GetChunk())
+	}
+	doc, err := export.Open(buf.Bytes(), key)
+	require.NoError(t, err)
+	assert.Equal(t, "user", doc.Login)
+
+	_, err = client.DeleteAccount(ctx, &pb.DeleteAccountRequest{Password: "wrong"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.DeleteAccount(ctx, &pb.DeleteAccountRequest{Password: "user"})
+	assert.NoError(t, err)
+}
+
+// expectSession токены тестов выданы в текущей эпохе сессий.
+func expectSession(m *database.MockStorage) {
+	m.EXPECT().GetSessionEpoch(gomock.Any(), gomock.Any()).Return(int64(0), nil).AnyTimes()
+}
@@ -0,0 +1,543 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/grpc/pb"
+	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/internal/core/keeper"
+	"github.com/playmixer/secret-keeper/pkg/jwt"
+	"github.com/playmixer/secret-keeper/pkg/tlsconf"
+)
+
+// Registration регистрация пользователя.
+func (s *Server) Registration(ctx context.Context, req *pb.RegistrationRequest) (*pb.RegistrationResponse, error) {
+	password := keeper.Password{Plain: req.GetPassword(), Salt: req.GetSalt(), Verifier: req.GetVerifier()}
+	recoveryKey, err := s.keeper.Registration(ctx, req.GetLogin(), password, req.GetRecovery(), req.GetInvite())
+	if err != nil {
+		return nil, s.statusError(err, "failed create user")
+	}
+	return &pb.RegistrationResponse{RecoveryKey: recoveryKey}, nil
+}
+
+// Policy правила регистрации.
+func (s *Server) Policy(_ context.Context, _ *pb.PolicyRequest) (*pb.PolicyResponse, error) {
+	info := s.keeper.Policy()
+	return &pb.PolicyResponse{
+		RegistrationMode:    info.RegistrationMode,
+		LoginPattern:        info.LoginPattern,
+		LoginMinLength:      int32(info.LoginMinLength),
+		LoginMaxLength:      int32(info.LoginMaxLength),
+		PasswordMinLength:   int32(info.PasswordMinLength),
+		PasswordMinStrength: int32(info.PasswordMinStrength),
+	}, nil
+}
+
+// Recover новый пароль по ключу восстановления.
+func (s *Server) Recover(ctx context.Context, req *pb.RecoverRequest) (*pb.RecoverResponse, error) {
+	ctx = keeper.WithClientIP(ctx, peerIP(ctx))
+	next := keeper.Password{Plain: req.GetNewPassword(), Salt: req.GetSalt(), Verifier: req.GetVerifier()}
+	user, recoveryKey, err := s.keeper.Recover(ctx, req.GetLogin(), req.GetRecoveryKey(), next)
+	if err != nil {
+		if errors.Is(err, keeper.ErrRecoveryNotValid) || errors.Is(err, keeper.ErrLoginNotValid) {
+			return nil, status.Error(codes.Unauthenticated, "login or recovery key not correct")
+		}
+		return nil, s.statusError(err, "failed recover access")
+	}
+
+	accessToken, err := s.newAccessToken(ctx, user.ID, user.SessionEpoch)
+	if err != nil {
+		return nil, s.statusError(err, "failed create access token")
+	}
+
+	return &pb.RecoverResponse{AccessToken: accessToken, RecoveryKey: recoveryKey}, nil
+}
+
+// Login аутентификация пользователя, возвращает токен доступа.
+func (s *Server) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	user, err := s.keeper.Login(keeper.WithClientIP(ctx, peerIP(ctx)), req.GetLogin(), req.GetPassword())
+	if err != nil {
+		if errors.Is(err, keeperr.ErrNotFound) ||
+			errors.Is(err, keeper.ErrLoginNotValid) ||
+			errors.Is(err, keeper.ErrPasswordNotValid) {
+			return nil, status.Error(codes.Unauthenticated, "login or password not correct")
+		}
+		return nil, s.statusError(err, "failed login user")
+	}
+
+	accessToken, err := s.newAccessToken(ctx, user.ID, user.SessionEpoch)
+	if err != nil {
+		return nil, s.statusError(err, "failed create access token")
+	}
+
+	return &pb.LoginResponse{AccessToken: accessToken}, nil
+}
+
+// SRPInit первый шаг входа по SRP-6a.
+func (s *Server) SRPInit(ctx context.Context, req *pb.SRPInitRequest) (*pb.SRPInitResponse, error) {
+	challenge, err := s.keeper.SRPInit(keeper.WithClientIP(ctx, peerIP(ctx)), req.GetLogin(), req.GetA())
+	if err != nil {
+		return nil, s.statusError(err, "failed srp init")
+	}
+	return &pb.SRPInitResponse{
+		Session: challenge.Session,
+		Salt:    challenge.Salt,
+		B:       challenge.ServerPublic,
+	}, nil
+}
+
+// SRPVerify второй шаг входа по SRP-6a, возвращает токен доступа и доказательство сервера.
+func (s *Server) SRPVerify(ctx context.Context, req *pb.SRPVerifyRequest) (*pb.SRPVerifyResponse, error) {
+	user, serverProof, err := s.keeper.SRPVerify(keeper.WithClientIP(ctx, peerIP(ctx)), req.GetSession(), req.GetM1())
+	if err != nil {
+		if errors.Is(err, keeperr.ErrNotFound) {
+			return nil, status.Error(codes.Unauthenticated, "login or password not correct")
+		}
+		return nil, s.statusError(err, "failed srp verify")
+	}
+
+	accessToken, err := s.newAccessToken(ctx, user.ID, user.SessionEpoch)
+	if err != nil {
+		return nil, s.statusError(err, "failed create access token")
+	}
+
+	return &pb.SRPVerifyResponse{AccessToken: accessToken, M2: serverProof}, nil
+}
+
+// ChangePassword смена пароля, возвращает новый токен, остальные сессии отзываются.
+func (s *Server) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.LoginResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+
+	ctx = keeper.WithClientIP(ctx, peerIP(ctx))
+	current := keeper.Proof{Password: req.GetOldPassword(), Session: req.GetSrpSession(), SRPProof: req.GetSrpProof()}
+	next := keeper.Password{Plain: req.GetNewPassword(), Salt: req.GetSalt(), Verifier: req.GetVerifier()}
+	epoch, err := s.keeper.ChangePassword(ctx, userID, current, next)
+	if err != nil {
+		return nil, s.statusError(err, "failed change password")
+	}
+
+	accessToken, err := s.newAccessToken(ctx, userID, epoch)
+	if err != nil {
+		return nil, s.statusError(err, "failed create access token")
+	}
+
+	return &pb.LoginResponse{AccessToken: accessToken}, nil
+}
+
+// DeleteAccount удалить учетную запись и все секреты пользователя.
+func (s *Server) DeleteAccount(ctx context.Context, req *pb.DeleteAccountRequest) (*pb.DeleteAccountResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+
+	ctx = keeper.WithClientIP(ctx, peerIP(ctx))
+	current := keeper.Proof{Password: req.GetPassword(), Session: req.GetSrpSession(), SRPProof: req.GetSrpProof()}
+	if err := s.keeper.DeleteAccount(ctx, userID, current); err != nil {
+		return nil, s.statusError(err, "failed delete account")
+	}
+	return &pb.DeleteAccountResponse{}, nil
+}
+
+// Export отдать зашифрованный архив данных пользователя потоком.
+func (s *Server) Export(_ *pb.ExportRequest, stream pb.Keeper_ExportServer) error {
+	ctx := stream.Context()
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return errUnauthenticated
+	}
+
+	archive, key, err := s.keeper.Export(keeper.WithClientIP(ctx, peerIP(ctx)), userID)
+	if err != nil {
+		return s.statusError(err, "failed export")
+	}
+
+	if err := stream.Send(&pb.ExportResponse{Payload: &pb.ExportResponse_Key{Key: key}}); err != nil {
+		return err
+	}
+	for start := 0; start < len(archive); start += chunkSize {
+		chunk := archive[start:min(start+chunkSize, len(archive))]
+		if err := stream.Send(&pb.ExportResponse{Payload: &pb.ExportResponse_Chunk{Chunk: chunk}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newAccessToken токен доступа пользователя в эпохе сессий epoch.
+// Если клиент предъявил сертификат, токен привязывается к его отпечатку.
+func (s *Server) newAccessToken(ctx context.Context, userID uint, epoch int64) (string, error) {
+	params := map[string]string{
+		"user_id":       strconv.Itoa(int(userID)),
+		"session_epoch": strconv.FormatInt(epoch, 10),
+	}
+	if device := peerDevice(ctx); device != "" {
+		params[claimDevice] = device
+	}
+	token, err := jwt.New(s.secretKey).Create(params)
+	if err != nil {
+		return "", fmt.Errorf("failed create token: %w", err)
+	}
+	return token, nil
+}
+
+// ListSecrets мета данные всех секретов пользователя.
+func (s *Server) ListSecrets(ctx context.Context, _ *pb.ListSecretsRequest) (*pb.ListSecretsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+
+	data, err := s.keeper.GetMetaDatasByUserID(ctx, userID)
+	if err != nil {
+		return nil, s.statusError(err, "failed get datas")
+	}
+
+	res := &pb.ListSecretsResponse{Items: make([]*pb.SecretMeta, 0, len(*data))}
+	for i := range *data {
+		res.Items = append(res.Items, secretMeta(&(*data)[i]))
+	}
+	return res, nil
+}
+
+// GetSecret секрет пользователя.
+func (s *Server) GetSecret(ctx context.Context, req *pb.GetSecretRequest) (*pb.Secret, error) {
+	secret, err := s.getUserSecret(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Secret{Meta: secretMeta(secret), Data: secret.Data}, nil
+}
+
+// CreateSecret создать секрет.
+func (s *Server) CreateSecret(ctx context.Context, req *pb.CreateSecretRequest) (*pb.SecretMeta, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+
+	data := req.GetData()
+	secret, err := s.keeper.NewSecret(ctx,
+		&data, req.GetTitle(), "", models.DataType(req.GetDataType()), req.GetUpdateDt(), userID)
+	if err != nil {
+		return nil, s.statusError(err, "failed create data")
+	}
+	return secretMeta(secret), nil
+}
+
+// UpdateSecret обновить секрет пользователя.
+func (s *Server) UpdateSecret(ctx context.Context, req *pb.UpdateSecretRequest) (*pb.SecretMeta, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+	data := req.GetData()
+	secret, err := s.keeper.UpdSecret(ctx, uint(req.GetId()),
+		&data, req.GetTitle(), nil, models.DataType(req.GetDataType()), req.GetUpdateDt(), userID)
+	if err != nil {
+		return nil, s.statusError(err, "failed update data")
+	}
+	return secretMeta(secret), nil
+}
+
+// DeleteSecret удалить секрет пользователя.
+func (s *Server) DeleteSecret(ctx context.Context, req *pb.DeleteSecretRequest) (*pb.DeleteSecretResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+
+	err = s.keeper.DelSecret(ctx, uint(req.GetId()), userID)
+	if err != nil {
+		return nil, s.statusError(err, "failed delete data")
+	}
+	return &pb.DeleteSecretResponse{}, nil
+}
+
+// Batch применить пакет операций в одной транзакции.
+func (s *Server) Batch(ctx context.Context, req *pb.BatchRequest) (*pb.BatchResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+
+	ops := make([]models.BatchOperation, 0, len(req.GetOperations()))
+	for _, op := range req.GetOperations() {
+		ops = append(ops, models.BatchOperation{
+			Action:         models.BatchAction(op.GetAction()),
+			ID:             uint(op.GetId()),
+			Title:          op.GetTitle(),
+			DataType:       models.DataType(op.GetDataType()),
+			IdempotencyKey: op.GetIdempotencyKey(),
+			Data:           op.GetData(),
+			UpdateDT:       op.GetUpdateDt(),
+		})
+	}
+
+	results, err := s.keeper.Batch(ctx, userID, &ops)
+	if err != nil {
+		return nil, s.statusError(err, "failed apply batch")
+	}
+
+	res := &pb.BatchResponse{Results: make([]*pb.BatchResult, 0, len(*results))}
+	for i, r := range *results {
+		item := &pb.BatchResult{
+			Action: string(ops[i].Action),
+			Status: r.Error == nil,
+			Meta:   &pb.SecretMeta{Id: uint64(ops[i].ID)},
+		}
+		switch {
+		case errors.Is(r.Error, keeperr.ErrNotFound):
+			item.Error = "not found content"
+		case r.Error != nil:
+			item.Error = r.Error.Error()
+		case r.Secret != nil:
+			item.Meta = secretMeta(r.Secret)
+		}
+		res.Results = append(res.Results, item)
+	}
+	return res, nil
+}
+
+// Sync изменения на сервере после указанного времени сервера.
+func (s *Server) Sync(ctx context.Context, req *pb.SyncRequest) (*pb.SyncResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+
+	// время фиксируем до чтения, чтобы изменения во время запроса попали в следующую синхронизацию.
+	serverTime := time.Now().UTC()
+	revision, err := s.keeper.GetRevision(ctx, userID)
+	if err != nil {
+		return nil, s.statusError(err, "failed get revision")
+	}
+	data, err := s.keeper.GetMetaDatasByUserID(ctx, userID)
+	if err != nil {
+		return nil, s.statusError(err, "failed get datas")
+	}
+
+	since := time.UnixMicro(req.GetSince())
+	res := &pb.SyncResponse{
+		Revision:   revision,
+		ServerTime: serverTime.UnixMicro(),
+	}
+	for i := range *data {
+		if req.GetSince() > 0 && (*data)[i].UpdatedAt.Before(since) {
+			continue
+		}
+		res.Items = append(res.Items, secretMeta(&(*data)[i]))
+	}
+	return res, nil
+}
+
+// Events поток изменений ревизии хранилища пользователя.
+func (s *Server) Events(_ *pb.EventsRequest, stream pb.Keeper_EventsServer) error {
+	ctx := stream.Context()
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return errUnauthenticated
+	}
+
+	events, unsubscribe := s.keeper.Subscribe(userID)
+	defer unsubscribe()
+
+	revision, err := s.keeper.GetRevision(ctx, userID)
+	if err != nil {
+		return s.statusError(err, "failed get revision")
+	}
+	if err := stream.Send(&pb.RevisionEvent{Revision: revision}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-events:
+			current, err := s.keeper.GetRevision(ctx, userID)
+			if err != nil {
+				return s.statusError(err, "failed get revision")
+			}
+			if current == revision {
+				continue
+			}
+			revision = current
+			if err := stream.Send(&pb.RevisionEvent{Revision: revision}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// UploadSecret создать или обновить секрет потоком.
+func (s *Server) UploadSecret(stream pb.Keeper_UploadSecretServer) error {
+	ctx := stream.Context()
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return errUnauthenticated
+	}
+
+	req, err := stream.Recv()
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "header expected")
+	}
+	header := req.GetHeader()
+	if header == nil {
+		return status.Error(codes.InvalidArgument, "first message must be header")
+	}
+	// обновлять можно только свой секрет, проверяем до приема данных.
+	if header.GetId() != 0 {
+		if _, err := s.getUserSecret(ctx, header.GetId()); err != nil {
+			return err
+		}
+	}
+
+	buf := bytes.Buffer{}
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if buf.Len()+len(req.GetChunk()) > s.maxUploadSize {
+			return status.Errorf(codes.ResourceExhausted, "data exceeds maximum %v bytes size", s.maxUploadSize)
+		}
+		buf.Write(req.GetChunk())
+	}
+
+	data := buf.Bytes()
+	var secret *models.Secret
+	if header.GetId() == 0 {
+		secret, err = s.keeper.NewSecret(ctx,
+			&data, header.GetTitle(), "", models.DataType(header.GetDataType()), header.GetUpdateDt(), userID)
+	} else {
+		secret, err = s.keeper.UpdSecret(ctx, uint(header.GetId()),
+			&data, header.GetTitle(), nil, models.DataType(header.GetDataType()), header.GetUpdateDt(), userID)
+	}
+	if err != nil {
+		return s.statusError(err, "failed save uploaded data")
+	}
+
+	return stream.SendAndClose(secretMeta(secret))
+}
+
+// DownloadSecret отдать секрет потоком.
+func (s *Server) DownloadSecret(req *pb.GetSecretRequest, stream pb.Keeper_DownloadSecretServer) error {
+	secret, err := s.getUserSecret(stream.Context(), req.GetId())
+	if err != nil {
+		return err
+	}
+
+	err = stream.Send(&pb.DownloadResponse{Payload: &pb.DownloadResponse_Meta{Meta: secretMeta(secret)}})
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(secret.Data); start += chunkSize {
+		chunk := secret.Data[start:min(start+chunkSize, len(secret.Data))]
+		err = stream.Send(&pb.DownloadResponse{Payload: &pb.DownloadResponse_Chunk{Chunk: chunk}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getUserSecret секрет пользователя, чужой секрет неотличим от отсутствующего.
+func (s *Server) getUserSecret(ctx context.Context, id uint64) (*models.Secret, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+
+	secret, err := s.keeper.GetSecret(ctx, uint(id), userID)
+	if err != nil {
+		return nil, s.statusError(err, "failed get data")
+	}
+	return secret, nil
+}
+
+// statusError переводит ошибку ядра в статус gRPC, неизвестные ошибки логируются и скрываются.
+func (s *Server) statusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, keeper.ErrLoginNotValid),
+		errors.Is(err, keeper.ErrPasswordNotValid),
+		errors.Is(err, keeper.ErrBatchNotValid),
+		errors.Is(err, keeper.ErrSRPNotValid):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, keeper.ErrPasswordWeak):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, keeper.ErrRegistrationClosed), errors.Is(err, keeper.ErrInviteNotValid):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, keeperr.ErrLoginNotUnique):
+		return status.Error(codes.AlreadyExists, "login not unique")
+	case errors.Is(err, keeperr.ErrLoginOrPasswordNotCorrect):
+		return status.Error(codes.Unauthenticated, "login or password not correct")
+	case errors.Is(err, keeper.ErrPasswordIncorrect):
+		return status.Error(codes.PermissionDenied, "current password not correct")
+	case errors.Is(err, keeper.ErrLegacyAuthDisabled), errors.Is(err, keeper.ErrPasswordUnchecked):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, keeperr.ErrNotFound):
+		return status.Error(codes.NotFound, "not found content")
+	case errors.Is(err, keeperr.ErrRateLimited), errors.Is(err, keeperr.ErrAccountLocked):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	s.log.Error(msg, zap.Error(err))
+	return status.Error(codes.Internal, "internal error")
+}
+
+// peerDevice отпечаток сертификата клиента, пустой - соединение без сертификата клиента.
+func peerDevice(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return ""
+	}
+	return tlsconf.PeerFingerprint(&info.State)
+}
+
+// peerIP адрес клиента без порта.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func secretMeta(secret *models.Secret) *pb.SecretMeta {
+	return &pb.SecretMeta{
+		Id:        uint64(secret.ID),
+		Title:     secret.Title,
+		DataType:  string(secret.DataType),
+		UpdateDt:  secret.UpdateDT,
+		IsDeleted: secret.IsDeleted,
+	}
+}
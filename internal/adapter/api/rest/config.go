@@ -4,4 +4,23 @@ package rest
 type Config struct {
 	Address   string `env:"REST_ADDRESS"`
 	SSLEnable bool   `env:"SSL_ENABLE"`
+	// CertFile и KeyFile сертификат и ключ сервера, перечитываются при изменении файлов.
+	CertFile string `env:"TLS_CERT_FILE"`
+	KeyFile  string `env:"TLS_KEY_FILE"`
+	// TLSMinVersion минимальная версия TLS: 1.2 или 1.3.
+	TLSMinVersion string `env:"TLS_MIN_VERSION"`
+	// TLSCipherSuites наборы шифров TLS 1.2 через запятую, пустой - наборы Go по умолчанию.
+	TLSCipherSuites []string `env:"TLS_CIPHER_SUITES" envSeparator:","`
+	// TLSClientCA CA клиентских сертификатов. Если задан, сервер требует сертификат клиента
+	// и привязывает к нему выданные токены.
+	TLSClientCA string `env:"TLS_CLIENT_CA"`
+	// AuthRateLimit запросов в минуту с одного адреса к регистрации и входу.
+	AuthRateLimit int `env:"AUTH_RATE_LIMIT"`
+	// APIRateLimit запросов в минуту с одного адреса ко всему API.
+	APIRateLimit int `env:"API_RATE_LIMIT"`
+	// TrustedProxies адреса и подсети прокси через запятую, которым сервер верит в X-Forwarded-For и X-Real-IP.
+	// Пустой - адрес клиента берется из подключения, заголовки игнорируются.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:","`
+	// AdminToken токен для административных ручек (выпуск приглашений), пустой - ручки выключены.
+	AdminToken string `env:"ADMIN_TOKEN"`
 }
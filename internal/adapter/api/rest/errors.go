@@ -0,0 +1,127 @@
+package rest
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
+	"github.com/playmixer/secret-keeper/internal/core/keeper"
+)
+
+// ErrorCode - стабильный код ошибки API, клиенты опираются на него, а не на текст.
+type ErrorCode string
+
+const (
+	CodeBadRequest         ErrorCode = "bad_request"
+	CodeUnauthorized       ErrorCode = "unauthorized"
+	CodeInvalidCredentials ErrorCode = "invalid_credentials"
+	CodeLoginInvalid       ErrorCode = "login_invalid"
+	CodeRecoveryInvalid    ErrorCode = "recovery_invalid"
+	CodePasswordInvalid    ErrorCode = "password_invalid"
+	CodePasswordIncorrect  ErrorCode = "password_incorrect"
+	CodeLoginTaken         ErrorCode = "login_taken"
+	CodeSecretNotFound     ErrorCode = "secret_not_found"
+	CodeBatchInvalid       ErrorCode = "batch_invalid"
+	CodeBatchActionUnknown ErrorCode = "batch_action_unknown"
+	CodeFilterInvalid      ErrorCode = "filter_invalid"
+	CodeRateLimited        ErrorCode = "rate_limited"
+	CodeAccountLocked      ErrorCode = "account_locked"
+	CodeLegacyAuthDisabled ErrorCode = "legacy_auth_disabled"
+	CodePasswordWeak       ErrorCode = "password_weak"
+	CodePasswordUnchecked  ErrorCode = "password_unchecked"
+	CodeRegistrationClosed ErrorCode = "registration_closed"
+	CodeInviteInvalid      ErrorCode = "invite_invalid"
+	CodeInternal           ErrorCode = "internal_error"
+
+	problemContentType = "application/problem+json"
+	problemTypePrefix  = "urn:gophkeeper:error:"
+)
+
+var (
+	errRequestNotValid = errors.New("request is not valid")
+	errUnauthorized    = errors.New("authorization required")
+	errDeviceNotBound  = errors.New("token is not bound to this client certificate")
+)
+
+// errorMapping соответствие ошибок ядра ответам API, проверяется по порядку.
+var errorMapping = []struct {
+	err    error
+	code   ErrorCode
+	status int
+}{
+	{errRequestNotValid, CodeBadRequest, http.StatusBadRequest},
+	{errUnauthorized, CodeUnauthorized, http.StatusUnauthorized},
+	{keeperr.ErrLoginOrPasswordNotCorrect, CodeInvalidCredentials, http.StatusUnauthorized},
+	{keeper.ErrRecoveryNotValid, CodeRecoveryInvalid, http.StatusUnauthorized},
+	{keeper.ErrLoginNotValid, CodeLoginInvalid, http.StatusBadRequest},
+	{keeper.ErrPasswordNotValid, CodePasswordInvalid, http.StatusBadRequest},
+	{keeper.ErrPasswordIncorrect, CodePasswordIncorrect, http.StatusForbidden},
+	{keeper.ErrLegacyAuthDisabled, CodeLegacyAuthDisabled, http.StatusForbidden},
+	{keeper.ErrSRPNotValid, CodeBadRequest, http.StatusBadRequest},
+	{keeper.ErrPasswordWeak, CodePasswordWeak, http.StatusBadRequest},
+	{keeper.ErrPasswordUnchecked, CodePasswordUnchecked, http.StatusForbidden},
+	{keeper.ErrRegistrationClosed, CodeRegistrationClosed, http.StatusForbidden},
+	{keeper.ErrInviteNotValid, CodeInviteInvalid, http.StatusForbidden},
+	{keeper.ErrInviteParamsNotValid, CodeBadRequest, http.StatusBadRequest},
+	{keeperr.ErrLoginNotUnique, CodeLoginTaken, http.StatusConflict},
+	{keeperr.ErrNotFound, CodeSecretNotFound, http.StatusNotFound},
+	{gorm.ErrRecordNotFound, CodeSecretNotFound, http.StatusNotFound},
+	{keeperr.ErrBatchActionUnknown, CodeBatchActionUnknown, http.StatusBadRequest},
+	{keeper.ErrBatchNotValid, CodeBatchInvalid, http.StatusBadRequest},
+	{keeper.ErrListFilterNotValid, CodeFilterInvalid, http.StatusBadRequest},
+	{errCursorNotValid, CodeFilterInvalid, http.StatusBadRequest},
+	{keeperr.ErrRateLimited, CodeRateLimited, http.StatusTooManyRequests},
+	{keeperr.ErrAccountLocked, CodeAccountLocked, http.StatusTooManyRequests},
+}
+
+// TProblem - ошибка в формате problem details (RFC 9457) с кодом ошибки.
+type TProblem struct {
+	Type   string    `json:"type"`
+	Title  string    `json:"title"`
+	Code   ErrorCode `json:"code"`
+	Detail string    `json:"detail,omitempty"`
+	Status int       `json:"status"`
+	// RetryAfter через сколько секунд можно повторить запрос, дублирует заголовок Retry-After.
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// errorCode код и http статус для ошибки, неизвестные ошибки считаются внутренними.
+func errorCode(err error) (ErrorCode, int) {
+	for _, m := range errorMapping {
+		if errors.Is(err, m.err) {
+			return m.code, m.status
+		}
+	}
+	return CodeInternal, http.StatusInternalServerError
+}
+
+// writeError отвечает ошибкой, внутренние ошибки логируются, их текст клиенту не отдается.
+func (s *Server) writeError(c *gin.Context, msg string, err error) {
+	code, status := errorCode(err)
+	problem := TProblem{
+		Type:   problemTypePrefix + string(code),
+		Title:  http.StatusText(status),
+		Code:   code,
+		Status: status,
+	}
+	if status >= http.StatusInternalServerError {
+		s.log.Error(msg, zap.Error(err))
+	} else {
+		problem.Detail = err.Error()
+	}
+
+	var retryErr *keeperr.RetryError
+	if errors.As(err, &retryErr) {
+		problem.RetryAfter = int(max(math.Ceil(retryErr.After.Seconds()), 1))
+		c.Header("Retry-After", strconv.Itoa(problem.RetryAfter))
+	}
+
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(status, problem)
+}
@@ -6,16 +6,19 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 
 	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
 	"github.com/playmixer/secret-keeper/internal/core/keeper"
-	"github.com/playmixer/secret-keeper/pkg/jwt"
 )
 
+// HeaderExportKey заголовок ответа экспорта с ключом к архиву.
+const HeaderExportKey = "X-Export-Key"
+
 var (
 	errFailedGetData = "failed get datas"
 )
@@ -28,49 +31,31 @@ var (
 // @Produce		json
 // @Param			registration	body		tHandlerRegistrationRequest		true	"registration"
 // @Success		201				{object}	tHandlerRegistrationResponse	"пользователь успешно зарегистрирован"
-// @failure		400				{object}	tResultErrorResponse			"неверный формат запроса"
-// @failure		409				{object}	tResultErrorResponse			"логин уже занят"
-// @failure		500				"внутренняя ошибка сервера"
+// @failure		400				{object}	TProblem						"неверный формат запроса"
+// @failure		403				{object}	TProblem						"регистрация закрыта или приглашение не верное"
+// @failure		409				{object}	TProblem						"логин уже занят"
+// @failure		429				{object}	TProblem						"слишком много запросов"
+// @failure		500				{object}	TProblem						"внутренняя ошибка сервера"
 // @Router			/auth/registration [post]
 func (s *Server) handlerRegistration(c *gin.Context) {
-	bBody, statusCode := s.readBody(c)
-	if statusCode > 0 {
-		c.Writer.WriteHeader(statusCode)
+	bBody, err := s.readBody(c)
+	if err != nil {
+		s.writeError(c, "", err)
 		return
 	}
 
 	jBody := tHandlerRegistrationRequest{}
-	err := json.Unmarshal(bBody, &jBody)
+	err = json.Unmarshal(bBody, &jBody)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusBadRequest)
+		s.writeError(c, "", fmt.Errorf("%w: %w", errRequestNotValid, err))
 		return
 	}
 
-	err = s.keeper.Registration(c.Request.Context(), jBody.Login, jBody.Password)
+	password := keeper.Password{Plain: jBody.Password, Salt: jBody.Salt, Verifier: jBody.Verifier}
+	recoveryKey, err := s.keeper.Registration(c.Request.Context(),
+		jBody.Login, password, jBody.Recovery, jBody.Invite)
 	if err != nil {
-		if errors.Is(err, keeper.ErrLoginNotValid) {
-			c.JSON(http.StatusBadRequest, tResultErrorResponse{
-				Status: false,
-				Error:  "Invalid login",
-			})
-			return
-		}
-		if errors.Is(err, keeper.ErrPasswordNotValid) {
-			c.JSON(http.StatusBadRequest, tResultErrorResponse{
-				Status: false,
-				Error:  "Invalid password",
-			})
-			return
-		}
-		if errors.Is(err, keeperr.ErrLoginNotUnique) {
-			c.JSON(http.StatusConflict, tResultErrorResponse{
-				Status: false,
-				Error:  "Login not unique",
-			})
-			return
-		}
-		c.Writer.WriteHeader(http.StatusInternalServerError)
-		s.log.Error("failed create user", zap.Error(err))
+		s.writeError(c, "failed create user", err)
 		return
 	}
 
@@ -79,6 +64,56 @@ func (s *Server) handlerRegistration(c *gin.Context) {
 			Status:  true,
 			Message: "Registration successful",
 		},
+		RecoveryKey: recoveryKey,
+	})
+}
+
+// @Summary	Recover access
+// @Schemes
+// @Description	новый пароль по ключу восстановления. Ключ одноразовый: в ответе выдается новый ключ,
+// @Description	все выданные ранее токены отзываются. Доли ключа (схема Шамира) объединяет клиент.
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			recover	body		tHandlerRecoverRequest	true	"логин, ключ восстановления и новый пароль"
+// @Success		200		{object}	tHandlerRecoverResponse	"пароль изменен"
+// @failure		400		{object}	TProblem				"неверный формат запроса или недопустимый пароль"
+// @failure		401		{object}	TProblem				"логин или ключ восстановления не верный"
+// @failure		429		{object}	TProblem				"слишком много попыток или логин заблокирован"
+// @failure		500		{object}	TProblem				"внутренняя ошибка сервера"
+// @Router			/auth/recover [post]
+func (s *Server) handlerRecover(c *gin.Context) {
+	reqData := tHandlerRecoverRequest{}
+	err := c.ShouldBindJSON(&reqData)
+	if err != nil {
+		s.writeError(c, "", fmt.Errorf("%w: %w", errRequestNotValid, err))
+		return
+	}
+
+	ctx := keeper.WithClientIP(c.Request.Context(), c.ClientIP())
+	next := keeper.Password{Plain: reqData.NewPassword, Salt: reqData.Salt, Verifier: reqData.Verifier}
+	user, recoveryKey, err := s.keeper.Recover(ctx, reqData.Login, reqData.RecoveryKey, next)
+	if err != nil {
+		if errors.Is(err, keeper.ErrLoginNotValid) {
+			err = keeper.ErrRecoveryNotValid
+		}
+		s.writeError(c, "failed recover access", err)
+		return
+	}
+
+	accessToken, err := s.newAccessToken(c, user.ID, user.SessionEpoch)
+	if err != nil {
+		s.writeError(c, "failed create access token", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tHandlerRecoverResponse{
+		tResultResponse: tResultResponse{
+			Status:  true,
+			Message: "Access recovered",
+		},
+		AccessToken: accessToken,
+		RecoveryKey: recoveryKey,
 	})
 }
 
@@ -90,48 +125,41 @@ func (s *Server) handlerRegistration(c *gin.Context) {
 // @Produce		json
 // @Param			auth	body		tHandlerLoginRequest	true	"auth"
 // @Success		200		{object}	tHandlerLoginResponse	"пользователь успешно аутентифицирован"
-// @failure		400		{object}	tResultErrorResponse	"неверный формат запроса"
-// @failure		401		{object}	tResultErrorResponse	"логин или пароль не верный"
-// @failure		500		"внутренняя ошибка сервера"
+// @failure		400		{object}	TProblem				"неверный формат запроса"
+// @failure		401		{object}	TProblem				"логин или пароль не верный"
+// @failure		429		{object}	TProblem				"слишком много попыток или логин заблокирован"
+// @failure		500		{object}	TProblem				"внутренняя ошибка сервера"
 // @Router			/auth/login [post]
 func (s *Server) handlerLogin(c *gin.Context) {
-	bBody, statusCode := s.readBody(c)
-	if statusCode > 0 {
-		c.Writer.WriteHeader(statusCode)
+	bBody, err := s.readBody(c)
+	if err != nil {
+		s.writeError(c, "", err)
 		return
 	}
 
 	jBody := tHandlerLoginRequest{}
-	err := json.Unmarshal(bBody, &jBody)
+	err = json.Unmarshal(bBody, &jBody)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusBadRequest)
+		s.writeError(c, "", fmt.Errorf("%w: %w", errRequestNotValid, err))
 		return
 	}
 
-	user, err := s.keeper.Login(c.Request.Context(), jBody.Login, jBody.Password)
+	ctx := keeper.WithClientIP(c.Request.Context(), c.ClientIP())
+	user, err := s.keeper.Login(ctx, jBody.Login, jBody.Password)
 	if err != nil {
-		if errors.Is(err, keeperr.ErrLoginOrPasswordNotCorrect) ||
-			errors.Is(err, keeperr.ErrNotFound) ||
+		// при входе не раскрываем, что именно не так: логин, пароль или пользователя нет.
+		if errors.Is(err, keeperr.ErrNotFound) ||
 			errors.Is(err, keeper.ErrLoginNotValid) ||
 			errors.Is(err, keeper.ErrPasswordNotValid) {
-			c.JSON(http.StatusUnauthorized, tResultErrorResponse{
-				Status: false,
-				Error:  "Login or password not correct",
-			})
-			return
+			err = keeperr.ErrLoginOrPasswordNotCorrect
 		}
-		s.log.Error("failed login user", zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		s.writeError(c, "failed login user", err)
 		return
 	}
 
-	jwtManager := jwt.New(s.secretKey)
-	accessToken, err := jwtManager.Create(map[string]string{
-		"user_id": strconv.Itoa(int(user.ID)),
-	})
+	accessToken, err := s.newAccessToken(c, user.ID, user.SessionEpoch)
 	if err != nil {
-		s.log.Error("failed create access token", zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		s.writeError(c, "failed create access token", err)
 		return
 	}
 
@@ -144,28 +172,126 @@ func (s *Server) handlerLogin(c *gin.Context) {
 	})
 }
 
+// @Summary	SRP login, step 1
+// @Schemes
+// @Description	начало входа по SRP-6a (RFC 5054, группа 2048 бит, SHA-256): пароль серверу не передается.
+// @Description	Для неизвестного логина ответ неотличим от настоящего, вход не пройдет на втором шаге.
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			init	body		tHandlerSRPInitRequest	true	"логин и открытый ключ клиента A"
+// @Success		200		{object}	tHandlerSRPInitResponse	"сессия, соль и открытый ключ сервера B"
+// @failure		400		{object}	TProblem				"неверный формат запроса"
+// @failure		429		{object}	TProblem				"слишком много попыток или логин заблокирован"
+// @failure		500		{object}	TProblem				"внутренняя ошибка сервера"
+// @Router			/auth/srp/init [post]
+func (s *Server) handlerSRPInit(c *gin.Context) {
+	reqData := tHandlerSRPInitRequest{}
+	if err := c.ShouldBindJSON(&reqData); err != nil {
+		s.writeError(c, "", fmt.Errorf("%w: %w", errRequestNotValid, err))
+		return
+	}
+
+	ctx := keeper.WithClientIP(c.Request.Context(), c.ClientIP())
+	challenge, err := s.keeper.SRPInit(ctx, reqData.Login, reqData.ClientPublic)
+	if err != nil {
+		s.writeError(c, "failed srp init", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tHandlerSRPInitResponse{
+		Session:      challenge.Session,
+		Salt:         challenge.Salt,
+		ServerPublic: challenge.ServerPublic,
+	})
+}
+
+// @Summary	SRP login, step 2
+// @Schemes
+// @Description	проверка доказательства клиента M1. В ответе токен и доказательство сервера M2:
+// @Description	клиент проверяет M2, чтобы убедиться, что сервер знает его верификатор.
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			verify	body		tHandlerSRPVerifyRequest	true	"сессия и доказательство клиента M1"
+// @Success		200		{object}	tHandlerSRPVerifyResponse	"пользователь успешно аутентифицирован"
+// @failure		400		{object}	TProblem					"неверный формат запроса"
+// @failure		401		{object}	TProblem					"логин или пароль не верный"
+// @failure		429		{object}	TProblem					"слишком много попыток или логин заблокирован"
+// @failure		500		{object}	TProblem					"внутренняя ошибка сервера"
+// @Router			/auth/srp/verify [post]
+func (s *Server) handlerSRPVerify(c *gin.Context) {
+	reqData := tHandlerSRPVerifyRequest{}
+	if err := c.ShouldBindJSON(&reqData); err != nil {
+		s.writeError(c, "", fmt.Errorf("%w: %w", errRequestNotValid, err))
+		return
+	}
+
+	ctx := keeper.WithClientIP(c.Request.Context(), c.ClientIP())
+	user, serverProof, err := s.keeper.SRPVerify(ctx, reqData.Session, reqData.Proof)
+	if err != nil {
+		if errors.Is(err, keeperr.ErrNotFound) {
+			err = keeperr.ErrLoginOrPasswordNotCorrect
+		}
+		s.writeError(c, "failed srp verify", err)
+		return
+	}
+
+	accessToken, err := s.newAccessToken(c, user.ID, user.SessionEpoch)
+	if err != nil {
+		s.writeError(c, "failed create access token", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tHandlerSRPVerifyResponse{
+		tResultResponse: tResultResponse{
+			Status:  true,
+			Message: "User authenticated",
+		},
+		AccessToken: accessToken,
+		ServerProof: serverProof,
+	})
+}
+
 // @Summary	Get Datas
 // @Schemes
-// @Description	получить данные пользователя
+// @Description	получить мета данные пользователя.
+// @Description	Без limit возвращается весь список с учетом фильтров, с limit - страница и next_cursor,
+// @Description	если есть следующая. Курсор действует только с той же сортировкой, в которой выдан.
 // @Tags			user
 // @Param			Authorization	header	string	true	"authorization"
+// @Param			limit			query	int		false	"размер страницы, до 1000"
+// @Param			cursor			query	string	false	"next_cursor предыдущей страницы"
+// @Param			data_type		query	string	false	"тип данных"	Enums(CARD, PASSWORD, TEXT, BINARY, SSH_KEY)
+// @Param			deleted			query	bool	false	"только удаленные (true) или только живые (false)"
+// @Param			updated_since	query	int		false	"update_dt не раньше, unix время"
+// @Param			folder			query	string	false	"папка"
+// @Param			sort			query	string	false	"поле сортировки"			Enums(id, title, update_dt)
+// @Param			order			query	string	false	"направление сортировки"	Enums(asc, desc)
 // @Accept			json
 // @Produce		json
 // @Success		200	{object}	tHalderGetDatasResponse	"данные получены"
-// @failure		401	"ошибка авторизации"
-// @failure		500	"внутренняя ошибка сервера"
+// @failure		400	{object}	TProblem				"неверные параметры запроса"
+// @failure		401	{object}	TProblem				"ошибка авторизации"
+// @failure		500	{object}	TProblem				"внутренняя ошибка сервера"
 // @Router			/user/data [get]
 func (s *Server) handlerGetDatas(c *gin.Context) {
 	userID, err := s.authUserID(c)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusUnauthorized)
+		s.writeError(c, "", errUnauthorized)
 		return
 	}
 
-	data, err := s.keeper.GetMetaDatasByUserID(c.Request.Context(), userID)
+	filter, err := parseListFilter(c)
 	if err != nil {
-		s.log.Error(errFailedGetData, zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		s.writeError(c, "", err)
+		return
+	}
+
+	data, next, err := s.keeper.ListSecrets(c.Request.Context(), userID, *filter)
+	if err != nil {
+		s.writeError(c, errFailedGetData, err)
+		return
 	}
 
 	res := []tHandlerGetData{}
@@ -174,18 +300,28 @@ func (s *Server) handlerGetDatas(c *gin.Context) {
 		res = append(res, tHandlerGetData{
 			ID:        _data[i].ID,
 			Title:     _data[i].Title,
+			Folder:    _data[i].Folder,
 			DataType:  _data[i].DataType,
 			IsDeleted: _data[i].IsDeleted,
 			UpdatedAt: _data[i].UpdateDT,
 		})
 	}
 
-	c.JSON(http.StatusOK, tHalderGetDatasResponse{
+	response := tHalderGetDatasResponse{
 		tResultResponse: tResultResponse{
 			Status: true,
 		},
 		Data: res,
-	})
+	}
+	if next != nil {
+		response.NextCursor, err = encodeListCursor(filter, next)
+		if err != nil {
+			s.writeError(c, "failed encode cursor", err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // @Summary	Get Data
@@ -197,39 +333,28 @@ func (s *Server) handlerGetDatas(c *gin.Context) {
 // @Accept			json
 // @Produce		json
 // @Success		200	{object}	THandlerGetDataResponse	"данные получены"
-// @failure		204	{object}	tResultErrorResponse	"нет данных"
-// @failure		400	{object}	tResultErrorResponse	"ошибка запроса"
-// @failure		401	"ошибка авторизации"
-// @failure		500	"внутренняя ошибка сервера"
+// @failure		404	{object}	TProblem				"данные не найдены"
+// @failure		400	{object}	TProblem				"ошибка запроса"
+// @failure		401	{object}	TProblem				"ошибка авторизации"
+// @failure		500	{object}	TProblem				"внутренняя ошибка сервера"
 // @Router			/user/data/{id} [get]
 func (s *Server) handlerGetData(c *gin.Context) {
-	_, err := s.authUserID(c)
+	userID, err := s.authUserID(c)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusUnauthorized)
+		s.writeError(c, "", errUnauthorized)
 		return
 	}
 
 	idS, _ := c.Params.Get("id")
 	id, err := strconv.Atoi(idS)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, tResultErrorResponse{
-			Status: false,
-			Error:  fmt.Sprintf("Data id `%v` is not correct", id),
-		})
+		s.writeError(c, "", fmt.Errorf("data id `%s`: %w", idS, errRequestNotValid))
 		return
 	}
 
-	data, err := s.keeper.GetSecret(c.Request.Context(), uint(id))
+	data, err := s.keeper.GetSecret(c.Request.Context(), uint(id), userID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNoContent, tResultErrorResponse{
-				Status: false,
-				Error:  "not found content",
-			})
-			return
-		}
-		s.log.Error(errFailedGetData, zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		s.writeError(c, errFailedGetData, err)
 		return
 	}
 
@@ -256,39 +381,28 @@ func (s *Server) handlerGetData(c *gin.Context) {
 // @Accept			json
 // @Produce		json
 // @Success		200	{object}	THandlerDelDataResponse	"данные удалены"
-// @failure		204	{object}	tResultErrorResponse	"нет данных"
-// @failure		400	{object}	tResultErrorResponse	"ошибка запроса"
-// @failure		401	"ошибка авторизации"
-// @failure		500	"внутренняя ошибка сервера"
+// @failure		404	{object}	TProblem				"данные не найдены"
+// @failure		400	{object}	TProblem				"ошибка запроса"
+// @failure		401	{object}	TProblem				"ошибка авторизации"
+// @failure		500	{object}	TProblem				"внутренняя ошибка сервера"
 // @Router			/user/data/{id} [delete]
 func (s *Server) handlerDelData(c *gin.Context) {
-	_, err := s.authUserID(c)
+	userID, err := s.authUserID(c)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusUnauthorized)
+		s.writeError(c, "", errUnauthorized)
 		return
 	}
 
 	idS, _ := c.Params.Get("id")
 	id, err := strconv.Atoi(idS)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, tResultErrorResponse{
-			Status: false,
-			Error:  fmt.Sprintf("Data id `%v` is not correct", id),
-		})
+		s.writeError(c, "", fmt.Errorf("data id `%s`: %w", idS, errRequestNotValid))
 		return
 	}
 
-	err = s.keeper.DelSecret(c.Request.Context(), uint(id))
+	err = s.keeper.DelSecret(c.Request.Context(), uint(id), userID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNoContent, tResultErrorResponse{
-				Status: false,
-				Error:  "not found content",
-			})
-			return
-		}
-		s.log.Error("failed delete data", zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		s.writeError(c, "failed delete data", err)
 		return
 	}
 
@@ -308,33 +422,28 @@ func (s *Server) handlerDelData(c *gin.Context) {
 // @Accept			json
 // @Produce		json
 // @Success		201	{object}	THandlerNewDataResponse	"данные добавлены"
-// @failure		400	{object}	tResultErrorResponse	"ошибка запроса"
-// @failure		401	"ошибка авторизации"
-// @failure		500	"внутренняя ошибка сервера"
+// @failure		400	{object}	TProblem				"ошибка запроса"
+// @failure		401	{object}	TProblem				"ошибка авторизации"
+// @failure		500	{object}	TProblem				"внутренняя ошибка сервера"
 // @Router			/user/data [post]
 func (s *Server) handlerNewData(c *gin.Context) {
 	userID, err := s.authUserID(c)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusUnauthorized)
+		s.writeError(c, "", errUnauthorized)
 		return
 	}
 
 	reqData := THandlerNewDataRequest{}
 	err = c.ShouldBindJSON(&reqData)
 	if err != nil {
-		s.log.Error("failed bind json", zap.Error(err))
-		c.JSON(http.StatusBadRequest, tResultErrorResponse{
-			Status: false,
-			Error:  "failed bind json",
-		})
+		s.writeError(c, "", fmt.Errorf("%w: %w", errRequestNotValid, err))
 		return
 	}
 
 	data, err := s.keeper.NewSecret(c.Request.Context(),
-		&reqData.Data, reqData.Title, reqData.DataType, reqData.UpdateDT, userID)
+		&reqData.Data, reqData.Title, reqData.Folder, reqData.DataType, reqData.UpdateDT, userID)
 	if err != nil {
-		s.log.Error(errFailedGetData, zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		s.writeError(c, errFailedGetData, err)
 		return
 	}
 
@@ -361,42 +470,34 @@ func (s *Server) handlerNewData(c *gin.Context) {
 // @Accept			json
 // @Produce		json
 // @Success		200	{object}	THandlerUpdDataResponse	"данные получены"
-// @failure		400	{object}	tResultErrorResponse	"ошибка запроса"
-// @failure		401	"ошибка авторизации"
-// @failure		500	"внутренняя ошибка сервера"
+// @failure		400	{object}	TProblem				"ошибка запроса"
+// @failure		401	{object}	TProblem				"ошибка авторизации"
+// @failure		500	{object}	TProblem				"внутренняя ошибка сервера"
 // @Router			/user/data/{id} [put]
 func (s *Server) handlerUpdData(c *gin.Context) {
 	userID, err := s.authUserID(c)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusUnauthorized)
+		s.writeError(c, "", errUnauthorized)
 		return
 	}
 	idS, _ := c.Params.Get("id")
 	id, err := strconv.Atoi(idS)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, tResultErrorResponse{
-			Status: false,
-			Error:  fmt.Sprintf("Data id `%v` is not correct", id),
-		})
+		s.writeError(c, "", fmt.Errorf("data id `%s`: %w", idS, errRequestNotValid))
 		return
 	}
 
 	rData := THandlerUpdDataRequest{}
 	err = c.ShouldBindJSON(&rData)
 	if err != nil {
-		s.log.Error("failed bind json", zap.Error(err))
-		c.JSON(http.StatusBadRequest, tResultErrorResponse{
-			Status: false,
-			Error:  "failed bind json",
-		})
+		s.writeError(c, "", fmt.Errorf("%w: %w", errRequestNotValid, err))
 		return
 	}
 
 	_, err = s.keeper.UpdSecret(c.Request.Context(),
-		uint(id), &rData.Data, rData.Title, rData.DataType, rData.UpdateDT, userID)
+		uint(id), &rData.Data, rData.Title, rData.Folder, rData.DataType, rData.UpdateDT, userID)
 	if err != nil {
-		s.log.Error(errFailedGetData, zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		s.writeError(c, errFailedGetData, err)
 		return
 	}
 
@@ -406,3 +507,328 @@ func (s *Server) handlerUpdData(c *gin.Context) {
 		},
 	})
 }
+
+// @Summary	Batch Data
+// @Schemes
+// @Description	применить пакет операций создания/обновления/удаления в одной транзакции
+// @Tags			user
+// @Param			Authorization	header	string					true	"authorization"
+// @Param			batch			body	THandlerBatchRequest	true	"операции"
+// @Accept			json
+// @Produce		json
+// @Success		200	{object}	THandlerBatchResponse	"операции применены, результат по каждой операции"
+// @failure		400	{object}	TProblem				"ошибка запроса"
+// @failure		401	{object}	TProblem				"ошибка авторизации"
+// @failure		500	{object}	TProblem				"внутренняя ошибка сервера"
+// @Router			/user/batch [post]
+func (s *Server) handlerBatch(c *gin.Context) {
+	userID, err := s.authUserID(c)
+	if err != nil {
+		s.writeError(c, "", errUnauthorized)
+		return
+	}
+
+	reqData := THandlerBatchRequest{}
+	err = c.ShouldBindJSON(&reqData)
+	if err != nil {
+		s.writeError(c, "", fmt.Errorf("%w: %w", errRequestNotValid, err))
+		return
+	}
+
+	ops := make([]models.BatchOperation, 0, len(reqData.Operations))
+	for _, op := range reqData.Operations {
+		ops = append(ops, models.BatchOperation{
+			Action:         op.Action,
+			ID:             op.ID,
+			Title:          op.Title,
+			Folder:         op.Folder,
+			DataType:       op.DataType,
+			IdempotencyKey: op.Key,
+			Data:           op.Data,
+			UpdateDT:       op.UpdateDT,
+		})
+	}
+
+	results, err := s.keeper.Batch(c.Request.Context(), userID, &ops)
+	if err != nil {
+		s.writeError(c, "failed apply batch", err)
+		return
+	}
+
+	res := make([]TBatchResult, 0, len(*results))
+	for i, r := range *results {
+		item := TBatchResult{
+			Action: ops[i].Action,
+			ID:     ops[i].ID,
+			Status: r.Error == nil,
+		}
+		switch {
+		case r.Error != nil:
+			item.Code, _ = errorCode(r.Error)
+			item.Error = r.Error.Error()
+		case r.Secret != nil:
+			item.ID = r.Secret.ID
+			item.Title = r.Secret.Title
+			item.DataType = r.Secret.DataType
+			item.UpdateDT = r.Secret.UpdateDT
+		}
+		res = append(res, item)
+	}
+
+	c.JSON(http.StatusOK, THandlerBatchResponse{
+		tResultResponse: tResultResponse{
+			Status: true,
+		},
+		Data: res,
+	})
+}
+
+// @Summary	Events
+// @Schemes
+// @Description	поток событий (Server-Sent Events) об изменении ревизии хранилища пользователя.
+// @Description	Сразу после подключения отправляется текущая ревизия, далее событие `revision` при каждом изменении.
+// @Tags			user
+// @Param			Authorization	header	string	true	"authorization"
+// @Produce		text/event-stream
+// @Success		200	{object}	tEventRevision	"поток событий"
+// @failure		401	{object}	TProblem		"ошибка авторизации"
+// @failure		500	{object}	TProblem		"внутренняя ошибка сервера"
+// @Router			/user/events [get]
+func (s *Server) handlerEvents(c *gin.Context) {
+	userID, err := s.authUserID(c)
+	if err != nil {
+		s.writeError(c, "", errUnauthorized)
+		return
+	}
+
+	ctx := c.Request.Context()
+	events, unsubscribe := s.keeper.Subscribe(userID)
+	defer unsubscribe()
+
+	revision, err := s.keeper.GetRevision(ctx, userID)
+	if err != nil {
+		s.writeError(c, "failed get revision", err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("revision", tEventRevision{Revision: revision})
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-events:
+			current, err := s.keeper.GetRevision(ctx, userID)
+			if err != nil {
+				s.log.Error("failed get revision", zap.Error(err))
+				return
+			}
+			if current == revision {
+				continue
+			}
+			revision = current
+			c.SSEvent("revision", tEventRevision{Revision: revision})
+			c.Writer.Flush()
+		}
+	}
+}
+
+// @Summary	Change password
+// @Schemes
+// @Description	смена пароля. Все выданные ранее токены отзываются, текущему клиенту возвращается новый токен.
+// @Description	Секреты шифруются ключом сервера, поэтому перешифровывать хранилище не требуется.
+// @Tags			user
+// @Param			Authorization	header	string							true	"authorization"
+// @Param			password		body	tHandlerChangePasswordRequest	true	"текущий и новый пароль"
+// @Accept			json
+// @Produce		json
+// @Success		200	{object}	tHandlerChangePasswordResponse	"пароль изменен"
+// @failure		400	{object}	TProblem						"неверный формат запроса или недопустимый пароль"
+// @failure		401	{object}	TProblem						"ошибка авторизации"
+// @failure		403	{object}	TProblem						"текущий пароль не верный"
+// @failure		429	{object}	TProblem						"слишком много попыток"
+// @failure		500	{object}	TProblem						"внутренняя ошибка сервера"
+// @Router			/user/password [post]
+func (s *Server) handlerChangePassword(c *gin.Context) {
+	userID, err := s.authUserID(c)
+	if err != nil {
+		s.writeError(c, "", errUnauthorized)
+		return
+	}
+
+	reqData := tHandlerChangePasswordRequest{}
+	err = c.ShouldBindJSON(&reqData)
+	if err != nil {
+		s.writeError(c, "", fmt.Errorf("%w: %w", errRequestNotValid, err))
+		return
+	}
+
+	ctx := keeper.WithClientIP(c.Request.Context(), c.ClientIP())
+	current := keeper.Proof{Password: reqData.OldPassword, Session: reqData.SRPSession, SRPProof: reqData.SRPProof}
+	next := keeper.Password{Plain: reqData.NewPassword, Salt: reqData.Salt, Verifier: reqData.Verifier}
+	epoch, err := s.keeper.ChangePassword(ctx, userID, current, next)
+	if err != nil {
+		s.writeError(c, "failed change password", err)
+		return
+	}
+
+	accessToken, err := s.newAccessToken(c, userID, epoch)
+	if err != nil {
+		s.writeError(c, "failed create access token", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tHandlerChangePasswordResponse{
+		tResultResponse: tResultResponse{
+			Status:  true,
+			Message: "Password changed",
+		},
+		AccessToken: accessToken,
+	})
+}
+
+// @Summary	Delete account
+// @Schemes
+// @Description	безвозвратно удаляет учетную запись и все секреты пользователя, нужно повторно ввести пароль.
+// @Description	Выданные пользователю токены перестают действовать.
+// @Tags			user
+// @Param			Authorization	header	string							true	"authorization"
+// @Param			password		body	tHandlerDeleteAccountRequest	true	"текущий пароль"
+// @Accept			json
+// @Produce		json
+// @Success		200	{object}	tResultResponse	"учетная запись удалена"
+// @failure		400	{object}	TProblem		"неверный формат запроса"
+// @failure		401	{object}	TProblem		"ошибка авторизации"
+// @failure		403	{object}	TProblem		"пароль не верный"
+// @failure		429	{object}	TProblem		"слишком много попыток"
+// @failure		500	{object}	TProblem		"внутренняя ошибка сервера"
+// @Router			/user [delete]
+func (s *Server) handlerDeleteAccount(c *gin.Context) {
+	userID, err := s.authUserID(c)
+	if err != nil {
+		s.writeError(c, "", errUnauthorized)
+		return
+	}
+
+	reqData := tHandlerDeleteAccountRequest{}
+	err = c.ShouldBindJSON(&reqData)
+	if err != nil {
+		s.writeError(c, "", fmt.Errorf("%w: %w", errRequestNotValid, err))
+		return
+	}
+
+	ctx := keeper.WithClientIP(c.Request.Context(), c.ClientIP())
+	current := keeper.Proof{Password: reqData.Password, Session: reqData.SRPSession, SRPProof: reqData.SRPProof}
+	if err := s.keeper.DeleteAccount(ctx, userID, current); err != nil {
+		s.writeError(c, "failed delete account", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tResultResponse{
+		Status:  true,
+		Message: "Account deleted",
+	})
+}
+
+// @Summary	Export data
+// @Schemes
+// @Description	зашифрованный архив всех секретов и мета данных пользователя (AES-256-GCM, gzip JSON).
+// @Description	Ключ к архиву отдается один раз в заголовке X-Export-Key и на сервере не сохраняется.
+// @Tags			user
+// @Param			Authorization	header	string	true	"authorization"
+// @Produce		octet-stream
+// @Success		200	{file}		file		"архив, ключ в заголовке X-Export-Key"
+// @failure		401	{object}	TProblem	"ошибка авторизации"
+// @failure		500	{object}	TProblem	"внутренняя ошибка сервера"
+// @Router			/user/export [get]
+func (s *Server) handlerExport(c *gin.Context) {
+	userID, err := s.authUserID(c)
+	if err != nil {
+		s.writeError(c, "", errUnauthorized)
+		return
+	}
+
+	ctx := keeper.WithClientIP(c.Request.Context(), c.ClientIP())
+	archive, key, err := s.keeper.Export(ctx, userID)
+	if err != nil {
+		s.writeError(c, "failed export", err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header(HeaderExportKey, key)
+	c.Header("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="secret-keeper-%s.skx"`, time.Now().Format("20060102")))
+	c.Data(http.StatusOK, "application/octet-stream", archive)
+}
+
+// @Summary	Registration policy
+// @Schemes
+// @Description	режим регистрации и правила для логина и пароля. Пароль, заданный верификатором SRP,
+// @Description	сервер не видит, поэтому клиент проверяет его по этим правилам сам.
+// @Tags			auth
+// @Produce		json
+// @Success		200	{object}	tHandlerPolicyResponse	"правила регистрации"
+// @failure		429	{object}	TProblem				"слишком много запросов"
+// @Router			/auth/policy [get]
+func (s *Server) handlerPolicy(c *gin.Context) {
+	info := s.keeper.Policy()
+	c.JSON(http.StatusOK, tHandlerPolicyResponse{
+		RegistrationMode:    info.RegistrationMode,
+		LoginPattern:        info.LoginPattern,
+		LoginMinLength:      info.LoginMinLength,
+		LoginMaxLength:      info.LoginMaxLength,
+		PasswordMinLength:   info.PasswordMinLength,
+		PasswordMinStrength: info.PasswordMinStrength,
+	})
+}
+
+// @Summary	Create invites
+// @Schemes
+// @Description	выпуск одноразовых кодов приглашения для регистрации. Коды отдаются один раз.
+// @Description	Ручка доступна, только если на сервере задан ADMIN_TOKEN.
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Param			Authorization	header		string							true	"Bearer ADMIN_TOKEN"
+// @Param			invites			body		tHandlerCreateInvitesRequest	true	"число и срок действия"
+// @Success		201				{object}	tHandlerCreateInvitesResponse	"коды приглашения"
+// @failure		400				{object}	TProblem						"неверный формат запроса"
+// @failure		401				{object}	TProblem						"ошибка авторизации"
+// @failure		429				{object}	TProblem						"слишком много запросов"
+// @failure		500				{object}	TProblem						"внутренняя ошибка сервера"
+// @Router			/admin/invites [post]
+func (s *Server) handlerCreateInvites(c *gin.Context) {
+	bBody, err := s.readBody(c)
+	if err != nil {
+		s.writeError(c, "", err)
+		return
+	}
+
+	jBody := tHandlerCreateInvitesRequest{}
+	err = json.Unmarshal(bBody, &jBody)
+	if err != nil {
+		s.writeError(c, "", fmt.Errorf("%w: %w", errRequestNotValid, err))
+		return
+	}
+
+	invites, err := s.keeper.CreateInvites(c.Request.Context(), jBody.Count, time.Duration(jBody.TTLHours)*time.Hour)
+	if err != nil {
+		s.writeError(c, "failed create invites", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tHandlerCreateInvitesResponse{Invites: invites})
+}
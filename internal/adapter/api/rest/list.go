@@ -0,0 +1,119 @@
+package rest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/internal/core/keeper"
+)
+
+var (
+	errCursorNotValid = errors.New("cursor is not valid")
+)
+
+// parseListFilter разбирает параметры запроса списка секретов.
+func parseListFilter(c *gin.Context) (*models.SecretFilter, error) {
+	filter := &models.SecretFilter{
+		Folder: c.Query("folder"),
+		Sort:   models.SortByID,
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("limit `%s`: %w", v, keeper.ErrListFilterNotValid)
+		}
+		filter.Limit = limit
+	}
+
+	if v := c.Query("data_type"); v != "" {
+		switch dataType := models.DataType(v); dataType {
+		case models.CARD, models.PASSWORD, models.TEXT, models.BINARY, models.SSH_KEY:
+			filter.DataType = dataType
+		default:
+			return nil, fmt.Errorf("data_type `%s`: %w", v, keeper.ErrListFilterNotValid)
+		}
+	}
+
+	if v := c.Query("deleted"); v != "" {
+		deleted, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("deleted `%s`: %w", v, keeper.ErrListFilterNotValid)
+		}
+		filter.Deleted = &deleted
+	}
+
+	if v := c.Query("updated_since"); v != "" {
+		since, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || since < 0 {
+			return nil, fmt.Errorf("updated_since `%s`: %w", v, keeper.ErrListFilterNotValid)
+		}
+		filter.UpdatedSince = since
+	}
+
+	if v := c.Query("sort"); v != "" {
+		switch sort := models.SecretSort(v); sort {
+		case models.SortByID, models.SortByTitle, models.SortByUpdateDT:
+			filter.Sort = sort
+		default:
+			return nil, fmt.Errorf("sort `%s`: %w", v, keeper.ErrListFilterNotValid)
+		}
+	}
+
+	switch v := c.Query("order"); v {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return nil, fmt.Errorf("order `%s`: %w", v, keeper.ErrListFilterNotValid)
+	}
+
+	if v := c.Query("cursor"); v != "" {
+		after, err := decodeListCursor(v, filter)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+
+	return filter, nil
+}
+
+func encodeListCursor(filter *models.SecretFilter, after *models.SecretCursor) (string, error) {
+	b, err := json.Marshal(tListCursor{
+		Sort:     filter.Sort,
+		Desc:     filter.Desc,
+		ID:       after.ID,
+		Title:    after.Title,
+		UpdateDT: after.UpdateDT,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed marshal cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeListCursor(v string, filter *models.SecretFilter) (*models.SecretCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return nil, errCursorNotValid
+	}
+	cursor := tListCursor{}
+	if err := json.Unmarshal(b, &cursor); err != nil {
+		return nil, errCursorNotValid
+	}
+	if cursor.Sort != filter.Sort || cursor.Desc != filter.Desc {
+		return nil, fmt.Errorf("cursor issued for another sort: %w", errCursorNotValid)
+	}
+	return &models.SecretCursor{
+		ID:       cursor.ID,
+		Title:    cursor.Title,
+		UpdateDT: cursor.UpdateDT,
+	}, nil
+}
@@ -1,12 +1,18 @@
 package rest
 
 import (
-	"net/http"
+	"crypto/subtle"
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 
+	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
 	"github.com/playmixer/secret-keeper/pkg/jwt"
+	"github.com/playmixer/secret-keeper/pkg/ratelimit"
+	"github.com/playmixer/secret-keeper/pkg/tlsconf"
 )
 
 func (s *Server) middlewareAuthorization(c *gin.Context) {
@@ -15,9 +21,90 @@ func (s *Server) middlewareAuthorization(c *gin.Context) {
 
 	token := strings.Replace(authHeader, "Bearer ", "", 1)
 	if ok, err := jwtManager.Verify(token); err != nil || !ok {
-		c.Writer.WriteHeader(http.StatusUnauthorized)
-		c.Abort()
+		s.writeError(c, "", errUnauthorized)
+		return
+	}
+
+	userID, err := s.authUserID(c)
+	if err != nil {
+		s.writeError(c, "", errUnauthorized)
+		return
+	}
+	epoch, err := s.authSessionEpoch(c)
+	if err != nil {
+		s.writeError(c, "", errUnauthorized)
+		return
+	}
+	if err := s.checkDevice(c); err != nil {
+		s.audit.Warn("token used from another device",
+			zap.Uint("user_id", userID),
+			zap.String("ip", c.ClientIP()))
+		s.writeError(c, "", fmt.Errorf("%w: %w", errUnauthorized, err))
+		return
+	}
+	// после смены пароля токены прошлых эпох отклоняются, удаленный пользователь тоже не проходит.
+	if err := s.keeper.CheckSession(c.Request.Context(), userID, epoch); err != nil {
+		if errors.Is(err, keeperr.ErrSessionRevoked) || errors.Is(err, keeperr.ErrNotFound) {
+			s.writeError(c, "", fmt.Errorf("%w: %w", errUnauthorized, keeperr.ErrSessionRevoked))
+			return
+		}
+		s.writeError(c, "failed check session", err)
+		return
 	}
 
 	c.Next()
 }
+
+// checkDevice при привязке к устройствам токен должен быть выдан сертификату, с которым пришел запрос.
+func (s *Server) checkDevice(c *gin.Context) error {
+	if !s.bindDevice {
+		return nil
+	}
+	params, err := s.authParams(c)
+	if err != nil {
+		return err
+	}
+	device := tlsconf.PeerFingerprint(c.Request.TLS)
+	if device == "" || subtle.ConstantTimeCompare([]byte(params[claimDevice]), []byte(device)) != 1 {
+		return errDeviceNotBound
+	}
+	return nil
+}
+
+// middlewareAdmin пропускает запросы с токеном администратора.
+func (s *Server) middlewareAdmin(c *gin.Context) {
+	token := strings.TrimPrefix(c.Request.Header.Get("Authorization"), "Bearer ")
+	if len(s.adminToken) == 0 || subtle.ConstantTimeCompare([]byte(token), s.adminToken) != 1 {
+		s.audit.Warn("admin authorization failed", zap.String("ip", c.ClientIP()))
+		s.writeError(c, "", errUnauthorized)
+		return
+	}
+	c.Next()
+}
+
+// middlewareRateLimit ограничивает частоту запросов с одного адреса, корзины разных scope независимы.
+func (s *Server) middlewareRateLimit(scope string, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.limiter == nil {
+			c.Next()
+			return
+		}
+
+		wait, err := s.limiter.Take(c.Request.Context(), scope+":"+c.ClientIP(), limit)
+		if err != nil {
+			s.writeError(c, "failed check rate limit", err)
+			return
+		}
+		if wait > 0 {
+			s.audit.Warn("request rejected, rate limited",
+				zap.String("scope", scope),
+				zap.String("ip", c.ClientIP()),
+				zap.String("path", c.FullPath()),
+				zap.Duration("retry_after", wait))
+			s.writeError(c, "", &keeperr.RetryError{Err: keeperr.ErrRateLimited, After: wait})
+			return
+		}
+
+		c.Next()
+	}
+}
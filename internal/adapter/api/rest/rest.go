@@ -1,17 +1,21 @@
 package rest
 
-//	@title		«GophKeeper»
-//	@version	1.0
-//	@BasePath	/api/v0
+// @title			«GophKeeper»
+// @version		1.0
+// @description	Ошибки отдаются в формате problem details (application/problem+json), поле code - стабильный код ошибки.
+// @BasePath		/api/v0
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -20,33 +24,81 @@ import (
 
 	_ "github.com/playmixer/secret-keeper/docs"
 	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/internal/core/keeper"
 	"github.com/playmixer/secret-keeper/pkg/jwt"
+	"github.com/playmixer/secret-keeper/pkg/ratelimit"
+	"github.com/playmixer/secret-keeper/pkg/tlsconf"
 )
 
 var (
+	defaultCertFile = "./cert/gophkeeper.crt"
+	defaultKeyFile  = "./cert/gophkeeper.key"
+
+	defaultAuthLimit = ratelimit.PerMinute(20)
+	defaultAPILimit  = ratelimit.PerMinute(600)
+
 	msgErrorCloseBody = "failed close body"
+
+	eventsKeepAlive = 30 * time.Second
 )
 
+// claimDevice отпечаток сертификата клиента, которому выдан токен.
+const claimDevice = "device"
+
 // Keeper - координатор.
 type Keeper interface {
-	Registration(ctx context.Context, login string, password string) error
+	Registration(ctx context.Context,
+		login string, password keeper.Password, withRecovery bool, invite string,
+	) (string, error)
+	Policy() keeper.PolicyInfo
+	CreateInvites(ctx context.Context, count int, ttl time.Duration) ([]string, error)
+	Recover(ctx context.Context, login, recoveryKey string, next keeper.Password) (*models.User, string, error)
 	Login(ctx context.Context, login, password string) (*models.User, error)
+	SRPInit(ctx context.Context, login string, clientPublic []byte) (*keeper.SRPChallenge, error)
+	SRPVerify(ctx context.Context, session string, clientProof []byte) (*models.User, []byte, error)
 	GetMetaDatasByUserID(ctx context.Context, userID uint) (*[]models.Secret, error)
-	GetSecret(ctx context.Context, ID uint) (*models.Secret, error)
+	GetSecret(ctx context.Context, ID, userID uint) (*models.Secret, error)
+	ListSecrets(ctx context.Context, userID uint, filter models.SecretFilter) (
+		*[]models.Secret, *models.SecretCursor, error,
+	)
 	NewSecret(ctx context.Context,
-		data *[]byte, title string, dataType models.DataType, updateDT int64, userID uint) (*models.Secret, error)
+		data *[]byte, title, folder string, dataType models.DataType, updateDT int64, userID uint,
+	) (*models.Secret, error)
 	UpdSecret(ctx context.Context, id uint,
-		data *[]byte, title string, dataType models.DataType, updateDT int64, userID uint) (*models.Secret, error)
-	DelSecret(ctx context.Context, id uint) error
+		data *[]byte, title string, folder *string, dataType models.DataType, updateDT int64, userID uint,
+	) (*models.Secret, error)
+	DelSecret(ctx context.Context, id uint, userID uint) error
+	Batch(ctx context.Context, userID uint, ops *[]models.BatchOperation) (*[]models.BatchResult, error)
+	GetRevision(ctx context.Context, userID uint) (int64, error)
+	Subscribe(userID uint) (<-chan struct{}, func())
+	ChangePassword(ctx context.Context, userID uint, current keeper.Proof, next keeper.Password) (int64, error)
+	CheckSession(ctx context.Context, userID uint, epoch int64) error
+	DeleteAccount(ctx context.Context, userID uint, current keeper.Proof) error
+	Export(ctx context.Context, userID uint) ([]byte, string, error)
 }
 
 // Server - сервер.
 type Server struct {
 	srv       *http.Server
+	tlsConfig *tls.Config
 	log       *zap.Logger
+	audit     *zap.Logger
 	keeper    Keeper
+	limiter   ratelimit.Backend
 	secretKey []byte
-	sslEnable bool
+	// adminToken токен администратора, пустой - административные ручки выключены.
+	adminToken []byte
+	certFile   string
+	keyFile    string
+	authLimit  ratelimit.Limit
+	apiLimit   ratelimit.Limit
+	sslEnable  bool
+	// bindDevice токен действует только с тем сертификатом клиента, с которым он выдан.
+	bindDevice bool
+	// trustedProxies подсети прокси, которым сервер верит в заголовках адреса клиента, nil - никому.
+	trustedProxies []*net.IPNet
+	// configErr ошибка разбора настроек, ее возвращает New.
+	configErr error
 }
 
 type option func(*Server)
@@ -55,7 +107,50 @@ type option func(*Server)
 func SetConfig(cfg Config) option {
 	return func(s *Server) {
 		s.srv.Addr = cfg.Address
+		if cfg.AuthRateLimit > 0 {
+			s.authLimit = ratelimit.PerMinute(cfg.AuthRateLimit)
+		}
+		if cfg.APIRateLimit > 0 {
+			s.apiLimit = ratelimit.PerMinute(cfg.APIRateLimit)
+		}
+		s.adminToken = []byte(cfg.AdminToken)
+		if cfg.CertFile != "" {
+			s.certFile = cfg.CertFile
+		}
+		if cfg.KeyFile != "" {
+			s.keyFile = cfg.KeyFile
+		}
+		s.trustedProxies, s.configErr = parseTrustedProxies(cfg.TrustedProxies)
+	}
+}
+
+// parseTrustedProxies разбирает адреса и подсети прокси, одиночный адрес становится подсетью из одного адреса.
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("trusted proxy %q is not ip address", proxy)
+			}
+			bits := net.IPv6len * 8
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, net.IPv4len*8
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", proxy, err)
+		}
+		nets = append(nets, ipNet)
 	}
+	return nets, nil
 }
 
 func SetLogger(log *zap.Logger) option {
@@ -76,17 +171,47 @@ func SetSSLEnable(enable bool) option {
 	}
 }
 
+// SetTLSConfig настройки TLS, вместо чтения сертификата из файлов при запуске.
+func SetTLSConfig(cfg *tls.Config) option {
+	return func(s *Server) {
+		s.tlsConfig = cfg
+	}
+}
+
+// SetDeviceBinding привязывает токены к сертификату клиента: токен, выданный устройству,
+// не принимается с другим сертификатом или без него. Имеет смысл, только если сервер требует сертификат клиента.
+func SetDeviceBinding(enable bool) option {
+	return func(s *Server) {
+		s.bindDevice = enable
+	}
+}
+
+// SetLimiter включает ограничение частоты запросов с одного адреса.
+func SetLimiter(backend ratelimit.Backend) option {
+	return func(s *Server) {
+		s.limiter = backend
+	}
+}
+
 // New создаём рест сервер.
 func New(keeper Keeper, options ...option) (*Server, error) {
 	s := &Server{
-		srv:    &http.Server{},
-		keeper: keeper,
-		log:    zap.NewNop(),
+		srv:       &http.Server{},
+		keeper:    keeper,
+		log:       zap.NewNop(),
+		authLimit: defaultAuthLimit,
+		apiLimit:  defaultAPILimit,
+		certFile:  defaultCertFile,
+		keyFile:   defaultKeyFile,
 	}
 
 	for _, opt := range options {
 		opt(s)
 	}
+	s.audit = s.log.Named("audit")
+	if s.configErr != nil {
+		return nil, fmt.Errorf("failed parse config: %w", s.configErr)
+	}
 
 	return s, nil
 }
@@ -94,12 +219,27 @@ func New(keeper Keeper, options ...option) (*Server, error) {
 // Engin - ручки сервера.
 func (s *Server) Engin() *gin.Engine {
 	r := gin.Default()
+	// адрес клиента для ограничения частоты и аудита берется из заголовков только от доверенных прокси,
+	// иначе клиент подставит любой адрес в X-Forwarded-For. Подсети уже разобраны в SetConfig.
+	var proxies []string
+	for _, ipNet := range s.trustedProxies {
+		proxies = append(proxies, ipNet.String())
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		s.log.Error("failed set trusted proxies", zap.Error(err))
+	}
 	api := r.Group("/api/v0")
+	api.Use(s.middlewareRateLimit("api", s.apiLimit))
 	{
 		auth := api.Group("/auth")
+		auth.Use(s.middlewareRateLimit("auth", s.authLimit))
 		{
 			auth.POST("/registration", s.handlerRegistration)
 			auth.POST("/login", s.handlerLogin)
+			auth.POST("/recover", s.handlerRecover)
+			auth.POST("/srp/init", s.handlerSRPInit)
+			auth.POST("/srp/verify", s.handlerSRPVerify)
+			auth.GET("/policy", s.handlerPolicy)
 		}
 		user := api.Group("/user")
 		user.Use(s.middlewareAuthorization)
@@ -109,6 +249,18 @@ func (s *Server) Engin() *gin.Engine {
 			user.POST("/data", s.handlerNewData)
 			user.PUT("/data/:id", s.handlerUpdData)
 			user.DELETE("/data/:id", s.handlerDelData)
+			user.POST("/batch", s.handlerBatch)
+			user.GET("/events", s.handlerEvents)
+			user.POST("/password", s.handlerChangePassword)
+			user.DELETE("", s.handlerDeleteAccount)
+			user.GET("/export", s.handlerExport)
+		}
+		if len(s.adminToken) > 0 {
+			admin := api.Group("/admin")
+			admin.Use(s.middlewareRateLimit("auth", s.authLimit), s.middlewareAdmin)
+			{
+				admin.POST("/invites", s.handlerCreateInvites)
+			}
 		}
 	}
 
@@ -126,7 +278,13 @@ func (s *Server) Run() error {
 			return fmt.Errorf("server has failed: %w", err)
 		}
 	case true:
-		if err := s.srv.ListenAndServeTLS("./cert/gophkeeper.crt", "./cert/gophkeeper.key"); err != nil {
+		certFile, keyFile := s.certFile, s.keyFile
+		if s.tlsConfig != nil {
+			// сертификат берется из настроек на каждое подключение.
+			s.srv.TLSConfig = s.tlsConfig
+			certFile, keyFile = "", ""
+		}
+		if err := s.srv.ListenAndServeTLS(certFile, keyFile); err != nil {
 			return fmt.Errorf("server has failed: %w", err)
 		}
 	}
@@ -134,18 +292,17 @@ func (s *Server) Run() error {
 	return nil
 }
 
-func (s *Server) readBody(c *gin.Context) ([]byte, int) {
+func (s *Server) readBody(c *gin.Context) ([]byte, error) {
 	bBody, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		s.log.Error("failed read body", zap.Error(err))
-		return []byte{}, http.StatusBadRequest
+		return []byte{}, fmt.Errorf("failed read body: %w", errors.Join(errRequestNotValid, err))
 	}
 	defer func() {
 		if err := c.Request.Body.Close(); err != nil {
 			s.log.Error(msgErrorCloseBody, zap.Error(err))
 		}
 	}()
-	return bBody, 0
+	return bBody, nil
 }
 
 func (s *Server) authParams(c *gin.Context) (map[string]string, error) {
@@ -176,3 +333,37 @@ func (s *Server) authUserID(c *gin.Context) (uint, error) {
 	}
 	return 0, errors.New("user_id not found from authorization")
 }
+
+// authSessionEpoch эпоха сессий из токена, у токенов без эпохи она нулевая.
+func (s *Server) authSessionEpoch(c *gin.Context) (int64, error) {
+	params, err := s.authParams(c)
+	if err != nil {
+		return 0, fmt.Errorf("failed get params from authorization: %w", err)
+	}
+	strEpoch, ok := params["session_epoch"]
+	if !ok {
+		return 0, nil
+	}
+	epoch, err := strconv.ParseInt(strEpoch, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed convert session_epoch to int: %w", err)
+	}
+	return epoch, nil
+}
+
+// newAccessToken токен доступа пользователя в эпохе сессий epoch.
+// Если клиент предъявил сертификат, токен привязывается к его отпечатку.
+func (s *Server) newAccessToken(c *gin.Context, userID uint, epoch int64) (string, error) {
+	params := map[string]string{
+		"user_id":       strconv.Itoa(int(userID)),
+		"session_epoch": strconv.FormatInt(epoch, 10),
+	}
+	if device := tlsconf.PeerFingerprint(c.Request.TLS); device != "" {
+		params[claimDevice] = device
+	}
+	token, err := jwt.New(s.secretKey).Create(params)
+	if err != nil {
+		return "", fmt.Errorf("failed create token: %w", err)
+	}
+	return token, nil
+}
@@ -9,17 +9,37 @@ type tResultResponse struct {
 	Status  bool   `json:"status"`
 }
 
-type tResultErrorResponse struct {
-	Error  string `json:"error"`
-	Status bool   `json:"status"`
-}
-
+// tHandlerRegistrationRequest пароль передается открытым текстом (устаревший вход)
+// или солью и верификатором SRP, вычисленными клиентом.
 type tHandlerRegistrationRequest struct {
 	Login    string `json:"login"`
-	Password string `json:"password"`
+	Password string `json:"password,omitempty"`
+	Salt     []byte `json:"salt,omitempty"`
+	Verifier []byte `json:"verifier,omitempty"`
+	// Recovery создать ключ восстановления доступа.
+	Recovery bool `json:"recovery"`
+	// Invite код приглашения, нужен в режиме регистрации по приглашениям.
+	Invite string `json:"invite,omitempty"`
 }
 
 type tHandlerRegistrationResponse struct {
+	// RecoveryKey ключ восстановления, отдается только в этом ответе.
+	RecoveryKey string `json:"recovery_key,omitempty"`
+	tResultResponse
+}
+
+type tHandlerRecoverRequest struct {
+	Login       string `json:"login"`
+	RecoveryKey string `json:"recovery_key"`
+	NewPassword string `json:"new_password,omitempty"`
+	Salt        []byte `json:"salt,omitempty"`
+	Verifier    []byte `json:"verifier,omitempty"`
+}
+
+// tHandlerRecoverResponse токен и новый ключ восстановления, использованный ключ больше не действует.
+type tHandlerRecoverResponse struct {
+	AccessToken string `json:"access_token"`
+	RecoveryKey string `json:"recovery_key"`
 	tResultResponse
 }
 
@@ -33,8 +53,58 @@ type tHandlerLoginResponse struct {
 	tResultResponse
 }
 
+// tHandlerChangePasswordRequest текущий пароль подтверждается паролем или доказательством SRP,
+// новый задается паролем или солью и верификатором SRP.
+type tHandlerChangePasswordRequest struct {
+	OldPassword string `json:"old_password,omitempty"`
+	NewPassword string `json:"new_password,omitempty"`
+	SRPSession  string `json:"srp_session,omitempty"`
+	SRPProof    []byte `json:"srp_proof,omitempty"`
+	Salt        []byte `json:"salt,omitempty"`
+	Verifier    []byte `json:"verifier,omitempty"`
+}
+
+// tHandlerChangePasswordResponse новый токен текущего клиента, остальные сессии отозваны.
+type tHandlerChangePasswordResponse struct {
+	AccessToken string `json:"access_token"`
+	tResultResponse
+}
+
+type tHandlerDeleteAccountRequest struct {
+	Password   string `json:"password,omitempty"`
+	SRPSession string `json:"srp_session,omitempty"`
+	SRPProof   []byte `json:"srp_proof,omitempty"`
+}
+
+// tHandlerSRPInitRequest логин и открытый ключ клиента A.
+type tHandlerSRPInitRequest struct {
+	Login        string `json:"login"`
+	ClientPublic []byte `json:"a"`
+}
+
+// tHandlerSRPInitResponse сессия входа, соль верификатора и открытый ключ сервера B.
+type tHandlerSRPInitResponse struct {
+	Session      string `json:"session"`
+	Salt         []byte `json:"salt"`
+	ServerPublic []byte `json:"b"`
+}
+
+// tHandlerSRPVerifyRequest доказательство клиента M1.
+type tHandlerSRPVerifyRequest struct {
+	Session string `json:"session"`
+	Proof   []byte `json:"m1"`
+}
+
+// tHandlerSRPVerifyResponse токен и доказательство сервера M2, клиент проверяет его до использования токена.
+type tHandlerSRPVerifyResponse struct {
+	AccessToken string `json:"access_token"`
+	ServerProof []byte `json:"m2"`
+	tResultResponse
+}
+
 type tHandlerGetData struct {
 	Title     string          `json:"title"`
+	Folder    string          `json:"folder"`
 	DataType  models.DataType `json:"data_type"`
 	UpdatedAt int64           `json:"update_dt"`
 	ID        uint            `json:"id"`
@@ -43,11 +113,22 @@ type tHandlerGetData struct {
 
 type tHalderGetDatasResponse struct {
 	tResultResponse
-	Data []tHandlerGetData `json:"data"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+	Data       []tHandlerGetData `json:"data"`
+}
+
+// tListCursor содержимое курсора страницы, привязан к сортировке в которой выдан.
+type tListCursor struct {
+	Sort     models.SecretSort `json:"s"`
+	Title    string            `json:"t,omitempty"`
+	ID       uint              `json:"i"`
+	UpdateDT int64             `json:"u,omitempty"`
+	Desc     bool              `json:"d,omitempty"`
 }
 
 type THandlerNewDataRequest struct {
 	Title    string          `json:"title"`
+	Folder   string          `json:"folder"`
 	DataType models.DataType `json:"data_type"`
 	Data     []byte          `json:"data"`
 	UpdateDT int64           `json:"update_dt"`
@@ -80,7 +161,9 @@ type THandlerGetDataResponse struct {
 }
 
 type THandlerUpdDataRequest struct {
-	Title     string          `json:"title"`
+	Title string `json:"title"`
+	// Folder папка секрета: без поля папка не меняется, пустая строка - перенести секрет из папки.
+	Folder    *string         `json:"folder"`
 	DataType  models.DataType `json:"data_type"`
 	Data      []byte          `json:"data"`
 	IsDeleted bool            `json:"is_deleted"`
@@ -94,3 +177,62 @@ type THandlerUpdDataResponse struct {
 type THandlerDelDataResponse struct {
 	tResultResponse
 }
+
+type TBatchOperation struct {
+	Action models.BatchAction `json:"action"`
+	Title  string             `json:"title"`
+	// Folder папка секрета: без поля при обновлении папка не меняется, пустая строка - перенести секрет из папки.
+	Folder   *string         `json:"folder,omitempty"`
+	DataType models.DataType `json:"data_type"`
+	Data     []byte          `json:"data"`
+	ID       uint            `json:"id"`
+	UpdateDT int64           `json:"update_dt"`
+	// Key ключ идемпотентности создания, повтор с тем же ключом не создает второй секрет.
+	Key string `json:"key,omitempty"`
+}
+
+type THandlerBatchRequest struct {
+	Operations []TBatchOperation `json:"operations"`
+}
+
+type TBatchResult struct {
+	Action   models.BatchAction `json:"action"`
+	Title    string             `json:"title"`
+	DataType models.DataType    `json:"data_type"`
+	Code     ErrorCode          `json:"code,omitempty"`
+	Error    string             `json:"error"`
+	ID       uint               `json:"id"`
+	UpdateDT int64              `json:"update_dt"`
+	Status   bool               `json:"status"`
+}
+
+type THandlerBatchResponse struct {
+	tResultResponse
+	Data []TBatchResult `json:"data"`
+}
+
+type tEventRevision struct {
+	Revision int64 `json:"revision"`
+}
+
+// tHandlerPolicyResponse правила регистрации. Пароль, заданный верификатором SRP,
+// клиент проверяет по ним сам: сервер его не видит.
+type tHandlerPolicyResponse struct {
+	// RegistrationMode open, invite или disabled.
+	RegistrationMode    string `json:"registration_mode"`
+	LoginPattern        string `json:"login_pattern,omitempty"`
+	LoginMinLength      int    `json:"login_min_length"`
+	LoginMaxLength      int    `json:"login_max_length"`
+	PasswordMinLength   int    `json:"password_min_length"`
+	PasswordMinStrength int    `json:"password_min_strength"`
+}
+
+type tHandlerCreateInvitesRequest struct {
+	Count int `json:"count"`
+	// TTLHours срок действия приглашений в часах.
+	TTLHours int `json:"ttl_hours"`
+}
+
+type tHandlerCreateInvitesResponse struct {
+	Invites []string `json:"invites"`
+}
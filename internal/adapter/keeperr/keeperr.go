@@ -1,10 +1,35 @@
 package keeperr
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	ErrNotFound = errors.New("not found")
 
+	ErrBatchActionUnknown = errors.New("batch action unknown")
+
 	ErrLoginNotUnique            = errors.New("login not unique")
 	ErrLoginOrPasswordNotCorrect = errors.New("login or password not correct")
+	ErrSessionRevoked            = errors.New("session revoked")
+	ErrInviteNotValid            = errors.New("invite code is not valid or already used")
+
+	ErrRateLimited   = errors.New("too many requests")
+	ErrAccountLocked = errors.New("account temporarily locked")
 )
+
+// RetryError - запрос отклонен, повторить его можно через After.
+type RetryError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", e.Err, e.After.Round(time.Second))
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
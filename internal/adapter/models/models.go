@@ -1,11 +1,49 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model
 	Login        string `gorm:"index:,unique"`
 	PasswordHash string
+	Revision     int64
+	// SessionEpoch эпоха сессий, токены с другой эпохой недействительны.
+	SessionEpoch int64
+	// RecoveryHash отпечаток ключа восстановления, пустой - ключ не создавался.
+	RecoveryHash string
+	SRPSalt      []byte
+	// SRPVerifier верификатор пароля SRP-6a, пустой - пользователь еще не входил по SRP.
+	SRPVerifier []byte
+}
+
+// Credentials данные проверки пароля пользователя.
+// Пустой PasswordHash - пароль серверу не известен, вход только по SRP.
+type Credentials struct {
+	PasswordHash string
+	SRPSalt      []byte
+	SRPVerifier  []byte
+}
+
+// Invite код приглашения для регистрации, хранится только отпечаток кода.
+type Invite struct {
+	gorm.Model
+	CodeHash  string `gorm:"index:,unique"`
+	ExpiresAt time.Time
+	// UsedAt время регистрации по приглашению, nil - приглашение не использовано.
+	UsedAt *time.Time
+}
+
+// Credentials текущие данные проверки пароля.
+func (u *User) Credentials() Credentials {
+	return Credentials{
+		PasswordHash: u.PasswordHash,
+		SRPSalt:      u.SRPSalt,
+		SRPVerifier:  u.SRPVerifier,
+	}
 }
 
 type DataType string
@@ -15,18 +53,22 @@ const (
 	PASSWORD DataType = "PASSWORD"
 	TEXT     DataType = "TEXT"
 	BINARY   DataType = "BINARY"
+	SSH_KEY  DataType = "SSH_KEY"
 )
 
 type Secret struct {
 	User User
 	gorm.Model
-	Title     string
-	DataType  DataType `sql:"type:ENUM('CARD', 'PASSWORD', 'TEXT', 'BINARY')" gorm:"data_type"`
-	MetaName  string
-	Data      []byte
-	UserID    uint
-	UpdateDT  int64
-	IsDeleted bool
+	Title    string
+	DataType DataType `sql:"type:ENUM('CARD', 'PASSWORD', 'TEXT', 'BINARY', 'SSH_KEY')" gorm:"data_type"`
+	MetaName string
+	Folder   string `gorm:"index"`
+	Data     []byte
+	UserID   uint `gorm:"uniqueIndex:idx_secret_idempotency,priority:1"`
+	// IdempotencyKey ключ, с которым клиент создал секрет, повторное создание с ним не дублирует секрет.
+	IdempotencyKey *string `gorm:"uniqueIndex:idx_secret_idempotency,priority:2"`
+	UpdateDT       int64
+	IsDeleted      bool
 }
 
 type Text struct {
@@ -42,11 +84,30 @@ type Card struct {
 	Expiry string `json:"Expiry"`
 }
 
+// PasswordSourceGit записи, созданные помощником git credential.
+const PasswordSourceGit = "git-credential"
+
 type Password struct {
 	Title    string `json:"Title"`
 	Site     string `json:"Site"`
 	Login    string `json:"Login"`
 	Password string `json:"Password"`
+	// OTP ключ одноразовых кодов второго фактора: URI otpauth://totp/... или секрет base32.
+	OTP string `json:"OTP,omitempty"`
+	// Source кто создал запись: пустой - пользователь, иначе программа, например PasswordSourceGit.
+	// Программа меняет и удаляет только свои записи.
+	Source string `json:"Source,omitempty"`
+}
+
+// SSHKey ключ SSH: закрытый ключ в формате OpenSSH или PEM, открытый ключ строкой authorized_keys.
+// Passphrase пароль зашифрованного закрытого ключа, пустой - ключ не зашифрован.
+type SSHKey struct {
+	Title       string `json:"Title"`
+	PrivateKey  string `json:"PrivateKey"`
+	PublicKey   string `json:"PublicKey"`
+	Fingerprint string `json:"Fingerprint"`
+	Comment     string `json:"Comment"`
+	Passphrase  string `json:"Passphrase"`
 }
 
 type Binary struct {
@@ -74,3 +135,65 @@ type FileMetaDataItem struct {
 	IsDeleted    bool     `json:"is_deleted"`
 	IsUpdated    bool     `json:"is_updated"`
 }
+
+// PendingOperation локальное изменение, которое еще не подтвердил сервер.
+// Данные не хранятся в операции, при отправке берется текущая локальная копия.
+type PendingOperation struct {
+	// Key ключ идемпотентности, с ним сервер не создаст секрет повторно.
+	Key     string      `json:"key"`
+	Action  BatchAction `json:"action"`
+	LocalID int64       `json:"local_id"`
+}
+
+type BatchAction string
+
+const (
+	BatchCreate BatchAction = "create"
+	BatchUpdate BatchAction = "update"
+	BatchDelete BatchAction = "delete"
+)
+
+type BatchOperation struct {
+	Action BatchAction
+	Title  string
+	// Folder папка секрета: nil - при обновлении папка не меняется, пустая - секрет вне папок.
+	Folder   *string
+	DataType DataType
+	// IdempotencyKey ключ создания: повтор операции с тем же ключом возвращает уже созданный секрет.
+	IdempotencyKey string
+	Data           []byte
+	ID             uint
+	UpdateDT       int64
+}
+
+type BatchResult struct {
+	Secret *Secret
+	Error  error
+}
+
+type SecretSort string
+
+const (
+	SortByID       SecretSort = "id"
+	SortByTitle    SecretSort = "title"
+	SortByUpdateDT SecretSort = "update_dt"
+)
+
+// SecretCursor позиция последнего отданного секрета, страница начинается строго после нее.
+type SecretCursor struct {
+	Title    string
+	ID       uint
+	UpdateDT int64
+}
+
+// SecretFilter параметры выборки списка секретов, нулевые значения не ограничивают выборку.
+type SecretFilter struct {
+	After        *SecretCursor
+	Deleted      *bool
+	DataType     DataType
+	Folder       string
+	Sort         SecretSort
+	Limit        int
+	UpdatedSince int64
+	Desc         bool
+}
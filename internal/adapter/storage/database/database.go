@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -44,29 +45,62 @@ func New(dsn string) (*Storage, error) {
 }
 
 func (s *Storage) migration() error {
-	if err := s.db.AutoMigrate(&models.User{}, &models.Secret{}); err != nil {
+	if err := s.db.AutoMigrate(&models.User{}, &models.Secret{}, &models.Invite{}); err != nil {
 		return fmt.Errorf("failed migrations: %w", err)
 	}
 	return nil
 }
 
-func (s *Storage) Registration(ctx context.Context, login, passwordHash string) error {
+// Registration создает пользователя. С непустым inviteHash в той же транзакции
+// погашается приглашение: неизвестное, просроченное или использованное дает ErrInviteNotValid.
+func (s *Storage) Registration(
+	ctx context.Context, login string, cred models.Credentials, recoveryHash, inviteHash string,
+) error {
 	user := &models.User{
 		Login:        login,
-		PasswordHash: passwordHash,
+		PasswordHash: cred.PasswordHash,
+		SRPSalt:      cred.SRPSalt,
+		SRPVerifier:  cred.SRPVerifier,
+		RecoveryHash: recoveryHash,
 	}
 
-	err := s.db.WithContext(ctx).Create(user).Error
-	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-			return fmt.Errorf("login not unique: %w %w", err, keeperr.ErrLoginNotUnique)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if inviteHash != "" {
+			now := time.Now()
+			res := tx.Model(&models.Invite{}).
+				Where("code_hash = ? AND used_at IS NULL AND expires_at > ?", inviteHash, now).
+				Update("used_at", now)
+			if res.Error != nil {
+				return fmt.Errorf("failed use invite: %w", res.Error)
+			}
+			if res.RowsAffected == 0 {
+				return keeperr.ErrInviteNotValid
+			}
+		}
+		if err := tx.Create(user).Error; err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+				return fmt.Errorf("login not unique: %w %w", err, keeperr.ErrLoginNotUnique)
+			}
+			return fmt.Errorf("failed create user: %w", err)
 		}
-		return fmt.Errorf("failed create user: %w", err)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed registration: %w", err)
 	}
 
 	return nil
 }
+
+// CreateInvites сохраняет новые приглашения.
+func (s *Storage) CreateInvites(ctx context.Context, invites []models.Invite) error {
+	if err := s.db.WithContext(ctx).Create(&invites).Error; err != nil {
+		return fmt.Errorf("failed create invites: %w", err)
+	}
+	return nil
+}
+
 func (s *Storage) GetUserByLogin(ctx context.Context, login string) (*models.User, error) {
 	user := &models.User{}
 	err := s.db.WithContext(ctx).Where("login = ?", login).First(user).Error
@@ -79,6 +113,159 @@ func (s *Storage) GetUserByLogin(ctx context.Context, login string) (*models.Use
 
 	return user, nil
 }
+
+// GetUserByID пользователь по идентификатору.
+func (s *Storage) GetUserByID(ctx context.Context, userID uint) (*models.User, error) {
+	user := &models.User{}
+	err := s.db.WithContext(ctx).Where("id = ?", userID).First(user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Join(keeperr.ErrNotFound, err)
+		}
+		return nil, fmt.Errorf("failed find user: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetSessionEpoch текущая эпоха сессий пользователя.
+func (s *Storage) GetSessionEpoch(ctx context.Context, userID uint) (int64, error) {
+	user := &models.User{}
+	err := s.db.WithContext(ctx).Select("session_epoch").Where("id = ?", userID).First(user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errors.Join(keeperr.ErrNotFound, err)
+		}
+		return 0, fmt.Errorf("failed get session epoch: %w", err)
+	}
+	return user.SessionEpoch, nil
+}
+
+// ChangePassword меняет данные проверки пароля и эпоху сессий одним обновлением.
+// Обновление проходит, только если данные не поменялись с момента проверки старого пароля.
+func (s *Storage) ChangePassword(ctx context.Context, userID uint, old, next models.Credentials) (int64, error) {
+	var epoch int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := whereCredentials(tx.Model(&models.User{}), userID, old).
+			Updates(credentialsUpdate(next))
+		if res.Error != nil {
+			return fmt.Errorf("failed update password: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("user `%v` with this password: %w", userID, keeperr.ErrNotFound)
+		}
+		user := &models.User{}
+		if err := tx.Select("session_epoch").Where("id = ?", userID).First(user).Error; err != nil {
+			return fmt.Errorf("failed get session epoch: %w", err)
+		}
+		epoch = user.SessionEpoch
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed change password user `%v`: %w", userID, err)
+	}
+	return epoch, nil
+}
+
+// RecoverPassword задает новый пароль и ключ восстановления, эпоха сессий увеличивается.
+// Обновление проходит, только если ключ восстановления еще не использован.
+func (s *Storage) RecoverPassword(
+	ctx context.Context, userID uint, recoveryHash string, next models.Credentials, newRecoveryHash string,
+) (int64, error) {
+	var epoch int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		update := credentialsUpdate(next)
+		update["recovery_hash"] = newRecoveryHash
+		res := tx.Model(&models.User{}).
+			Where("id = ? AND recovery_hash = ?", userID, recoveryHash).
+			Updates(update)
+		if res.Error != nil {
+			return fmt.Errorf("failed update password: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("user `%v` with this recovery key: %w", userID, keeperr.ErrNotFound)
+		}
+		user := &models.User{}
+		if err := tx.Select("session_epoch").Where("id = ?", userID).First(user).Error; err != nil {
+			return fmt.Errorf("failed get session epoch: %w", err)
+		}
+		epoch = user.SessionEpoch
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed recover password user `%v`: %w", userID, err)
+	}
+	return epoch, nil
+}
+
+// SetVerifier сохраняет верификатор SRP пользователю, у которого его еще нет.
+// Пароль не меняется, поэтому эпоха сессий остается прежней.
+func (s *Storage) SetVerifier(ctx context.Context, userID uint, salt, verifier []byte) error {
+	err := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND (srp_verifier IS NULL OR length(srp_verifier) = 0)", userID).
+		Updates(map[string]any{
+			"srp_salt":     salt,
+			"srp_verifier": verifier,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed set verifier user `%v`: %w", userID, err)
+	}
+	return nil
+}
+
+// UpdatePasswordHash заменяет хеш пароля пересчитанным, если пароль не сменили с момента проверки.
+// Пароль тот же, поэтому эпоха сессий остается прежней.
+func (s *Storage) UpdatePasswordHash(ctx context.Context, userID uint, oldHash, newHash string) error {
+	err := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND password_hash = ?", userID, oldHash).
+		Update("password_hash", newHash).Error
+	if err != nil {
+		return fmt.Errorf("failed update password hash user `%v`: %w", userID, err)
+	}
+	return nil
+}
+
+// whereCredentials условие, что данные проверки пароля не поменялись с момента проверки.
+func whereCredentials(tx *gorm.DB, userID uint, cred models.Credentials) *gorm.DB {
+	tx = tx.Where("id = ? AND password_hash = ?", userID, cred.PasswordHash)
+	if len(cred.SRPVerifier) == 0 {
+		return tx.Where("(srp_verifier IS NULL OR length(srp_verifier) = 0)")
+	}
+	return tx.Where("srp_verifier = ?", cred.SRPVerifier)
+}
+
+// credentialsUpdate новые данные проверки пароля, смена пароля отзывает выданные токены.
+func credentialsUpdate(next models.Credentials) map[string]any {
+	return map[string]any{
+		"password_hash": next.PasswordHash,
+		"srp_salt":      next.SRPSalt,
+		"srp_verifier":  next.SRPVerifier,
+		"session_epoch": gorm.Expr("session_epoch + ?", 1),
+	}
+}
+
+// DeleteUser удаляет пользователя и все его секреты безвозвратно одной транзакцией.
+// Удаление проходит, только если пароль не поменялся с момента его проверки.
+func (s *Storage) DeleteUser(ctx context.Context, userID uint, old models.Credentials) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := whereCredentials(tx.Unscoped(), userID, old).Delete(&models.User{})
+		if res.Error != nil {
+			return fmt.Errorf("failed delete user: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("user `%v` with this password: %w", userID, keeperr.ErrNotFound)
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Secret{}).Error; err != nil {
+			return fmt.Errorf("failed delete secrets: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed delete user `%v`: %w", userID, err)
+	}
+	return nil
+}
+
 func (s *Storage) GetMetaDatasByUserID(ctx context.Context, userID uint) (*[]models.Secret, error) {
 	data := []models.Secret{}
 	err := s.db.Where("user_id = ?", userID).Find(&data).Error
@@ -91,8 +278,71 @@ func (s *Storage) GetMetaDatasByUserID(ctx context.Context, userID uint) (*[]mod
 	return &data, nil
 }
 
+// ListSecrets выборка мета данных пользователя с пагинацией по курсору.
+func (s *Storage) ListSecrets(ctx context.Context, userID uint, filter models.SecretFilter) (*[]models.Secret, error) {
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID)
+	if filter.DataType != "" {
+		query = query.Where("data_type = ?", filter.DataType)
+	}
+	if filter.Deleted != nil {
+		query = query.Where("is_deleted = ?", *filter.Deleted)
+	}
+	if filter.UpdatedSince > 0 {
+		query = query.Where("update_dt >= ?", filter.UpdatedSince)
+	}
+	if filter.Folder != "" {
+		query = query.Where("folder = ?", filter.Folder)
+	}
+
+	column := string(filter.Sort)
+	if column == "" {
+		column = string(models.SortByID)
+	}
+	cmp, order := ">", "ASC"
+	if filter.Desc {
+		cmp, order = "<", "DESC"
+	}
+
+	if filter.After != nil {
+		var value any
+		switch filter.Sort {
+		case models.SortByTitle:
+			value = filter.After.Title
+		case models.SortByUpdateDT:
+			value = filter.After.UpdateDT
+		}
+		if value == nil {
+			query = query.Where("id "+cmp+" ?", filter.After.ID)
+		} else {
+			// id добавлен к сортировке, чтобы одинаковые значения не терялись между страницами.
+			query = query.Where(
+				fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, cmp),
+				value, value, filter.After.ID)
+		}
+	}
+
+	query = query.Order(column + " " + order)
+	if column != string(models.SortByID) {
+		query = query.Order("id " + order)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	data := []models.Secret{}
+	if err := query.Find(&data).Error; err != nil {
+		return nil, fmt.Errorf("failed list data: %w", err)
+	}
+	return &data, nil
+}
+
 func (s *Storage) NewSecret(ctx context.Context, secret *models.Secret) (*models.Secret, error) {
-	err := s.db.Create(secret).Error
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(secret).Error; err != nil {
+			return fmt.Errorf("failed create secret: %w", err)
+		}
+		return bumpRevision(tx, secret.UserID)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed create secret: %w", err)
 	}
@@ -100,33 +350,193 @@ func (s *Storage) NewSecret(ctx context.Context, secret *models.Secret) (*models
 	return secret, nil
 }
 
-func (s *Storage) GetSecret(ctx context.Context, id uint) (*models.Secret, error) {
+// GetSecret секрет пользователя userID, чужой секрет неотличим от отсутствующего.
+func (s *Storage) GetSecret(ctx context.Context, id, userID uint) (*models.Secret, error) {
 	secret := &models.Secret{}
-	err := s.db.Where("id = ?", id).First(secret).Error
+	err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(secret).Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Join(keeperr.ErrNotFound, err)
+		}
 		return nil, fmt.Errorf("failed get secret: %w", err)
 	}
 	return secret, nil
 }
 
-func (s *Storage) UpdSecret(ctx context.Context, secret *models.Secret) (*models.Secret, error) {
-	err := s.db.Where("id = ?", secret.ID).Save(secret).Error
+// UpdSecret обновляет секрет владельца secret.UserID. Без setFolder папка секрета остается прежней:
+// клиенты без поддержки папок ее не передают. С setFolder секрет переносится в secret.Folder, пустая - вне папок.
+// Меняются только данные секрета, ключ идемпотентности и дата создания остаются.
+func (s *Storage) UpdSecret(ctx context.Context, secret *models.Secret, setFolder bool) (*models.Secret, error) {
+	current := &models.Secret{}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("id = ? AND user_id = ?", secret.ID, secret.UserID).First(current).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.Join(keeperr.ErrNotFound, err)
+			}
+			return fmt.Errorf("failed get secret id=`%v`: %w", secret.ID, err)
+		}
+		update := map[string]any{
+			"title":     secret.Title,
+			"data":      secret.Data,
+			"data_type": secret.DataType,
+			"update_dt": secret.UpdateDT,
+		}
+		if setFolder {
+			update["folder"] = secret.Folder
+		}
+		err = tx.Model(current).Where("id = ? AND user_id = ?", secret.ID, secret.UserID).Updates(update).Error
+		if err != nil {
+			return fmt.Errorf("failed save secret: %w", err)
+		}
+		return bumpRevision(tx, secret.UserID)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed update secret: %w", err)
 	}
-	return secret, nil
+	return current, nil
 }
 
-func (s *Storage) DelSecret(ctx context.Context, id uint) error {
-	secret, err := s.GetSecret(ctx, id)
+// DelSecret помечает удаленным секрет пользователя userID.
+func (s *Storage) DelSecret(ctx context.Context, id, userID uint) error {
+	secret, err := s.GetSecret(ctx, id, userID)
 	if err != nil {
 		return fmt.Errorf("failed get secret: %w", err)
 	}
 	secret.IsDeleted = true
 	secret.Data = []byte{}
-	err = s.db.Save(secret).Error
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(secret).Error; err != nil {
+			return fmt.Errorf("failed save secret: %w", err)
+		}
+		return bumpRevision(tx, secret.UserID)
+	})
 	if err != nil {
 		return fmt.Errorf("failed save deleting secret id=`%v`: %w", id, err)
 	}
 	return nil
 }
+
+// GetRevision текущая ревизия хранилища пользователя.
+func (s *Storage) GetRevision(ctx context.Context, userID uint) (int64, error) {
+	user := &models.User{}
+	err := s.db.WithContext(ctx).Select("revision").Where("id = ?", userID).First(user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errors.Join(keeperr.ErrNotFound, err)
+		}
+		return 0, fmt.Errorf("failed get revision: %w", err)
+	}
+	return user.Revision, nil
+}
+
+// bumpRevision увеличивает ревизию хранилища пользователя, вызывается внутри транзакции изменения.
+func bumpRevision(tx *gorm.DB, userID uint) error {
+	err := tx.Model(&models.User{}).
+		Where("id = ?", userID).
+		UpdateColumn("revision", gorm.Expr("revision + ?", 1)).Error
+	if err != nil {
+		return fmt.Errorf("failed bump revision user `%v`: %w", userID, err)
+	}
+	return nil
+}
+
+func (s *Storage) Batch(ctx context.Context, userID uint, ops *[]models.BatchOperation) (*[]models.BatchResult, error) {
+	results := make([]models.BatchResult, len(*ops))
+	changed := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range *ops {
+			op := (*ops)[i]
+			// каждая операция в своей точке сохранения, ошибка операции не откатывает остальные.
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				secret, err := applyBatchOperation(tx, userID, &op)
+				if err != nil {
+					return err
+				}
+				results[i].Secret = secret
+				return nil
+			})
+			if err != nil {
+				if !errors.Is(err, keeperr.ErrNotFound) && !errors.Is(err, keeperr.ErrBatchActionUnknown) {
+					return fmt.Errorf("failed apply operation #%v: %w", i, err)
+				}
+				results[i].Error = err
+				continue
+			}
+			changed = true
+		}
+		if !changed {
+			return nil
+		}
+		return bumpRevision(tx, userID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed batch transaction: %w", err)
+	}
+
+	return &results, nil
+}
+
+func applyBatchOperation(tx *gorm.DB, userID uint, op *models.BatchOperation) (*models.Secret, error) {
+	if op.Action == models.BatchCreate {
+		secret := &models.Secret{
+			UserID:   userID,
+			Title:    op.Title,
+			DataType: op.DataType,
+			Data:     op.Data,
+			UpdateDT: op.UpdateDT,
+		}
+		if op.IdempotencyKey != "" {
+			secret.IdempotencyKey = &op.IdempotencyKey
+			// повтор создания: клиент не получил ответ, секрет уже создан.
+			existing := &models.Secret{}
+			err := tx.Where("user_id = ? AND idempotency_key = ?", userID, op.IdempotencyKey).First(existing).Error
+			if err == nil {
+				return existing, nil
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("failed get secret by idempotency key: %w", err)
+			}
+		}
+		if op.Folder != nil {
+			secret.Folder = *op.Folder
+		}
+		if err := tx.Create(secret).Error; err != nil {
+			return nil, fmt.Errorf("failed create secret: %w", err)
+		}
+		return secret, nil
+	}
+
+	secret := &models.Secret{}
+	err := tx.Where("id = ? AND user_id = ?", op.ID, userID).First(secret).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Join(keeperr.ErrNotFound, err)
+		}
+		return nil, fmt.Errorf("failed get secret id=`%v`: %w", op.ID, err)
+	}
+
+	switch op.Action {
+	case models.BatchUpdate:
+		secret.Title = op.Title
+		secret.DataType = op.DataType
+		secret.Data = op.Data
+		secret.UpdateDT = op.UpdateDT
+		if op.Folder != nil {
+			secret.Folder = *op.Folder
+		}
+	case models.BatchDelete:
+		secret.IsDeleted = true
+		secret.Data = []byte{}
+		if op.UpdateDT > 0 {
+			secret.UpdateDT = op.UpdateDT
+		}
+	default:
+		return nil, fmt.Errorf("action `%s`: %w", op.Action, keeperr.ErrBatchActionUnknown)
+	}
+
+	if err := tx.Save(secret).Error; err != nil {
+		return nil, fmt.Errorf("failed save secret id=`%v`: %w", op.ID, err)
+	}
+	return secret, nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -16,13 +17,31 @@ import (
 
 var (
 	lengthNameFile uint = 20
+
+	ErrNotOpen = errors.New("storage is not open")
+)
+
+// queueSuffix файл очереди изменений рядом с файлом мета данных,
+// sessionSuffix - файл сессии для входа без сервера, lockSuffix - отметка, что клиент заблокирован,
+// flockSuffix - файл межпроцессной блокировки хранилища.
+const (
+	queueSuffix   = ".queue"
+	sessionSuffix = ".session"
+	lockSuffix    = ".locked"
+	flockSuffix   = ".flock"
 )
 
+// Storage локальное хранилище пользователя, mu защищает открытое хранилище, мета данные и очередь:
+// к хранилищу одновременно обращаются интерфейс и фоновая синхронизация. Хранилище одного пользователя
+// могут открыть и другие процессы клиента (подкоманды, помощник git), поэтому каждое обращение
+// берет межпроцессную блокировку и перечитывает мета данные и очередь с диска.
 type Storage struct {
 	log      *zap.Logger
 	path     string
 	filename string
 	store    []models.FileMetaDataItem
+	queue    []models.PendingOperation
+	mu       sync.Mutex
 }
 
 type option func(*Storage)
@@ -53,14 +72,56 @@ func Init(options ...option) (*Storage, error) {
 }
 
 func (s *Storage) Open(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.log.Debug("Open store")
 	s.filename = tools.GetMD5Hash(name)
 	s.store = []models.FileMetaDataItem{}
+	s.queue = []models.PendingOperation{}
 
 	err := os.Mkdir(s.path, tools.Mode0755)
 	if err != nil && !errors.Is(err, os.ErrExist) {
 		return fmt.Errorf("failed create data directory: %w", err)
 	}
+	if err := s.locked(func() error { return nil }); err != nil {
+		s.filename = ""
+		return err
+	}
+	return nil
+}
+
+// locked выполняет fn под межпроцессной блокировкой с мета данными и очередью, перечитанными с диска:
+// изменения других процессов клиента не теряются при записи.
+func (s *Storage) locked(fn func() error) error {
+	if s.filename == "" {
+		return ErrNotOpen
+	}
+	f, err := os.OpenFile(s.getFullPath(s.filename+flockSuffix), os.O_CREATE|os.O_RDWR, tools.Mode0600)
+	if err != nil {
+		return fmt.Errorf("failed open lock file: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			s.log.Error("failed close lock file", zap.Error(err))
+		}
+	}()
+	if err := lockFile(f); err != nil {
+		return err
+	}
+	defer func() {
+		if err := unlockFile(f); err != nil {
+			s.log.Error("failed unlock lock file", zap.Error(err))
+		}
+	}()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// load читает мета данные и очередь, отсутствующий файл мета данных создается.
+func (s *Storage) load() error {
 	f, err := os.OpenFile(s.getFullPath(s.filename), os.O_CREATE|os.O_RDONLY, tools.Mode0600)
 	if err != nil {
 		return fmt.Errorf("failed open storage file: %w", err)
@@ -77,19 +138,150 @@ func (s *Storage) Open(name string) error {
 		return fmt.Errorf("failed read from storage file: %w", err)
 	}
 
-	if len(bData) == 0 {
+	store := []models.FileMetaDataItem{}
+	if len(bData) > 0 {
+		err = json.Unmarshal(bData, &store)
+		if err != nil {
+			return fmt.Errorf("failed unmarshal storage data: %w", err)
+		}
+	}
+	s.store = store
+
+	return s.openQueue()
+}
+
+// openQueue читает очередь неотправленных изменений, отсутствующий файл - очередь пуста.
+func (s *Storage) openQueue() error {
+	queue := []models.PendingOperation{}
+	bQueue, err := os.ReadFile(s.getFullPath(s.filename + queueSuffix))
+	if errors.Is(err, os.ErrNotExist) {
+		s.queue = queue
 		return nil
 	}
+	if err != nil {
+		return fmt.Errorf("failed read queue file: %w", err)
+	}
+	err = json.Unmarshal(bQueue, &queue)
+	if err != nil {
+		return fmt.Errorf("failed unmarshal queue: %w", err)
+	}
+	s.queue = queue
+	return nil
+}
+
+// saveQueue записывает очередь сразу при изменении, чтобы изменения пережили перезапуск клиента.
+func (s *Storage) saveQueue() error {
+	if s.filename == "" {
+		return ErrNotOpen
+	}
+	bQueue, err := json.Marshal(s.queue)
+	if err != nil {
+		return fmt.Errorf("failed marshal queue: %w", err)
+	}
+	err = os.WriteFile(s.getFullPath(s.filename+queueSuffix), bQueue, tools.Mode0600)
+	if err != nil {
+		return fmt.Errorf("failed write queue file: %w", err)
+	}
+	return nil
+}
+
+// Operations неотправленные изменения в порядке внесения.
+func (s *Storage) Operations() []models.PendingOperation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reload()
+	return append([]models.PendingOperation{}, s.queue...)
+}
+
+// AddOperation добавляет изменение в конец очереди.
+func (s *Storage) AddOperation(op models.PendingOperation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.locked(func() error {
+		s.queue = append(s.queue, op)
+		return s.saveQueue()
+	})
+}
+
+// RemoveOperation убирает из очереди изменение с ключом key.
+func (s *Storage) RemoveOperation(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.locked(func() error {
+		queue := make([]models.PendingOperation, 0, len(s.queue))
+		for _, op := range s.queue {
+			if op.Key != key {
+				queue = append(queue, op)
+			}
+		}
+		s.queue = queue
+		return s.saveQueue()
+	})
+}
+
+// Session зашифрованная сессия пользователя name, хранилище для чтения открывать не нужно.
+// Нет сессии - ошибка os.ErrNotExist.
+func (s *Storage) Session(name string) ([]byte, error) {
+	data, err := os.ReadFile(s.getFullPath(tools.GetMD5Hash(name) + sessionSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("failed read session file: %w", err)
+	}
+	return data, nil
+}
 
-	err = json.Unmarshal(bData, &s.store)
+// SaveSession сохраняет зашифрованную сессию пользователя name.
+func (s *Storage) SaveSession(name string, data []byte) error {
+	err := os.Mkdir(s.path, tools.Mode0755)
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("failed create data directory: %w", err)
+	}
+	err = os.WriteFile(s.getFullPath(tools.GetMD5Hash(name)+sessionSuffix), data, tools.Mode0600)
 	if err != nil {
-		return fmt.Errorf("failed unmarshal storage data: %w", err)
+		return fmt.Errorf("failed write session file: %w", err)
 	}
+	return nil
+}
 
+// RemoveSession удаляет сессию пользователя name, если она есть.
+func (s *Storage) RemoveSession(name string) error {
+	err := os.Remove(s.getFullPath(tools.GetMD5Hash(name) + sessionSuffix))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed remove session file: %w", err)
+	}
 	return nil
 }
 
+// SetLocked отмечает, что клиент пользователя name заблокирован, или снимает отметку.
+// Отметку видят другие процессы клиента, например помощник git.
+func (s *Storage) SetLocked(name string, locked bool) error {
+	path := s.getFullPath(tools.GetMD5Hash(name) + lockSuffix)
+	if !locked {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed remove lock file: %w", err)
+		}
+		return nil
+	}
+	err := os.Mkdir(s.path, tools.Mode0755)
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("failed create data directory: %w", err)
+	}
+	if err := os.WriteFile(path, nil, tools.Mode0600); err != nil {
+		return fmt.Errorf("failed write lock file: %w", err)
+	}
+	return nil
+}
+
+// Locked клиент пользователя name заблокирован.
+func (s *Storage) Locked(name string) bool {
+	_, err := os.Stat(s.getFullPath(tools.GetMD5Hash(name) + lockSuffix))
+	return err == nil
+}
+
 func (s *Storage) save() error {
+	if s.filename == "" {
+		return ErrNotOpen
+	}
 	s.log.Debug("Save store", zap.String("store", fmt.Sprint(s.store)))
 	bStore, err := json.Marshal(s.store)
 	if err != nil {
@@ -107,12 +299,72 @@ func (s *Storage) save() error {
 }
 
 func (s *Storage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.log.Debug("Close store", zap.String("store", fmt.Sprint(s.store)))
-	err := s.save()
+	if s.filename == "" {
+		return nil
+	}
+	err := s.locked(s.save)
 	if err != nil {
 		return fmt.Errorf("failed save storage: %w", err)
 	}
+	// после закрытия в памяти не остается данных пользователя, а запоздавшая запись не затрет файл.
 	s.store = []models.FileMetaDataItem{}
+	s.queue = []models.PendingOperation{}
+	s.filename = ""
+	return nil
+}
+
+// Drop удаляет локальную копию хранилища целиком: файлы данных и файл мета данных.
+func (s *Storage) Drop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log.Debug("Drop store")
+	if s.filename == "" {
+		s.store = []models.FileMetaDataItem{}
+		s.queue = []models.PendingOperation{}
+		return nil
+	}
+	if err := s.locked(s.drop); err != nil {
+		return err
+	}
+	// файл блокировки удаляется последним, его может держать открытым другой процесс клиента.
+	err := os.Remove(s.getFullPath(s.filename + flockSuffix))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.log.Error("failed remove flock file", zap.Error(err))
+	}
+	return nil
+}
+
+func (s *Storage) drop() error {
+	for _, m := range s.store {
+		if m.OriginalPath == "" {
+			continue
+		}
+		err := os.Remove(s.getFullPath(m.OriginalPath))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed remove data: %w", err)
+		}
+	}
+	err := os.Remove(s.getFullPath(s.filename))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed remove storage file: %w", err)
+	}
+	err = os.Remove(s.getFullPath(s.filename + queueSuffix))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed remove queue file: %w", err)
+	}
+	err = os.Remove(s.getFullPath(s.filename + sessionSuffix))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed remove session file: %w", err)
+	}
+	err = os.Remove(s.getFullPath(s.filename + lockSuffix))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed remove lock file: %w", err)
+	}
+	s.store = []models.FileMetaDataItem{}
+	s.queue = []models.PendingOperation{}
 	return nil
 }
 
@@ -125,6 +377,9 @@ func (s *Storage) UpdateDate() int64 {
 }
 
 func (s *Storage) writeFile(filename string, data *[]byte) error {
+	if s.filename == "" {
+		return ErrNotOpen
+	}
 	err := os.WriteFile(s.getFullPath(filename), *data, tools.Mode0600)
 	if err != nil {
 		return fmt.Errorf("failed write new card: %w", err)
@@ -134,6 +389,17 @@ func (s *Storage) writeFile(filename string, data *[]byte) error {
 }
 
 func (s *Storage) OpenData(id int64) (*[]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var data *[]byte
+	err := s.locked(func() (err error) {
+		data, err = s.openData(id)
+		return err
+	})
+	return data, err
+}
+
+func (s *Storage) openData(id int64) (*[]byte, error) {
 	res := []byte{}
 	filename := ""
 	for _, e := range s.store {
@@ -166,11 +432,33 @@ func (s *Storage) OpenData(id int64) (*[]byte, error) {
 	return &res, nil
 }
 
+// GetAll копия мета данных, ее можно читать, пока синхронизация меняет хранилище.
 func (s *Storage) GetAll() (*[]models.FileMetaDataItem, error) {
-	return &s.store, nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reload()
+	store := append([]models.FileMetaDataItem{}, s.store...)
+	return &store, nil
+}
+
+// reload перечитывает хранилище для чтения, при ошибке остаются данные, прочитанные раньше.
+func (s *Storage) reload() {
+	if s.filename == "" {
+		return
+	}
+	if err := s.locked(func() error { return nil }); err != nil {
+		s.log.Error("failed reload store", zap.Error(err))
+	}
 }
 
 func (s *Storage) Get(id int64) (*models.FileMetaDataItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reload()
+	return s.get(id)
+}
+
+func (s *Storage) get(id int64) (*models.FileMetaDataItem, error) {
 	for _, v := range s.store {
 		if v.ID == id {
 			return &v, nil
@@ -180,6 +468,12 @@ func (s *Storage) Get(id int64) (*models.FileMetaDataItem, error) {
 }
 
 func (s *Storage) UpdMeta(m *models.FileMetaDataItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.locked(func() error { return s.updMeta(m) })
+}
+
+func (s *Storage) updMeta(m *models.FileMetaDataItem) error {
 	s.log.Debug("upd meta store", zap.String("data", fmt.Sprint(*m)))
 	newStore := []models.FileMetaDataItem{}
 	for _, v := range s.store {
@@ -190,16 +484,30 @@ func (s *Storage) UpdMeta(m *models.FileMetaDataItem) error {
 		}
 	}
 	s.store = newStore
-	return nil
+	return s.save()
 }
 
 func (s *Storage) GetData(id int64) (*models.FileMetaDataItem, *[]byte, error) {
-	m, err := s.Get(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var (
+		m    *models.FileMetaDataItem
+		data *[]byte
+	)
+	err := s.locked(func() (err error) {
+		m, data, err = s.getData(id)
+		return err
+	})
+	return m, data, err
+}
+
+func (s *Storage) getData(id int64) (*models.FileMetaDataItem, *[]byte, error) {
+	m, err := s.get(id)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed found data: %w", err)
 	}
 
-	bData, err := s.OpenData(id)
+	bData, err := s.openData(id)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed open data: %w", err)
 	}
@@ -209,7 +517,23 @@ func (s *Storage) GetData(id int64) (*models.FileMetaDataItem, *[]byte, error) {
 
 func (s *Storage) NewData(eID uint, updateDT int64, title string, dataType models.DataType, data *[]byte) (
 	*models.FileMetaDataItem, error) {
-	filename := s.filename + tools.RandomString(lengthNameFile)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var m *models.FileMetaDataItem
+	err := s.locked(func() (err error) {
+		m, err = s.newData(eID, updateDT, title, dataType, data)
+		return err
+	})
+	return m, err
+}
+
+func (s *Storage) newData(eID uint, updateDT int64, title string, dataType models.DataType, data *[]byte) (
+	*models.FileMetaDataItem, error) {
+	suffix, err := tools.RandomString(lengthNameFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed generate data filename: %w", err)
+	}
+	filename := s.filename + suffix
 
 	m := models.FileMetaDataItem{
 		ID:           time.Now().UnixMicro(),
@@ -223,17 +547,26 @@ func (s *Storage) NewData(eID uint, updateDT int64, title string, dataType model
 		m.UpdateDT = updateDT
 	}
 
-	err := s.writeFile(filename, data)
+	err = s.writeFile(filename, data)
 	if err != nil {
 		return nil, fmt.Errorf("failed write new card: %w", err)
 	}
 
 	s.store = append(s.store, m)
+	if err := s.save(); err != nil {
+		return nil, err
+	}
 
 	return &m, nil
 }
 
 func (s *Storage) EditData(id int64, m *models.FileMetaDataItem, data *[]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.locked(func() error { return s.editData(id, m, data) })
+}
+
+func (s *Storage) editData(id int64, m *models.FileMetaDataItem, data *[]byte) error {
 	s.log.Debug("edit data", zap.Int64("id", id), zap.String("meta", fmt.Sprint(*m)), zap.Int("len", len(*data)))
 	err := s.writeFile(m.OriginalPath, data)
 	if err != nil {
@@ -241,7 +574,7 @@ func (s *Storage) EditData(id int64, m *models.FileMetaDataItem, data *[]byte) e
 	}
 
 	// m.UpdateDT = s.updateDate()
-	err = s.UpdMeta(m)
+	err = s.updMeta(m)
 	if err != nil {
 		return fmt.Errorf("failed upd meta store: %w", err)
 	}
@@ -250,7 +583,13 @@ func (s *Storage) EditData(id int64, m *models.FileMetaDataItem, data *[]byte) e
 }
 
 func (s *Storage) DelData(id int64) error {
-	m, _, err := s.GetData(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.locked(func() error { return s.delData(id) })
+}
+
+func (s *Storage) delData(id int64) error {
+	m, _, err := s.getData(id)
 	if err != nil {
 		return fmt.Errorf("failed get data: %w", err)
 	}
@@ -262,7 +601,7 @@ func (s *Storage) DelData(id int64) error {
 	}
 
 	m.IsDeleted = true
-	err = s.UpdMeta(m)
+	err = s.updMeta(m)
 	if err != nil {
 		return fmt.Errorf("failed upd meta store: %w", err)
 	}
@@ -271,7 +610,13 @@ func (s *Storage) DelData(id int64) error {
 }
 
 func (s *Storage) UploadFileToPath(id int64, path string) error {
-	m, err := s.Get(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.locked(func() error { return s.uploadFileToPath(id, path) })
+}
+
+func (s *Storage) uploadFileToPath(id int64, path string) error {
+	m, err := s.get(id)
 	if err != nil {
 		return fmt.Errorf("failed get meta data: %w", err)
 	}
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
 	"github.com/playmixer/secret-keeper/pkg/tools"
 )
 
@@ -36,3 +37,104 @@ func TestInit(t *testing.T) {
 		})
 	}
 }
+
+func TestStorage_Drop(t *testing.T) {
+	s, err := Init(SetPath("./test_drop"), SetLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll("./test_drop/")
+	}()
+	assert.NoError(t, s.Open("user"))
+	data := []byte("text")
+	m, err := s.NewData(1, 0, "title", models.TEXT, &data)
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Drop())
+	_, err = os.Stat("./test_drop/" + m.OriginalPath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat("./test_drop/" + tools.GetMD5Hash("user"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	items, err := s.GetAll()
+	assert.NoError(t, err)
+	assert.Empty(t, *items)
+}
+
+func TestStorage_queue(t *testing.T) {
+	s, err := Init(SetPath("./test_queue"), SetLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll("./test_queue/")
+	}()
+	assert.NoError(t, s.Open("user"))
+	assert.NoError(t, s.AddOperation(models.PendingOperation{Key: "a", Action: models.BatchCreate, LocalID: 1}))
+	assert.NoError(t, s.AddOperation(models.PendingOperation{Key: "b", Action: models.BatchUpdate, LocalID: 1}))
+	assert.NoError(t, s.RemoveOperation("a"))
+	assert.NoError(t, s.Close())
+
+	// очередь переживает перезапуск клиента.
+	assert.NoError(t, s.Open("user"))
+	assert.Equal(t, []models.PendingOperation{{Key: "b", Action: models.BatchUpdate, LocalID: 1}}, s.Operations())
+
+	assert.NoError(t, s.Drop())
+	assert.Empty(t, s.Operations())
+	_, err = os.Stat("./test_queue/" + tools.GetMD5Hash("user") + queueSuffix)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestStorage_SetLocked(t *testing.T) {
+	s, err := Init(SetPath("./test_locked"), SetLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll("./test_locked/")
+	}()
+	assert.False(t, s.Locked("user"))
+	assert.NoError(t, s.SetLocked("user", true))
+	assert.True(t, s.Locked("user"))
+	assert.False(t, s.Locked("other"))
+
+	// отметку видит другой процесс, которому хранилище открывать не нужно.
+	other, err := Init(SetPath("./test_locked"), SetLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	assert.True(t, other.Locked("user"))
+
+	assert.NoError(t, s.SetLocked("user", false))
+	assert.False(t, s.Locked("user"))
+	assert.NoError(t, s.SetLocked("user", false))
+}
+
+func TestStorage_twoProcesses(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll("./test_processes/")
+	}()
+	// хранилище одного пользователя открыто в двух процессах клиента, например в интерфейсе и подкоманде.
+	first, err := Init(SetPath("./test_processes"), SetLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	second, err := Init(SetPath("./test_processes"), SetLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	assert.NoError(t, first.Open("user"))
+	assert.NoError(t, second.Open("user"))
+
+	data := []byte("text")
+	m1, err := first.NewData(0, 0, "first", models.TEXT, &data)
+	assert.NoError(t, err)
+	m2, err := second.NewData(0, 0, "second", models.TEXT, &data)
+	assert.NoError(t, err)
+	assert.NoError(t, first.AddOperation(models.PendingOperation{Key: "a", Action: models.BatchCreate, LocalID: m1.ID}))
+	assert.NoError(t, second.AddOperation(models.PendingOperation{Key: "b", Action: models.BatchCreate, LocalID: m2.ID}))
+
+	for _, s := range []*Storage{first, second} {
+		items, err := s.GetAll()
+		assert.NoError(t, err)
+		assert.Len(t, *items, 2)
+		assert.Len(t, s.Operations(), 2)
+	}
+
+	assert.NoError(t, first.Close())
+	assert.NoError(t, second.DelData(m1.ID))
+	assert.NoError(t, second.Close())
+	assert.NoError(t, first.Open("user"))
+	m, err := first.Get(m1.ID)
+	assert.NoError(t, err)
+	assert.True(t, m.IsDeleted)
+	assert.Len(t, first.Operations(), 2)
+}
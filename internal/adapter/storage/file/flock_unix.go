@@ -0,0 +1,24 @@
+//go:build !windows
+
+package file
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockFile ждет исключительную блокировку файла, блокировку видят другие процессы клиента.
+func lockFile(f *os.File) error {
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed flock: %w", err)
+	}
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
+		return fmt.Errorf("failed unlock flock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package file
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile ждет исключительную блокировку файла, блокировку видят другие процессы клиента.
+func lockFile(f *os.File) error {
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0,
+		math.MaxUint32, math.MaxUint32, &windows.Overlapped{})
+	if err != nil {
+		return fmt.Errorf("failed lock file: %w", err)
+	}
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	err := windows.UnlockFileEx(windows.Handle(f.Fd()), 0, math.MaxUint32, math.MaxUint32, &windows.Overlapped{})
+	if err != nil {
+		return fmt.Errorf("failed unlock file: %w", err)
+	}
+	return nil
+}
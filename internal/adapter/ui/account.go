@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// exportPage сохраняет зашифрованный архив всех данных и показывает ключ к нему.
+func (t *terminal) exportPage() {
+	path := fmt.Sprintf("secret-keeper-%s.skx", time.Now().Format("20060102"))
+	lenInput := 40
+
+	form := tview.NewForm().
+		AddInputField("Файл", path, lenInput, nil, func(text string) { path = text }).
+		AddButton("Сохранить", func() {
+			key, err := t.api.EventExport(path)
+			if err != nil {
+				t.errorPage(fmt.Sprintf("Ошибка экспорта: %v", err), t.exportPage)
+				return
+			}
+			t.exportKeyPage(path, key)
+		}).
+		AddButton(btnLableBack, t.settingsPage)
+	form.SetBorder(true).SetTitle("Экспорт данных").SetTitleAlign(tview.AlignLeft)
+	t.app.SetRoot(form, true).SetFocus(form).EnableMouse(true).ForceDraw()
+}
+
+func (t *terminal) exportKeyPage(path, key string) {
+	form := tview.NewForm().
+		AddTextView("Файл", path, recoveryKeyWidth+5, 1, false, false).
+		AddTextView("Ключ", key, recoveryKeyWidth+5, 2, false, false).
+		AddTextView("", "Архив зашифрован, без ключа его не открыть. Ключ показывается один раз.",
+			recoveryKeyWidth*2, 1, false, false).
+		AddButton("Готово", t.settingsPage)
+	form.SetBorder(true).SetTitle("Экспорт данных").SetTitleAlign(tview.AlignLeft)
+	t.app.SetRoot(form, true).SetFocus(form).EnableMouse(true).ForceDraw()
+}
+
+// deleteAccountPage удаление учетной записи с повторным вводом пароля и подтверждением.
+func (t *terminal) deleteAccountPage() {
+	var password string
+	isDraw := true
+	lenInput := 20
+
+	form := tview.NewForm().
+		AddTextView("", "Учетная запись и все данные будут удалены безвозвратно.", recoveryKeyWidth*2, 1, false, false).
+		AddPasswordField("Пароль", "", lenInput, '*', func(text string) { password = text }).
+		AddButton("Удалить", func() {
+			t.modal("Удалить учетную запись и все данные?", map[string]func(){
+				"Удалить": func() {
+					if err := t.api.EventDeleteAccount(password); err != nil {
+						t.errorPage(fmt.Sprintf("Ошибка удаления: %v", err), t.deleteAccountPage)
+						return
+					}
+					t.leave()
+					t.modal("Учетная запись удалена", map[string]func(){
+						"Ok": func() {
+							if err := t.Run(&isDraw); err != nil {
+								t.errorPage(err.Error(), t.authPage)
+							}
+						},
+					})
+				},
+				"Отмена": t.deleteAccountPage,
+			})
+		}).
+		AddButton(btnLableBack, t.settingsPage)
+	form.SetBorder(true).SetTitle("Удаление учетной записи").SetTitleAlign(tview.AlignLeft)
+	t.app.SetRoot(form, true).SetFocus(form).EnableMouse(true).ForceDraw()
+}
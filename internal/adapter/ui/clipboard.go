@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/pkg/totp"
+)
+
+type clipboard interface {
+	Copy(text string) error
+	Clear(copied string) error
+}
+
+// SetClipboard буфер обмена для кнопок копирования, clearAfter - через сколько его очистить, 0 - не очищать.
+func SetClipboard(cb clipboard, clearAfter time.Duration) option {
+	return func(t *terminal) {
+		t.clipboard = cb
+		t.clipClearAfter = clearAfter
+	}
+}
+
+// copyValue кнопка копирования значения label в буфер обмена, back - страница, на которую вернуться при ошибке.
+func (t *terminal) copyValue(label string, value func() string, back func()) func() {
+	return func() {
+		if t.clipboard == nil {
+			t.errorPage("Буфер обмена недоступен", back)
+			return
+		}
+		// предыдущее значение убираем сразу, иначе его очистка сотрет новое.
+		t.clearClipboard()
+		text := value()
+		if err := t.clipboard.Copy(text); err != nil {
+			t.errorPage(fmt.Sprintf("Не удалось скопировать: %v", err), back)
+			return
+		}
+		t.clipLabel = label
+		t.clipValue = text
+		if t.clipClearAfter > 0 {
+			t.clipUntil = time.Now().Add(t.clipClearAfter)
+			t.startClipTicker()
+		}
+		t.app.ForceDraw()
+	}
+}
+
+// copyOTP кнопка копирования текущего одноразового кода по ключу key.
+func (t *terminal) copyOTP(key func() string, back func()) func() {
+	return func() {
+		k, err := totp.Parse(key())
+		if err != nil {
+			t.errorPage(err.Error(), back)
+			return
+		}
+		t.copyValue("OTP код", func() string { return k.Code(time.Now()) }, back)()
+	}
+}
+
+// startClipTicker раз в секунду обновляет отсчет в строке состояния и очищает буфер по истечении времени.
+func (t *terminal) startClipTicker() {
+	if t.clipStop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	t.clipStop = stop
+	ticker := time.NewTicker(time.Second)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				t.app.QueueUpdateDraw(t.tickClipboard)
+			}
+		}
+	}()
+}
+
+func (t *terminal) tickClipboard() {
+	if t.clipValue != "" && !time.Now().Before(t.clipUntil) {
+		t.clearClipboard()
+	}
+}
+
+// clearClipboard очищает буфер обмена, если в нем скопированное клиентом значение.
+func (t *terminal) clearClipboard() {
+	if t.clipStop != nil {
+		close(t.clipStop)
+		t.clipStop = nil
+	}
+	if t.clipValue == "" {
+		return
+	}
+	if err := t.clipboard.Clear(t.clipValue); err != nil {
+		t.log.Error("failed clear clipboard", zap.Error(err))
+	}
+	t.clipLabel = ""
+	t.clipValue = ""
+}
+
+// clipboardStatus текст строки состояния о скопированном значении, пустой - ничего не скопировано.
+func (t *terminal) clipboardStatus() string {
+	if t.clipValue == "" {
+		return ""
+	}
+	if t.clipClearAfter <= 0 {
+		return fmt.Sprintf(" %s скопирован ", t.clipLabel)
+	}
+	left := time.Until(t.clipUntil).Round(time.Second)
+	return fmt.Sprintf(" %s скопирован, буфер обмена очистится через %d сек. ", t.clipLabel, int(left.Seconds()))
+}
+
+// drawStatus строка состояния поверх нижней строки любой страницы.
+func (t *terminal) drawStatus(screen tcell.Screen) {
+	status := t.clipboardStatus()
+	if status == "" {
+		return
+	}
+	width, height := screen.Size()
+	tview.Print(screen, status, 0, height-1, width, tview.AlignRight, tcell.ColorYellow)
+}
@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rivo/tview"
+
+	"github.com/playmixer/secret-keeper/pkg/generator"
+)
+
+var generatorKinds = []string{"Символы", "Произносимый", "Фраза"}
+
+const (
+	generatorKindPassword = iota
+	generatorKindPronounceable
+	generatorKindPassphrase
+)
+
+// generatePassword кнопка генератора, результат записывается в поле пароля формы form.
+func (t *terminal) generatePassword(form *tview.Form) func() {
+	back := func() {
+		t.app.SetRoot(form, true).SetFocus(form).ForceDraw()
+	}
+	return func() {
+		t.generatorPage(func(value string) {
+			if field, ok := form.GetFormItemByLabel(inputLabelPassword).(*tview.InputField); ok {
+				field.SetText(value)
+			}
+			back()
+		}, back)
+	}
+}
+
+// generatePasswordValue пароль выбранного типа, size - длина пароля или число слов фразы.
+func generatePasswordValue(kind, size int, opts generator.PasswordOptions) (*generator.Secret, error) {
+	switch kind {
+	case generatorKindPronounceable:
+		return generator.Pronounceable(size)
+	case generatorKindPassphrase:
+		return generator.Passphrase(size, "-")
+	default:
+		opts.Length = size
+		return generator.Password(opts)
+	}
+}
+
+func (t *terminal) generatorPage(use func(value string), back func()) {
+	kind := generatorKindPassword
+	size := strconv.Itoa(generator.DefaultLength)
+	opts := generator.DefaultPasswordOptions
+	var secret *generator.Secret
+	lenInput := 20
+	lenResult := 60
+
+	result := tview.NewInputField().SetLabel("Результат").SetFieldWidth(lenResult)
+	strength := tview.NewTextView().SetLabel("Стойкость").SetSize(1, lenResult)
+	generate := func() {
+		n, err := strconv.Atoi(size)
+		if err == nil {
+			secret, err = generatePasswordValue(kind, n, opts)
+		}
+		if err != nil {
+			secret = nil
+			result.SetText("")
+			strength.SetText(fmt.Sprintf("ошибка: %v", err))
+			return
+		}
+		result.SetText(secret.Value)
+		strength.SetText(fmt.Sprintf("%.0f бит, %s", secret.Entropy, generator.Strength(secret.Entropy)))
+	}
+
+	form := tview.NewForm().
+		AddDropDown("Тип", generatorKinds, kind, func(_ string, index int) {
+			kind = index
+		}).
+		AddInputField("Длина или число слов", size, lenInput, isNumber(), func(text string) { size = text }).
+		AddCheckbox("a-z", opts.Lower, func(checked bool) { opts.Lower = checked }).
+		AddCheckbox("A-Z", opts.Upper, func(checked bool) { opts.Upper = checked }).
+		AddCheckbox("0-9", opts.Digits, func(checked bool) { opts.Digits = checked }).
+		AddCheckbox("Спецсимволы", opts.Symbols, func(checked bool) { opts.Symbols = checked }).
+		AddCheckbox("Без похожих (l, 1, O, 0)", opts.ExcludeAmbiguous, func(checked bool) {
+			opts.ExcludeAmbiguous = checked
+		}).
+		AddFormItem(result).
+		AddFormItem(strength).
+		AddButton(btnLabelGenerate, generate).
+		AddButton("Использовать", func() {
+			if secret == nil {
+				return
+			}
+			use(result.GetText())
+		}).
+		AddButton(btnLableBack, back)
+	generate()
+	form.SetBorder(true).SetTitle("Генератор паролей").SetTitleAlign(tview.AlignLeft)
+	t.app.SetRoot(form, true).SetFocus(form).ForceDraw()
+}
@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"go.uber.org/zap"
+)
+
+// SetAutoLock блокировать клиент после d без действий пользователя, 0 - не блокировать.
+func SetAutoLock(d time.Duration) option {
+	return func(t *terminal) {
+		t.autoLock = d
+	}
+}
+
+// watchInput любое нажатие или щелчок откладывает автоблокировку, Ctrl+L и Ctrl+Z блокируют клиент.
+// Терминал в raw режиме, поэтому Ctrl+Z приходит клавишей, а не SIGTSTP: вместо приостановки клиент блокируется.
+func (t *terminal) watchInput() {
+	t.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyCtrlL, tcell.KeyCtrlZ:
+			if t.signedIn && !t.locked {
+				t.lock()
+				return nil
+			}
+		}
+		t.touch()
+		return event
+	})
+	t.app.SetMouseCapture(func(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction) {
+		if action != tview.MouseMove {
+			t.touch()
+		}
+		return event, action
+	})
+}
+
+// enter пользователь вошел или разблокировал клиент.
+func (t *terminal) enter() {
+	t.signedIn = true
+	t.locked = false
+	if t.autoLock > 0 {
+		if t.idle == nil {
+			t.idle = time.AfterFunc(t.autoLock, t.Lock)
+		} else {
+			t.idle.Reset(t.autoLock)
+		}
+	}
+	t.mainPage()
+}
+
+// leave пользователь вышел, блокировать нечего.
+func (t *terminal) leave() {
+	t.signedIn = false
+	t.locked = false
+	if t.idle != nil {
+		t.idle.Stop()
+	}
+}
+
+func (t *terminal) touch() {
+	if t.signedIn && !t.locked && t.idle != nil {
+		t.idle.Reset(t.autoLock)
+	}
+}
+
+// Lock блокирует клиент из другой горутины: по таймеру или сигналу.
+func (t *terminal) Lock() {
+	t.app.QueueUpdateDraw(t.lock)
+}
+
+// lock закрывает данные пользователя и показывает экран разблокировки.
+// Экран показывается и при ошибке закрытия, чтобы данные не остались на виду.
+func (t *terminal) lock() {
+	if !t.signedIn || t.locked {
+		return
+	}
+	if t.idle != nil {
+		t.idle.Stop()
+	}
+	t.clearClipboard()
+	if err := t.api.EventLock(); err != nil {
+		t.log.Error("failed lock", zap.Error(err))
+	}
+	t.locked = true
+	t.lockPage()
+}
+
+func (t *terminal) lockPage() {
+	var password string
+	lenInput := 20
+
+	form := tview.NewForm().
+		AddTextView("Login", t.api.LockedLogin(), lenInput, 1, false, false).
+		AddPasswordField("Password", "", lenInput, '*', func(text string) { password = text }).
+		AddButton("Разблокировать", func() {
+			if err := t.api.EventUnlock(password); err != nil {
+				t.errorPage(err.Error(), t.lockPage)
+				return
+			}
+			t.enter()
+		}).
+		AddButton(btnLabelExit, t.Close)
+	form.SetBorder(true).SetTitle("Клиент заблокирован").SetTitleAlign(tview.AlignLeft)
+	t.app.SetRoot(form, true).SetFocus(form).EnableMouse(true).ForceDraw()
+}
@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+var (
+	recoveryKeyWidth   = 45
+	recoveryTextHeight = 8
+	maxLenShareCount   = 3
+)
+
+// recoveryKeyPage показывает ключ восстановления, ключ нужно записать: повторно его не показать.
+func (t *terminal) recoveryKeyPage(key string, next func()) {
+	form := tview.NewForm().
+		AddTextView("Ключ", key, recoveryKeyWidth, 1, false, false).
+		AddTextView("", "Запишите ключ и храните отдельно от пароля. Он показывается один раз,\n"+
+			"после восстановления доступа выдается новый ключ.", recoveryKeyWidth*2, 2, false, false).
+		AddButton("Разделить на доли", func() { t.splitRecoveryKeyPage(key, next) }).
+		AddButton("Готово", next)
+	form.SetBorder(true).SetTitle("Ключ восстановления").SetTitleAlign(tview.AlignLeft)
+	t.app.SetRoot(form, true).SetFocus(form).EnableMouse(true).ForceDraw()
+}
+
+// splitRecoveryKeyPage делит ключ на доли по схеме Шамира для доверенных людей.
+func (t *terminal) splitRecoveryKeyPage(key string, next func()) {
+	parts := "5"
+	threshold := "3"
+	lenInput := 5
+
+	form := tview.NewForm().
+		AddInputField("Всего долей", parts, lenInput, summCheck(isNumber(), length(maxLenShareCount)),
+			func(text string) { parts = text }).
+		AddInputField("Нужно для восстановления", threshold, lenInput, summCheck(isNumber(), length(maxLenShareCount)),
+			func(text string) { threshold = text }).
+		AddButton("Разделить", func() {
+			n, errN := strconv.Atoi(parts)
+			m, errM := strconv.Atoi(threshold)
+			if errN != nil || errM != nil {
+				t.errorPage("Укажите число долей и порог", func() { t.splitRecoveryKeyPage(key, next) })
+				return
+			}
+			shares, err := t.api.EventSplitRecoveryKey(key, n, m)
+			if err != nil {
+				t.errorPage(err.Error(), func() { t.splitRecoveryKeyPage(key, next) })
+				return
+			}
+			t.recoverySharesPage(shares, m, next)
+		}).
+		AddButton(btnLableBack, func() { t.recoveryKeyPage(key, next) })
+	form.SetBorder(true).SetTitle("Доли ключа восстановления").SetTitleAlign(tview.AlignLeft)
+	t.app.SetRoot(form, true).SetFocus(form).EnableMouse(true).ForceDraw()
+}
+
+func (t *terminal) recoverySharesPage(shares []string, threshold int, next func()) {
+	lines := make([]string, 0, len(shares))
+	for i, share := range shares {
+		lines = append(lines, fmt.Sprintf("%d: %s", i+1, share))
+	}
+
+	form := tview.NewForm().
+		AddTextView("", fmt.Sprintf("Отдайте каждую долю отдельному человеку. Для восстановления нужно %d из %d.",
+			threshold, len(shares)), recoveryKeyWidth*2, 1, false, false).
+		AddTextView("Доли", strings.Join(lines, "\n"), recoveryKeyWidth+5, len(lines), false, true).
+		AddButton("Готово", next)
+	form.SetBorder(true).SetTitle("Доли ключа восстановления").SetTitleAlign(tview.AlignLeft)
+	t.app.SetRoot(form, true).SetFocus(form).EnableMouse(true).ForceDraw()
+}
+
+// recoverPage мастер восстановления доступа: ключ целиком или доли ключа, по одной в строке.
+func (t *terminal) recoverPage() {
+	var login string
+	var material string
+	var password string
+	var password2 string
+	isDraw := true
+	lenInput := 20
+
+	form := tview.NewForm().
+		AddInputField("Login", "", lenInput, nil, func(text string) { login = text }).
+		AddTextArea("Ключ или доли", "", recoveryKeyWidth+5, recoveryTextHeight, 0,
+			func(text string) { material = text }).
+		AddPasswordField("Новый пароль", "", lenInput, '*', func(text string) { password = text }).
+		AddPasswordField("Повтор пароля", "", lenInput, '*', func(text string) { password2 = text }).
+		AddButton("Восстановить", func() {
+			key, err := t.api.EventRecover(login, strings.Split(material, "\n"), password, password2)
+			if err != nil {
+				t.errorPage(fmt.Sprintf("Ошибка восстановления: %v", err), t.recoverPage)
+				return
+			}
+			t.recoveryKeyPage(key, t.enter)
+		}).
+		AddButton(btnLableBack, func() {
+			if err := t.Run(&isDraw); err != nil {
+				t.errorPage(err.Error(), t.recoverPage)
+			}
+		}).
+		AddButton(btnLabelExit, t.Close)
+	form.SetBorder(true).SetTitle("Восстановление доступа").SetTitleAlign(tview.AlignLeft)
+	t.app.SetRoot(form, true).SetFocus(form).EnableMouse(true).ForceDraw()
+}
@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/rivo/tview"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/pkg/sshkey"
+)
+
+var inputLabelPrivateKey = "Закрытый ключ"
+
+// sshKeyKinds типы ключей генератора в выпадающем списке, rsaBits - размеры ключей RSA.
+var (
+	sshKeyKinds = []string{"ed25519", "RSA 2048", "RSA 3072", "RSA 4096"}
+	rsaBits     = []int{0, 2048, 3072, 4096}
+)
+
+// generateSSHKeyValue новый ключ типа kind из sshKeyKinds.
+func generateSSHKeyValue(kind int, comment, passphrase string) (*sshkey.Key, error) {
+	keyKind, bits := sshkey.Ed25519, 0
+	if kind > 0 && kind < len(rsaBits) {
+		keyKind, bits = sshkey.RSA, rsaBits[kind]
+	}
+	key, err := sshkey.Generate(keyKind, bits, comment, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed generate ssh key: %w", err)
+	}
+	return key, nil
+}
+
+// sshKeyForm форма ключа SSH: поля ключа и генератор, который заменяет закрытый ключ новым.
+// Открытый ключ и отпечаток вычисляются клиентом при сохранении.
+func (t *terminal) sshKeyForm(key *models.SSHKey) *tview.Form {
+	kind := 0
+	lenLong := 70
+	lenShort := 30
+	height := 8
+	form := tview.NewForm().
+		AddInputField(inputLabelTitle, key.Title, lenShort, nil, func(text string) { key.Title = text }).
+		AddTextArea(inputLabelPrivateKey, key.PrivateKey, lenLong, height, 0, func(text string) { key.PrivateKey = text }).
+		AddInputField("Комментарий", key.Comment, lenShort, nil, func(text string) { key.Comment = text }).
+		AddPasswordField("Пароль ключа", key.Passphrase, lenShort, '*', func(text string) { key.Passphrase = text }).
+		AddDropDown("Тип нового ключа", sshKeyKinds, kind, func(_ string, index int) { kind = index })
+	form.AddButton(btnLabelGenerate, func() {
+		generated, err := generateSSHKeyValue(kind, key.Comment, key.Passphrase)
+		if err != nil {
+			t.errorPage(fmt.Sprintf("Ошибка генерации ключа: %v", err), func() {
+				t.app.SetRoot(form, true).SetFocus(form).ForceDraw()
+			})
+			return
+		}
+		if field, ok := form.GetFormItemByLabel(inputLabelPrivateKey).(*tview.TextArea); ok {
+			field.SetText(generated.PrivateKey, false)
+		}
+	})
+	return form
+}
+
+func (t *terminal) newSSHKeyPage() {
+	key := &models.SSHKey{}
+	form := t.sshKeyForm(key).
+		AddButton(btnLabelAdd, func() {
+			_, err := t.api.EventNewSSHKey(0, key.Title, key.PrivateKey, key.Comment, key.Passphrase)
+			if err != nil {
+				t.errorPage(err.Error(), func() { t.newSSHKeyPage() })
+				return
+			}
+			t.mainPage()
+		}).
+		AddButton(btnLableBack, func() { t.mainPage() })
+	form.SetBorder(true).SetTitle("Добавить ключ SSH").SetTitleAlign(tview.AlignLeft)
+	t.app.SetRoot(form, true).SetFocus(form).ForceDraw()
+}
+
+func (t *terminal) editSSHKeyPage(id int64) {
+	key, err := t.api.EventGetSSHKey(id)
+	if err != nil {
+		t.errorPage(errGetData, func() { t.mainPage() })
+		return
+	}
+	lenLong := 70
+	back := func() { t.editSSHKeyPage(id) }
+	publicKey := key.PublicKey
+	form := t.sshKeyForm(key).
+		AddTextView("Отпечаток", key.Fingerprint, lenLong, 1, false, false).
+		AddTextView("Открытый ключ", publicKey, lenLong, 2, true, false).
+		AddButton("Копировать открытый ключ", t.copyValue("Открытый ключ", func() string { return publicKey }, back)).
+		AddButton(btnLabelSave, func() {
+			err := t.api.EventEditSSHKey(id, key.Title, key.PrivateKey, key.Comment, key.Passphrase)
+			if err != nil {
+				t.errorPage(err.Error(), back)
+				return
+			}
+			t.mainPage()
+		}).
+		AddButton(btnLabelDelete, func() {
+			t.modal(fmt.Sprintf("Удалить ключ SSH `%s`", key.Title), map[string]func(){
+				"Да": func() {
+					err := t.api.EventDeleteSSHKey(id)
+					if err != nil {
+						t.errorPage(fmt.Sprintf("Ошибка удаления ключа `%v`: %v", id, err), back)
+						return
+					}
+					t.mainPage()
+				},
+				"Отмена": back,
+			})
+		}).
+		AddButton(btnLableBack, func() { t.mainPage() })
+	form.SetBorder(true).SetTitle("Ключ SSH").SetTitleAlign(tview.AlignLeft)
+	t.app.SetRoot(form, true).SetFocus(form).ForceDraw()
+}
@@ -9,14 +9,20 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/pkg/certpin"
 )
 
 type api interface {
-	EventRegistration(login, password, password2 string) error
+	EventRegistration(login, password, password2 string, withRecovery bool, invite string) (string, error)
+	EventRecover(login string, material []string, newPassword, newPassword2 string) (string, error)
+	EventSplitRecoveryKey(key string, parts, threshold int) ([]string, error)
+	EventDeleteAccount(password string) error
+	EventExport(path string) (string, error)
 	EventLogout() error
+	EventClose() error
 	EventAuthorization(login, password string) error
 	EventGetMetaDatas() (*[]models.FileMetaDataItem, error)
-	EventNewCard(eID uint, title, number, cvv, pin, date string) error
+	EventNewCard(eID uint, title, number, cvv, pin, date string) (*models.FileMetaDataItem, error)
 	EventGetCard(id int64) (*models.Card, error)
 	EventEditCard(id int64, title, number, cvv, pin, date string) error
 	EventDeleteCard(id int64) error
@@ -24,15 +30,31 @@ type api interface {
 	EventGetText(id int64) (*models.Text, error)
 	EventEditText(id int64, title, text string) error
 	EventDeleteText(id int64) error
-	EventNewPassword(eID uint, title, site, login, password string) (*models.FileMetaDataItem, error)
+	EventNewPassword(eID uint, title, site, login, password, otp string) (*models.FileMetaDataItem, error)
 	EventGetPassword(id int64) (*models.Password, error)
-	EventEditPassword(id int64, title, site, login, password string) error
+	EventEditPassword(id int64, title, site, login, password, otp string) error
 	EventDeletePassword(id int64) error
 	EventNewFile(eID uint, title, path string) (*models.FileMetaDataItem, error)
 	EventGetFile(id int64) (*models.Binary, error)
 	EventEditFile(id int64, title, path string) error
 	EventUploadFile(id int64, path string) error
 	EventDeleteFile(id int64) error
+	EventNewSSHKey(eID uint, title, privateKey, comment, passphrase string) (*models.FileMetaDataItem, error)
+	EventGetSSHKey(id int64) (*models.SSHKey, error)
+	EventEditSSHKey(id int64, title, privateKey, comment, passphrase string) error
+	EventDeleteSSHKey(id int64) error
+	EventChangePassword(oldPassword, newPassword, newPassword2 string) error
+	EventPendingPin() *certpin.Change
+	EventTrustServer(trust bool) error
+	Insecure() bool
+	Offline() bool
+	PendingChanges() int
+	SessionExpired() bool
+	EventLock() error
+	EventUnlock(password string) error
+	EventUnlockUser(login, password string) error
+	Locked(login string) bool
+	LockedLogin() string
 }
 
 var (
@@ -45,6 +67,9 @@ var (
 	inputLabelNumberCard = "Номер карты"
 	inputLabelCVV        = "CVV"
 	inputLabelPing       = "Pin код"
+	inputLabelPassword   = "Пароль"
+	inputLabelOTP        = "OTP ключ"
+	btnLabelGenerate     = "Сгенерировать"
 
 	maxLenCVV        = 3
 	maxLenPIN        = 6
@@ -55,12 +80,22 @@ var (
 )
 
 type terminal struct {
-	app     *tview.Application
-	api     api
-	log     *zap.Logger
-	version string
-	date    string
-	commit  string
+	app            *tview.Application
+	api            api
+	log            *zap.Logger
+	clipboard      clipboard
+	idle           *time.Timer
+	clipStop       chan struct{}
+	clipUntil      time.Time
+	version        string
+	date           string
+	commit         string
+	clipLabel      string
+	clipValue      string
+	autoLock       time.Duration
+	clipClearAfter time.Duration
+	signedIn       bool
+	locked         bool
 }
 
 type option func(*terminal)
@@ -84,6 +119,8 @@ func New(ctx context.Context, api api, log *zap.Logger, options ...option) (*ter
 	for _, opt := range options {
 		opt(t)
 	}
+	t.watchInput()
+	t.app.SetAfterDrawFunc(t.drawStatus)
 
 	return t, nil
 }
@@ -100,10 +137,25 @@ func (t *terminal) Run(isDraw *bool) error {
 
 // Close закрываем терминал.
 func (t *terminal) Close() {
+	t.leave()
+	t.clearClipboard()
+	if err := t.api.EventClose(); err != nil {
+		t.log.Error("failed close event", zap.Error(err))
+	}
+	t.app.Stop()
+}
+
+// logout выход из учетной записи с удалением сохраненной сессии, клиент остается на стартовой странице.
+func (t *terminal) logout() {
+	t.leave()
+	t.clearClipboard()
 	if err := t.api.EventLogout(); err != nil {
 		t.log.Error("failed logout event", zap.Error(err))
 	}
-	t.app.Stop()
+	isDraw := true
+	if err := t.Run(&isDraw); err != nil {
+		t.errorPage(err.Error(), t.authPage)
+	}
 }
 
 func (t *terminal) startPage(btnSignIn func(), btnReg func(), isDraw *bool) {
@@ -111,9 +163,13 @@ func (t *terminal) startPage(btnSignIn func(), btnReg func(), isDraw *bool) {
 	form := tview.NewForm().
 		AddTextView("Версия", t.version, lenVersionString, 1, true, false).
 		AddTextView("Коммит", t.commit, lenVersionString, 1, false, false).
-		AddTextView("Дата", t.date, lenVersionString, 1, false, false).
-		AddButton("Войти", btnSignIn).
+		AddTextView("Дата", t.date, lenVersionString, 1, false, false)
+	if t.api.Insecure() {
+		form.AddTextView("ВНИМАНИЕ", "сертификат сервера не проверяется (API_INSECURE)", 0, 1, false, false)
+	}
+	form.AddButton("Войти", btnSignIn).
 		AddButton("Регистрация", btnReg).
+		AddButton("Восстановить доступ", t.recoverPage).
 		AddButton(btnLabelExit, t.Close)
 	form.SetBorder(true).SetTitle("GophKeeper").SetTitleAlign(tview.AlignLeft)
 	if isDraw != nil && *isDraw {
@@ -135,12 +191,16 @@ func (t *terminal) authPage() {
 		AddInputField("Login", "", lenInput, nil, func(text string) { login = text }).
 		AddPasswordField("Password", "", lenInput, '*', func(text string) { password = text }).
 		AddButton("Войти", func() {
-			err := t.api.EventAuthorization(login, password)
-			if err != nil {
+			signIn := t.api.EventAuthorization
+			// клиент закрыли заблокированным: вход паролем его разблокирует.
+			if t.api.Locked(login) {
+				signIn = t.api.EventUnlockUser
+			}
+			if err := signIn(login, password); err != nil {
 				t.errorPage(err.Error(), t.authPage)
 				return
 			}
-			t.mainPage()
+			t.enter()
 		}).
 		AddButton(btnLableBack, func() {
 			if err := t.Run(&isDraw); err != nil {
@@ -158,6 +218,8 @@ func (t *terminal) registratingPage() {
 	var login string
 	var password string
 	var password2 string
+	var withRecovery bool
+	var invite string
 	isDraw := true
 	letField := 20
 
@@ -165,20 +227,27 @@ func (t *terminal) registratingPage() {
 		AddInputField("Login", "", letField, nil, func(text string) { login = text }).
 		AddPasswordField("Password", "", letField, '*', func(text string) { password = text }).
 		AddPasswordField("Password2", "", letField, '*', func(text string) { password2 = text }).
+		AddCheckbox("Ключ восстановления", false, func(checked bool) { withRecovery = checked }).
+		AddInputField("Приглашение", "", letField, nil, func(text string) { invite = text }).
 		AddButton("Зарегистрироваться", func() {
-			err := t.api.EventRegistration(login, password, password2)
+			key, err := t.api.EventRegistration(login, password, password2, withRecovery, invite)
 			if err != nil {
 				t.errorPage(fmt.Sprintf("Ошибка регистрации: %v", err), func() { t.registratingPage() })
 				return
 			}
+			toStart := func() {
+				if err := t.Run(&isDraw); err != nil {
+					t.errorPage(err.Error(), func() {
+						t.authPage()
+					})
+				}
+			}
+			if key != "" {
+				t.recoveryKeyPage(key, toStart)
+				return
+			}
 			t.modal("Вы успешно зарегестрировались", map[string]func(){
-				"Ok": func() {
-					if err := t.Run(&isDraw); err != nil {
-						t.errorPage(err.Error(), func() {
-							t.authPage()
-						})
-					}
-				},
+				"Ok": toStart,
 			})
 		}).
 		AddButton(btnLableBack, func() {
@@ -213,6 +282,8 @@ func (t *terminal) mainPage() {
 					t.editPasswordPage(e.ID)
 				case models.BINARY:
 					t.editFilePage(e.ID)
+				case models.SSH_KEY:
+					t.editSSHKeyPage(e.ID)
 				}
 			})
 		}
@@ -223,14 +294,61 @@ func (t *terminal) mainPage() {
 		AddItem("Добавить карту", "", 'c', func() { t.newCardPage() }).
 		AddItem("Добавить пару логин/пароль", "", 'p', func() { t.newPasswordPage() }).
 		AddItem("Добавить файл", "", 'f', func() { t.newFilePage() }).
+		AddItem("Добавить ключ SSH", "", 'k', func() { t.newSSHKeyPage() }).
 		AddItem("Обновить", "", 'r', func() { t.mainPage() }).
+		AddItem("Настройки", "", 's', func() { t.settingsPage() }).
+		AddItem("Заблокировать", "Ctrl+L", 'l', t.lock).
+		AddItem("Выйти из учетной записи", "", 'o', t.logout).
 		AddItem(btnLabelExit, "Press to exit", 'q', t.Close).
-		SetBorder(true).SetTitle("Список сохраненных данных")
+		SetBorder(true)
+	title := "Список сохраненных данных"
+	if t.api.Offline() {
+		title += " (нет связи с сервером)"
+	}
+	if n := t.api.PendingChanges(); n > 0 {
+		title += fmt.Sprintf(" (не отправлено: %d)", n)
+	}
+	if t.api.SessionExpired() {
+		title += " (сессия истекла, войдите заново для синхронизации)"
+	}
+	list.SetTitle(title)
 
 	t.app.SetRoot(list, true).SetFocus(list).EnableMouse(true).ForceDraw()
 }
 
+func (t *terminal) settingsPage() {
+	var oldPassword string
+	var newPassword string
+	var newPassword2 string
+	lenInput := 20
+
+	form := tview.NewForm().
+		AddPasswordField("Текущий пароль", "", lenInput, '*', func(text string) { oldPassword = text }).
+		AddPasswordField("Новый пароль", "", lenInput, '*', func(text string) { newPassword = text }).
+		AddPasswordField("Повтор пароля", "", lenInput, '*', func(text string) { newPassword2 = text }).
+		AddButton("Сменить пароль", func() {
+			err := t.api.EventChangePassword(oldPassword, newPassword, newPassword2)
+			if err != nil {
+				t.errorPage(fmt.Sprintf("Ошибка смены пароля: %v", err), t.settingsPage)
+				return
+			}
+			t.modal("Пароль изменен, остальные сессии завершены", map[string]func(){
+				"Ok": t.mainPage,
+			})
+		}).
+		AddButton("Экспорт данных", t.exportPage).
+		AddButton("Удалить аккаунт", t.deleteAccountPage).
+		AddButton(btnLableBack, t.mainPage).
+		AddButton(btnLabelExit, t.Close)
+	form.SetBorder(true).SetTitle("Настройки").SetTitleAlign(tview.AlignLeft)
+	t.app.SetRoot(form, true).SetFocus(form).EnableMouse(true).ForceDraw()
+}
+
 func (t *terminal) errorPage(message string, okBtn func()) {
+	if change := t.api.EventPendingPin(); change != nil {
+		t.pinChangePage(change, okBtn)
+		return
+	}
 	width := 100
 	height := 5
 	form := tview.NewForm().
@@ -240,6 +358,28 @@ func (t *terminal) errorPage(message string, okBtn func()) {
 	t.app.SetRoot(form, true).SetFocus(form).EnableMouse(true).ForceDraw()
 }
 
+// pinChangePage ключ сервера сменился: пользователь решает, доверять ли новому ключу.
+func (t *terminal) pinChangePage(change *certpin.Change, next func()) {
+	width := 100
+	height := 8
+	message := fmt.Sprintf("Ключ сервера %s изменился.\nБыл:   %s\nСейчас: %s\n"+
+		"Если сертификат сервера не перевыпускали, подключение могут перехватывать.",
+		change.Server, change.Known, change.Presented)
+	resolve := func(trust bool) {
+		if err := t.api.EventTrustServer(trust); err != nil {
+			t.errorPage(err.Error(), next)
+			return
+		}
+		next()
+	}
+	form := tview.NewForm().
+		AddTextView("Внимание", message, width, height, false, false).
+		AddButton("Не доверять", func() { resolve(false) }).
+		AddButton("Доверять новому ключу", func() { resolve(true) })
+	form.SetBorder(true).SetTitle("Смена ключа сервера").SetTitleAlign(tview.AlignLeft)
+	t.app.SetRoot(form, true).SetFocus(form).EnableMouse(true).ForceDraw()
+}
+
 func (t *terminal) newCardPage() {
 	var title string
 	var number string
@@ -260,7 +400,7 @@ func (t *terminal) newCardPage() {
 			func(text string) { pin = text }).
 		AddInputField("Дата", time.Now().Format(time.DateOnly), lenDate, nil, func(text string) { date = text }).
 		AddButton(btnLabelAdd, func() {
-			err := t.api.EventNewCard(0, title, number, cvv, pin, date)
+			_, err := t.api.EventNewCard(0, title, number, cvv, pin, date)
 			if err != nil {
 				t.errorPage(err.Error(), func() { t.newCardPage() })
 				return
@@ -280,6 +420,7 @@ func (t *terminal) editCardPage(id int64) {
 	}
 	lenLong := 20
 	lenShort := 10
+	back := func() { t.editCardPage(id) }
 
 	form := tview.NewForm().
 		AddInputField(inputLabelTitle, card.Title, lenLong, nil, func(text string) { card.Title = text }).
@@ -290,6 +431,8 @@ func (t *terminal) editCardPage(id int64) {
 		AddInputField(inputLabelPing, card.PIN, lenShort, summCheck(isNumber(), length(maxLenPIN)),
 			func(text string) { card.PIN = text }).
 		AddInputField("Дата", card.Expiry, lenShort, nil, func(text string) { card.Expiry = text }).
+		AddButton("Копировать номер", t.copyValue("Номер карты", func() string { return card.Number }, back)).
+		AddButton("Копировать CVV", t.copyValue("CVV", func() string { return card.CVV }, back)).
 		AddButton(btnLabelSave, func() {
 			err := t.api.EventEditCard(id, card.Title, card.Number, card.CVV, card.PIN, card.Expiry)
 			if err != nil {
@@ -389,14 +532,17 @@ func (t *terminal) newPasswordPage() {
 	var site string
 	var login string
 	var password string
+	var otp string
 	lenInput := 20
 	form := tview.NewForm().
 		AddInputField(inputLabelTitle, "", lenInput, nil, func(text string) { title = text }).
 		AddInputField("Сайт", "", lenInput, nil, func(text string) { site = text }).
 		AddInputField("Логин", "", lenInput, nil, func(text string) { login = text }).
-		AddInputField("Пароль", "", lenInput, nil, func(text string) { password = text }).
+		AddInputField(inputLabelPassword, "", lenInput, nil, func(text string) { password = text }).
+		AddInputField(inputLabelOTP, "", lenInput, nil, func(text string) { otp = text })
+	form.AddButton(btnLabelGenerate, t.generatePassword(form)).
 		AddButton(btnLabelAdd, func() {
-			_, err := t.api.EventNewPassword(0, title, site, login, password)
+			_, err := t.api.EventNewPassword(0, title, site, login, password, otp)
 			if err != nil {
 				t.errorPage(err.Error(), func() { t.newPasswordPage() })
 				return
@@ -434,13 +580,19 @@ func (t *terminal) editPasswordPage(id int64) {
 	}
 	lenLong := 40
 	lenShort := 20
+	back := func() { t.editPasswordPage(id) }
 	form := tview.NewForm().
 		AddInputField(inputLabelTitle, psw.Title, lenLong, nil, func(text string) { psw.Title = text }).
 		AddInputField("Сайт", psw.Site, lenLong, nil, func(text string) { psw.Site = text }).
 		AddInputField("Логин", psw.Login, lenShort, nil, func(text string) { psw.Login = text }).
-		AddInputField("Пароль", psw.Password, lenShort, nil, func(text string) { psw.Password = text }).
+		AddInputField(inputLabelPassword, psw.Password, lenShort, nil, func(text string) { psw.Password = text }).
+		AddInputField(inputLabelOTP, psw.OTP, lenLong, nil, func(text string) { psw.OTP = text })
+	form.AddButton(btnLabelGenerate, t.generatePassword(form)).
+		AddButton("Копировать логин", t.copyValue("Логин", func() string { return psw.Login }, back)).
+		AddButton("Копировать пароль", t.copyValue("Пароль", func() string { return psw.Password }, back)).
+		AddButton("Копировать OTP", t.copyOTP(func() string { return psw.OTP }, back)).
 		AddButton(btnLabelSave, func() {
-			err := t.api.EventEditPassword(id, psw.Title, psw.Site, psw.Login, psw.Password)
+			err := t.api.EventEditPassword(id, psw.Title, psw.Site, psw.Login, psw.Password, psw.OTP)
 			if err != nil {
 				t.errorPage(err.Error(), func() { t.editPasswordPage(id) })
 				return
@@ -450,7 +602,7 @@ func (t *terminal) editPasswordPage(id int64) {
 		AddButton(btnLabelDelete, func() {
 			t.modal(fmt.Sprintf("Удалить пароль `%s`", psw.Title), map[string]func(){
 				"Да": func() {
-					err := t.api.EventDeleteCard(id)
+					err := t.api.EventDeletePassword(id)
 					if err != nil {
 						t.errorPage(fmt.Sprintf("Ошибка удаления пароля `%v`: %v", id, err), func() { t.editPasswordPage(id) })
 						return
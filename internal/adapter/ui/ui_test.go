@@ -3,12 +3,17 @@ package ui
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
 	"github.com/playmixer/secret-keeper/internal/adapter/storage/file"
 	"github.com/playmixer/secret-keeper/internal/core/uiapi"
+	"github.com/playmixer/secret-keeper/pkg/generator"
+	"github.com/playmixer/secret-keeper/pkg/totp"
 )
 
 func Test_terminal_startPage(t *testing.T) {
@@ -123,6 +128,26 @@ func Test_terminal_mainPage(t *testing.T) {
 	}
 }
 
+func Test_terminal_settingsPage(t *testing.T) {
+	client := createUI(t)
+	client.settingsPage()
+}
+
+func Test_terminal_accountPages(t *testing.T) {
+	client := createUI(t)
+	client.exportPage()
+	client.exportKeyPage("export.skx", "AAAA-BBBB")
+	client.deleteAccountPage()
+}
+
+func Test_terminal_recoveryPages(t *testing.T) {
+	client := createUI(t)
+	client.recoverPage()
+	client.recoveryKeyPage("AAAA-BBBB", func() {})
+	client.splitRecoveryKeyPage("AAAA-BBBB", func() {})
+	client.recoverySharesPage([]string{"AAAA-BBBB", "CCCC-DDDD"}, 2, func() {})
+}
+
 func Test_terminal_errorPage(t *testing.T) {
 	type args struct {
 		message string
@@ -352,3 +377,138 @@ func Test_terminal_uploadFilePage(t *testing.T) {
 		})
 	}
 }
+
+func Test_terminal_lock(t *testing.T) {
+	client := createUI(t)
+	capture := client.app.GetInputCapture()
+	ctrlL := tcell.NewEventKey(tcell.KeyCtrlL, 0, tcell.ModCtrl)
+
+	// до входа блокировать нечего, клавиша уходит дальше.
+	assert.Equal(t, ctrlL, capture(ctrlL))
+	assert.False(t, client.locked)
+
+	client.enter()
+	assert.Nil(t, capture(ctrlL))
+	assert.True(t, client.locked)
+	client.lock()
+	assert.True(t, client.locked)
+
+	client.leave()
+	assert.False(t, client.signedIn)
+	assert.False(t, client.locked)
+}
+
+type fakeClipboard struct {
+	value string
+}
+
+func (c *fakeClipboard) Copy(text string) error {
+	c.value = text
+	return nil
+}
+
+func (c *fakeClipboard) Clear(copied string) error {
+	if c.value == copied {
+		c.value = ""
+	}
+	return nil
+}
+
+func Test_terminal_copyValue(t *testing.T) {
+	client := createUI(t)
+	cb := &fakeClipboard{}
+	SetClipboard(cb, time.Minute)(client)
+
+	client.copyValue("Пароль", func() string { return "secret" }, func() {})()
+	assert.Equal(t, "secret", cb.value)
+	assert.Contains(t, client.clipboardStatus(), "Пароль скопирован")
+	assert.Contains(t, client.clipboardStatus(), "60 сек.")
+
+	// до истечения срока буфер не очищается.
+	client.tickClipboard()
+	assert.Equal(t, "secret", cb.value)
+
+	client.clipUntil = time.Now().Add(-time.Second)
+	client.tickClipboard()
+	assert.Empty(t, cb.value)
+	assert.Empty(t, client.clipboardStatus())
+
+	// значение, скопированное пользователем позже, не стирается.
+	client.copyValue("Логин", func() string { return "user" }, func() {})()
+	cb.value = "other"
+	client.clearClipboard()
+	assert.Equal(t, "other", cb.value)
+}
+
+func Test_terminal_copyOTP(t *testing.T) {
+	client := createUI(t)
+	cb := &fakeClipboard{}
+	SetClipboard(cb, time.Minute)(client)
+
+	key, err := totp.Parse("JBSWY3DPEHPK3PXP")
+	assert.NoError(t, err)
+	before := key.Code(time.Now())
+	client.copyOTP(func() string { return "JBSWY3DPEHPK3PXP" }, func() {})()
+	// код мог смениться на границе периода.
+	assert.Contains(t, []string{before, key.Code(time.Now())}, cb.value)
+	assert.Contains(t, client.clipboardStatus(), "OTP код скопирован")
+
+	// без ключа копировать нечего, буфер не меняется.
+	copied := cb.value
+	client.copyOTP(func() string { return "" }, func() {})()
+	assert.Equal(t, copied, cb.value)
+}
+
+func Test_terminal_generatePassword(t *testing.T) {
+	client := createUI(t)
+	form := tview.NewForm().AddInputField(inputLabelPassword, "", 20, nil, nil)
+	client.generatePassword(form)()
+	_, ok := client.app.GetFocus().(*tview.DropDown)
+	assert.True(t, ok)
+}
+
+func Test_generatePasswordValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    int
+		size    int
+		wantErr bool
+	}{
+		{"password", generatorKindPassword, 16, false},
+		{"pronounceable", generatorKindPronounceable, 12, false},
+		{"passphrase", generatorKindPassphrase, 5, false},
+		{"short password", generatorKindPassword, 2, true},
+		{"too many words", generatorKindPassphrase, 100, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, err := generatePasswordValue(tt.kind, tt.size, generator.DefaultPasswordOptions)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotEmpty(t, secret.Value)
+			assert.Positive(t, secret.Entropy)
+		})
+	}
+}
+
+func Test_terminal_sshKeyPages(t *testing.T) {
+	client := createUI(t)
+	client.newSSHKeyPage()
+	_, ok := client.app.GetFocus().(*tview.InputField)
+	assert.True(t, ok)
+	client.editSSHKeyPage(1)
+}
+
+func Test_generateSSHKeyValue(t *testing.T) {
+	for kind, name := range sshKeyKinds[:2] {
+		t.Run(name, func(t *testing.T) {
+			key, err := generateSSHKeyValue(kind, "user@host", "")
+			assert.NoError(t, err)
+			assert.NotEmpty(t, key.PrivateKey)
+			assert.Contains(t, key.PublicKey, "user@host")
+		})
+	}
+}
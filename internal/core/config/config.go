@@ -4,19 +4,25 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
 
+	"github.com/playmixer/secret-keeper/internal/adapter/api/grpc"
 	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
 	"github.com/playmixer/secret-keeper/internal/adapter/storage"
 	"github.com/playmixer/secret-keeper/internal/adapter/storage/database"
+	"github.com/playmixer/secret-keeper/internal/core/keeper"
 	"github.com/playmixer/secret-keeper/internal/core/uiapi"
+	"github.com/playmixer/secret-keeper/pkg/passhash"
+	"github.com/playmixer/secret-keeper/pkg/policy"
 )
 
 // Config - конфиг сервиса.
 type Config struct {
 	Rest        *rest.Config
+	GRPC        *grpc.Config
 	Storage     *storage.Config
 	Client      *uiapi.Config
 	SecretKey   string `env:"SECRET_KEY"`
@@ -24,23 +30,95 @@ type Config struct {
 	LogLevel    string `env:"LOG_LEVEL"`
 	LogPath     string `env:"LOG_PATH"`
 	FileMaxSize int64  `env:"FILE_MAX_SIZE"`
+	// AutoLock клиент блокируется после этого времени без действий пользователя, 0 - не блокируется.
+	AutoLock time.Duration `env:"AUTO_LOCK"`
+	// ClipboardClear скопированное в буфер обмена стирается через это время, 0 - не стирается.
+	ClipboardClear time.Duration `env:"CLIPBOARD_CLEAR"`
+	// ClipboardOSC52 копировать и escape последовательностью OSC 52, работает в терминале по SSH.
+	ClipboardOSC52 bool `env:"CLIPBOARD_OSC52"`
+	// LoginRateLimit попыток входа в минуту на один логин.
+	LoginRateLimit int `env:"LOGIN_RATE_LIMIT"`
+	// LoginLockoutThreshold неудачных попыток входа подряд до блокировки логина, 0 - не блокировать.
+	LoginLockoutThreshold int `env:"LOGIN_LOCKOUT_THRESHOLD"`
+	// LegacyPasswordAuth разрешить вход и смену пароля с передачей пароля серверу.
+	// Нужен, пока у всех пользователей не появится верификатор SRP.
+	LegacyPasswordAuth bool `env:"LEGACY_PASSWORD_AUTH"`
+	// PasswordHash алгоритм хеширования паролей: argon2id или bcrypt.
+	PasswordHash string `env:"PASSWORD_HASH"`
+	// Argon2Memory память Argon2id в КиБ.
+	Argon2Memory  uint32 `env:"ARGON2_MEMORY"`
+	Argon2Time    uint32 `env:"ARGON2_TIME"`
+	Argon2Threads uint8  `env:"ARGON2_THREADS"`
+	BcryptCost    int    `env:"BCRYPT_COST"`
+	// LoginPattern регулярное выражение для логинов новых пользователей, пустое - любые символы.
+	LoginPattern   string `env:"LOGIN_PATTERN"`
+	LoginMinLength int    `env:"LOGIN_MIN_LENGTH"`
+	LoginMaxLength int    `env:"LOGIN_MAX_LENGTH"`
+	// PasswordMinLength минимальная длина нового пароля в символах.
+	PasswordMinLength int `env:"PASSWORD_MIN_LENGTH"`
+	// PasswordMinStrength минимальная оценка стойкости нового пароля от 0 до 4, 0 - не проверять.
+	PasswordMinStrength int `env:"PASSWORD_MIN_STRENGTH"`
+	// PasswordDenylist файл с запрещенными паролями, по одному в строке.
+	PasswordDenylist string `env:"PASSWORD_DENYLIST"`
+	// ClientPasswordCheck принимать новые пароли верификатором SRP при строгих правилах. Такой пароль сервер
+	// не видит, правила PasswordMinStrength и PasswordDenylist проверяет только клиент, и свой клиент их обойдет.
+	// По умолчанию выключено: пароль задается с передачей серверу (нужен LegacyPasswordAuth), включение
+	// означает, что оператор доверяет проверке в клиентах.
+	ClientPasswordCheck bool `env:"CLIENT_PASSWORD_CHECK"`
+	// RegistrationMode режим регистрации: open, invite или disabled.
+	RegistrationMode string `env:"REGISTRATION_MODE"`
 }
 
 var (
-	defaultFileMaxSizeUpload int64 = 819200
+	defaultFileMaxSizeUpload     int64 = 819200
+	defaultAutoLock                    = 5 * time.Minute
+	defaultClipboardClear              = 30 * time.Second
+	defaultLoginRateLimit              = 10
+	defaultLoginLockoutThreshold       = 5
+	defaultBcryptCost                  = 14
+	defaultLoginMinLength              = 3
+	defaultLoginMaxLength              = 64
+	defaultPasswordMinLength           = 8
+	defaultPasswordMinStrength         = 2
 )
 
 // Init - инициализация конфига.
 func Init(isClient bool) (*Config, error) {
 	cfg := &Config{
-		Rest: &rest.Config{},
+		Rest: &rest.Config{
+			CertFile:      "./cert/gophkeeper.crt",
+			KeyFile:       "./cert/gophkeeper.key",
+			TLSMinVersion: "1.2",
+		},
+		GRPC: &grpc.Config{},
 		Storage: &storage.Config{
 			Database: database.Config{},
 		},
 		Client: &uiapi.Config{
-			APIAddress: "https://localhost:8443",
+			APIAddress:   "https://localhost:8443",
+			GRPCAddress:  "localhost:3200",
+			GRPCTLS:      true,
+			AuthMode:     uiapi.AuthModeSRP,
+			OfflineLogin: true,
 		},
-		FileMaxSize: defaultFileMaxSizeUpload,
+		FileMaxSize:           defaultFileMaxSizeUpload,
+		AutoLock:              defaultAutoLock,
+		ClipboardClear:        defaultClipboardClear,
+		ClipboardOSC52:        true,
+		LoginRateLimit:        defaultLoginRateLimit,
+		LoginLockoutThreshold: defaultLoginLockoutThreshold,
+		LegacyPasswordAuth:    true,
+		PasswordHash:          passhash.AlgorithmArgon2id,
+		Argon2Memory:          passhash.DefaultArgon2Params.Memory,
+		Argon2Time:            passhash.DefaultArgon2Params.Time,
+		Argon2Threads:         passhash.DefaultArgon2Params.Threads,
+		BcryptCost:            defaultBcryptCost,
+		LoginPattern:          policy.DefaultLoginPattern.String(),
+		LoginMinLength:        defaultLoginMinLength,
+		LoginMaxLength:        defaultLoginMaxLength,
+		PasswordMinLength:     defaultPasswordMinLength,
+		PasswordMinStrength:   defaultPasswordMinStrength,
+		RegistrationMode:      keeper.RegistrationOpen,
 	}
 
 	cfgFile := ".env"
@@ -0,0 +1,95 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
+	"github.com/playmixer/secret-keeper/pkg/export"
+)
+
+// DeleteAccount безвозвратно удаляет учетную запись и все секреты пользователя.
+// Токены удаленного пользователя перестают действовать, так как проверка сессии его не находит.
+func (k *Keeper) DeleteAccount(ctx context.Context, userID uint, current Proof) error {
+	user, err := k.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed find user: %w", err)
+	}
+
+	if err := k.checkLogin(ctx, user.Login); err != nil {
+		return err
+	}
+	if err := k.verifyProof(ctx, user, current); err != nil {
+		return err
+	}
+
+	if err := k.store.DeleteUser(ctx, userID, user.Credentials()); err != nil {
+		// пароль успели сменить параллельным запросом.
+		if errors.Is(err, keeperr.ErrNotFound) {
+			return ErrPasswordIncorrect
+		}
+		return fmt.Errorf("failed delete user: %w", err)
+	}
+	// подписчики событий перечитают ревизию и получат отказ.
+	k.notifier.publish(userID)
+	k.resetLoginFailures(ctx, user.Login)
+	k.audit.Info("account deleted",
+		zap.String("login", user.Login),
+		zap.Uint("user_id", user.ID),
+		zap.String("ip", clientIP(ctx)))
+
+	return nil
+}
+
+// Export зашифрованный архив всех действующих секретов пользователя в открытом виде.
+// Возвращает архив и печатный ключ к нему, ключ нигде не сохраняется.
+func (k *Keeper) Export(ctx context.Context, userID uint) ([]byte, string, error) {
+	user, err := k.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed find user: %w", err)
+	}
+	secrets, err := k.store.GetMetaDatasByUserID(ctx, userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed get secrets by user `%v`: %w", userID, err)
+	}
+
+	doc := &export.Document{
+		ExportedAt: time.Now().UTC(),
+		Login:      user.Login,
+		Revision:   user.Revision,
+		Secrets:    make([]export.Secret, 0, len(*secrets)),
+	}
+	for _, secret := range *secrets {
+		if secret.IsDeleted {
+			continue
+		}
+		data, err := k.decrypt([]byte(k.encryptKey), secret.Data)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed decrypt secret `%v`: %w", secret.ID, err)
+		}
+		doc.Secrets = append(doc.Secrets, export.Secret{
+			ID:       secret.ID,
+			Title:    secret.Title,
+			Folder:   secret.Folder,
+			DataType: string(secret.DataType),
+			Data:     data,
+			UpdateDT: secret.UpdateDT,
+		})
+	}
+
+	archive, key, err := export.Seal(doc)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed seal archive: %w", err)
+	}
+	k.audit.Info("account exported",
+		zap.String("login", user.Login),
+		zap.Uint("user_id", user.ID),
+		zap.String("ip", clientIP(ctx)),
+		zap.Int("secrets", len(doc.Secrets)))
+
+	return archive, key, nil
+}
@@ -0,0 +1,107 @@
+package keeper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/internal/mocks/storage/database"
+	"github.com/playmixer/secret-keeper/pkg/export"
+)
+
+const testPasswordHash = "$2a$14$M2qLheAVBq/0yqT6NBUleewVIjlhOY4EqzCfEdgg3M0vBvKJA6Ct."
+
+func TestKeeper_DeleteAccount(t *testing.T) {
+	user := &models.User{Model: gorm.Model{ID: 1}, Login: "user", PasswordHash: testPasswordHash}
+
+	tests := []struct {
+		name     string
+		password string
+		mock     func(store *database.MockStorage)
+		wantErr  error
+	}{
+		{
+			name:     "ok",
+			password: "user",
+			mock: func(store *database.MockStorage) {
+				store.EXPECT().GetUserByID(gomock.Any(), uint(1)).Return(user, nil)
+				store.EXPECT().DeleteUser(gomock.Any(), uint(1), user.Credentials()).Return(nil)
+			},
+		},
+		{
+			name:     "wrong password",
+			password: "other",
+			mock: func(store *database.MockStorage) {
+				store.EXPECT().GetUserByID(gomock.Any(), uint(1)).Return(user, nil)
+			},
+			wantErr: ErrPasswordIncorrect,
+		},
+		{
+			name:     "password changed concurrently",
+			password: "user",
+			mock: func(store *database.MockStorage) {
+				store.EXPECT().GetUserByID(gomock.Any(), uint(1)).Return(user, nil)
+				store.EXPECT().DeleteUser(gomock.Any(), uint(1), user.Credentials()).Return(keeperr.ErrNotFound)
+			},
+			wantErr: ErrPasswordIncorrect,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := database.NewMockStorage(ctrl)
+			tt.mock(store)
+			k, err := New(store)
+			assert.NoError(t, err)
+
+			events, unsubscribe := k.Subscribe(1)
+			defer unsubscribe()
+
+			err = k.DeleteAccount(context.TODO(), 1, Proof{Password: tt.password})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, events)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, events, 1)
+		})
+	}
+}
+
+func TestKeeper_Export(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	encryptKey := "RZLMAOIOuljexYLh5S47O9kfVI7O1Ll0"
+	store := database.NewMockStorage(ctrl)
+	k, err := New(store, SetEncryptKey(encryptKey))
+	assert.NoError(t, err)
+
+	data, err := k.encrypt([]byte(encryptKey), []byte(`{"Text":"hello"}`))
+	assert.NoError(t, err)
+	store.EXPECT().GetUserByID(gomock.Any(), uint(1)).
+		Return(&models.User{Model: gorm.Model{ID: 1}, Login: "user", Revision: 3}, nil)
+	store.EXPECT().GetMetaDatasByUserID(gomock.Any(), uint(1)).Return(&[]models.Secret{
+		{Model: gorm.Model{ID: 10}, Title: "note", Folder: "work", DataType: models.TEXT, Data: data, UpdateDT: 5},
+		{Model: gorm.Model{ID: 11}, Title: "gone", DataType: models.TEXT, IsDeleted: true},
+	}, nil)
+
+	archive, key, err := k.Export(context.TODO(), 1)
+	assert.NoError(t, err)
+
+	doc, err := export.Open(archive, key)
+	assert.NoError(t, err)
+	assert.Equal(t, "user", doc.Login)
+	assert.Equal(t, int64(3), doc.Revision)
+	assert.Equal(t, []export.Secret{
+		{ID: 10, Title: "note", Folder: "work", DataType: "TEXT", Data: []byte(`{"Text":"hello"}`), UpdateDT: 5},
+	}, doc.Secrets)
+}
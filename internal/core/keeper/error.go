@@ -3,6 +3,19 @@ package keeper
 import "errors"
 
 var (
-	ErrPasswordNotValid = errors.New("password is not valid")
-	ErrLoginNotValid    = errors.New("login is not valid")
+	ErrPasswordNotValid     = errors.New("password is not valid")
+	ErrLoginNotValid        = errors.New("login is not valid")
+	ErrPasswordIncorrect    = errors.New("current password is not correct")
+	ErrRecoveryNotValid     = errors.New("login or recovery key not correct")
+	ErrBatchNotValid        = errors.New("batch is not valid")
+	ErrListFilterNotValid   = errors.New("list filter is not valid")
+	ErrSRPNotValid          = errors.New("srp handshake is not valid")
+	ErrLegacyAuthDisabled   = errors.New("password authentication is disabled, use srp")
+	ErrPasswordWeak         = errors.New("password does not meet policy")
+	ErrPasswordUnchecked    = errors.New("server must check new password, send it with password auth")
+	ErrRegistrationClosed   = errors.New("registration is closed")
+	ErrInviteNotValid       = errors.New("invite code is not valid")
+	ErrInviteParamsNotValid = errors.New("invite count or ttl is not valid")
+
+	ErrRegistrationModeNotExists = errors.New("registration mode not exists")
 )
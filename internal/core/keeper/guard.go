@@ -0,0 +1,125 @@
+package keeper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/pkg/ratelimit"
+)
+
+const (
+	loginKeyPrefix = "login:"
+)
+
+var (
+	defaultLoginLimit = ratelimit.PerMinute(10)
+	defaultLockout    = ratelimit.Lockout{
+		Threshold: 5,
+		Base:      time.Minute,
+		Max:       time.Hour,
+		Window:    24 * time.Hour,
+	}
+)
+
+type clientIPKey struct{}
+
+// WithClientIP контекст с адресом клиента, адрес попадает в журнал аудита входа.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+func clientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// checkLogin не допускает проверку пароля для заблокированного логина и слишком частых попыток.
+func (k *Keeper) checkLogin(ctx context.Context, login string) error {
+	if k.limiter == nil {
+		return nil
+	}
+	if err := k.checkLockout(ctx, login); err != nil {
+		return err
+	}
+
+	wait, err := k.limiter.Take(ctx, loginKeyPrefix+login, k.loginLimit)
+	if err != nil {
+		return fmt.Errorf("failed check login rate: %w", err)
+	}
+	if wait > 0 {
+		k.audit.Warn("login rejected, rate limited",
+			zap.String("login", login),
+			zap.String("ip", clientIP(ctx)),
+			zap.Duration("retry_after", wait))
+		return &keeperr.RetryError{Err: keeperr.ErrRateLimited, After: wait}
+	}
+
+	return nil
+}
+
+// checkLockout не допускает проверку пароля для заблокированного логина, попытка не расходует лимит частоты.
+func (k *Keeper) checkLockout(ctx context.Context, login string) error {
+	if k.limiter == nil {
+		return nil
+	}
+	locked, err := k.limiter.LockedFor(ctx, loginKeyPrefix+login)
+	if err != nil {
+		return fmt.Errorf("failed check lockout: %w", err)
+	}
+	if locked > 0 {
+		k.audit.Warn("login rejected, account locked",
+			zap.String("login", login),
+			zap.String("ip", clientIP(ctx)),
+			zap.Duration("locked_for", locked))
+		return &keeperr.RetryError{Err: keeperr.ErrAccountLocked, After: locked}
+	}
+	return nil
+}
+
+// loginFailed учитывает неудачный вход, после серии неудач подряд логин блокируется.
+func (k *Keeper) loginFailed(ctx context.Context, login string) {
+	fields := []zap.Field{zap.String("login", login), zap.String("ip", clientIP(ctx))}
+	if k.limiter == nil {
+		k.audit.Info("login failed", fields...)
+		return
+	}
+
+	count, locked, err := k.limiter.Fail(ctx, loginKeyPrefix+login, k.lockout)
+	if err != nil {
+		k.audit.Error("failed count login failure", append(fields, zap.Error(err))...)
+		return
+	}
+	fields = append(fields, zap.Int("failures", count))
+	if locked > 0 {
+		k.audit.Warn("account locked", append(fields, zap.Duration("locked_for", locked))...)
+		return
+	}
+	k.audit.Info("login failed", fields...)
+}
+
+// loginSucceeded сбрасывает счетчик неудач после успешного входа.
+func (k *Keeper) loginSucceeded(ctx context.Context, user *models.User) {
+	fields := []zap.Field{
+		zap.String("login", user.Login),
+		zap.Uint("user_id", user.ID),
+		zap.String("ip", clientIP(ctx)),
+	}
+	k.resetLoginFailures(ctx, user.Login)
+	k.audit.Info("login succeeded", fields...)
+}
+
+// resetLoginFailures забывает неудачные попытки входа логина.
+func (k *Keeper) resetLoginFailures(ctx context.Context, login string) {
+	if k.limiter == nil {
+		return
+	}
+	if err := k.limiter.Reset(ctx, loginKeyPrefix+login); err != nil {
+		k.audit.Error("failed reset login failures",
+			zap.String("login", login), zap.String("ip", clientIP(ctx)), zap.Error(err))
+	}
+}
@@ -0,0 +1,82 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/internal/mocks/storage/database"
+	"github.com/playmixer/secret-keeper/pkg/ratelimit"
+)
+
+func TestKeeper_LoginLockout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := database.NewMockStorage(ctrl)
+	store.EXPECT().GetUserByLogin(gomock.Any(), "ghost").Return(nil, keeperr.ErrNotFound).Times(3)
+
+	core, logs := observer.New(zap.InfoLevel)
+	k, err := New(store,
+		SetLimiter(ratelimit.NewMemory()),
+		SetLockoutThreshold(3),
+		SetAuditLogger(zap.New(core)),
+	)
+	assert.NoError(t, err)
+
+	ctx := WithClientIP(context.TODO(), "10.0.0.1")
+	for range 3 {
+		_, err = k.Login(ctx, "ghost", "password")
+		assert.ErrorIs(t, err, keeperr.ErrNotFound)
+	}
+
+	_, err = k.Login(ctx, "ghost", "password")
+	assert.ErrorIs(t, err, keeperr.ErrAccountLocked)
+	var retryErr *keeperr.RetryError
+	if assert.True(t, errors.As(err, &retryErr)) {
+		assert.InDelta(t, time.Minute, retryErr.After, float64(time.Second))
+	}
+
+	locked := logs.FilterMessage("account locked").All()
+	if assert.Len(t, locked, 1) {
+		assert.Equal(t, "10.0.0.1", locked[0].ContextMap()["ip"])
+		assert.Equal(t, int64(3), locked[0].ContextMap()["failures"])
+	}
+	assert.Equal(t, 1, logs.FilterMessage("login rejected, account locked").Len())
+}
+
+func TestKeeper_LoginRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := database.NewMockStorage(ctrl)
+	store.EXPECT().GetUserByLogin(gomock.Any(), "user").Return(&models.User{
+		Model:        gorm.Model{ID: 1},
+		Login:        "user",
+		PasswordHash: "$2a$14$M2qLheAVBq/0yqT6NBUleewVIjlhOY4EqzCfEdgg3M0vBvKJA6Ct.",
+	}, nil).Times(1)
+	store.EXPECT().SetVerifier(gomock.Any(), uint(1), gomock.Any(), gomock.Any()).Return(nil)
+	store.EXPECT().UpdatePasswordHash(gomock.Any(), uint(1), gomock.Any(), gomock.Any()).Return(nil)
+
+	k, err := New(store,
+		SetLimiter(ratelimit.NewMemory()),
+		SetLoginLimit(ratelimit.PerMinute(1)),
+	)
+	assert.NoError(t, err)
+
+	user, err := k.Login(context.TODO(), "user", "user")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), user.ID)
+
+	_, err = k.Login(context.TODO(), "user", "user")
+	assert.ErrorIs(t, err, keeperr.ErrRateLimited)
+}
@@ -0,0 +1,107 @@
+package keeper
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/pkg/recoverykey"
+)
+
+// Режимы регистрации.
+const (
+	RegistrationOpen     = "open"
+	RegistrationInvite   = "invite"
+	RegistrationDisabled = "disabled"
+)
+
+const (
+	inviteMaxCount = 100
+	// inviteSize длина кода приглашения в байтах, 80 бит: код одноразовый и с ограниченным сроком.
+	inviteSize = 10
+)
+
+// PolicyInfo правила регистрации для клиента. Пароль, заданный верификатором SRP,
+// сервер проверить не может, поэтому клиент проверяет его сам по этим правилам.
+type PolicyInfo struct {
+	RegistrationMode    string
+	LoginPattern        string
+	LoginMinLength      int
+	LoginMaxLength      int
+	PasswordMinLength   int
+	PasswordMinStrength int
+}
+
+// Policy текущие правила регистрации.
+func (k *Keeper) Policy() PolicyInfo {
+	info := PolicyInfo{
+		RegistrationMode:    k.regMode,
+		LoginMinLength:      k.policy.LoginMinLength,
+		LoginMaxLength:      k.policy.LoginMaxLength,
+		PasswordMinLength:   k.policy.PasswordMinLength,
+		PasswordMinStrength: k.policy.PasswordMinStrength,
+	}
+	if k.policy.LoginPattern != nil {
+		info.LoginPattern = k.policy.LoginPattern.String()
+	}
+	return info
+}
+
+// CreateInvites выпускает count одноразовых кодов приглашения со сроком действия ttl.
+// Коды возвращаются один раз, на сервере хранятся только их отпечатки.
+func (k *Keeper) CreateInvites(ctx context.Context, count int, ttl time.Duration) ([]string, error) {
+	if count < 1 || count > inviteMaxCount {
+		return nil, fmt.Errorf("count %v: %w", count, ErrInviteParamsNotValid)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("ttl %v: %w", ttl, ErrInviteParamsNotValid)
+	}
+
+	expires := time.Now().Add(ttl)
+	codes := make([]string, 0, count)
+	invites := make([]models.Invite, 0, count)
+	for range count {
+		code, hash, err := newInviteCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		invites = append(invites, models.Invite{CodeHash: hash, ExpiresAt: expires})
+	}
+	if err := k.store.CreateInvites(ctx, invites); err != nil {
+		return nil, fmt.Errorf("failed create invites: %w", err)
+	}
+	k.audit.Info("invites created",
+		zap.Int("count", count),
+		zap.Time("expires_at", expires),
+		zap.String("ip", clientIP(ctx)))
+
+	return codes, nil
+}
+
+// checkRegistration проверяет, что регистрация разрешена, и возвращает отпечаток кода приглашения.
+func (k *Keeper) checkRegistration(invite string) (string, error) {
+	switch k.regMode {
+	case RegistrationDisabled:
+		return "", ErrRegistrationClosed
+	case RegistrationInvite:
+		code, err := recoverykey.Parse(invite)
+		if err != nil || len(code) != inviteSize {
+			return "", ErrInviteNotValid
+		}
+		return recoverykey.Hash(code), nil
+	}
+	return "", nil
+}
+
+func newInviteCode() (string, string, error) {
+	code := make([]byte, inviteSize)
+	if _, err := rand.Read(code); err != nil {
+		return "", "", fmt.Errorf("failed generate invite code: %w", err)
+	}
+	return recoverykey.Format(code), recoverykey.Hash(code), nil
+}
@@ -0,0 +1,174 @@
+package keeper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/internal/mocks/storage/database"
+	"github.com/playmixer/secret-keeper/pkg/policy"
+	"github.com/playmixer/secret-keeper/pkg/recoverykey"
+	"github.com/playmixer/secret-keeper/pkg/srp"
+)
+
+func TestKeeper_RegistrationPolicy(t *testing.T) {
+	invite := recoverykey.Format(make([]byte, inviteSize))
+	inviteHash := recoverykey.Hash(make([]byte, inviteSize))
+	salt, err := srp.NewSalt()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		mode     string
+		login    string
+		password Password
+		invite   string
+		mock     func(store *database.MockStorage)
+		wantErr  error
+	}{
+		{
+			name:     "open",
+			mode:     RegistrationOpen,
+			login:    "alice",
+			password: Password{Plain: "kqzmvbtr-31"},
+			invite:   "ignored",
+			mock: func(store *database.MockStorage) {
+				store.EXPECT().Registration(gomock.Any(), "alice", gomock.Any(), "", "").Return(nil)
+			},
+		},
+		{
+			name:     "login charset",
+			mode:     RegistrationOpen,
+			login:    "al ice",
+			password: Password{Plain: "kqzmvbtr-31"},
+			wantErr:  ErrLoginNotValid,
+		},
+		{
+			name:     "weak password",
+			mode:     RegistrationOpen,
+			login:    "alice",
+			password: Password{Plain: "qwerty1990"},
+			wantErr:  ErrPasswordWeak,
+		},
+		{
+			name:     "denied password",
+			mode:     RegistrationOpen,
+			login:    "alice",
+			password: Password{Plain: "Xk9#mP2$vLq"},
+			wantErr:  ErrPasswordWeak,
+		},
+		{
+			name:     "verifier is not trusted by default",
+			mode:     RegistrationOpen,
+			login:    "alice",
+			password: Password{Salt: salt, Verifier: srp.Verifier("alice", "1", salt)},
+			wantErr:  ErrPasswordUnchecked,
+		},
+		{
+			name:     "disabled",
+			mode:     RegistrationDisabled,
+			login:    "alice",
+			password: Password{Plain: "kqzmvbtr-31"},
+			wantErr:  ErrRegistrationClosed,
+		},
+		{
+			name:     "invite",
+			mode:     RegistrationInvite,
+			login:    "alice",
+			password: Password{Plain: "kqzmvbtr-31"},
+			invite:   invite,
+			mock: func(store *database.MockStorage) {
+				store.EXPECT().Registration(gomock.Any(), "alice", gomock.Any(), "", inviteHash).Return(nil)
+			},
+		},
+		{
+			name:     "invite missing",
+			mode:     RegistrationInvite,
+			login:    "alice",
+			password: Password{Plain: "kqzmvbtr-31"},
+			wantErr:  ErrInviteNotValid,
+		},
+		{
+			name:     "invite used",
+			mode:     RegistrationInvite,
+			login:    "alice",
+			password: Password{Plain: "kqzmvbtr-31"},
+			invite:   invite,
+			mock: func(store *database.MockStorage) {
+				store.EXPECT().Registration(gomock.Any(), "alice", gomock.Any(), "", inviteHash).
+					Return(keeperr.ErrInviteNotValid)
+			},
+			wantErr: ErrInviteNotValid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := database.NewMockStorage(ctrl)
+			if tt.mock != nil {
+				tt.mock(store)
+			}
+			pol := &policy.Policy{
+				LoginPattern:        policy.DefaultLoginPattern,
+				LoginMinLength:      3,
+				PasswordMinLength:   8,
+				PasswordMinStrength: 3,
+			}
+			pol.SetDenylist([]string{"xk9#mp2$vlq"})
+			k, err := New(store,
+				SetPolicy(pol),
+				SetRegistrationMode(tt.mode),
+				SetPasswordHasher(testHasher(t, 1024)),
+			)
+			require.NoError(t, err)
+
+			_, err = k.Registration(context.TODO(), tt.login, tt.password, false, tt.invite)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	_, err = New(nil, SetRegistrationMode("closed"))
+	assert.ErrorIs(t, err, ErrRegistrationModeNotExists)
+}
+
+func TestKeeper_CreateInvites(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var stored []models.Invite
+	store := database.NewMockStorage(ctrl)
+	store.EXPECT().CreateInvites(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, invites []models.Invite) error {
+			stored = invites
+			return nil
+		})
+	k, err := New(store)
+	require.NoError(t, err)
+
+	codes, err := k.CreateInvites(context.TODO(), 2, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, codes, 2)
+	require.Len(t, stored, 2)
+	assert.NotEqual(t, codes[0], codes[1])
+	for i, code := range codes {
+		data, err := recoverykey.Parse(code)
+		require.NoError(t, err)
+		assert.Equal(t, recoverykey.Hash(data), stored[i].CodeHash)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), stored[i].ExpiresAt, time.Minute)
+	}
+
+	_, err = k.CreateInvites(context.TODO(), 0, time.Hour)
+	assert.ErrorIs(t, err, ErrInviteParamsNotValid)
+	_, err = k.CreateInvites(context.TODO(), inviteMaxCount+1, time.Hour)
+	assert.ErrorIs(t, err, ErrInviteParamsNotValid)
+	_, err = k.CreateInvites(context.TODO(), 1, 0)
+	assert.ErrorIs(t, err, ErrInviteParamsNotValid)
+}
@@ -11,27 +11,64 @@ import (
 	"io"
 	"time"
 
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 
 	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
 	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/pkg/passhash"
+	"github.com/playmixer/secret-keeper/pkg/policy"
+	"github.com/playmixer/secret-keeper/pkg/ratelimit"
 )
 
 // Storage интерфейс хранилища.
 type Storage interface {
-	Registration(ctx context.Context, login, passwordHash string) error
+	Registration(ctx context.Context, login string, cred models.Credentials, recoveryHash, inviteHash string) error
+	CreateInvites(ctx context.Context, invites []models.Invite) error
 	GetUserByLogin(ctx context.Context, login string) (*models.User, error)
+	GetUserByID(ctx context.Context, userID uint) (*models.User, error)
+	GetSessionEpoch(ctx context.Context, userID uint) (int64, error)
+	ChangePassword(ctx context.Context, userID uint, old, next models.Credentials) (int64, error)
+	RecoverPassword(ctx context.Context,
+		userID uint, recoveryHash string, next models.Credentials, newRecoveryHash string,
+	) (int64, error)
+	SetVerifier(ctx context.Context, userID uint, salt, verifier []byte) error
+	UpdatePasswordHash(ctx context.Context, userID uint, oldHash, newHash string) error
+	DeleteUser(ctx context.Context, userID uint, old models.Credentials) error
 	GetMetaDatasByUserID(ctx context.Context, userID uint) (*[]models.Secret, error)
+	ListSecrets(ctx context.Context, userID uint, filter models.SecretFilter) (*[]models.Secret, error)
 	NewSecret(ctx context.Context, secret *models.Secret) (*models.Secret, error)
-	GetSecret(ctx context.Context, id uint) (*models.Secret, error)
-	UpdSecret(ctx context.Context, secret *models.Secret) (*models.Secret, error)
-	DelSecret(ctx context.Context, id uint) error
+	GetSecret(ctx context.Context, id, userID uint) (*models.Secret, error)
+	UpdSecret(ctx context.Context, secret *models.Secret, setFolder bool) (*models.Secret, error)
+	DelSecret(ctx context.Context, id, userID uint) error
+	Batch(ctx context.Context, userID uint, ops *[]models.BatchOperation) (*[]models.BatchResult, error)
+	GetRevision(ctx context.Context, userID uint) (int64, error)
 }
 
+const (
+	batchMaxSize = 500
+	// batchKeyMaxLength длина ключа идемпотентности операции.
+	batchKeyMaxLength = 64
+	listMaxLimit      = 1000
+)
+
 // Keeper - Keeper.
 type Keeper struct {
 	store      Storage
+	limiter    ratelimit.Backend
+	notifier   *notifier
+	srp        *srpSessions
+	audit      *zap.Logger
+	hasher     passhash.Hasher
+	policy     *policy.Policy
+	regMode    string
 	encryptKey string
+	srpFakeKey []byte
+	loginLimit ratelimit.Limit
+	lockout    ratelimit.Lockout
+	legacyAuth bool
+	// clientPasswordCheck принимать новые пароли верификатором SRP, доверив проверку правил клиенту.
+	clientPasswordCheck bool
 }
 
 type option func(*Keeper)
@@ -43,44 +80,158 @@ func SetEncryptKey(key string) option {
 	}
 }
 
+// SetLimiter включает защиту входа: ограничение частоты попыток на логин и блокировку после неудач подряд.
+func SetLimiter(backend ratelimit.Backend) option {
+	return func(k *Keeper) {
+		k.limiter = backend
+	}
+}
+
+// SetLoginLimit частота попыток входа на один логин.
+func SetLoginLimit(limit ratelimit.Limit) option {
+	return func(k *Keeper) {
+		k.loginLimit = limit
+	}
+}
+
+// SetLockoutThreshold число неудачных попыток входа подряд, после которого логин блокируется, 0 - не блокировать.
+func SetLockoutThreshold(n int) option {
+	return func(k *Keeper) {
+		k.lockout.Threshold = n
+	}
+}
+
+// SetAuditLogger журнал аудита входов и блокировок.
+func SetAuditLogger(log *zap.Logger) option {
+	return func(k *Keeper) {
+		k.audit = log
+	}
+}
+
+// SetLegacyPasswordAuth разрешить вход и смену пароля с передачей пароля серверу.
+// Нужно на время перехода на SRP: при входе с паролем у пользователя появляется верификатор SRP.
+func SetLegacyPasswordAuth(enable bool) option {
+	return func(k *Keeper) {
+		k.legacyAuth = enable
+	}
+}
+
+// SetClientPasswordCheck принимать новый пароль верификатором SRP. Такой пароль сервер не видит
+// и проверить по правилам не может: проверка остается клиенту, и свой клиент ее обойдет. По умолчанию выключено:
+// при строгих правилах (Policy.Strict) регистрация и смена пароля возможны только с передачей пароля серверу.
+func SetClientPasswordCheck(enable bool) option {
+	return func(k *Keeper) {
+		k.clientPasswordCheck = enable
+	}
+}
+
+// SetPasswordHasher алгоритм хеширования новых паролей, по умолчанию Argon2id.
+// Хеши других алгоритмов проверяются и пересчитываются при следующем входе.
+func SetPasswordHasher(h passhash.Hasher) option {
+	return func(k *Keeper) {
+		k.hasher = h
+	}
+}
+
+// SetPolicy правила для логинов новых пользователей и новых паролей.
+// По умолчанию логин и пароль должны быть только непустыми.
+func SetPolicy(p *policy.Policy) option {
+	return func(k *Keeper) {
+		k.policy = p
+	}
+}
+
+// SetRegistrationMode режим регистрации: RegistrationOpen, RegistrationInvite или RegistrationDisabled.
+func SetRegistrationMode(mode string) option {
+	return func(k *Keeper) {
+		k.regMode = mode
+	}
+}
+
 // New - создаем Keeper.
 func New(store Storage, options ...option) (*Keeper, error) {
 	k := &Keeper{
 		store:      store,
+		notifier:   newNotifier(),
+		srp:        newSRPSessions(),
+		audit:      zap.NewNop(),
 		encryptKey: "",
+		srpFakeKey: make([]byte, srpSessionSize),
+		loginLimit: defaultLoginLimit,
+		lockout:    defaultLockout,
+		legacyAuth: true,
+		policy:     &policy.Policy{},
+		regMode:    RegistrationOpen,
 	}
 
 	for _, opt := range options {
 		opt(k)
 	}
 
+	if k.hasher == nil {
+		hasher, err := passhash.NewArgon2id(passhash.DefaultArgon2Params)
+		if err != nil {
+			return nil, fmt.Errorf("failed create password hasher: %w", err)
+		}
+		k.hasher = hasher
+	}
+	switch k.regMode {
+	case RegistrationOpen, RegistrationInvite, RegistrationDisabled:
+	default:
+		return nil, fmt.Errorf("registration mode `%s`: %w", k.regMode, ErrRegistrationModeNotExists)
+	}
+	if _, err := rand.Read(k.srpFakeKey); err != nil {
+		return nil, fmt.Errorf("failed generate srp key: %w", err)
+	}
+
 	return k, nil
 }
 
 // Registration регистрация пользователя.
-func (k *Keeper) Registration(ctx context.Context, login, password string) error {
+// С withRecovery создается ключ восстановления, он возвращается один раз и на сервере не хранится.
+// В режиме RegistrationInvite нужен код приглашения, он погашается вместе с созданием пользователя.
+func (k *Keeper) Registration(ctx context.Context,
+	login string, password Password, withRecovery bool, invite string,
+) (string, error) {
+	inviteHash, err := k.checkRegistration(invite)
+	if err != nil {
+		return "", err
+	}
 	if err := validateLogin(login); err != nil {
-		return fmt.Errorf("login invalid: %w", err)
+		return "", fmt.Errorf("login invalid: %w", err)
 	}
-	if err := validatePassword(password); err != nil {
-		return fmt.Errorf("password invalid: %w", err)
+	if err := k.policy.CheckLogin(login); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrLoginNotValid, err)
 	}
-
-	passwordHash, err := hashPassword(password)
+	cred, err := k.credentials(login, password)
 	if err != nil {
-		return fmt.Errorf("failed hashing password: %w", err)
+		return "", fmt.Errorf("password invalid: %w", err)
+	}
+
+	var recoveryKey, recoveryHash string
+	if withRecovery {
+		recoveryKey, recoveryHash, err = newRecoveryKey()
+		if err != nil {
+			return "", err
+		}
 	}
 
-	err = k.store.Registration(ctx, login, passwordHash)
+	err = k.store.Registration(ctx, login, cred, recoveryHash, inviteHash)
 	if err != nil {
-		return fmt.Errorf("failed user regstration: %w", err)
+		if errors.Is(err, keeperr.ErrInviteNotValid) {
+			return "", ErrInviteNotValid
+		}
+		return "", fmt.Errorf("failed user regstration: %w", err)
 	}
 
-	return nil
+	return recoveryKey, nil
 }
 
-// Login авторизация пользователя.
+// Login авторизация пользователя с передачей пароля серверу.
 func (k *Keeper) Login(ctx context.Context, login, password string) (*models.User, error) {
+	if !k.legacyAuth {
+		return nil, ErrLegacyAuthDisabled
+	}
 	if err := validateLogin(login); err != nil {
 		return nil, fmt.Errorf("login invalid: %w", err)
 	}
@@ -88,18 +239,83 @@ func (k *Keeper) Login(ctx context.Context, login, password string) (*models.Use
 		return nil, fmt.Errorf("password invalid: %w", err)
 	}
 
+	if err := k.checkLogin(ctx, login); err != nil {
+		return nil, err
+	}
+
 	user, err := k.store.GetUserByLogin(ctx, login)
 	if err != nil {
+		// несуществующий логин учитывается как неудача, чтобы блокировка не выдавала наличие пользователя.
+		if errors.Is(err, keeperr.ErrNotFound) {
+			k.loginFailed(ctx, login)
+		}
 		return nil, fmt.Errorf("failed find user: %w", err)
 	}
 
-	if !checkPasswordHash(password, user.PasswordHash) {
+	if !k.checkPasswordHash(password, user.PasswordHash) {
+		k.loginFailed(ctx, login)
 		return nil, keeperr.ErrLoginOrPasswordNotCorrect
 	}
+	k.loginSucceeded(ctx, user)
+	k.rehashPassword(ctx, user, password)
+	k.migrateVerifier(ctx, user, password)
 
 	return user, nil
 }
 
+// ChangePassword смена пароля пользователя, выданные ранее токены перестают действовать.
+// Текущий пароль подтверждается паролем или доказательством SRP, новый задается паролем или верификатором.
+// Возвращает новую эпоху сессий, с ней выдается токен текущему клиенту.
+func (k *Keeper) ChangePassword(ctx context.Context, userID uint, current Proof, next Password) (int64, error) {
+	if err := k.validateNewPassword(next); err != nil {
+		return 0, fmt.Errorf("new password invalid: %w", err)
+	}
+	if current.Password != "" && current.Password == next.Plain {
+		return 0, fmt.Errorf("new password equals current: %w", ErrPasswordNotValid)
+	}
+
+	user, err := k.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed find user: %w", err)
+	}
+
+	// подбор текущего пароля по украденному токену ограничивается так же, как подбор при входе.
+	if err := k.checkLogin(ctx, user.Login); err != nil {
+		return 0, err
+	}
+	if err := k.verifyProof(ctx, user, current); err != nil {
+		return 0, err
+	}
+
+	cred, err := k.credentials(user.Login, next)
+	if err != nil {
+		return 0, fmt.Errorf("new password invalid: %w", err)
+	}
+	epoch, err := k.store.ChangePassword(ctx, userID, user.Credentials(), cred)
+	if err != nil {
+		return 0, fmt.Errorf("failed change password: %w", err)
+	}
+	k.audit.Info("password changed, sessions revoked",
+		zap.String("login", user.Login),
+		zap.Uint("user_id", user.ID),
+		zap.String("ip", clientIP(ctx)),
+		zap.Int64("session_epoch", epoch))
+
+	return epoch, nil
+}
+
+// CheckSession проверяет, что токен выдан в текущей эпохе сессий пользователя.
+func (k *Keeper) CheckSession(ctx context.Context, userID uint, epoch int64) error {
+	current, err := k.store.GetSessionEpoch(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed get session epoch: %w", err)
+	}
+	if current != epoch {
+		return keeperr.ErrSessionRevoked
+	}
+	return nil
+}
+
 // GetMetaDatasByUserID получить все мето данные пользователя из стора.
 func (k *Keeper) GetMetaDatasByUserID(ctx context.Context, userID uint) (*[]models.Secret, error) {
 	secret, err := k.store.GetMetaDatasByUserID(ctx, userID)
@@ -109,15 +325,52 @@ func (k *Keeper) GetMetaDatasByUserID(ctx context.Context, userID uint) (*[]mode
 	return secret, nil
 }
 
-// GetSecret получить данные из стора.
-func (k *Keeper) GetSecret(ctx context.Context, id uint) (*models.Secret, error) {
-	secret, err := k.store.GetSecret(ctx, id)
+// ListSecrets мета данные пользователя по фильтру.
+// При заданном лимите возвращает курсор следующей страницы, если она есть.
+func (k *Keeper) ListSecrets(ctx context.Context, userID uint, filter models.SecretFilter) (
+	*[]models.Secret, *models.SecretCursor, error,
+) {
+	if filter.Limit < 0 || filter.Limit > listMaxLimit {
+		return nil, nil, fmt.Errorf("limit %v: %w", filter.Limit, ErrListFilterNotValid)
+	}
+	if filter.After != nil && filter.Limit == 0 {
+		return nil, nil, fmt.Errorf("cursor without limit: %w", ErrListFilterNotValid)
+	}
+	switch filter.Sort {
+	case models.SortByID, models.SortByTitle, models.SortByUpdateDT:
+	case "":
+		filter.Sort = models.SortByID
+	default:
+		return nil, nil, fmt.Errorf("sort `%s`: %w", filter.Sort, ErrListFilterNotValid)
+	}
+
+	limit := filter.Limit
+	if limit > 0 {
+		// лишняя запись показывает, что есть следующая страница.
+		filter.Limit++
+	}
+	secrets, err := k.store.ListSecrets(ctx, userID, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed list secrets by user `%v`: %w", userID, err)
+	}
+
+	if limit == 0 || len(*secrets) <= limit {
+		return secrets, nil, nil
+	}
+	page := (*secrets)[:limit]
+	last := page[limit-1]
+	return &page, &models.SecretCursor{ID: last.ID, Title: last.Title, UpdateDT: last.UpdateDT}, nil
+}
+
+// GetSecret получить данные пользователя из стора, чужой секрет не находится.
+func (k *Keeper) GetSecret(ctx context.Context, id, userID uint) (*models.Secret, error) {
+	secret, err := k.store.GetSecret(ctx, id, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed get secret: %w", err)
 	}
 	eData, err := k.decrypt([]byte(k.encryptKey), secret.Data)
 	if err != nil {
-		return nil, fmt.Errorf("failed encrypt data with key `%s`: %w", k.encryptKey, err)
+		return nil, fmt.Errorf("failed decrypt data: %w", err)
 	}
 	secret.Data = eData
 
@@ -126,7 +379,8 @@ func (k *Keeper) GetSecret(ctx context.Context, id uint) (*models.Secret, error)
 
 // NewSecret создаем данные в сторе.
 func (k *Keeper) NewSecret(ctx context.Context,
-	data *[]byte, title string, dataType models.DataType, updateDT int64, userID uint) (*models.Secret, error) {
+	data *[]byte, title, folder string, dataType models.DataType, updateDT int64, userID uint,
+) (*models.Secret, error) {
 	eData, err := k.encrypt([]byte(k.encryptKey), *data)
 	if err != nil {
 		return nil, fmt.Errorf("failed encrypt data: %w", err)
@@ -134,6 +388,7 @@ func (k *Keeper) NewSecret(ctx context.Context,
 	secret := &models.Secret{
 		UserID:   userID,
 		Title:    title,
+		Folder:   folder,
 		DataType: dataType,
 		Data:     eData,
 	}
@@ -148,13 +403,14 @@ func (k *Keeper) NewSecret(ctx context.Context,
 	if err != nil {
 		return nil, fmt.Errorf("failed create secret: %w", err)
 	}
+	k.notifier.publish(userID)
 
 	return secret, nil
 }
 
-// UpdSecret обновляем данные в сторе.
+// UpdSecret обновляем данные в сторе. folder nil - папка не меняется, пустая - секрет переносится из папки.
 func (k *Keeper) UpdSecret(
-	ctx context.Context, id uint, data *[]byte, title string,
+	ctx context.Context, id uint, data *[]byte, title string, folder *string,
 	dataType models.DataType, updateDT int64, userID uint,
 ) (*models.Secret, error) {
 	eData, err := k.encrypt([]byte(k.encryptKey), *data)
@@ -171,10 +427,14 @@ func (k *Keeper) UpdSecret(
 		UpdateDT: updateDT,
 		UserID:   userID,
 	}
-	secret, err = k.store.UpdSecret(ctx, secret)
+	if folder != nil {
+		secret.Folder = *folder
+	}
+	secret, err = k.store.UpdSecret(ctx, secret, folder != nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed update secret: %w", err)
 	}
+	k.notifier.publish(userID)
 
 	return secret, nil
 }
@@ -215,10 +475,68 @@ func (k *Keeper) decrypt(key, text []byte) ([]byte, error) {
 }
 
 // DelSecret удаляем данные из стора.
-func (k *Keeper) DelSecret(ctx context.Context, id uint) error {
-	err := k.store.DelSecret(ctx, id)
+func (k *Keeper) DelSecret(ctx context.Context, id uint, userID uint) error {
+	err := k.store.DelSecret(ctx, id, userID)
 	if err != nil {
 		return fmt.Errorf("failed delete secret: %w", err)
 	}
+	k.notifier.publish(userID)
 	return nil
 }
+
+// Batch применяем пакет операций над данными пользователя в одной транзакции.
+func (k *Keeper) Batch(ctx context.Context, userID uint, ops *[]models.BatchOperation) (*[]models.BatchResult, error) {
+	if len(*ops) == 0 || len(*ops) > batchMaxSize {
+		return nil, fmt.Errorf("batch size %v: %w", len(*ops), ErrBatchNotValid)
+	}
+
+	now := time.Now().UTC().Unix()
+	eOps := make([]models.BatchOperation, 0, len(*ops))
+	for i, op := range *ops {
+		switch op.Action {
+		case models.BatchCreate, models.BatchUpdate:
+			eData, err := k.encrypt([]byte(k.encryptKey), op.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed encrypt data #%v: %w", i, err)
+			}
+			op.Data = eData
+		case models.BatchDelete:
+			op.Data = nil
+		default:
+			return nil, fmt.Errorf("operation #%v action `%s`: %w", i, op.Action, ErrBatchNotValid)
+		}
+		if len(op.IdempotencyKey) > batchKeyMaxLength {
+			return nil, fmt.Errorf("operation #%v idempotency key too long: %w", i, ErrBatchNotValid)
+		}
+		if op.Action != models.BatchCreate && op.ID == 0 {
+			return nil, fmt.Errorf("operation #%v without id: %w", i, ErrBatchNotValid)
+		}
+		if op.UpdateDT <= 0 {
+			op.UpdateDT = now
+		}
+		eOps = append(eOps, op)
+	}
+
+	results, err := k.store.Batch(ctx, userID, &eOps)
+	if err != nil {
+		return nil, fmt.Errorf("failed apply batch: %w", err)
+	}
+	k.notifier.publish(userID)
+
+	return results, nil
+}
+
+// GetRevision текущая ревизия хранилища пользователя.
+func (k *Keeper) GetRevision(ctx context.Context, userID uint) (int64, error) {
+	revision, err := k.store.GetRevision(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed get revision user `%v`: %w", userID, err)
+	}
+	return revision, nil
+}
+
+// Subscribe подписка на изменения хранилища пользователя.
+// Возвращает канал уведомлений и функцию отписки, которую нужно вызвать по завершении.
+func (k *Keeper) Subscribe(userID uint) (<-chan struct{}, func()) {
+	return k.notifier.subscribe(userID)
+}
@@ -0,0 +1,54 @@
+package keeper
+
+import (
+	"sync"
+)
+
+// notifier рассылает подписчикам уведомления об изменении хранилища пользователя.
+type notifier struct {
+	subs map[uint]map[chan struct{}]struct{}
+	mu   sync.Mutex
+}
+
+func newNotifier() *notifier {
+	return &notifier{
+		subs: map[uint]map[chan struct{}]struct{}{},
+	}
+}
+
+func (n *notifier) subscribe(userID uint) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	n.mu.Lock()
+	if _, ok := n.subs[userID]; !ok {
+		n.subs[userID] = map[chan struct{}]struct{}{}
+	}
+	n.subs[userID][ch] = struct{}{}
+	n.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			n.mu.Lock()
+			defer n.mu.Unlock()
+			delete(n.subs[userID], ch)
+			if len(n.subs[userID]) == 0 {
+				delete(n.subs, userID)
+			}
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// publish не блокируется: если подписчик не успел прочитать прошлое уведомление, новое сливается с ним.
+func (n *notifier) publish(userID uint) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for ch := range n.subs[userID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package keeper
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/pkg/recoverykey"
+)
+
+// newRecoveryKey печатный ключ восстановления и его отпечаток для хранения.
+func newRecoveryKey() (string, string, error) {
+	key, err := recoverykey.Generate()
+	if err != nil {
+		return "", "", fmt.Errorf("failed create recovery key: %w", err)
+	}
+	return recoverykey.Format(key), recoverykey.Hash(key), nil
+}
+
+// Recover задает новый пароль по ключу восстановления.
+// Использованный ключ заменяется новым, выданные ранее токены перестают действовать.
+// Секреты шифруются ключом сервера, поэтому доступ к ним сохраняется.
+func (k *Keeper) Recover(ctx context.Context, login, recoveryKey string, next Password) (*models.User, string, error) {
+	if err := validateLogin(login); err != nil {
+		return nil, "", fmt.Errorf("login invalid: %w", err)
+	}
+	if err := k.validateNewPassword(next); err != nil {
+		return nil, "", fmt.Errorf("new password invalid: %w", err)
+	}
+	if err := k.checkLogin(ctx, login); err != nil {
+		return nil, "", err
+	}
+
+	key, err := recoverykey.Parse(recoveryKey)
+	if err != nil {
+		k.loginFailed(ctx, login)
+		return nil, "", fmt.Errorf("%w: %w", ErrRecoveryNotValid, err)
+	}
+
+	user, err := k.store.GetUserByLogin(ctx, login)
+	if err != nil {
+		if errors.Is(err, keeperr.ErrNotFound) {
+			k.loginFailed(ctx, login)
+			return nil, "", ErrRecoveryNotValid
+		}
+		return nil, "", fmt.Errorf("failed find user: %w", err)
+	}
+	hash := recoverykey.Hash(key)
+	if user.RecoveryHash == "" || subtle.ConstantTimeCompare([]byte(hash), []byte(user.RecoveryHash)) != 1 {
+		k.loginFailed(ctx, login)
+		return nil, "", ErrRecoveryNotValid
+	}
+
+	cred, err := k.credentials(login, next)
+	if err != nil {
+		return nil, "", fmt.Errorf("new password invalid: %w", err)
+	}
+	newKey, newKeyHash, err := newRecoveryKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	epoch, err := k.store.RecoverPassword(ctx, user.ID, user.RecoveryHash, cred, newKeyHash)
+	if err != nil {
+		// ключ успели использовать параллельным запросом.
+		if errors.Is(err, keeperr.ErrNotFound) {
+			return nil, "", ErrRecoveryNotValid
+		}
+		return nil, "", fmt.Errorf("failed recover password: %w", err)
+	}
+	user.SessionEpoch = epoch
+	user.PasswordHash = cred.PasswordHash
+	user.SRPSalt = cred.SRPSalt
+	user.SRPVerifier = cred.SRPVerifier
+	user.RecoveryHash = newKeyHash
+
+	k.resetLoginFailures(ctx, login)
+	k.audit.Info("account recovered, sessions revoked",
+		zap.String("login", user.Login),
+		zap.Uint("user_id", user.ID),
+		zap.String("ip", clientIP(ctx)),
+		zap.Int64("session_epoch", epoch))
+
+	return user, newKey, nil
+}
@@ -0,0 +1,115 @@
+package keeper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/internal/mocks/storage/database"
+	"github.com/playmixer/secret-keeper/pkg/ratelimit"
+	"github.com/playmixer/secret-keeper/pkg/recoverykey"
+)
+
+func TestKeeper_Recover(t *testing.T) {
+	bKey, err := recoverykey.Generate()
+	assert.NoError(t, err)
+	key := recoverykey.Format(bKey)
+	keyHash := recoverykey.Hash(bKey)
+
+	user := func() *models.User {
+		return &models.User{Model: gorm.Model{ID: 1}, Login: "user", RecoveryHash: keyHash}
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		mock    func(store *database.MockStorage)
+		wantErr error
+	}{
+		{
+			name: "ok",
+			key:  key,
+			mock: func(store *database.MockStorage) {
+				store.EXPECT().GetUserByLogin(gomock.Any(), "user").Return(user(), nil)
+				store.EXPECT().RecoverPassword(gomock.Any(), uint(1), keyHash, gomock.Any(), gomock.Any()).
+					Return(int64(2), nil)
+			},
+		},
+		{
+			name: "wrong key",
+			key:  "AAAA-BBBB-CCCC",
+			mock: func(store *database.MockStorage) {
+				store.EXPECT().GetUserByLogin(gomock.Any(), "user").Return(user(), nil)
+			},
+			wantErr: ErrRecoveryNotValid,
+		},
+		{
+			name:    "malformed key",
+			key:     "!!!",
+			mock:    func(store *database.MockStorage) {},
+			wantErr: ErrRecoveryNotValid,
+		},
+		{
+			name: "unknown login",
+			key:  key,
+			mock: func(store *database.MockStorage) {
+				store.EXPECT().GetUserByLogin(gomock.Any(), "user").Return(nil, keeperr.ErrNotFound)
+			},
+			wantErr: ErrRecoveryNotValid,
+		},
+		{
+			name: "key already used",
+			key:  key,
+			mock: func(store *database.MockStorage) {
+				store.EXPECT().GetUserByLogin(gomock.Any(), "user").Return(user(), nil)
+				store.EXPECT().RecoverPassword(gomock.Any(), uint(1), keyHash, gomock.Any(), gomock.Any()).
+					Return(int64(0), keeperr.ErrNotFound)
+			},
+			wantErr: ErrRecoveryNotValid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := database.NewMockStorage(ctrl)
+			tt.mock(store)
+			k, err := New(store)
+			assert.NoError(t, err)
+
+			got, newKey, err := k.Recover(context.TODO(), "user", tt.key, Password{Plain: "newpassword"})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), got.SessionEpoch)
+			assert.NotEqual(t, key, newKey)
+			bNewKey, err := recoverykey.Parse(newKey)
+			assert.NoError(t, err)
+			assert.Equal(t, recoverykey.Hash(bNewKey), got.RecoveryHash)
+		})
+	}
+}
+
+func TestKeeper_RecoverLockout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := database.NewMockStorage(ctrl)
+	k, err := New(store, SetLimiter(ratelimit.NewMemory()), SetLockoutThreshold(2))
+	assert.NoError(t, err)
+
+	for range 2 {
+		_, _, err = k.Recover(context.TODO(), "user", "!!!", Password{Plain: "newpassword"})
+		assert.ErrorIs(t, err, ErrRecoveryNotValid)
+	}
+	_, _, err = k.Recover(context.TODO(), "user", "!!!", Password{Plain: "newpassword"})
+	assert.ErrorIs(t, err, keeperr.ErrAccountLocked)
+}
@@ -0,0 +1,322 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/pkg/srp"
+)
+
+const (
+	srpSessionTTL  = 2 * time.Minute
+	srpMaxSessions = 10000
+	// srpMaxPerLogin и srpMaxPerIP не дают одному логину или адресу занять весь запас сессий,
+	// на адрес больше: за NAT одним адресом ходят многие клиенты.
+	srpMaxPerLogin = 20
+	srpMaxPerIP    = 200
+	srpSessionSize = 16
+)
+
+// Password новый пароль: открытый текст (устаревший вход с паролем) или соль и верификатор SRP,
+// вычисленные клиентом. С верификатором сервер пароль не узнает.
+type Password struct {
+	Plain    string
+	Salt     []byte
+	Verifier []byte
+}
+
+// Proof подтверждение текущего пароля: открытый текст или доказательство из сессии SRPInit.
+type Proof struct {
+	Password string
+	Session  string
+	SRPProof []byte
+}
+
+// SRPChallenge ответ сервера на начало входа по SRP.
+type SRPChallenge struct {
+	Session      string
+	Salt         []byte
+	ServerPublic []byte
+}
+
+type srpSession struct {
+	expires  time.Time
+	server   *srp.Server
+	login    string
+	ip       string
+	verifier []byte
+	// userID 0 - подставная сессия для неизвестного логина, она никогда не проходит проверку.
+	userID uint
+}
+
+// srpSessions незавершенные входы по SRP. Хранятся в памяти процесса:
+// для нескольких реплик сервера запросы одного входа должны попадать на одну реплику.
+type srpSessions struct {
+	now   func() time.Time
+	items map[string]*srpSession
+	// owners число сессий на логин и на адрес клиента.
+	owners map[string]int
+	mu     sync.Mutex
+}
+
+func newSRPSessions() *srpSessions {
+	return &srpSessions{
+		now:    time.Now,
+		items:  map[string]*srpSession{},
+		owners: map[string]int{},
+	}
+}
+
+// owners ключи счетчиков сессии, без адреса (вызов не из сети) считается только логин.
+func (session *srpSession) owners() []string {
+	if session.ip == "" {
+		return []string{loginKeyPrefix + session.login}
+	}
+	return []string{loginKeyPrefix + session.login, "ip:" + session.ip}
+}
+
+// full сессию нельзя добавить: занят общий запас или запас ее логина либо адреса.
+func (s *srpSessions) full(session *srpSession) bool {
+	if len(s.items) >= srpMaxSessions || s.owners[loginKeyPrefix+session.login] >= srpMaxPerLogin {
+		return true
+	}
+	return session.ip != "" && s.owners["ip:"+session.ip] >= srpMaxPerIP
+}
+
+func (s *srpSessions) remove(id string, session *srpSession) {
+	delete(s.items, id)
+	for _, key := range session.owners() {
+		if s.owners[key]--; s.owners[key] <= 0 {
+			delete(s.owners, key)
+		}
+	}
+}
+
+func (s *srpSessions) add(session *srpSession) (string, error) {
+	b := make([]byte, srpSessionSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed generate session: %w", err)
+	}
+	id := hex.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.full(session) {
+		for key, item := range s.items {
+			if now.After(item.expires) {
+				s.remove(key, item)
+			}
+		}
+	}
+	if s.full(session) {
+		return "", &keeperr.RetryError{Err: keeperr.ErrRateLimited, After: srpSessionTTL}
+	}
+	session.expires = now.Add(srpSessionTTL)
+	s.items[id] = session
+	for _, key := range session.owners() {
+		s.owners[key]++
+	}
+	return id, nil
+}
+
+// take забирает сессию: доказательство к одной сессии можно проверить только один раз.
+func (s *srpSessions) take(id string) *srpSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.items[id]
+	if !ok {
+		return nil
+	}
+	s.remove(id, session)
+	if s.now().After(session.expires) {
+		return nil
+	}
+	return session
+}
+
+// validateNewPassword проверка нового пароля до обращения к хранилищу.
+func (k *Keeper) validateNewPassword(p Password) error {
+	if len(p.Verifier) > 0 {
+		if !srp.ValidVerifier(p.Salt, p.Verifier) {
+			return fmt.Errorf("srp verifier: %w", ErrPasswordNotValid)
+		}
+		if !k.clientPasswordCheck && k.policy.Strict() {
+			return ErrPasswordUnchecked
+		}
+		return nil
+	}
+	if !k.legacyAuth {
+		return ErrLegacyAuthDisabled
+	}
+	return validatePassword(p.Plain)
+}
+
+// credentials данные проверки нового пароля. Открытый пароль сервер уже знает,
+// поэтому сразу вычисляет и верификатор: пользователь сможет входить по SRP.
+func (k *Keeper) credentials(login string, p Password) (models.Credentials, error) {
+	if err := k.validateNewPassword(p); err != nil {
+		return models.Credentials{}, err
+	}
+	if len(p.Verifier) > 0 {
+		return models.Credentials{SRPSalt: p.Salt, SRPVerifier: p.Verifier}, nil
+	}
+	// пароль, заданный верификатором, сервер проверить не может, его проверяет клиент по Policy.
+	if err := k.policy.CheckPassword(login, p.Plain); err != nil {
+		return models.Credentials{}, fmt.Errorf("%w: %w", ErrPasswordWeak, err)
+	}
+
+	hash, err := k.hashPassword(p.Plain)
+	if err != nil {
+		return models.Credentials{}, err
+	}
+	salt, err := srp.NewSalt()
+	if err != nil {
+		return models.Credentials{}, fmt.Errorf("failed create srp salt: %w", err)
+	}
+	return models.Credentials{
+		PasswordHash: hash,
+		SRPSalt:      salt,
+		SRPVerifier:  srp.Verifier(login, p.Plain, salt),
+	}, nil
+}
+
+// verifyProof проверяет подтверждение текущего пароля пользователя, неудача учитывается как неудачный вход.
+func (k *Keeper) verifyProof(ctx context.Context, user *models.User, p Proof) error {
+	if p.Session == "" && !k.legacyAuth {
+		return ErrLegacyAuthDisabled
+	}
+
+	ok := false
+	if p.Session != "" {
+		session := k.srp.take(p.Session)
+		if session != nil && session.userID == user.ID && bytes.Equal(session.verifier, user.SRPVerifier) {
+			_, err := session.server.Verify(p.SRPProof)
+			ok = err == nil
+		}
+	} else {
+		ok = k.checkPasswordHash(p.Password, user.PasswordHash)
+	}
+	if !ok {
+		k.loginFailed(ctx, user.Login)
+		return ErrPasswordIncorrect
+	}
+	return nil
+}
+
+// SRPInit первый шаг входа по SRP: по логину и открытому ключу клиента отдает соль и открытый ключ сервера.
+// Для неизвестного логина ответ неотличим от настоящего, вход просто не пройдет на втором шаге.
+func (k *Keeper) SRPInit(ctx context.Context, login string, clientPublic []byte) (*SRPChallenge, error) {
+	if err := validateLogin(login); err != nil {
+		return nil, fmt.Errorf("login invalid: %w", err)
+	}
+	if err := k.checkLogin(ctx, login); err != nil {
+		return nil, err
+	}
+
+	session := &srpSession{login: login, ip: clientIP(ctx)}
+	user, err := k.store.GetUserByLogin(ctx, login)
+	switch {
+	case err == nil && len(user.SRPVerifier) > 0:
+		session.userID = user.ID
+		session.verifier = user.SRPVerifier
+	case err == nil, errors.Is(err, keeperr.ErrNotFound):
+		// соль подставной сессии постоянна для логина, иначе повторный запрос выдал бы отсутствие пользователя.
+		mac := hmac.New(sha256.New, k.srpFakeKey)
+		mac.Write([]byte(login))
+		user = &models.User{SRPSalt: mac.Sum(nil)[:srp.SaltSize]}
+		random, errSalt := srp.NewSalt()
+		if errSalt != nil {
+			return nil, fmt.Errorf("failed create fake verifier: %w", errSalt)
+		}
+		user.SRPVerifier = srp.Verifier(login, hex.EncodeToString(random), user.SRPSalt)
+	default:
+		return nil, fmt.Errorf("failed find user: %w", err)
+	}
+
+	server, err := srp.NewServer(login, user.SRPSalt, user.SRPVerifier, clientPublic)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSRPNotValid, err)
+	}
+	session.server = server
+	id, err := k.srp.add(session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SRPChallenge{
+		Session:      id,
+		Salt:         user.SRPSalt,
+		ServerPublic: server.PublicKey(),
+	}, nil
+}
+
+// SRPVerify второй шаг входа по SRP: проверяет доказательство клиента и отдает доказательство сервера,
+// по нему клиент убеждается, что говорит с сервером, знающим его верификатор.
+func (k *Keeper) SRPVerify(ctx context.Context, sessionID string, clientProof []byte) (*models.User, []byte, error) {
+	session := k.srp.take(sessionID)
+	if session == nil {
+		return nil, nil, fmt.Errorf("srp session expired: %w", keeperr.ErrLoginOrPasswordNotCorrect)
+	}
+	// частоту попыток учел первый шаг, здесь только блокировка, выставленная между шагами.
+	if err := k.checkLockout(ctx, session.login); err != nil {
+		return nil, nil, err
+	}
+
+	var serverProof []byte
+	var err error
+	if session.userID != 0 {
+		serverProof, err = session.server.Verify(clientProof)
+	}
+	if session.userID == 0 || err != nil {
+		k.loginFailed(ctx, session.login)
+		return nil, nil, keeperr.ErrLoginOrPasswordNotCorrect
+	}
+
+	user, err := k.store.GetUserByID(ctx, session.userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed find user: %w", err)
+	}
+	// пароль сменили между шагами входа.
+	if !bytes.Equal(user.SRPVerifier, session.verifier) {
+		k.loginFailed(ctx, session.login)
+		return nil, nil, keeperr.ErrLoginOrPasswordNotCorrect
+	}
+	k.loginSucceeded(ctx, user)
+
+	return user, serverProof, nil
+}
+
+// migrateVerifier создает верификатор SRP при входе с паролем, дальше пользователь может входить по SRP.
+func (k *Keeper) migrateVerifier(ctx context.Context, user *models.User, password string) {
+	if len(user.SRPVerifier) > 0 {
+		return
+	}
+	salt, err := srp.NewSalt()
+	if err != nil {
+		k.audit.Error("failed create srp salt", zap.Error(err))
+		return
+	}
+	verifier := srp.Verifier(user.Login, password, salt)
+	if err := k.store.SetVerifier(ctx, user.ID, salt, verifier); err != nil {
+		k.audit.Error("failed set srp verifier", zap.Uint("user_id", user.ID), zap.Error(err))
+		return
+	}
+	user.SRPSalt = salt
+	user.SRPVerifier = verifier
+	k.audit.Info("srp verifier created", zap.String("login", user.Login), zap.Uint("user_id", user.ID))
+}
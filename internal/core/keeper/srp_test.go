@@ -0,0 +1,272 @@
+package keeper
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/keeperr"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/internal/mocks/storage/database"
+	"github.com/playmixer/secret-keeper/pkg/passhash"
+	"github.com/playmixer/secret-keeper/pkg/policy"
+	"github.com/playmixer/secret-keeper/pkg/ratelimit"
+	"github.com/playmixer/secret-keeper/pkg/srp"
+)
+
+func srpUser(t *testing.T, password string) *models.User {
+	t.Helper()
+
+	salt, err := srp.NewSalt()
+	require.NoError(t, err)
+	return &models.User{
+		Model:       gorm.Model{ID: 1},
+		Login:       "user",
+		SRPSalt:     salt,
+		SRPVerifier: srp.Verifier("user", password, salt),
+	}
+}
+
+// srpHandshake первый шаг входа клиента и доказательство клиента.
+func srpHandshake(t *testing.T, k *Keeper, login, password string) (*srp.Client, *SRPChallenge, []byte) {
+	t.Helper()
+
+	client, err := srp.NewClient(login, password)
+	require.NoError(t, err)
+	challenge, err := k.SRPInit(context.TODO(), login, client.PublicKey())
+	require.NoError(t, err)
+	proof, err := client.Proof(challenge.Salt, challenge.ServerPublic)
+	require.NoError(t, err)
+	return client, challenge, proof
+}
+
+func TestKeeper_SRPLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{name: "ok", password: "password"},
+		{name: "wrong password", password: "other", wantErr: keeperr.ErrLoginOrPasswordNotCorrect},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			user := srpUser(t, "password")
+			store := database.NewMockStorage(ctrl)
+			store.EXPECT().GetUserByLogin(gomock.Any(), "user").Return(user, nil)
+			store.EXPECT().GetUserByID(gomock.Any(), uint(1)).Return(user, nil).AnyTimes()
+			k, err := New(store, SetLegacyPasswordAuth(false))
+			require.NoError(t, err)
+
+			client, challenge, proof := srpHandshake(t, k, "user", tt.password)
+			got, serverProof, err := k.SRPVerify(context.TODO(), challenge.Session, proof)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, uint(1), got.ID)
+			assert.NoError(t, client.VerifyServer(serverProof))
+
+			// сессия одноразовая.
+			_, _, err = k.SRPVerify(context.TODO(), challenge.Session, proof)
+			assert.ErrorIs(t, err, keeperr.ErrLoginOrPasswordNotCorrect)
+		})
+	}
+}
+
+func TestKeeper_SRPLoginRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := srpUser(t, "password")
+	store := database.NewMockStorage(ctrl)
+	store.EXPECT().GetUserByLogin(gomock.Any(), "user").Return(user, nil)
+	store.EXPECT().GetUserByID(gomock.Any(), uint(1)).Return(user, nil)
+	k, err := New(store,
+		SetLegacyPasswordAuth(false),
+		SetLimiter(ratelimit.NewMemory()),
+		SetLoginLimit(ratelimit.PerMinute(1)),
+	)
+	require.NoError(t, err)
+
+	// оба шага одного входа расходуют одну попытку.
+	_, challenge, proof := srpHandshake(t, k, "user", "password")
+	_, _, err = k.SRPVerify(context.TODO(), challenge.Session, proof)
+	require.NoError(t, err)
+
+	client, err := srp.NewClient("user", "password")
+	require.NoError(t, err)
+	_, err = k.SRPInit(context.TODO(), "user", client.PublicKey())
+	assert.ErrorIs(t, err, keeperr.ErrRateLimited)
+}
+
+func TestKeeper_SRPInitUnknownLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := database.NewMockStorage(ctrl)
+	store.EXPECT().GetUserByLogin(gomock.Any(), "ghost").Return(nil, keeperr.ErrNotFound).Times(2)
+	k, err := New(store)
+	require.NoError(t, err)
+
+	_, first, proof := srpHandshake(t, k, "ghost", "password")
+	_, second, _ := srpHandshake(t, k, "ghost", "password")
+	assert.Equal(t, first.Salt, second.Salt)
+	assert.Len(t, first.Salt, srp.SaltSize)
+
+	_, _, err = k.SRPVerify(context.TODO(), first.Session, proof)
+	assert.ErrorIs(t, err, keeperr.ErrLoginOrPasswordNotCorrect)
+}
+
+func TestKeeper_SRPChangePassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := srpUser(t, "password")
+	salt, err := srp.NewSalt()
+	require.NoError(t, err)
+	next := Password{Salt: salt, Verifier: srp.Verifier("user", "new", salt)}
+
+	store := database.NewMockStorage(ctrl)
+	store.EXPECT().GetUserByLogin(gomock.Any(), "user").Return(user, nil)
+	store.EXPECT().GetUserByID(gomock.Any(), uint(1)).Return(user, nil)
+	store.EXPECT().ChangePassword(gomock.Any(), uint(1), user.Credentials(),
+		models.Credentials{SRPSalt: next.Salt, SRPVerifier: next.Verifier}).Return(int64(1), nil)
+	k, err := New(store, SetLegacyPasswordAuth(false))
+	require.NoError(t, err)
+
+	_, challenge, proof := srpHandshake(t, k, "user", "password")
+	epoch, err := k.ChangePassword(context.TODO(), 1, Proof{Session: challenge.Session, SRPProof: proof}, next)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), epoch)
+}
+
+func TestKeeper_LegacyAuthDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	k, err := New(database.NewMockStorage(ctrl), SetLegacyPasswordAuth(false))
+	require.NoError(t, err)
+
+	_, err = k.Login(context.TODO(), "user", "password")
+	assert.ErrorIs(t, err, ErrLegacyAuthDisabled)
+	_, err = k.Registration(context.TODO(), "user", Password{Plain: "password"}, false, "")
+	assert.ErrorIs(t, err, ErrLegacyAuthDisabled)
+	_, err = k.ChangePassword(context.TODO(), 1, Proof{Password: "password"}, Password{Plain: "new"})
+	assert.ErrorIs(t, err, ErrLegacyAuthDisabled)
+}
+
+func TestKeeper_ClientPasswordCheck(t *testing.T) {
+	denylist := &policy.Policy{}
+	denylist.SetDenylist([]string{"password"})
+	tests := []struct {
+		name    string
+		policy  *policy.Policy
+		allow   bool
+		wantErr error
+	}{
+		{name: "strength", policy: &policy.Policy{PasswordMinStrength: 2}, wantErr: ErrPasswordUnchecked},
+		{name: "denylist", policy: denylist, wantErr: ErrPasswordUnchecked},
+		{name: "length only", policy: &policy.Policy{PasswordMinLength: 8}},
+		{name: "allowed", policy: &policy.Policy{PasswordMinStrength: 2}, allow: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			salt, err := srp.NewSalt()
+			require.NoError(t, err)
+			next := Password{Salt: salt, Verifier: srp.Verifier("user", "password", salt)}
+			store := database.NewMockStorage(ctrl)
+			if tt.wantErr == nil {
+				store.EXPECT().Registration(gomock.Any(), "user", gomock.Any(), "", "").Return(nil)
+			}
+			k, err := New(store, SetPolicy(tt.policy), SetClientPasswordCheck(tt.allow))
+			require.NoError(t, err)
+
+			_, err = k.Registration(context.TODO(), "user", next, false, "")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				_, err = k.ChangePassword(context.TODO(), 1, Proof{Password: "current"}, next)
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestKeeper_RegistrationCreatesVerifier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := database.NewMockStorage(ctrl)
+	store.EXPECT().Registration(gomock.Any(), "user", gomock.Any(), "", "").
+		DoAndReturn(func(_ context.Context, login string, cred models.Credentials, _, _ string) error {
+			ok, err := passhash.Verify("password", cred.PasswordHash)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, srp.Verifier(login, "password", cred.SRPSalt), cred.SRPVerifier)
+			return nil
+		})
+	k, err := New(store)
+	require.NoError(t, err)
+
+	_, err = k.Registration(context.TODO(), "user", Password{Plain: "password"}, false, "")
+	assert.NoError(t, err)
+}
+
+func Test_srpSessions_expired(t *testing.T) {
+	now := time.Now()
+	sessions := newSRPSessions()
+	sessions.now = func() time.Time { return now }
+
+	id, err := sessions.add(&srpSession{login: "user"})
+	require.NoError(t, err)
+	now = now.Add(srpSessionTTL + time.Second)
+	assert.Nil(t, sessions.take(id))
+}
+
+func Test_srpSessions_limits(t *testing.T) {
+	now := time.Now()
+	sessions := newSRPSessions()
+	sessions.now = func() time.Time { return now }
+
+	for range srpMaxPerLogin {
+		_, err := sessions.add(&srpSession{login: "ghost"})
+		require.NoError(t, err)
+	}
+	_, err := sessions.add(&srpSession{login: "ghost"})
+	assert.ErrorIs(t, err, keeperr.ErrRateLimited)
+	// чужой логин не страдает.
+	_, err = sessions.add(&srpSession{login: "user"})
+	assert.NoError(t, err)
+
+	// один адрес перебирает логины.
+	for i := range srpMaxPerIP {
+		_, err = sessions.add(&srpSession{login: fmt.Sprint("login", i), ip: "192.0.2.1"})
+		require.NoError(t, err)
+	}
+	_, err = sessions.add(&srpSession{login: "other", ip: "192.0.2.1"})
+	assert.ErrorIs(t, err, keeperr.ErrRateLimited)
+	_, err = sessions.add(&srpSession{login: "other", ip: "192.0.2.2"})
+	assert.NoError(t, err)
+
+	// истекшие сессии освобождают место до отказа.
+	now = now.Add(srpSessionTTL + time.Second)
+	_, err = sessions.add(&srpSession{login: "ghost"})
+	assert.NoError(t, err)
+	_, err = sessions.add(&srpSession{login: "other", ip: "192.0.2.1"})
+	assert.NoError(t, err)
+}
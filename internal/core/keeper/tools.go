@@ -1,7 +1,13 @@
 package keeper
 
 import (
-	"golang.org/x/crypto/bcrypt"
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/pkg/passhash"
 )
 
 func validateLogin(login string) error {
@@ -18,13 +24,42 @@ func validatePassword(password string) error {
 	return nil
 }
 
-func hashPassword(password string) (string, error) {
-	cost := 14
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), cost)
-	return string(bytes), err
+func (k *Keeper) hashPassword(password string) (string, error) {
+	hash, err := k.hasher.Hash(password)
+	if err != nil {
+		return "", fmt.Errorf("failed hash password: %w", err)
+	}
+	return hash, nil
+}
+
+// checkPasswordHash проверка пароля по хешу любого поддерживаемого алгоритма.
+// Пустой хеш у пользователей, зарегистрированных по SRP, - обычное несовпадение, а не ошибка.
+func (k *Keeper) checkPasswordHash(password, hash string) bool {
+	if hash == "" {
+		return false
+	}
+	ok, err := passhash.Verify(password, hash)
+	if err != nil {
+		k.audit.Error("failed verify password hash", zap.Error(err))
+	}
+	return ok
 }
 
-func checkPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+// rehashPassword пересчитывает хеш после успешного входа, если он слабее текущей политики.
+// Ошибка не мешает входу: хеш пересчитается при следующем.
+func (k *Keeper) rehashPassword(ctx context.Context, user *models.User, password string) {
+	if !k.hasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+	hash, err := k.hashPassword(password)
+	if err != nil {
+		k.audit.Error("failed rehash password", zap.Uint("user_id", user.ID), zap.Error(err))
+		return
+	}
+	if err := k.store.UpdatePasswordHash(ctx, user.ID, user.PasswordHash, hash); err != nil {
+		k.audit.Error("failed update password hash", zap.Uint("user_id", user.ID), zap.Error(err))
+		return
+	}
+	user.PasswordHash = hash
+	k.audit.Info("password rehashed", zap.String("login", user.Login), zap.Uint("user_id", user.ID))
 }
@@ -1,60 +1,135 @@
 package keeper
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/internal/mocks/storage/database"
+	"github.com/playmixer/secret-keeper/pkg/passhash"
 )
 
-func Test_hashPassword(t *testing.T) {
+// testHasher слабые параметры Argon2id, чтобы тесты шли быстро.
+func testHasher(t *testing.T, memory uint32) passhash.Hasher {
+	t.Helper()
+	h, err := passhash.NewArgon2id(passhash.Argon2Params{Memory: memory, Time: 1, Threads: 1})
+	require.NoError(t, err)
+	return h
+}
+
+func TestKeeper_hashPassword(t *testing.T) {
+	bcryptHasher, err := passhash.NewBcrypt(4)
+	require.NoError(t, err)
+
 	tests := []struct {
 		name     string
+		hasher   passhash.Hasher
 		password string
 		wantErr  bool
 	}{
 		{
 			name:     "ok",
+			hasher:   testHasher(t, 1024),
 			password: "user",
-			wantErr:  false,
 		},
 		{
-			name:     "logn password",
-			password: "1222222222222222222222222222222222222222222222222222222222222222222222222",
+			name:     "long password",
+			hasher:   testHasher(t, 1024),
+			password: strings.Repeat("1", 100),
+		},
+		{
+			name:     "long password bcrypt",
+			hasher:   bcryptHasher,
+			password: strings.Repeat("1", 100),
 			wantErr:  true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := hashPassword(tt.password)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("hashPassword() error = %v, wantErr %v", err, tt.wantErr)
+			k, err := New(nil, SetPasswordHasher(tt.hasher))
+			require.NoError(t, err)
+
+			hash, err := k.hashPassword(tt.password)
+			if tt.wantErr {
+				assert.Error(t, err)
 				return
 			}
-			if !tt.wantErr && got == "" {
-				t.Errorf("hashPassword() = %v, empty", got)
-			}
+			assert.NoError(t, err)
+			assert.True(t, k.checkPasswordHash(tt.password, hash))
+			assert.False(t, k.checkPasswordHash(tt.password+"x", hash))
 		})
 	}
 }
 
-func Test_checkPasswordHash(t *testing.T) {
+func TestKeeper_checkPasswordHashEmpty(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	k, err := New(nil, SetAuditLogger(zap.New(core)))
+	require.NoError(t, err)
+
+	assert.False(t, k.checkPasswordHash("user", ""))
+	assert.Zero(t, logs.Len())
+}
+
+func TestKeeper_LoginRehash(t *testing.T) {
+	current := testHasher(t, 2048)
+	weakHash, err := testHasher(t, 1024).Hash("user")
+	require.NoError(t, err)
+	currentHash, err := current.Hash("user")
+	require.NoError(t, err)
+
 	tests := []struct {
-		name     string
-		password string
-		want     bool
+		name       string
+		hash       string
+		updateErr  error
+		wantRehash bool
 	}{
-		{
-			name:     "ok",
-			password: "test",
-			want:     true,
-		},
+		{name: "bcrypt", hash: testPasswordHash, wantRehash: true},
+		{name: "weaker argon2id", hash: weakHash, wantRehash: true},
+		{name: "current policy", hash: currentHash},
+		{name: "update failed", hash: weakHash, updateErr: errors.New("db down"), wantRehash: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			hash, err := hashPassword(tt.password)
-			if err != nil {
-				t.Error("failed generate hash password")
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			user := &models.User{Model: gorm.Model{ID: 1}, Login: "user", PasswordHash: tt.hash, SRPVerifier: []byte{1}}
+			store := database.NewMockStorage(ctrl)
+			store.EXPECT().GetUserByLogin(gomock.Any(), "user").Return(user, nil)
+			var newHash string
+			if tt.wantRehash {
+				store.EXPECT().UpdatePasswordHash(gomock.Any(), uint(1), tt.hash, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ uint, _, hash string) error {
+						newHash = hash
+						return tt.updateErr
+					})
+			}
+			k, err := New(store, SetPasswordHasher(current))
+			require.NoError(t, err)
+
+			got, err := k.Login(context.TODO(), "user", "user")
+			require.NoError(t, err)
+			if !tt.wantRehash {
+				assert.Equal(t, tt.hash, got.PasswordHash)
+				return
 			}
-			if got := checkPasswordHash(tt.password, hash); got != tt.want {
-				t.Errorf("checkPasswordHash() = %v, want %v", got, tt.want)
+			assert.False(t, current.NeedsRehash(newHash))
+			ok, err := passhash.Verify("user", newHash)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			if tt.updateErr != nil {
+				assert.Equal(t, tt.hash, got.PasswordHash)
+			} else {
+				assert.Equal(t, newHash, got.PasswordHash)
 			}
 		})
 	}
@@ -0,0 +1,133 @@
+package uiapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
+	"github.com/playmixer/secret-keeper/pkg/tools"
+)
+
+// EventDeleteAccount безвозвратно удаляет учетную запись на сервере и локальную копию данных.
+func (k *keepClient) EventDeleteAccount(password string) error {
+	if k.token == "" {
+		return codeError(rest.CodeUnauthorized)
+	}
+
+	ctx := k.sessionContext()
+	current, err := k.currentSecret(ctx, password)
+	if err != nil {
+		return err
+	}
+
+	deleteAccount := k.deleteAccount
+	if k.rpc != nil {
+		deleteAccount = k.rpcDeleteAccount
+	}
+	if err := deleteAccount(ctx, current); err != nil {
+		return err
+	}
+
+	k.token = ""
+	k.login = ""
+	k.revision.Store(0)
+	if err := k.store.Drop(); err != nil {
+		k.log.Error("failed drop store", zap.Error(err))
+		return fmt.Errorf("учетная запись удалена, но локальные данные не удалены: %w", err)
+	}
+	return nil
+}
+
+func (k *keepClient) deleteAccount(ctx context.Context, current currentSecret) error {
+	bReq, err := json.Marshal(tDeleteAccountRequest{
+		Password:   current.Password,
+		SRPSession: current.Session,
+		SRPProof:   current.Proof,
+	})
+	if err != nil {
+		return fmt.Errorf("failed marshal request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	r, err := k.newRequest(ctx, http.MethodDelete, k.apiURL+"/api/v0/user", &bReq)
+	if err != nil {
+		k.log.Error("failed create request", zap.Error(err))
+		return fmt.Errorf("failed create request: %w", err)
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			k.log.Error(errMessageFailedCloseBody, zap.Error(err))
+		}
+	}()
+
+	res, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf(formatStringError, errMessageFailedReadBody, err)
+	}
+	if r.StatusCode != http.StatusOK {
+		return responseError(r.StatusCode, res)
+	}
+	return nil
+}
+
+// EventExport сохраняет зашифрованный архив всех данных пользователя в path.
+// Возвращает ключ к архиву, сервер его не хранит.
+func (k *keepClient) EventExport(path string) (string, error) {
+	if k.token == "" {
+		return "", codeError(rest.CodeUnauthorized)
+	}
+	if path == "" {
+		return "", errors.New("укажите файл для архива")
+	}
+
+	exportData := k.export
+	if k.rpc != nil {
+		exportData = k.rpcExport
+	}
+	archive, key, err := exportData(k.sessionContext())
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(path, archive, tools.Mode0600); err != nil {
+		return "", fmt.Errorf("failed write archive: %w", err)
+	}
+	return key, nil
+}
+
+func (k *keepClient) export(ctx context.Context) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	r, err := k.newRequest(ctx, http.MethodGet, k.apiURL+"/api/v0/user/export", nil)
+	if err != nil {
+		k.log.Error("failed create request", zap.Error(err))
+		return nil, "", fmt.Errorf("failed create request: %w", err)
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			k.log.Error(errMessageFailedCloseBody, zap.Error(err))
+		}
+	}()
+
+	res, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf(formatStringError, errMessageFailedReadBody, err)
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, "", responseError(r.StatusCode, res)
+	}
+
+	key := r.Header.Get(rest.HeaderExportKey)
+	if key == "" {
+		return nil, "", errors.New("сервер не вернул ключ архива")
+	}
+	return res, key, nil
+}
@@ -0,0 +1,103 @@
+package uiapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
+	"github.com/playmixer/secret-keeper/internal/adapter/storage/file"
+)
+
+func Test_keepClient_EventDeleteAccount(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantToken string
+		wantErr   string
+	}{
+		{
+			name:   "ok",
+			status: http.StatusOK,
+			body:   `{"status":true}`,
+		},
+		{
+			name:      "wrong password",
+			status:    http.StatusForbidden,
+			body:      `{"code":"password_incorrect","status":403}`,
+			wantToken: "token",
+			wantErr:   errorMessages[rest.CodePasswordIncorrect],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := file.Init(file.SetPath(dir))
+			assert.NoError(t, err)
+			assert.NoError(t, s.Open("user"))
+			k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false), SetAuthMode(AuthModePassword))
+			assert.NoError(t, err)
+			k.token = "token"
+			k.newRequest = func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+				w := httptest.NewRecorder()
+				if method != http.MethodDelete || !strings.HasSuffix(url, "/api/v0/user") ||
+					!strings.Contains(string(*data), `"password":"user"`) {
+					w.WriteHeader(http.StatusNotFound)
+					return w.Result(), nil
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.WriteString(tt.body)
+				return w.Result(), nil
+			}
+
+			err = k.EventDeleteAccount("user")
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				entries, err := os.ReadDir(dir)
+				assert.NoError(t, err)
+				assert.Empty(t, entries)
+			}
+			assert.Equal(t, tt.wantToken, k.token)
+		})
+	}
+}
+
+func Test_keepClient_EventExport(t *testing.T) {
+	s, err := file.Init()
+	assert.NoError(t, err)
+	k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
+	assert.NoError(t, err)
+	k.token = "token"
+	k.newRequest = func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		if method != http.MethodGet || !strings.HasSuffix(url, "/api/v0/user/export") {
+			w.WriteHeader(http.StatusNotFound)
+			return w.Result(), nil
+		}
+		w.Header().Set(rest.HeaderExportKey, "AAAA-BBBB")
+		_, _ = w.WriteString("archive")
+		return w.Result(), nil
+	}
+
+	path := filepath.Join(t.TempDir(), "export.skx")
+	key, err := k.EventExport(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "AAAA-BBBB", key)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "archive", string(data))
+
+	k.token = ""
+	_, err = k.EventExport(path)
+	assert.EqualError(t, err, errorMessages[rest.CodeUnauthorized])
+}
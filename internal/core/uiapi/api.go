@@ -1,19 +1,21 @@
 package uiapi
 
 import (
-	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 
 	"go.uber.org/zap"
 
 	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
 	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/pkg/totp"
 )
 
 var (
@@ -27,6 +29,51 @@ var (
 )
 
 func (k *keepClient) EventAuthorization(login, password string) error {
+	signIn := k.signIn
+	switch {
+	case k.useSRP():
+		signIn = k.srpSignIn
+	case k.rpc != nil:
+		signIn = k.rpcSignIn
+	}
+	token, err := signIn(k.ctx, login, password)
+	offline := false
+	if err != nil {
+		// без связи с сервером входим по сохраненной сессии, синхронизация начнется, когда сервер станет доступен.
+		if !k.offlineLogin || !Unreachable(err) {
+			return err
+		}
+		var offlineErr error
+		token, offlineErr = k.offlineSignIn(login, password)
+		if offlineErr != nil {
+			k.log.Debug("offline login failed", zap.Error(offlineErr))
+			if errors.Is(offlineErr, os.ErrNotExist) {
+				return err
+			}
+			return offlineErr
+		}
+		k.log.Info("offline login", zap.String("login", login))
+		offline = true
+	}
+
+	err = k.store.Open(login)
+	if err != nil {
+		k.log.Error("failed open store", zap.Error(err))
+		return fmt.Errorf("failed open store: %w", err)
+	}
+
+	k.token = token
+	k.login = login
+	k.sessionExpired.Store(false)
+	k.unlockedOffline.Store(offline)
+	if !offline {
+		k.saveSession(password)
+	}
+	k.startSession()
+	return nil
+}
+
+func (k *keepClient) signIn(ctx context.Context, login, password string) (string, error) {
 	req := tSignInRequest{
 		Login:    login,
 		Password: password,
@@ -35,19 +82,21 @@ func (k *keepClient) EventAuthorization(login, password string) error {
 	bReq, err := json.Marshal(req)
 	if err != nil {
 		k.log.Error("failed marshal request", zap.Error(err))
-		return fmt.Errorf("failed marshal request: %w", err)
+		return "", fmt.Errorf("failed marshal request: %w", err)
 	}
 
-	r, err := k.newRequest(http.MethodPost, k.apiURL+"/api/v0/auth/login", &bReq)
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	r, err := k.newRequest(ctx, http.MethodPost, k.apiURL+"/api/v0/auth/login", &bReq)
 	if err != nil {
 		k.log.Error("failed create request", zap.Error(err))
-		return fmt.Errorf("failed create request: %w", err)
+		return "", fmt.Errorf("failed create request: %w", err)
 	}
 
 	res, err := io.ReadAll(r.Body)
 	if err != nil {
 		k.log.Error(errMessageFailedReadBody, zap.Error(err))
-		return fmt.Errorf(formatStringError, errMessageFailedReadBody, err)
+		return "", fmt.Errorf(formatStringError, errMessageFailedReadBody, err)
 	}
 	defer func() {
 		err := r.Body.Close()
@@ -56,33 +105,37 @@ func (k *keepClient) EventAuthorization(login, password string) error {
 		}
 	}()
 
-	if r.StatusCode == http.StatusUnauthorized {
-		return errors.New("неверные данные авторизации")
+	if r.StatusCode != http.StatusOK {
+		return "", responseError(r.StatusCode, res)
 	}
 
 	result := tSignInResponse{}
 	err = json.Unmarshal(res, &result)
 	if err != nil {
 		k.log.Error(errMessageFailedUnmarshal, zap.Error(err))
-		return fmt.Errorf(formatStringError, errMessageFailedUnmarshal, err)
-	}
-
-	err = k.store.Open(login)
-	if err != nil {
-		k.log.Error("failed open store", zap.Error(err))
-		return fmt.Errorf("failed open store: %w", err)
+		return "", fmt.Errorf(formatStringError, errMessageFailedUnmarshal, err)
 	}
 
-	k.token = result.AccessToken
-	return nil
+	return result.AccessToken, nil
 }
 
+// EventLogout выход из учетной записи, сохраненная сессия удаляется: войти без сервера будет нельзя.
 func (k *keepClient) EventLogout() error {
 	k.log.Debug("event logout", zap.String("token", k.token))
 	if k.token == "" {
 		return nil
 	}
+	k.forgetSession()
+	return k.EventClose()
+}
+
+// EventClose закрывает хранилище при выходе из клиента, сохраненная сессия остается для входа без сервера.
+func (k *keepClient) EventClose() error {
+	if k.token == "" {
+		return nil
+	}
 
+	k.endSession()
 	err := k.store.Close()
 	if err != nil {
 		k.log.Error("failed close store", zap.Error(err))
@@ -90,27 +143,102 @@ func (k *keepClient) EventLogout() error {
 	}
 	k.log.Debug("store closed")
 	k.token = ""
+	k.login = ""
+	k.revision.Store(0)
 	return nil
 }
 
-func (k *keepClient) EventRegistration(login, password, password2 string) error {
+// EventLock блокирует клиент: локальное хранилище закрывается, токен забывается до EventUnlock.
+// Изменения в очереди остаются на диске и уйдут на сервер после разблокировки.
+// Блокировку видят другие процессы клиента: помощник git и агент ssh не отдают секреты, пока она не снята.
+func (k *keepClient) EventLock() error {
+	if k.token == "" {
+		return nil
+	}
+	login := k.login
+	if err := k.EventClose(); err != nil {
+		return err
+	}
+	k.lockedLogin = login
+	k.unlockedOffline.Store(false)
+	if err := k.store.SetLocked(login, true); err != nil {
+		k.log.Error("failed mark lock", zap.Error(err))
+	}
+	return nil
+}
+
+// EventUnlock разблокирует клиент паролем пользователя, который его заблокировал.
+func (k *keepClient) EventUnlock(password string) error {
+	if k.lockedLogin == "" {
+		return codeError(rest.CodeUnauthorized)
+	}
+	return k.EventUnlockUser(k.lockedLogin, password)
+}
+
+// EventUnlockUser входит и снимает блокировку пользователя login, например оставшуюся после выхода
+// из заблокированного клиента. Отметку блокировки для других процессов клиента снимает только разблокировка.
+func (k *keepClient) EventUnlockUser(login, password string) error {
+	if err := k.EventAuthorization(login, password); err != nil {
+		return err
+	}
+	k.lockedLogin = ""
+	if err := k.store.SetLocked(login, false); err != nil {
+		k.log.Error("failed clear lock", zap.Error(err))
+	}
+	return nil
+}
+
+// Locked клиент пользователя login заблокирован, в этом или другом процессе. Блокировка снимается разблокировкой.
+func (k *keepClient) Locked(login string) bool {
+	return k.store.Locked(login)
+}
+
+// LockedLogin логин пользователя, который заблокировал клиент, пустой - клиент не заблокирован.
+func (k *keepClient) LockedLogin() string {
+	return k.lockedLogin
+}
+
+// EventRegistration регистрация, с withRecovery возвращается ключ восстановления доступа.
+// invite код приглашения, нужен, если сервер регистрирует только по приглашениям.
+func (k *keepClient) EventRegistration(login, password, password2 string, withRecovery bool, invite string) (
+	string, error,
+) {
 	if password != password2 {
-		return errors.New("повторный пароль не совпадает")
+		return "", errors.New("повторный пароль не совпадает")
+	}
+	secret, err := k.newSecret(k.ctx, login, password)
+	if err != nil {
+		return "", err
+	}
+	if k.rpc != nil {
+		return k.rpcRegistration(k.ctx, login, secret, withRecovery, invite)
 	}
+	return k.registration(k.ctx, login, secret, withRecovery, invite)
+}
+
+func (k *keepClient) registration(ctx context.Context, login string, secret newSecret, withRecovery bool,
+	invite string,
+) (string, error) {
 	req := tRegistrationRequest{
 		Login:    login,
-		Password: password,
+		Password: secret.Password,
+		Salt:     secret.Salt,
+		Verifier: secret.Verifier,
+		Recovery: withRecovery,
+		Invite:   invite,
 	}
 	bReq, err := json.Marshal(req)
 	if err != nil {
 		k.log.Error("failed marshal request", zap.Error(err))
-		return fmt.Errorf("failed marshal request: %w", err)
+		return "", fmt.Errorf("failed marshal request: %w", err)
 	}
 
-	r, err := k.newRequest(http.MethodPost, k.apiURL+"/api/v0/auth/registration", &bReq)
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	r, err := k.newRequest(ctx, http.MethodPost, k.apiURL+"/api/v0/auth/registration", &bReq)
 	if err != nil {
 		k.log.Error("failed create request", zap.Error(err))
-		return fmt.Errorf("failed create request: %w", err)
+		return "", fmt.Errorf("failed create request: %w", err)
 	}
 	defer func() {
 		err := r.Body.Close()
@@ -122,30 +250,101 @@ func (k *keepClient) EventRegistration(login, password, password2 string) error
 	res, err := io.ReadAll(r.Body)
 	if err != nil {
 		k.log.Error(errMessageFailedReadBody, zap.Error(err))
-		return fmt.Errorf(formatStringError, errMessageFailedReadBody, err)
+		return "", fmt.Errorf(formatStringError, errMessageFailedReadBody, err)
+	}
+
+	if r.StatusCode != http.StatusCreated {
+		return "", responseError(r.StatusCode, res)
 	}
 
 	result := tRegistrationResponse{}
-	err = json.Unmarshal(res, &result)
+	if err := json.Unmarshal(res, &result); err != nil {
+		return "", fmt.Errorf(formatStringError, errMessageFailedUnmarshal, err)
+	}
+	return result.RecoveryKey, nil
+}
+
+// EventChangePassword смена пароля учетной записи.
+// Сервер отзывает остальные сессии и выдает новый токен, клиент продолжает работу с ним.
+func (k *keepClient) EventChangePassword(oldPassword, newPassword, newPassword2 string) error {
+	if newPassword != newPassword2 {
+		return errors.New("повторный пароль не совпадает")
+	}
+	if k.token == "" {
+		return codeError(rest.CodeUnauthorized)
+	}
+
+	ctx := k.sessionContext()
+	next, err := k.newSecret(ctx, k.login, newPassword)
 	if err != nil {
-		k.log.Error(errMessageFailedUnmarshal, zap.Error(err))
-		return fmt.Errorf(formatStringError, errMessageFailedUnmarshal, err)
+		return err
+	}
+	current, err := k.currentSecret(ctx, oldPassword)
+	if err != nil {
+		return err
 	}
 
-	if r.StatusCode != http.StatusCreated {
-		if r.StatusCode == http.StatusBadRequest {
-			return errors.New("Ошибка запроса: " + result.Error)
-		}
-		if r.StatusCode == http.StatusConflict {
-			return errors.New("логин уже занят")
-		}
-		return errors.New("Ошибка: " + result.Error)
+	changePassword := k.changePassword
+	if k.rpc != nil {
+		changePassword = k.rpcChangePassword
+	}
+	token, err := changePassword(ctx, current, next)
+	if err != nil {
+		return err
 	}
 
+	k.token = token
+	k.saveSession(newPassword)
 	return nil
 }
 
-func (k *keepClient) EventNewCard(eID uint, title, number, cvv, pin, date string) error {
+func (k *keepClient) changePassword(ctx context.Context, current currentSecret, next newSecret) (string, error) {
+	bReq, err := json.Marshal(tChangePasswordRequest{
+		OldPassword: current.Password,
+		SRPSession:  current.Session,
+		SRPProof:    current.Proof,
+		NewPassword: next.Password,
+		Salt:        next.Salt,
+		Verifier:    next.Verifier,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed marshal request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	r, err := k.newRequest(ctx, http.MethodPost, k.apiURL+"/api/v0/user/password", &bReq)
+	if err != nil {
+		k.log.Error("failed create request", zap.Error(err))
+		return "", fmt.Errorf("failed create request: %w", err)
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			k.log.Error(errMessageFailedCloseBody, zap.Error(err))
+		}
+	}()
+
+	res, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", fmt.Errorf(formatStringError, errMessageFailedReadBody, err)
+	}
+	if r.StatusCode != http.StatusOK {
+		return "", responseError(r.StatusCode, res)
+	}
+
+	result := tSignInResponse{}
+	if err := json.Unmarshal(res, &result); err != nil {
+		return "", fmt.Errorf(formatStringError, errMessageFailedUnmarshal, err)
+	}
+	if result.AccessToken == "" {
+		return "", errors.New("сервер не вернул новый токен")
+	}
+	return result.AccessToken, nil
+}
+
+func (k *keepClient) EventNewCard(eID uint, title, number, cvv, pin, date string) (
+	*models.FileMetaDataItem, error,
+) {
 	card := &models.Card{
 		Title:  title,
 		Number: number,
@@ -156,15 +355,19 @@ func (k *keepClient) EventNewCard(eID uint, title, number, cvv, pin, date string
 
 	bDate, err := json.Marshal(card)
 	if err != nil {
-		return fmt.Errorf("failed marshal card: %w", err)
+		return nil, fmt.Errorf("failed marshal card: %w", err)
 	}
-	_, err = k.store.NewData(eID, 0, title, models.CARD, &bDate)
+	m, err := k.store.NewData(eID, 0, title, models.CARD, &bDate)
 	if err != nil {
 		k.log.Error(errMessageFailedCreateCard, zap.Error(err))
-		return fmt.Errorf(formatStringError, errMessageFailedCreateCard, err)
+		return nil, fmt.Errorf(formatStringError, errMessageFailedCreateCard, err)
 	}
 
-	return nil
+	if err := k.queueChange(models.BatchCreate, m.ID); err != nil {
+		return nil, err
+	}
+
+	return m, nil
 }
 
 func (k *keepClient) EventEditCard(id int64, title, number, cvv, pin, date string) error {
@@ -193,11 +396,16 @@ func (k *keepClient) EventEditCard(id int64, title, number, cvv, pin, date strin
 		return fmt.Errorf("failed edit card %w", err)
 	}
 
-	return nil
+	return k.queueChange(models.BatchUpdate, id)
 }
 
 func (k *keepClient) EventDeleteCard(id int64) error {
-	return k.eventDeleteData(id)
+	return k.eventDeleteLocal(id)
+}
+
+// EventDelete удаляет запись любого типа.
+func (k *keepClient) EventDelete(id int64) error {
+	return k.eventDeleteLocal(id)
 }
 
 func (k *keepClient) EventGetMetaDatas() (*[]models.FileMetaDataItem, error) {
@@ -265,11 +473,19 @@ func (k *keepClient) EventEditText(id int64, title, text string) error {
 		return fmt.Errorf("failed edit text: %w", err)
 	}
 
-	return nil
+	return k.queueChange(models.BatchUpdate, id)
 }
 
 func (k *keepClient) EventDeleteText(id int64) error {
-	return k.eventDeleteData(id)
+	return k.eventDeleteLocal(id)
+}
+
+// eventDeleteLocal удаление записи пользователем, удаление уходит на сервер через очередь.
+func (k *keepClient) eventDeleteLocal(id int64) error {
+	if err := k.eventDeleteData(id); err != nil {
+		return err
+	}
+	return k.queueChange(models.BatchDelete, id)
 }
 
 func (k *keepClient) eventDeleteData(id int64) error {
@@ -282,37 +498,44 @@ func (k *keepClient) eventDeleteData(id int64) error {
 	return nil
 }
 
-func newRequest(k *keepClient) keepRequest {
-	return func(method, url string, data *[]byte) (*http.Response, error) {
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-		client := &http.Client{
-			Transport: tr,
-		}
-
-		if data == nil {
-			data = &[]byte{}
-		}
+// eventGetExternalMetaDatas все мета данные пользователя, список забирается постранично.
+func (k *keepClient) eventGetExternalMetaDatas(ctx context.Context) (*[]models.MetaDataItem, error) {
+	if k.rpc != nil {
+		return k.rpcGetExternalMetaDatas(ctx)
+	}
 
-		body := bytes.NewBuffer(*data)
-		var req *http.Request
-		req, err := http.NewRequest(method, url, body)
+	result := []models.MetaDataItem{}
+	cursor := ""
+	for {
+		data, err := k.getExternalMetaDatasPage(ctx, cursor)
 		if err != nil {
-			return nil, fmt.Errorf("failed create http client: %w", err)
+			return nil, err
 		}
-		req.Header.Set("Authorization", "Bearer "+k.token)
-		res, err := client.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf(formatStringError, errMessageFailedRequest, err)
+		for _, d := range data.Data {
+			result = append(result, models.MetaDataItem{
+				ID:        d.ID,
+				Title:     d.Title,
+				DataType:  d.DataType,
+				UpdatedDT: d.UpdatedAt,
+				IsDeleted: d.IsDeleted,
+			})
 		}
-
-		return res, nil
+		if data.NextCursor == "" {
+			return &result, nil
+		}
+		cursor = data.NextCursor
 	}
 }
 
-func (k *keepClient) eventGetExternalMetaDatas() (*[]models.MetaDataItem, error) {
-	r, err := k.newRequest(http.MethodGet, k.apiURL+"/api/v0/user/data", nil)
+func (k *keepClient) getExternalMetaDatasPage(ctx context.Context, cursor string) (*tHalderGetDatasResponse, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(listPageSize))
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	r, err := k.newRequest(ctx, http.MethodGet, k.apiURL+"/api/v0/user/data?"+query.Encode(), nil)
 	if err != nil {
 		return nil, fmt.Errorf(formatStringError, errMessageFailedRequest, err)
 	}
@@ -329,8 +552,8 @@ func (k *keepClient) eventGetExternalMetaDatas() (*[]models.MetaDataItem, error)
 		}
 	}()
 
-	if r.StatusCode == http.StatusUnauthorized {
-		return nil, errors.New("failed authenticate user")
+	if r.StatusCode != http.StatusOK {
+		return nil, responseError(r.StatusCode, res)
 	}
 
 	data := tHalderGetDatasResponse{}
@@ -340,22 +563,17 @@ func (k *keepClient) eventGetExternalMetaDatas() (*[]models.MetaDataItem, error)
 		return nil, fmt.Errorf(formatStringError, errMessageFailedUnmarshal, err)
 	}
 
-	result := []models.MetaDataItem{}
-	for _, d := range data.Data {
-		result = append(result, models.MetaDataItem{
-			ID:        d.ID,
-			Title:     d.Title,
-			DataType:  d.DataType,
-			UpdatedDT: d.UpdatedAt,
-			IsDeleted: d.IsDeleted,
-		})
-	}
-
-	return &result, nil
+	return &data, nil
 }
 
-func (k *keepClient) eventGetExternalData(id uint) (*models.MetaDataItem, error) {
-	r, err := k.newRequest(http.MethodGet, fmt.Sprintf("%s/api/v0/user/data/%v", k.apiURL, id), nil)
+func (k *keepClient) eventGetExternalData(ctx context.Context, id uint) (*models.MetaDataItem, error) {
+	if k.rpc != nil {
+		return k.rpcGetExternalData(ctx, id)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	r, err := k.newRequest(ctx, http.MethodGet, fmt.Sprintf("%s/api/v0/user/data/%v", k.apiURL, id), nil)
 	if err != nil {
 		return nil, fmt.Errorf(formatStringError, errMessageFailedRequest, err)
 	}
@@ -372,8 +590,8 @@ func (k *keepClient) eventGetExternalData(id uint) (*models.MetaDataItem, error)
 		}
 	}()
 
-	if r.StatusCode == http.StatusUnauthorized {
-		return nil, errors.New("failed authenticate user")
+	if r.StatusCode != http.StatusOK {
+		return nil, responseError(r.StatusCode, res)
 	}
 
 	data := rest.THandlerGetDataResponse{}
@@ -395,64 +613,21 @@ func (k *keepClient) eventGetExternalData(id uint) (*models.MetaDataItem, error)
 	return result, nil
 }
 
-func (k *keepClient) eventUpdExternalData(id uint,
-	title string, data *[]byte, dataType models.DataType, updateDT int64) error {
-	req := rest.THandlerUpdDataRequest{
-		Title:    title,
-		DataType: dataType,
-		Data:     *data,
-		UpdateDT: updateDT,
+func (k *keepClient) eventBatchExternalData(
+	ctx context.Context, ops *[]rest.TBatchOperation,
+) (*[]rest.TBatchResult, error) {
+	if k.rpc != nil {
+		return k.rpcBatchExternalData(ctx, ops)
 	}
-	bBody, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("failed marshal data: %w", err)
-	}
-	url := fmt.Sprintf("%s/api/v0/user/data/%v", k.apiURL, id)
-	r, err := k.newRequest(http.MethodPut, url, &bBody)
-	if err != nil {
-		return fmt.Errorf(formatStringError, errMessageFailedRequest, err)
-	}
-
-	if r.StatusCode != http.StatusOK {
-		k.log.Error("api", zap.String("url", url), zap.String("error", "api return not OK"))
-		return errors.New("api return not OK")
-	}
-
-	res, err := io.ReadAll(r.Body)
-	if err != nil {
-		k.log.Error(errMessageFailedReadBody, zap.Error(err))
-		return fmt.Errorf(formatStringError, errMessageFailedReadBody, err)
-	}
-	defer func() {
-		err := r.Body.Close()
-		if err != nil {
-			k.log.Error(errMessageFailedCloseBody, zap.Error(err))
-		}
-	}()
 
-	response := rest.THandlerUpdDataResponse{}
-	err = json.Unmarshal(res, &response)
-	if err != nil {
-		return fmt.Errorf(formatStringError, errMessageFailedUnmarshal, err)
-	}
-
-	return nil
-}
-
-func (k *keepClient) eventAddExternalData(title string, data *[]byte, dataType models.DataType, updateDT int64) (
-	*models.MetaDataItem, error,
-) {
-	req := rest.THandlerNewDataRequest{
-		Title:    title,
-		DataType: dataType,
-		Data:     *data,
-		UpdateDT: updateDT,
-	}
-	bBody, err := json.Marshal(req)
+	bBody, err := json.Marshal(rest.THandlerBatchRequest{Operations: *ops})
 	if err != nil {
 		return nil, fmt.Errorf("failed marshal data: %w", err)
 	}
-	r, err := k.newRequest(http.MethodPost, k.apiURL+"/api/v0/user/data", &bBody)
+	url := k.apiURL + "/api/v0/user/batch"
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	r, err := k.newRequest(ctx, http.MethodPost, url, &bBody)
 	if err != nil {
 		return nil, fmt.Errorf(formatStringError, errMessageFailedRequest, err)
 	}
@@ -469,47 +644,19 @@ func (k *keepClient) eventAddExternalData(title string, data *[]byte, dataType m
 		}
 	}()
 
-	response := rest.THandlerNewDataResponse{}
-	err = json.Unmarshal(res, &response)
-	if err != nil {
-		return nil, fmt.Errorf(formatStringError, errMessageFailedUnmarshal, err)
-	}
-
-	return &models.MetaDataItem{
-		ID:        response.Data.ID,
-		Title:     response.Data.Title,
-		Data:      data,
-		DataType:  response.Data.DataType,
-		UpdatedDT: response.Data.UpdateDT,
-	}, nil
-}
-
-func (k *keepClient) eventDeleteExternalData(id uint) error {
-	k.log.Debug("delete external data", zap.Uint("id", id))
-	r, err := k.newRequest(http.MethodDelete, fmt.Sprintf("%s/api/v0/user/data/%v", k.apiURL, id), nil)
-	if err != nil {
-		return fmt.Errorf(formatStringError, errMessageFailedRequest, err)
-	}
-
-	res, err := io.ReadAll(r.Body)
-	if err != nil {
-		k.log.Error(errMessageFailedReadBody, zap.Error(err))
-		return fmt.Errorf(formatStringError, errMessageFailedReadBody, err)
+	if r.StatusCode != http.StatusOK {
+		k.log.Error("api", zap.String("url", url), zap.Int("status", r.StatusCode), zap.ByteString("body", res))
+		return nil, responseError(r.StatusCode, res)
 	}
-	defer func() {
-		err := r.Body.Close()
-		if err != nil {
-			k.log.Error(errMessageFailedCloseBody, zap.Error(err))
-		}
-	}()
 
-	response := rest.THandlerNewDataResponse{}
+	response := rest.THandlerBatchResponse{}
 	err = json.Unmarshal(res, &response)
 	if err != nil {
-		return fmt.Errorf(formatStringError, errMessageFailedUnmarshal, err)
+		k.log.Error(errMessageFailedUnmarshal, zap.Error(err))
+		return nil, fmt.Errorf(formatStringError, errMessageFailedUnmarshal, err)
 	}
 
-	return nil
+	return &response.Data, nil
 }
 
 func (k *keepClient) EventNewText(eID uint, title, text string) (*models.FileMetaDataItem, error) {
@@ -529,6 +676,10 @@ func (k *keepClient) EventNewText(eID uint, title, text string) (*models.FileMet
 		return nil, fmt.Errorf(formatStringError, errMessageFailedCreateCard, err)
 	}
 
+	if err := k.queueChange(models.BatchCreate, m.ID); err != nil {
+		return nil, err
+	}
+
 	return m, nil
 }
 
@@ -548,14 +699,31 @@ func (k *keepClient) EventGetPassword(id int64) (*models.Password, error) {
 	return psw, nil
 }
 
-func (k *keepClient) EventNewPassword(eID uint, title, site, login, password string) (*models.FileMetaDataItem, error) {
-	psw := &models.Password{
+// EventNewPassword новый пароль, otp ключ одноразовых кодов сайта, пустой - второго фактора нет.
+func (k *keepClient) EventNewPassword(eID uint, title, site, login, password, otp string) (
+	*models.FileMetaDataItem, error,
+) {
+	if err := checkOTP(otp); err != nil {
+		return nil, err
+	}
+	return k.newPassword(eID, &models.Password{Title: title, Site: site, Login: login, Password: password, OTP: otp})
+}
+
+// EventNewPasswordFrom новый пароль, созданный программой source, а не пользователем.
+func (k *keepClient) EventNewPasswordFrom(eID uint, source, title, site, login, password string) (
+	*models.FileMetaDataItem, error,
+) {
+	return k.newPassword(eID, &models.Password{
 		Title:    title,
 		Site:     site,
 		Password: password,
 		Login:    login,
-	}
+		Source:   source,
+	})
+}
 
+func (k *keepClient) newPassword(eID uint, psw *models.Password) (*models.FileMetaDataItem, error) {
+	title := psw.Title
 	bPsw, err := json.Marshal(psw)
 	if err != nil {
 		return nil, fmt.Errorf("failed marshal text: %w", err)
@@ -567,25 +735,40 @@ func (k *keepClient) EventNewPassword(eID uint, title, site, login, password str
 		return nil, fmt.Errorf(formatStringError, errMessageFailedCreateCard, err)
 	}
 
+	if err := k.queueChange(models.BatchCreate, m.ID); err != nil {
+		return nil, err
+	}
+
 	return m, nil
 }
 
-func (k *keepClient) EventEditPassword(id int64, title, site, login, password string) error {
+// EventEditPassword изменяет пароль, создатель записи сохраняется.
+func (k *keepClient) EventEditPassword(id int64, title, site, login, password, otp string) error {
+	if err := checkOTP(otp); err != nil {
+		return err
+	}
+	m, data, err := k.store.GetData(id)
+	if err != nil {
+		return fmt.Errorf("failed get data from store id=`%v`: %w", id, err)
+	}
+	old := &models.Password{}
+	if err := json.Unmarshal(*data, old); err != nil {
+		return fmt.Errorf("failed unmarshal data: %w", err)
+	}
+
 	txt := &models.Password{
 		Title:    title,
 		Site:     site,
 		Password: password,
 		Login:    login,
+		OTP:      otp,
+		Source:   old.Source,
 	}
 	bData, err := json.Marshal(txt)
 	if err != nil {
 		return fmt.Errorf("failed marshal password to byte: %w", err)
 	}
 
-	m, _, err := k.store.GetData(id)
-	if err != nil {
-		return fmt.Errorf("failed get data from store id=`%v`: %w", id, err)
-	}
 	m.Title = title
 	m.UpdateDT = k.store.UpdateDate()
 
@@ -595,11 +778,22 @@ func (k *keepClient) EventEditPassword(id int64, title, site, login, password st
 		return fmt.Errorf("failed edit password: %w", err)
 	}
 
+	return k.queueChange(models.BatchUpdate, id)
+}
+
+// checkOTP ключ одноразовых кодов пустой или распознается.
+func checkOTP(otp string) error {
+	if otp == "" {
+		return nil
+	}
+	if _, err := totp.Parse(otp); err != nil {
+		return fmt.Errorf("failed parse otp key: %w", err)
+	}
 	return nil
 }
 
 func (k *keepClient) EventDeletePassword(id int64) error {
-	return k.eventDeleteData(id)
+	return k.eventDeleteLocal(id)
 }
 
 func (k *keepClient) EventNewFile(eID uint, title, path string) (*models.FileMetaDataItem, error) {
@@ -632,6 +826,9 @@ func (k *keepClient) EventNewFile(eID uint, title, path string) (*models.FileMet
 	if err != nil {
 		return nil, fmt.Errorf("failed update meta data: %w", err)
 	}
+	if err := k.queueChange(models.BatchCreate, m.ID); err != nil {
+		return nil, err
+	}
 
 	return m, nil
 }
@@ -673,6 +870,7 @@ func (k *keepClient) EventEditFile(id int64, title, path string) error {
 		return fmt.Errorf("failed read file: %w", err)
 	}
 
+	m.Title = title
 	err = k.store.EditData(id, m, &data)
 	if err != nil {
 		k.log.Error("failed edit password", zap.Error(err))
@@ -686,7 +884,7 @@ func (k *keepClient) EventEditFile(id int64, title, path string) error {
 		return fmt.Errorf("failed update meta data: %w", err)
 	}
 
-	return nil
+	return k.queueChange(models.BatchUpdate, id)
 }
 
 func (k *keepClient) EventUploadFile(id int64, path string) error {
@@ -697,7 +895,7 @@ func (k *keepClient) EventUploadFile(id int64, path string) error {
 }
 
 func (k *keepClient) EventDeleteFile(id int64) error {
-	if err := k.eventDeleteData(id); err != nil {
+	if err := k.eventDeleteLocal(id); err != nil {
 		return fmt.Errorf("failed delete file: %w", err)
 	}
 	return nil
@@ -9,11 +9,13 @@ import (
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
+	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
 	"github.com/playmixer/secret-keeper/internal/adapter/models"
 	"github.com/playmixer/secret-keeper/internal/adapter/storage/file"
 )
@@ -22,12 +24,12 @@ func Test_keepClient_eventGetExternalMetaDatas(t *testing.T) {
 	tests := []struct {
 		name     string
 		want     *[]models.MetaDataItem
-		fRequest func(method, url string, data *[]byte) (*http.Response, error)
+		fRequest func(_ context.Context, method, url string, data *[]byte) (*http.Response, error)
 		wantErr  bool
 	}{
 		{
 			name: "ok",
-			fRequest: func(method, url string, data *[]byte) (*http.Response, error) {
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				w := httptest.NewRecorder()
 				res := tHalderGetDatasResponse{
 					tResultResponse: tResultResponse{Status: true},
@@ -46,9 +48,38 @@ func Test_keepClient_eventGetExternalMetaDatas(t *testing.T) {
 			want:    &[]models.MetaDataItem{},
 			wantErr: false,
 		},
+		{
+			name: "pages",
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+				w := httptest.NewRecorder()
+				res := tHalderGetDatasResponse{
+					tResultResponse: tResultResponse{Status: true},
+					Data:            []tHandlerGetData{{ID: 1, Title: "first"}},
+					NextCursor:      "next",
+				}
+				if strings.Contains(url, "cursor=next") {
+					res.Data = []tHandlerGetData{{ID: 2, Title: "second", IsDeleted: true}}
+					res.NextCursor = ""
+				}
+				bRes, err := json.Marshal(res)
+				if err != nil {
+					return nil, fmt.Errorf("any error: %w", err)
+				}
+				_, err = w.Write(bRes)
+				if err != nil {
+					return nil, fmt.Errorf("any error: %w", err)
+				}
+				return w.Result(), nil
+			},
+			want: &[]models.MetaDataItem{
+				{ID: 1, Title: "first"},
+				{ID: 2, Title: "second", IsDeleted: true},
+			},
+			wantErr: false,
+		},
 		{
 			name: "empty response",
-			fRequest: func(method, url string, data *[]byte) (*http.Response, error) {
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				w := httptest.NewRecorder()
 				_, err := w.Write([]byte{})
 				if err != nil {
@@ -61,7 +92,7 @@ func Test_keepClient_eventGetExternalMetaDatas(t *testing.T) {
 		},
 		{
 			name: "not auth",
-			fRequest: func(method, url string, data *[]byte) (*http.Response, error) {
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				w := httptest.NewRecorder()
 				_, err := w.Write([]byte{})
 				if err != nil {
@@ -75,7 +106,7 @@ func Test_keepClient_eventGetExternalMetaDatas(t *testing.T) {
 		},
 		{
 			name: "request error",
-			fRequest: func(method, url string, data *[]byte) (*http.Response, error) {
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				w := httptest.NewRecorder()
 				_, err := w.Write([]byte{})
 				if err != nil {
@@ -95,7 +126,7 @@ func Test_keepClient_eventGetExternalMetaDatas(t *testing.T) {
 			k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
 			assert.NoError(t, err)
 			k.newRequest = tt.fRequest
-			got, err := k.eventGetExternalMetaDatas()
+			got, err := k.eventGetExternalMetaDatas(context.TODO())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("keepClient.eventGetExternalMetaDatas() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -111,12 +142,12 @@ func Test_keepClient_EventAuthorization(t *testing.T) {
 	tests := []struct {
 		name     string
 		want     *tSignInResponse
-		fRequest func(method, url string, data *[]byte) (*http.Response, error)
+		fRequest func(_ context.Context, method, url string, data *[]byte) (*http.Response, error)
 		wantErr  bool
 	}{
 		{
 			name: "ok",
-			fRequest: func(method, url string, data *[]byte) (*http.Response, error) {
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				w := httptest.NewRecorder()
 				res := tSignInResponse{
 					tResultResponse: tResultResponse{Status: true},
@@ -136,7 +167,7 @@ func Test_keepClient_EventAuthorization(t *testing.T) {
 		},
 		{
 			name: "empty response",
-			fRequest: func(method, url string, data *[]byte) (*http.Response, error) {
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				w := httptest.NewRecorder()
 				_, err := w.Write([]byte{})
 				if err != nil {
@@ -149,7 +180,7 @@ func Test_keepClient_EventAuthorization(t *testing.T) {
 		},
 		{
 			name: "not auth",
-			fRequest: func(method, url string, data *[]byte) (*http.Response, error) {
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				w := httptest.NewRecorder()
 				_, err := w.Write([]byte{})
 				if err != nil {
@@ -163,7 +194,7 @@ func Test_keepClient_EventAuthorization(t *testing.T) {
 		},
 		{
 			name: "request error",
-			fRequest: func(method, url string, data *[]byte) (*http.Response, error) {
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				return nil, errors.New("any")
 			},
 			want:    nil,
@@ -174,7 +205,7 @@ func Test_keepClient_EventAuthorization(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			s, err := file.Init()
 			assert.NoError(t, err)
-			k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
+			k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false), SetAuthMode(AuthModePassword))
 			assert.NoError(t, err)
 			k.newRequest = tt.fRequest
 			err = k.EventAuthorization("user", "password")
@@ -190,12 +221,12 @@ func Test_keepClient_eventGetExternalData(t *testing.T) {
 	tests := []struct {
 		name     string
 		want     *models.MetaDataItem
-		fRequest func(method, url string, data *[]byte) (*http.Response, error)
+		fRequest func(_ context.Context, method, url string, data *[]byte) (*http.Response, error)
 		wantErr  bool
 	}{
 		{
 			name: "ok",
-			fRequest: func(method, url string, data *[]byte) (*http.Response, error) {
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				w := httptest.NewRecorder()
 				res := tSignInResponse{
 					tResultResponse: tResultResponse{Status: true},
@@ -215,7 +246,7 @@ func Test_keepClient_eventGetExternalData(t *testing.T) {
 		},
 		{
 			name: "empty response",
-			fRequest: func(method, url string, data *[]byte) (*http.Response, error) {
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				w := httptest.NewRecorder()
 				_, err := w.Write([]byte{})
 				if err != nil {
@@ -228,7 +259,7 @@ func Test_keepClient_eventGetExternalData(t *testing.T) {
 		},
 		{
 			name: "not auth",
-			fRequest: func(method, url string, data *[]byte) (*http.Response, error) {
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				w := httptest.NewRecorder()
 				_, err := w.Write([]byte{})
 				if err != nil {
@@ -242,7 +273,7 @@ func Test_keepClient_eventGetExternalData(t *testing.T) {
 		},
 		{
 			name: "request error",
-			fRequest: func(method, url string, data *[]byte) (*http.Response, error) {
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				return nil, errors.New("any")
 			},
 			want:    nil,
@@ -256,7 +287,7 @@ func Test_keepClient_eventGetExternalData(t *testing.T) {
 			k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
 			assert.NoError(t, err)
 			k.newRequest = tt.fRequest
-			got, err := k.eventGetExternalData(1)
+			got, err := k.eventGetExternalData(context.TODO(), 1)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("keepClient.eventGetExternalData() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -272,12 +303,12 @@ func Test_keepClient_EventLogout(t *testing.T) {
 	tests := []struct {
 		name    string
 		wantErr bool
-		req     func(method, url string, data *[]byte) (*http.Response, error)
+		req     func(_ context.Context, method, url string, data *[]byte) (*http.Response, error)
 	}{
 		{
 			name:    "ok",
 			wantErr: false,
-			req: func(method, url string, data *[]byte) (*http.Response, error) {
+			req: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				w := httptest.NewRecorder()
 				res := tSignInResponse{
 					tResultResponse: tResultResponse{Status: true},
@@ -297,7 +328,7 @@ func Test_keepClient_EventLogout(t *testing.T) {
 		{
 			name:    "not auth",
 			wantErr: false,
-			req: func(method, url string, data *[]byte) (*http.Response, error) {
+			req: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				w := httptest.NewRecorder()
 				res := tSignInResponse{
 					tResultResponse: tResultResponse{Status: false},
@@ -318,7 +349,7 @@ func Test_keepClient_EventLogout(t *testing.T) {
 		{
 			name:    "error",
 			wantErr: false,
-			req: func(method, url string, data *[]byte) (*http.Response, error) {
+			req: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
 				w := httptest.NewRecorder()
 				res := tSignInResponse{
 					tResultResponse: tResultResponse{Status: true},
@@ -341,7 +372,7 @@ func Test_keepClient_EventLogout(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			s, err := file.Init()
 			assert.NoError(t, err)
-			k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
+			k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false), SetAuthMode(AuthModePassword))
 			assert.NoError(t, err)
 			k.newRequest = tt.req
 			err = k.EventAuthorization("user", "password")
@@ -353,3 +384,283 @@ func Test_keepClient_EventLogout(t *testing.T) {
 		})
 	}
 }
+
+func Test_keepClient_flushQueue(t *testing.T) {
+	tests := []struct {
+		name           string
+		fRequest       func(_ context.Context, method, url string, data *[]byte) (*http.Response, error)
+		wantExternalID uint
+		wantQueue      int
+	}{
+		{
+			name: "ok",
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+				req := rest.THandlerBatchRequest{}
+				if err := json.Unmarshal(*data, &req); err != nil {
+					return nil, fmt.Errorf("any error: %w", err)
+				}
+				res := rest.THandlerBatchResponse{}
+				for i, op := range req.Operations {
+					if op.Key == "" {
+						return nil, errors.New("operation without idempotency key")
+					}
+					res.Data = append(res.Data, rest.TBatchResult{
+						Action: op.Action,
+						ID:     uint(100 + i),
+						Status: true,
+					})
+				}
+				bRes, err := json.Marshal(res)
+				if err != nil {
+					return nil, fmt.Errorf("any error: %w", err)
+				}
+				w := httptest.NewRecorder()
+				_, err = w.Write(bRes)
+				if err != nil {
+					return nil, fmt.Errorf("any error: %w", err)
+				}
+				return w.Result(), nil
+			},
+			wantExternalID: 100,
+		},
+		{
+			name: "server error",
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+				w := httptest.NewRecorder()
+				w.WriteHeader(http.StatusInternalServerError)
+				return w.Result(), nil
+			},
+			wantExternalID: 0,
+			wantQueue:      1,
+		},
+		{
+			name: "operation failed",
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+				bRes, err := json.Marshal(rest.THandlerBatchResponse{
+					Data: []rest.TBatchResult{{Action: models.BatchCreate, Status: false, Error: "any"}},
+				})
+				if err != nil {
+					return nil, fmt.Errorf("any error: %w", err)
+				}
+				w := httptest.NewRecorder()
+				_, err = w.Write(bRes)
+				if err != nil {
+					return nil, fmt.Errorf("any error: %w", err)
+				}
+				return w.Result(), nil
+			},
+			wantExternalID: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := file.Init(file.SetPath("./test_push"))
+			assert.NoError(t, err)
+			defer func() {
+				_ = os.RemoveAll("./test_push")
+			}()
+			err = s.Open("user")
+			assert.NoError(t, err)
+			k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
+			assert.NoError(t, err)
+			k.newRequest = tt.fRequest
+
+			m, err := k.EventNewText(0, "title", "text")
+			assert.NoError(t, err)
+			assert.Len(t, s.Operations(), 1)
+
+			k.flushQueue(context.TODO())
+
+			got, err := s.Get(m.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantExternalID, got.ExternalID)
+			assert.Len(t, s.Operations(), tt.wantQueue)
+		})
+	}
+}
+
+func Test_keepClient_EventSync(t *testing.T) {
+	writeJSON := func(v any) (*http.Response, error) {
+		bRes, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("any error: %w", err)
+		}
+		w := httptest.NewRecorder()
+		_, err = w.Write(bRes)
+		if err != nil {
+			return nil, fmt.Errorf("any error: %w", err)
+		}
+		return w.Result(), nil
+	}
+	list := func() (*http.Response, error) {
+		return writeJSON(tHalderGetDatasResponse{
+			tResultResponse: tResultResponse{Status: true},
+			Data:            []tHandlerGetData{{ID: 100, Title: "title"}},
+		})
+	}
+	tests := []struct {
+		name     string
+		token    string
+		fRequest func(_ context.Context, method, url string, data *[]byte) (*http.Response, error)
+		wantErr  error
+		wantCode rest.ErrorCode
+	}{
+		{
+			name:  "ok",
+			token: "token",
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+				if method == http.MethodGet {
+					return list()
+				}
+				return writeJSON(rest.THandlerBatchResponse{
+					Data: []rest.TBatchResult{{Action: models.BatchCreate, ID: 100, Status: true}},
+				})
+			},
+		},
+		{
+			name:  "changes not pushed",
+			token: "token",
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+				if method == http.MethodGet {
+					return list()
+				}
+				w := httptest.NewRecorder()
+				w.WriteHeader(http.StatusInternalServerError)
+				return w.Result(), nil
+			},
+			wantErr: ErrPendingChanges,
+		},
+		{
+			name:     "not signed in",
+			wantCode: rest.CodeUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := file.Init(file.SetPath("./test_sync"))
+			assert.NoError(t, err)
+			defer func() {
+				_ = os.RemoveAll("./test_sync")
+			}()
+			err = s.Open("user")
+			assert.NoError(t, err)
+			k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
+			assert.NoError(t, err)
+			k.newRequest = tt.fRequest
+			k.token = tt.token
+
+			_, err = k.EventNewText(0, "title", "text")
+			assert.NoError(t, err)
+
+			err = k.EventSync()
+			switch {
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			case tt.wantCode != "":
+				assert.Equal(t, tt.wantCode, Code(err))
+			default:
+				assert.NoError(t, err)
+				assert.Empty(t, s.Operations())
+			}
+		})
+	}
+}
+
+func Test_keepClient_EventChangePassword(t *testing.T) {
+	tests := []struct {
+		name         string
+		token        string
+		newPassword2 string
+		fRequest     func(_ context.Context, method, url string, data *[]byte) (*http.Response, error)
+		wantToken    string
+		wantErr      string
+	}{
+		{
+			name:         "ok",
+			token:        "old",
+			newPassword2: "new",
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+				w := httptest.NewRecorder()
+				if method != http.MethodPost || !strings.HasSuffix(url, "/api/v0/user/password") {
+					w.WriteHeader(http.StatusNotFound)
+					return w.Result(), nil
+				}
+				_, _ = w.WriteString(`{"status":true,"access_token":"fresh"}`)
+				return w.Result(), nil
+			},
+			wantToken: "fresh",
+		},
+		{
+			name:         "wrong password",
+			token:        "old",
+			newPassword2: "new",
+			fRequest: func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+				w := httptest.NewRecorder()
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.WriteString(`{"code":"password_incorrect","status":403}`)
+				return w.Result(), nil
+			},
+			wantToken: "old",
+			wantErr:   "неверный текущий пароль",
+		},
+		{
+			name:         "repeat mismatch",
+			token:        "old",
+			newPassword2: "other",
+			wantToken:    "old",
+			wantErr:      "повторный пароль не совпадает",
+		},
+		{
+			name:         "not authorized",
+			newPassword2: "new",
+			wantErr:      "требуется авторизация",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := file.Init()
+			assert.NoError(t, err)
+			k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false), SetAuthMode(AuthModePassword))
+			assert.NoError(t, err)
+			k.newRequest = tt.fRequest
+			k.token = tt.token
+
+			err = k.EventChangePassword("current", "new", tt.newPassword2)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantToken, k.token)
+		})
+	}
+}
+
+func Test_keepClient_EventEditPassword(t *testing.T) {
+	s, err := file.Init(file.SetPath("./test_password"))
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll("./test_password")
+	}()
+	assert.NoError(t, s.Open("user"))
+	k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
+	assert.NoError(t, err)
+
+	m, err := k.EventNewPasswordFrom(0, models.PasswordSourceGit, "git", "github.com", "bob", "old")
+	assert.NoError(t, err)
+	assert.NoError(t, k.EventEditPassword(m.ID, "git", "github.com", "bob", "new", ""))
+
+	got, err := k.EventGetPassword(m.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", got.Password)
+	assert.Equal(t, models.PasswordSourceGit, got.Source)
+
+	// ключ OTP проверяется при сохранении.
+	assert.Error(t, k.EventEditPassword(m.ID, "git", "github.com", "bob", "new", "not base32!"))
+	assert.NoError(t, k.EventEditPassword(m.ID, "git", "github.com", "bob", "new", "JBSWY3DPEHPK3PXP"))
+	got, err = k.EventGetPassword(m.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", got.OTP)
+	_, err = k.EventNewPassword(0, "site", "example.com", "bob", "pass", "otpauth://hotp/x?secret=JBSWY3DPEHPK3PXP")
+	assert.Error(t, err)
+}
@@ -1,5 +1,21 @@
 package uiapi
 
 type Config struct {
-	APIAddress string `env:"API_ADDRESS"`
+	APIAddress  string `env:"API_ADDRESS"`
+	Transport   string `env:"API_TRANSPORT"`
+	GRPCAddress string `env:"API_GRPC_ADDRESS"`
+	GRPCTLS     bool   `env:"API_GRPC_TLS"`
+	// AuthMode способ входа: srp - пароль не покидает клиента, password - устаревший вход паролем.
+	AuthMode string `env:"AUTH_MODE"`
+	// ClientCertFile и ClientKeyFile сертификат устройства, нужен, если сервер требует сертификат клиента.
+	ClientCertFile string `env:"CLIENT_CERT_FILE"`
+	ClientKeyFile  string `env:"CLIENT_KEY_FILE"`
+	// CAFile CA сертификата сервера в дополнение к системным.
+	CAFile string `env:"API_CA_FILE"`
+	// PinFile файл запомненных ключей серверов, пустой - ключ сервера не запоминается.
+	PinFile string `env:"API_PIN_FILE"`
+	// Insecure не проверять сертификат сервера, только для отладки.
+	Insecure bool `env:"API_INSECURE"`
+	// OfflineLogin хранить сессию, зашифрованную паролем, для входа без связи с сервером.
+	OfflineLogin bool `env:"API_OFFLINE_LOGIN"`
 }
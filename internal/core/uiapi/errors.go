@@ -0,0 +1,102 @@
+package uiapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
+)
+
+// errorMessages сообщения пользователю по кодам ошибок сервера.
+var errorMessages = map[rest.ErrorCode]string{
+	rest.CodeBadRequest:         "ошибка запроса",
+	rest.CodeUnauthorized:       "требуется авторизация",
+	rest.CodeInvalidCredentials: "неверные данные авторизации",
+	rest.CodeLoginInvalid:       "недопустимый логин",
+	rest.CodeRecoveryInvalid:    "неверный логин или ключ восстановления",
+	rest.CodePasswordInvalid:    "недопустимый пароль",
+	rest.CodePasswordIncorrect:  "неверный текущий пароль",
+	rest.CodeLoginTaken:         "логин уже занят",
+	rest.CodeSecretNotFound:     "данные не найдены на сервере",
+	rest.CodeBatchInvalid:       "некорректный пакет изменений",
+	rest.CodeBatchActionUnknown: "неизвестная операция в пакете изменений",
+	rest.CodeFilterInvalid:      "некорректные параметры списка",
+	rest.CodeRateLimited:        "слишком много запросов",
+	rest.CodeAccountLocked:      "вход временно заблокирован после неудачных попыток",
+	rest.CodeLegacyAuthDisabled: "сервер не принимает пароль, нужен вход по SRP (AUTH_MODE=srp)",
+	rest.CodePasswordWeak:       "пароль не соответствует требованиям сервера",
+	rest.CodePasswordUnchecked:  "сервер проверяет новые пароли сам, задайте пароль с AUTH_MODE=password",
+	rest.CodeRegistrationClosed: "регистрация на сервере закрыта",
+	rest.CodeInviteInvalid:      "неверный, просроченный или использованный код приглашения",
+	rest.CodeInternal:           "внутренняя ошибка сервера",
+}
+
+var (
+	errorMessagePasswordEmpty = "пароль не может быть пустым"
+	errorMessageServerProof   = "сервер не подтвердил знание пароля, вход отменен"
+)
+
+// apiError ошибка сервера с текстом для пользователя.
+type apiError struct {
+	Code    rest.ErrorCode
+	Message string
+	Status  int
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+// codeError ошибка с кодом без ответа сервера, например обнаруженная клиентом.
+func codeError(code rest.ErrorCode) error {
+	return &apiError{Code: code, Message: errorMessages[code]}
+}
+
+// Code код ошибки сервера, пустой - у ошибки нет кода, например сервер недоступен.
+func Code(err error) rest.ErrorCode {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// responseError ошибка из ответа сервера. Если тело не problem details (старый сервер), код выбирается по статусу.
+func responseError(status int, body []byte) error {
+	problem := rest.TProblem{}
+	if err := json.Unmarshal(body, &problem); err != nil || problem.Code == "" {
+		problem.Code = codeByStatus(status)
+	}
+
+	message, ok := errorMessages[problem.Code]
+	if !ok {
+		message = "ошибка сервера: " + string(problem.Code)
+	}
+	if problem.RetryAfter > 0 {
+		message = fmt.Sprintf("%s, повторите через %d сек.", message, problem.RetryAfter)
+	}
+	return &apiError{
+		Code:    problem.Code,
+		Message: message,
+		Status:  status,
+	}
+}
+
+func codeByStatus(status int) rest.ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return rest.CodeBadRequest
+	case http.StatusUnauthorized:
+		return rest.CodeUnauthorized
+	case http.StatusNotFound:
+		return rest.CodeSecretNotFound
+	case http.StatusConflict:
+		return rest.CodeLoginTaken
+	case http.StatusTooManyRequests:
+		return rest.CodeRateLimited
+	default:
+		return rest.CodeInternal
+	}
+}
@@ -0,0 +1,67 @@
+package uiapi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
+)
+
+func Test_responseError(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		wantCode rest.ErrorCode
+		wantMsg  string
+	}{
+		{
+			name:     "problem",
+			body:     `{"type":"urn:gophkeeper:error:login_taken","code":"login_taken","status":409}`,
+			status:   http.StatusConflict,
+			wantCode: rest.CodeLoginTaken,
+			wantMsg:  "логин уже занят",
+		},
+		{
+			name:     "account locked",
+			body:     `{"code":"account_locked","status":429,"retry_after":60}`,
+			status:   http.StatusTooManyRequests,
+			wantCode: rest.CodeAccountLocked,
+			wantMsg:  "вход временно заблокирован после неудачных попыток, повторите через 60 сек.",
+		},
+		{
+			name:     "unknown code",
+			body:     `{"code":"quota_exceeded","status":403}`,
+			status:   http.StatusForbidden,
+			wantCode: "quota_exceeded",
+			wantMsg:  "ошибка сервера: quota_exceeded",
+		},
+		{
+			name:     "empty body",
+			body:     "",
+			status:   http.StatusUnauthorized,
+			wantCode: rest.CodeUnauthorized,
+			wantMsg:  "требуется авторизация",
+		},
+		{
+			name:     "not json",
+			body:     "bad gateway",
+			status:   http.StatusBadGateway,
+			wantCode: rest.CodeInternal,
+			wantMsg:  "внутренняя ошибка сервера",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := responseError(tt.status, []byte(tt.body))
+
+			var apiErr *apiError
+			assert.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, tt.wantCode, apiErr.Code)
+			assert.Equal(t, tt.status, apiErr.Status)
+			assert.EqualError(t, err, tt.wantMsg)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package uiapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	eventsRetryDelay = 5 * time.Second
+
+	errEventsStreamClosed = errors.New("events stream closed")
+)
+
+const (
+	eventRevision = "revision"
+)
+
+// listenEvents держит подключение к потоку событий сервера, при обрыве переподключается.
+func (k *keepClient) listenEvents(ctx context.Context) {
+	for {
+		if k.token != "" {
+			err := k.eventSubscribe(ctx)
+			if err != nil {
+				k.log.Debug("events stream", zap.Error(err))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventsRetryDelay):
+		}
+	}
+}
+
+func (k *keepClient) eventSubscribe(ctx context.Context) error {
+	if k.rpc != nil {
+		return k.rpcEventSubscribe(ctx)
+	}
+
+	r, err := k.newRequest(ctx, http.MethodGet, k.apiURL+"/api/v0/user/events", nil)
+	if err != nil {
+		return fmt.Errorf(formatStringError, errMessageFailedRequest, err)
+	}
+	defer func() {
+		err := r.Body.Close()
+		if err != nil {
+			k.log.Debug(errMessageFailedCloseBody, zap.Error(err))
+		}
+	}()
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("events stream: %w", responseError(r.StatusCode, nil))
+	}
+
+	k.log.Debug("events stream connected")
+	k.eventsConnected.Store(true)
+	defer k.eventsConnected.Store(false)
+
+	return k.readEvents(r.Body)
+}
+
+// readEvents разбирает поток Server-Sent Events.
+func (k *keepClient) readEvents(body io.Reader) error {
+	scanner := bufio.NewScanner(body)
+	event := ""
+	data := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			k.handleEvent(event, data)
+			event, data = "", ""
+		case strings.HasPrefix(line, ":"):
+			// комментарий, сервер так поддерживает соединение.
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed read events: %w", err)
+	}
+	return errEventsStreamClosed
+}
+
+func (k *keepClient) handleEvent(event, data string) {
+	if event != eventRevision {
+		return
+	}
+
+	rev := tEventRevision{}
+	if err := json.Unmarshal([]byte(data), &rev); err != nil {
+		k.log.Error(errMessageFailedUnmarshal, zap.Error(err), zap.String("event", event))
+		return
+	}
+	k.onRevision(rev.Revision)
+}
+
+// onRevision запрашивает синхронизацию, если ревизия хранилища на сервере изменилась.
+func (k *keepClient) onRevision(revision int64) {
+	if k.revision.Swap(revision) == revision {
+		return
+	}
+	k.log.Debug("revision changed", zap.Int64("revision", revision))
+	k.requestSync()
+}
+
+// requestSync просит воркер синхронизировать данные, повторные запросы до начала синхронизации схлопываются.
+func (k *keepClient) requestSync() {
+	select {
+	case k.syncCh <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,72 @@
+package uiapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/storage/file"
+)
+
+func Test_keepClient_readEvents(t *testing.T) {
+	tests := []struct {
+		name         string
+		stream       string
+		lastRevision int64
+		wantRevision int64
+		wantSync     bool
+	}{
+		{
+			name:         "revision changed",
+			stream:       "event:revision\ndata:{\"revision\":5}\n\n",
+			lastRevision: 4,
+			wantRevision: 5,
+			wantSync:     true,
+		},
+		{
+			name:         "same revision",
+			stream:       "event:revision\ndata:{\"revision\":5}\n\n",
+			lastRevision: 5,
+			wantRevision: 5,
+			wantSync:     false,
+		},
+		{
+			name:         "keep alive and unknown event",
+			stream:       ": ping\n\nevent:other\ndata:{\"revision\":9}\n\n",
+			lastRevision: 1,
+			wantRevision: 1,
+			wantSync:     false,
+		},
+		{
+			name:         "broken data",
+			stream:       "event:revision\ndata:{\n\n",
+			lastRevision: 1,
+			wantRevision: 1,
+			wantSync:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := file.Init()
+			assert.NoError(t, err)
+			k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
+			assert.NoError(t, err)
+			k.revision.Store(tt.lastRevision)
+
+			err = k.readEvents(strings.NewReader(tt.stream))
+			assert.ErrorIs(t, err, errEventsStreamClosed)
+			assert.Equal(t, tt.wantRevision, k.revision.Load())
+
+			gotSync := false
+			select {
+			case <-k.syncCh:
+				gotSync = true
+			default:
+			}
+			assert.Equal(t, tt.wantSync, gotSync)
+		})
+	}
+}
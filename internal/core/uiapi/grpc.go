@@ -0,0 +1,363 @@
+package uiapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/grpc/pb"
+	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+)
+
+// TransportGRPC значение API_TRANSPORT, при котором клиент работает с сервером по gRPC.
+const TransportGRPC = "grpc"
+
+var (
+	rpcTimeout = 30 * time.Second
+)
+
+// newRPCClient подключение к gRPC серверу, соединение закрывается вместе с контекстом клиента.
+// При nil tlsConfig соединение без TLS.
+func newRPCClient(ctx context.Context, address string, tlsConfig *tls.Config) (pb.KeeperClient, error) {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed create grpc client: %w", err)
+	}
+	context.AfterFunc(ctx, func() { _ = conn.Close() })
+
+	return pb.NewKeeperClient(conn), nil
+}
+
+// rpcContext контекст вызова с токеном авторизации.
+func (k *keepClient) rpcContext(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+k.token)
+}
+
+func (k *keepClient) rpcSignIn(ctx context.Context, login, password string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	res, err := k.rpc.Login(ctx, &pb.LoginRequest{Login: login, Password: password})
+	if err != nil {
+		return "", rpcSignInError(err)
+	}
+	return res.GetAccessToken(), nil
+}
+
+// rpcSignInError ошибка входа для пользователя.
+func rpcSignInError(err error) error {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		return codeError(rest.CodeInvalidCredentials)
+	case codes.ResourceExhausted:
+		return errors.New("вход временно недоступен: " + status.Convert(err).Message())
+	case codes.PermissionDenied:
+		return codeError(rest.CodeLegacyAuthDisabled)
+	}
+	return fmt.Errorf(formatStringError, errMessageFailedRequest, err)
+}
+
+func (k *keepClient) rpcSRPInit(ctx context.Context, login string, clientPublic []byte) (*tSRPInitResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	res, err := k.rpc.SRPInit(ctx, &pb.SRPInitRequest{Login: login, A: clientPublic})
+	if err != nil {
+		return nil, rpcSignInError(err)
+	}
+	return &tSRPInitResponse{
+		Session:      res.GetSession(),
+		Salt:         res.GetSalt(),
+		ServerPublic: res.GetB(),
+	}, nil
+}
+
+func (k *keepClient) rpcSRPVerify(ctx context.Context, session string, proof []byte) (*tSRPVerifyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	res, err := k.rpc.SRPVerify(ctx, &pb.SRPVerifyRequest{Session: session, M1: proof})
+	if err != nil {
+		return nil, rpcSignInError(err)
+	}
+	return &tSRPVerifyResponse{AccessToken: res.GetAccessToken(), ServerProof: res.GetM2()}, nil
+}
+
+func (k *keepClient) rpcRegistration(ctx context.Context, login string, secret newSecret, withRecovery bool,
+	invite string,
+) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	res, err := k.rpc.Registration(ctx, &pb.RegistrationRequest{
+		Login:    login,
+		Password: secret.Password,
+		Salt:     secret.Salt,
+		Verifier: secret.Verifier,
+		Recovery: withRecovery,
+		Invite:   invite,
+	})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.InvalidArgument:
+			return "", errors.New("Ошибка запроса: " + status.Convert(err).Message())
+		case codes.AlreadyExists:
+			return "", errors.New("логин уже занят")
+		case codes.FailedPrecondition:
+			return "", codeError(rest.CodePasswordWeak)
+		case codes.PermissionDenied:
+			return "", errors.New("регистрация отклонена: " + status.Convert(err).Message())
+		default:
+			return "", errors.New("Ошибка: " + status.Convert(err).Message())
+		}
+	}
+	return res.GetRecoveryKey(), nil
+}
+
+func (k *keepClient) rpcRecover(ctx context.Context, login, recoveryKey string, next newSecret) (
+	string, string, error,
+) {
+	ctx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	res, err := k.rpc.Recover(ctx, &pb.RecoverRequest{
+		Login:       login,
+		RecoveryKey: recoveryKey,
+		NewPassword: next.Password,
+		Salt:        next.Salt,
+		Verifier:    next.Verifier,
+	})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unauthenticated:
+			return "", "", codeError(rest.CodeRecoveryInvalid)
+		case codes.InvalidArgument:
+			return "", "", codeError(rest.CodePasswordInvalid)
+		case codes.ResourceExhausted:
+			return "", "", errors.New("восстановление временно недоступно: " + status.Convert(err).Message())
+		}
+		return "", "", fmt.Errorf(formatStringError, errMessageFailedRequest, err)
+	}
+	return res.GetAccessToken(), res.GetRecoveryKey(), nil
+}
+
+func (k *keepClient) rpcChangePassword(ctx context.Context, current currentSecret, next newSecret) (string, error) {
+	ctx, cancel := context.WithTimeout(k.rpcContext(ctx), rpcTimeout)
+	defer cancel()
+
+	res, err := k.rpc.ChangePassword(ctx, &pb.ChangePasswordRequest{
+		OldPassword: current.Password,
+		SrpSession:  current.Session,
+		SrpProof:    current.Proof,
+		NewPassword: next.Password,
+		Salt:        next.Salt,
+		Verifier:    next.Verifier,
+	})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.PermissionDenied:
+			return "", codeError(rest.CodePasswordIncorrect)
+		case codes.InvalidArgument:
+			return "", codeError(rest.CodePasswordInvalid)
+		case codes.Unauthenticated:
+			return "", codeError(rest.CodeUnauthorized)
+		}
+		return "", fmt.Errorf(formatStringError, errMessageFailedRequest, err)
+	}
+	return res.GetAccessToken(), nil
+}
+
+func (k *keepClient) rpcDeleteAccount(ctx context.Context, current currentSecret) error {
+	ctx, cancel := context.WithTimeout(k.rpcContext(ctx), rpcTimeout)
+	defer cancel()
+
+	_, err := k.rpc.DeleteAccount(ctx, &pb.DeleteAccountRequest{
+		Password:   current.Password,
+		SrpSession: current.Session,
+		SrpProof:   current.Proof,
+	})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.PermissionDenied:
+			return codeError(rest.CodePasswordIncorrect)
+		case codes.Unauthenticated:
+			return codeError(rest.CodeUnauthorized)
+		case codes.ResourceExhausted:
+			return errors.New("удаление временно недоступно: " + status.Convert(err).Message())
+		}
+		return fmt.Errorf(formatStringError, errMessageFailedRequest, err)
+	}
+	return nil
+}
+
+// rpcExport получает архив потоком: первым сообщением ключ, далее части архива.
+func (k *keepClient) rpcExport(ctx context.Context) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(k.rpcContext(ctx), rpcTimeout)
+	defer cancel()
+
+	stream, err := k.rpc.Export(ctx, &pb.ExportRequest{})
+	if err != nil {
+		return nil, "", fmt.Errorf(formatStringError, errMessageFailedRequest, err)
+	}
+
+	var key string
+	buf := bytes.Buffer{}
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed receive export: %w", err)
+		}
+		if res.GetKey() != "" {
+			key = res.GetKey()
+			continue
+		}
+		buf.Write(res.GetChunk())
+	}
+	if key == "" {
+		return nil, "", errors.New("stream without key")
+	}
+	return buf.Bytes(), key, nil
+}
+
+func (k *keepClient) rpcGetExternalMetaDatas(ctx context.Context) (*[]models.MetaDataItem, error) {
+	ctx, cancel := context.WithTimeout(k.rpcContext(ctx), rpcTimeout)
+	defer cancel()
+
+	res, err := k.rpc.ListSecrets(ctx, &pb.ListSecretsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf(formatStringError, errMessageFailedRequest, err)
+	}
+
+	result := make([]models.MetaDataItem, 0, len(res.GetItems()))
+	for _, d := range res.GetItems() {
+		result = append(result, metaDataItem(d))
+	}
+	return &result, nil
+}
+
+// rpcGetExternalData получает секрет потоком, большие файлы не упираются в лимит размера сообщения.
+func (k *keepClient) rpcGetExternalData(ctx context.Context, id uint) (*models.MetaDataItem, error) {
+	ctx, cancel := context.WithTimeout(k.rpcContext(ctx), rpcTimeout)
+	defer cancel()
+
+	stream, err := k.rpc.DownloadSecret(ctx, &pb.GetSecretRequest{Id: uint64(id)})
+	if err != nil {
+		return nil, fmt.Errorf(formatStringError, errMessageFailedRequest, err)
+	}
+
+	var result *models.MetaDataItem
+	buf := bytes.Buffer{}
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed receive data: %w", err)
+		}
+		if meta := res.GetMeta(); meta != nil {
+			item := metaDataItem(meta)
+			result = &item
+			continue
+		}
+		buf.Write(res.GetChunk())
+	}
+	if result == nil {
+		return nil, errors.New("stream without meta")
+	}
+
+	data := buf.Bytes()
+	result.Data = &data
+	return result, nil
+}
+
+func (k *keepClient) rpcBatchExternalData(
+	ctx context.Context, ops *[]rest.TBatchOperation,
+) (*[]rest.TBatchResult, error) {
+	ctx, cancel := context.WithTimeout(k.rpcContext(ctx), rpcTimeout)
+	defer cancel()
+
+	req := &pb.BatchRequest{Operations: make([]*pb.BatchOperation, 0, len(*ops))}
+	for _, op := range *ops {
+		req.Operations = append(req.Operations, &pb.BatchOperation{
+			Action:         string(op.Action),
+			Id:             uint64(op.ID),
+			Title:          op.Title,
+			DataType:       string(op.DataType),
+			Data:           op.Data,
+			UpdateDt:       op.UpdateDT,
+			IdempotencyKey: op.Key,
+		})
+	}
+
+	res, err := k.rpc.Batch(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf(formatStringError, errMessageFailedRequest, err)
+	}
+
+	result := make([]rest.TBatchResult, 0, len(res.GetResults()))
+	for _, r := range res.GetResults() {
+		result = append(result, rest.TBatchResult{
+			Action:   models.BatchAction(r.GetAction()),
+			Title:    r.GetMeta().GetTitle(),
+			DataType: models.DataType(r.GetMeta().GetDataType()),
+			Error:    r.GetError(),
+			ID:       uint(r.GetMeta().GetId()),
+			UpdateDT: r.GetMeta().GetUpdateDt(),
+			Status:   r.GetStatus(),
+		})
+	}
+	return &result, nil
+}
+
+func (k *keepClient) rpcEventSubscribe(ctx context.Context) error {
+	stream, err := k.rpc.Events(k.rpcContext(ctx), &pb.EventsRequest{})
+	if err != nil {
+		return fmt.Errorf(formatStringError, errMessageFailedRequest, err)
+	}
+
+	k.log.Debug("events stream connected")
+	k.eventsConnected.Store(true)
+	defer k.eventsConnected.Store(false)
+
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return errEventsStreamClosed
+		}
+		if err != nil {
+			return fmt.Errorf("failed read events: %w", err)
+		}
+		k.onRevision(event.GetRevision())
+	}
+}
+
+func metaDataItem(meta *pb.SecretMeta) models.MetaDataItem {
+	return models.MetaDataItem{
+		ID:        uint(meta.GetId()),
+		Title:     meta.GetTitle(),
+		DataType:  models.DataType(meta.GetDataType()),
+		UpdatedDT: meta.GetUpdateDt(),
+		IsDeleted: meta.GetIsDeleted(),
+	}
+}
@@ -0,0 +1,152 @@
+package uiapi
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/grpc/pb"
+	"github.com/playmixer/secret-keeper/internal/adapter/storage/file"
+)
+
+type testKeeperServer struct {
+	pb.UnimplementedKeeperServer
+	data      []byte
+	revisions []int64
+}
+
+func (s *testKeeperServer) DownloadSecret(req *pb.GetSecretRequest, stream pb.Keeper_DownloadSecretServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	if len(md.Get("authorization")) == 0 || md.Get("authorization")[0] != "Bearer token" {
+		return status.Error(codes.Unauthenticated, "authorization required")
+	}
+	if req.GetId() != 1 {
+		return status.Error(codes.NotFound, "not found content")
+	}
+	err := stream.Send(&pb.DownloadResponse{Payload: &pb.DownloadResponse_Meta{Meta: &pb.SecretMeta{
+		Id:       1,
+		Title:    "file",
+		DataType: "BINARY",
+		UpdateDt: 10,
+	}}})
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(s.data); start += 100 {
+		err := stream.Send(&pb.DownloadResponse{Payload: &pb.DownloadResponse_Chunk{
+			Chunk: s.data[start:min(start+100, len(s.data))],
+		}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *testKeeperServer) Events(_ *pb.EventsRequest, stream pb.Keeper_EventsServer) error {
+	for _, r := range s.revisions {
+		if err := stream.Send(&pb.RevisionEvent{Revision: r}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestRPCClient(t *testing.T, srv pb.KeeperServer) *keepClient {
+	t.Helper()
+
+	listen := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	pb.RegisterKeeperServer(server, srv)
+	go func() {
+		_ = server.Serve(listen)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listen.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	s, err := file.Init()
+	assert.NoError(t, err)
+	k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
+	assert.NoError(t, err)
+	k.rpc = pb.NewKeeperClient(conn)
+	k.token = "token"
+
+	return k
+}
+
+func Test_keepClient_rpcGetExternalData(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      uint
+		token   string
+		wantErr bool
+	}{
+		{
+			name:  "ok",
+			id:    1,
+			token: "token",
+		},
+		{
+			name:    "not found",
+			id:      2,
+			token:   "token",
+			wantErr: true,
+		},
+		{
+			name:    "unauthorized",
+			id:      1,
+			token:   "",
+			wantErr: true,
+		},
+	}
+	data := bytes.Repeat([]byte("data"), 1000)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := newTestRPCClient(t, &testKeeperServer{data: data})
+			k.token = tt.token
+
+			got, err := k.eventGetExternalData(context.TODO(), tt.id)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "file", got.Title)
+			assert.Equal(t, int64(10), got.UpdatedDT)
+			assert.Equal(t, data, *got.Data)
+		})
+	}
+}
+
+func Test_keepClient_rpcEventSubscribe(t *testing.T) {
+	k := newTestRPCClient(t, &testKeeperServer{revisions: []int64{3, 3, 4}})
+	k.revision.Store(3)
+
+	err := k.eventSubscribe(context.TODO())
+	assert.ErrorIs(t, err, errEventsStreamClosed)
+	assert.Equal(t, int64(4), k.revision.Load())
+	assert.False(t, k.eventsConnected.Load())
+
+	select {
+	case <-k.syncCh:
+	default:
+		t.Error("sync not requested")
+	}
+}
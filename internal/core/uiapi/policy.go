@@ -0,0 +1,100 @@
+package uiapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/grpc/pb"
+	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
+	"github.com/playmixer/secret-keeper/pkg/policy"
+)
+
+// passwordPolicy правила паролей сервера. Пароль, заданный верификатором SRP, сервер не видит,
+// поэтому в режиме srp клиент проверяет его сам. Если сервер правил не отдает, возвращается nil.
+func (k *keepClient) passwordPolicy(ctx context.Context) *policy.Policy {
+	var (
+		info *tPolicyResponse
+		err  error
+	)
+	if k.rpc != nil {
+		info, err = k.rpcPolicy(ctx)
+	} else {
+		info, err = k.restPolicy(ctx)
+	}
+	if err != nil {
+		k.log.Debug("failed get registration policy", zap.Error(err))
+		return nil
+	}
+	return &policy.Policy{
+		PasswordMinLength:   info.PasswordMinLength,
+		PasswordMinStrength: info.PasswordMinStrength,
+	}
+}
+
+// checkPassword проверка нового пароля по правилам сервера до вычисления верификатора SRP.
+func (k *keepClient) checkPassword(ctx context.Context, login, password string) error {
+	pol := k.passwordPolicy(ctx)
+	if pol == nil {
+		return nil
+	}
+	err := pol.CheckPassword(login, password)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, policy.ErrPasswordShort):
+		return fmt.Errorf("пароль слишком короткий, нужно не меньше %d символов", max(pol.PasswordMinLength, 1))
+	case errors.Is(err, policy.ErrPasswordIsLogin):
+		return errors.New("пароль не должен содержать логин")
+	case errors.Is(err, policy.ErrPasswordWeak):
+		return errors.New("пароль слишком простой: добавьте слов, цифр или символов")
+	}
+	return codeError(rest.CodePasswordWeak)
+}
+
+func (k *keepClient) restPolicy(ctx context.Context) (*tPolicyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	r, err := k.newRequest(ctx, http.MethodGet, k.apiURL+"/api/v0/auth/policy", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed create request: %w", err)
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			k.log.Error(errMessageFailedCloseBody, zap.Error(err))
+		}
+	}()
+
+	res, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf(formatStringError, errMessageFailedReadBody, err)
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, responseError(r.StatusCode, res)
+	}
+	result := tPolicyResponse{}
+	if err := json.Unmarshal(res, &result); err != nil {
+		return nil, fmt.Errorf(formatStringError, errMessageFailedUnmarshal, err)
+	}
+	return &result, nil
+}
+
+func (k *keepClient) rpcPolicy(ctx context.Context) (*tPolicyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	res, err := k.rpc.Policy(ctx, &pb.PolicyRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed get policy: %w", err)
+	}
+	return &tPolicyResponse{
+		RegistrationMode:    res.GetRegistrationMode(),
+		PasswordMinLength:   int(res.GetPasswordMinLength()),
+		PasswordMinStrength: int(res.GetPasswordMinStrength()),
+	}, nil
+}
@@ -0,0 +1,148 @@
+package uiapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+)
+
+const operationKeySize = 16
+
+// queueChange ставит локальное изменение в очередь отправки на сервер и запускает синхронизацию.
+func (k *keepClient) queueChange(action models.BatchAction, localID int64) error {
+	if err := k.enqueue(action, localID); err != nil {
+		return err
+	}
+	k.requestSync()
+	return nil
+}
+
+// enqueue добавляет изменение в очередь. Повторная правка записи, правка которой уже ждет отправки,
+// не добавляется: при отправке берется последняя версия записи.
+func (k *keepClient) enqueue(action models.BatchAction, localID int64) error {
+	if action == models.BatchUpdate {
+		for _, op := range k.store.Operations() {
+			if op.LocalID == localID && op.Action == models.BatchUpdate {
+				return nil
+			}
+		}
+	}
+
+	key, err := newOperationKey()
+	if err != nil {
+		return err
+	}
+	err = k.store.AddOperation(models.PendingOperation{Key: key, Action: action, LocalID: localID})
+	if err != nil {
+		return fmt.Errorf("failed queue change: %w", err)
+	}
+	return nil
+}
+
+// newOperationKey случайный ключ идемпотентности операции.
+func newOperationKey() (string, error) {
+	key := make([]byte, operationKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed generate operation key: %w", err)
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// PendingChanges число локальных изменений, которые еще не приняты сервером.
+func (k *keepClient) PendingChanges() int {
+	if k.token == "" {
+		return 0
+	}
+	return len(k.store.Operations())
+}
+
+func (k *keepClient) pendingLocalIDs() map[int64]bool {
+	ids := make(map[int64]bool)
+	for _, op := range k.store.Operations() {
+		ids[op.LocalID] = true
+	}
+	return ids
+}
+
+// flushQueue отправляет очередь изменений по порядку, пока она не опустеет или сервер не станет недоступен.
+// Правка или удаление записи, создание которой еще не подтверждено, ждет ID от сервера
+// и уходит следующим пакетом.
+func (k *keepClient) flushQueue(ctx context.Context) {
+	for {
+		queue := k.store.Operations()
+		if len(queue) == 0 {
+			return
+		}
+
+		changes := make([]pendingChange, 0, min(len(queue), batchMaxOperations))
+		creating := make(map[int64]bool)
+		dropped := false
+		for _, op := range queue {
+			if len(changes) == batchMaxOperations {
+				break
+			}
+			meta, err := k.store.Get(op.LocalID)
+			if err == nil && op.Action != models.BatchCreate && meta.ExternalID == 0 {
+				if creating[op.LocalID] {
+					break
+				}
+				err = fmt.Errorf("record `%v` is not on server", op.LocalID)
+			}
+			var change *pendingChange
+			if err == nil {
+				change, err = k.queuedChange(op, meta)
+			}
+			if err != nil {
+				k.log.Error("drop queued change", zap.Error(err), zap.String("action", string(op.Action)))
+				if err := k.store.RemoveOperation(op.Key); err != nil {
+					k.log.Error("failed remove queued change", zap.Error(err))
+					return
+				}
+				dropped = true
+				continue
+			}
+			if op.Action == models.BatchCreate {
+				creating[op.LocalID] = true
+			}
+			changes = append(changes, *change)
+		}
+
+		if len(changes) == 0 {
+			if dropped {
+				continue
+			}
+			return
+		}
+		if !k.pushChanges(ctx, changes) {
+			return
+		}
+	}
+}
+
+// queuedChange операция для сервера по изменению из очереди с текущей версией записи.
+func (k *keepClient) queuedChange(op models.PendingOperation, meta *models.FileMetaDataItem) (*pendingChange, error) {
+	if op.Action == models.BatchDelete {
+		// файл удаленной записи уже стерт, для удаления достаточно ID.
+		return &pendingChange{
+			localID: op.LocalID,
+			op: rest.TBatchOperation{
+				Action:   models.BatchDelete,
+				ID:       meta.ExternalID,
+				UpdateDT: meta.UpdateDT,
+				Key:      op.Key,
+			},
+		}, nil
+	}
+	change, err := k.localChange(op.Action, op.LocalID, meta.ExternalID)
+	if err != nil {
+		return nil, err
+	}
+	change.op.Key = op.Key
+	return change, nil
+}
@@ -0,0 +1,142 @@
+package uiapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/pkg/recoverykey"
+	"github.com/playmixer/secret-keeper/pkg/shamir"
+)
+
+// EventSplitRecoveryKey делит ключ восстановления на parts долей, любые threshold из них восстанавливают ключ.
+// Доли раздаются доверенным людям, сервер о них не знает.
+func (k *keepClient) EventSplitRecoveryKey(key string, parts, threshold int) ([]string, error) {
+	bKey, err := recoverykey.Parse(key)
+	if err != nil {
+		return nil, errors.New("некорректный ключ восстановления")
+	}
+	shares, err := shamir.Split(bKey, parts, threshold)
+	if err != nil {
+		return nil, fmt.Errorf("нужно от 2 до 255 долей, порог от 2 до числа долей: %w", err)
+	}
+
+	result := make([]string, 0, len(shares))
+	for _, share := range shares {
+		result = append(result, recoverykey.Format(share))
+	}
+	return result, nil
+}
+
+// recoveryKey ключ восстановления из введенных строк: одна строка - сам ключ, несколько - доли ключа.
+func recoveryKey(material []string) (string, error) {
+	lines := make([]string, 0, len(material))
+	for _, line := range material {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	switch len(lines) {
+	case 0:
+		return "", errors.New("введите ключ восстановления или доли ключа")
+	case 1:
+		return lines[0], nil
+	}
+
+	shares := make([][]byte, 0, len(lines))
+	for i, line := range lines {
+		share, err := recoverykey.Parse(line)
+		if err != nil {
+			return "", fmt.Errorf("некорректная доля %d", i+1)
+		}
+		shares = append(shares, share)
+	}
+	key, err := shamir.Combine(shares)
+	if err != nil {
+		return "", errors.New("доли ключа не подходят друг к другу")
+	}
+	return recoverykey.Format(key), nil
+}
+
+// EventRecover задает новый пароль по ключу восстановления или его долям и входит в учетную запись.
+// Возвращает новый ключ восстановления, использованный ключ и его доли больше не действуют.
+func (k *keepClient) EventRecover(login string, material []string, newPassword, newPassword2 string) (string, error) {
+	if newPassword != newPassword2 {
+		return "", errors.New("повторный пароль не совпадает")
+	}
+	key, err := recoveryKey(material)
+	if err != nil {
+		return "", err
+	}
+
+	next, err := k.newSecret(k.ctx, login, newPassword)
+	if err != nil {
+		return "", err
+	}
+
+	recoverAccess := k.recover
+	if k.rpc != nil {
+		recoverAccess = k.rpcRecover
+	}
+	token, newKey, err := recoverAccess(k.ctx, login, key, next)
+	if err != nil {
+		return "", err
+	}
+
+	if err := k.store.Open(login); err != nil {
+		k.log.Error("failed open store", zap.Error(err))
+		return "", fmt.Errorf("failed open store: %w", err)
+	}
+	k.token = token
+	k.login = login
+	k.sessionExpired.Store(false)
+	k.saveSession(newPassword)
+	return newKey, nil
+}
+
+func (k *keepClient) recover(ctx context.Context, login, key string, next newSecret) (string, string, error) {
+	bReq, err := json.Marshal(tRecoverRequest{
+		Login:       login,
+		RecoveryKey: key,
+		NewPassword: next.Password,
+		Salt:        next.Salt,
+		Verifier:    next.Verifier,
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed marshal request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	r, err := k.newRequest(ctx, http.MethodPost, k.apiURL+"/api/v0/auth/recover", &bReq)
+	if err != nil {
+		k.log.Error("failed create request", zap.Error(err))
+		return "", "", fmt.Errorf("failed create request: %w", err)
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			k.log.Error(errMessageFailedCloseBody, zap.Error(err))
+		}
+	}()
+
+	res, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", "", fmt.Errorf(formatStringError, errMessageFailedReadBody, err)
+	}
+	if r.StatusCode != http.StatusOK {
+		return "", "", responseError(r.StatusCode, res)
+	}
+
+	result := tRecoverResponse{}
+	if err := json.Unmarshal(res, &result); err != nil {
+		return "", "", fmt.Errorf(formatStringError, errMessageFailedUnmarshal, err)
+	}
+	return result.AccessToken, result.RecoveryKey, nil
+}
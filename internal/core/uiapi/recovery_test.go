@@ -0,0 +1,129 @@
+package uiapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
+	"github.com/playmixer/secret-keeper/internal/adapter/storage/file"
+	"github.com/playmixer/secret-keeper/pkg/recoverykey"
+)
+
+func Test_keepClient_EventSplitRecoveryKey(t *testing.T) {
+	bKey, err := recoverykey.Generate()
+	assert.NoError(t, err)
+	key := recoverykey.Format(bKey)
+
+	s, err := file.Init()
+	assert.NoError(t, err)
+	k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
+	assert.NoError(t, err)
+
+	shares, err := k.EventSplitRecoveryKey(key, 5, 3)
+	assert.NoError(t, err)
+	assert.Len(t, shares, 5)
+
+	got, err := recoveryKey([]string{shares[4], "", shares[0], " " + shares[2] + " "})
+	assert.NoError(t, err)
+	assert.Equal(t, key, got)
+
+	_, err = k.EventSplitRecoveryKey(key, 2, 3)
+	assert.Error(t, err)
+	_, err = k.EventSplitRecoveryKey("!!!", 5, 3)
+	assert.Error(t, err)
+}
+
+func Test_recoveryKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		material []string
+		want     string
+		wantErr  bool
+	}{
+		{name: "key", material: []string{"", " AAAA-BBBB "}, want: "AAAA-BBBB"},
+		{name: "empty", material: []string{"", " "}, wantErr: true},
+		{name: "bad share", material: []string{"AAAA", "!!!"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := recoveryKey(tt.material)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_keepClient_EventRecover(t *testing.T) {
+	tests := []struct {
+		name         string
+		newPassword2 string
+		status       int
+		body         string
+		wantKey      string
+		wantToken    string
+		wantErr      string
+	}{
+		{
+			name:         "ok",
+			newPassword2: "new",
+			status:       http.StatusOK,
+			body:         `{"status":true,"access_token":"token","recovery_key":"NEW-KEY"}`,
+			wantKey:      "NEW-KEY",
+			wantToken:    "token",
+		},
+		{
+			name:         "key not valid",
+			newPassword2: "new",
+			status:       http.StatusUnauthorized,
+			body:         `{"code":"recovery_invalid","status":401}`,
+			wantErr:      errorMessages[rest.CodeRecoveryInvalid],
+		},
+		{
+			name:         "repeat mismatch",
+			newPassword2: "other",
+			wantErr:      "повторный пароль не совпадает",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() { _ = os.RemoveAll("./data") }()
+			s, err := file.Init()
+			assert.NoError(t, err)
+			k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
+			assert.NoError(t, err)
+			k.newRequest = func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+				w := httptest.NewRecorder()
+				req := tRecoverRequest{}
+				if method != http.MethodPost || !strings.HasSuffix(url, "/api/v0/auth/recover") ||
+					json.Unmarshal(*data, &req) != nil || req.RecoveryKey != "AAAA-BBBB" {
+					w.WriteHeader(http.StatusNotFound)
+					return w.Result(), nil
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.WriteString(tt.body)
+				return w.Result(), nil
+			}
+
+			got, err := k.EventRecover("user", []string{"AAAA-BBBB"}, "new", tt.newPassword2)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantKey, got)
+			assert.Equal(t, tt.wantToken, k.token)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package uiapi
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
+	"github.com/playmixer/secret-keeper/pkg/certpin"
+	"github.com/playmixer/secret-keeper/pkg/sealbox"
+)
+
+// sessionParams параметры вывода ключа сессии из пароля.
+var sessionParams = sealbox.DefaultParams
+
+// tSession сессия, которая хранится на устройстве зашифрованной паролем пользователя.
+type tSession struct {
+	Login       string `json:"login"`
+	AccessToken string `json:"access_token"`
+}
+
+// saveSession шифрует текущую сессию паролем и сохраняет для входа без сервера.
+// Если вход без сервера выключен, удаляет сессию, сохраненную раньше.
+// Ошибка только пишется в журнал: вход с сервером от нее не зависит.
+func (k *keepClient) saveSession(password string) {
+	if !k.offlineLogin {
+		k.forgetSession()
+		return
+	}
+	data, err := json.Marshal(tSession{Login: k.login, AccessToken: k.token})
+	if err != nil {
+		k.log.Error("failed marshal session", zap.Error(err))
+		return
+	}
+	sealed, err := sealbox.Seal(password, data, sessionParams)
+	if err != nil {
+		k.log.Error("failed seal session", zap.Error(err))
+		return
+	}
+	if err := k.store.SaveSession(k.login, sealed); err != nil {
+		k.log.Error("failed save session", zap.Error(err))
+	}
+}
+
+// offlineSignIn вход по сохраненной сессии, пароль проверяется расшифровкой сессии.
+func (k *keepClient) offlineSignIn(login, password string) (string, error) {
+	sealed, err := k.store.Session(login)
+	if err != nil {
+		return "", fmt.Errorf("failed read session: %w", err)
+	}
+	data, err := sealbox.Open(password, sealed)
+	if errors.Is(err, sealbox.ErrPasswordNotValid) {
+		return "", codeError(rest.CodeInvalidCredentials)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed open session: %w", err)
+	}
+
+	session := tSession{}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return "", fmt.Errorf("failed unmarshal session: %w", err)
+	}
+	if session.Login != login || session.AccessToken == "" {
+		return "", errors.New("сохраненная сессия повреждена, войдите при связи с сервером")
+	}
+	return session.AccessToken, nil
+}
+
+// forgetSession удаляет сохраненную сессию, войти без сервера можно будет только после входа с сервером.
+func (k *keepClient) forgetSession() {
+	if err := k.store.RemoveSession(k.login); err != nil {
+		k.log.Error("failed remove session", zap.Error(err))
+	}
+}
+
+// SessionExpired сервер отклонил сессию, для синхронизации нужно войти заново.
+func (k *keepClient) SessionExpired() bool {
+	return k.sessionExpired.Load()
+}
+
+// Unreachable запрос не дошел до сервера или сервер перегружен. Отказ в доверии сертификату
+// сюда не относится: о нем нужно спросить пользователя.
+func Unreachable(err error) bool {
+	if errors.Is(err, ErrOffline) {
+		return true
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Status {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) || errors.Is(err, certpin.ErrPinChanged) {
+		return false
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// unauthorized сервер не принял токен.
+func unauthorized(err error) bool {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == rest.CodeUnauthorized
+	}
+	return status.Code(err) == codes.Unauthenticated
+}
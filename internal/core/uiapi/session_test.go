@@ -0,0 +1,231 @@
+package uiapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/internal/adapter/storage/file"
+	"github.com/playmixer/secret-keeper/pkg/sealbox"
+)
+
+func Test_keepClient_offlineLogin(t *testing.T) {
+	sessionParams = sealbox.Params{Memory: 64, Time: 1, Threads: 1}
+	defer func() {
+		sessionParams = sealbox.DefaultParams
+	}()
+
+	online := func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		_, _ = w.WriteString(`{"status":true,"access_token":"saved"}`)
+		return w.Result(), nil
+	}
+	offline := func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+		return nil, fmt.Errorf("failed request: %w", ErrOffline)
+	}
+	rejected := func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.WriteString(`{"code":"invalid_credentials","status":401}`)
+		return w.Result(), nil
+	}
+
+	tests := []struct {
+		name        string
+		login       string
+		password    string
+		fRequest    keepRequest
+		wantToken   string
+		wantErr     string
+		wantOffline bool
+	}{
+		{
+			name:        "offline",
+			login:       "user",
+			password:    "password",
+			fRequest:    offline,
+			wantToken:   "saved",
+			wantOffline: true,
+		},
+		{
+			name:     "offline wrong password",
+			login:    "user",
+			password: "wrong",
+			fRequest: offline,
+			wantErr:  errorMessages[rest.CodeInvalidCredentials],
+		},
+		{
+			name:     "offline without session",
+			login:    "other",
+			password: "password",
+			fRequest: offline,
+			wantErr:  ErrOffline.Error(),
+		},
+		{
+			name:     "server rejects password",
+			login:    "user",
+			password: "password",
+			fRequest: rejected,
+			wantErr:  errorMessages[rest.CodeInvalidCredentials],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := file.Init(file.SetPath("./test_session"))
+			require.NoError(t, err)
+			defer func() {
+				_ = os.RemoveAll("./test_session")
+			}()
+			k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false), SetAuthMode(AuthModePassword),
+				SetOfflineLogin(true))
+			require.NoError(t, err)
+			k.newRequest = online
+			require.NoError(t, k.EventAuthorization("user", "password"))
+			require.NoError(t, s.Close())
+
+			// перезапуск клиента.
+			k, err = New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false), SetAuthMode(AuthModePassword),
+				SetOfflineLogin(true))
+			require.NoError(t, err)
+			k.newRequest = tt.fRequest
+			err = k.EventAuthorization(tt.login, tt.password)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Empty(t, k.token)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantToken, k.token)
+			assert.Equal(t, tt.wantOffline, k.Offline())
+
+			// закрытие клиента сессию не удаляет, удаляет выход из учетной записи.
+			assert.NoError(t, k.EventClose())
+			assert.NoError(t, k.EventAuthorization(tt.login, tt.password))
+			assert.NoError(t, k.EventLogout())
+			assert.Error(t, k.EventAuthorization(tt.login, tt.password))
+
+			// вход с выключенным SetOfflineLogin удаляет сессию, сохраненную раньше.
+			k.newRequest = online
+			assert.NoError(t, k.EventAuthorization(tt.login, tt.password))
+			assert.NoError(t, k.EventClose())
+			k.offlineLogin = false
+			assert.NoError(t, k.EventAuthorization(tt.login, tt.password))
+			assert.NoError(t, k.EventClose())
+			k.newRequest = offline
+			assert.Error(t, k.EventAuthorization(tt.login, tt.password))
+		})
+	}
+}
+
+func Test_keepClient_EventLock(t *testing.T) {
+	s, err := file.Init(file.SetPath("./test_lock"))
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll("./test_lock")
+	}()
+	k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false), SetAuthMode(AuthModePassword))
+	require.NoError(t, err)
+	k.newRequest = func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		if !strings.Contains(string(*data), `"password":"password"`) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.WriteString(`{"code":"invalid_credentials","status":401}`)
+			return w.Result(), nil
+		}
+		_, _ = w.WriteString(`{"status":true,"access_token":"token"}`)
+		return w.Result(), nil
+	}
+
+	assert.Error(t, k.EventUnlock("password"))
+	require.NoError(t, k.EventAuthorization("user", "password"))
+	_, err = k.EventNewText(0, "title", "text")
+	require.NoError(t, err)
+
+	require.NoError(t, k.EventLock())
+	assert.Empty(t, k.token)
+	assert.Equal(t, "user", k.LockedLogin())
+	assert.True(t, k.Locked("user"))
+	items, err := s.GetAll()
+	require.NoError(t, err)
+	assert.Empty(t, *items)
+	assert.Empty(t, s.Operations())
+
+	assert.Error(t, k.EventUnlock("wrong"))
+	assert.Equal(t, "user", k.LockedLogin())
+	assert.True(t, k.Locked("user"))
+	require.NoError(t, k.EventUnlock("password"))
+	assert.Empty(t, k.LockedLogin())
+	assert.False(t, k.Locked("user"))
+	assert.Equal(t, "token", k.token)
+	items, err = s.GetAll()
+	require.NoError(t, err)
+	assert.Len(t, *items, 1)
+	assert.Len(t, s.Operations(), 1)
+
+	// вход, например подкомандой, отметку блокировки не снимает, снимает только разблокировка.
+	require.NoError(t, k.EventLock())
+	require.NoError(t, k.EventAuthorization("user", "password"))
+	assert.True(t, k.Locked("user"))
+	require.NoError(t, k.EventClose())
+	assert.Error(t, k.EventUnlockUser("user", "wrong"))
+	assert.True(t, k.Locked("user"))
+	require.NoError(t, k.EventUnlockUser("user", "password"))
+	assert.False(t, k.Locked("user"))
+	assert.Empty(t, k.LockedLogin())
+}
+
+func Test_keepClient_EventLock_stopsWorker(t *testing.T) {
+	s, err := file.Init(file.SetPath("./test_lock_worker"))
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll("./test_lock_worker")
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	k, err := New(ctx, s, zap.NewNop(), SetAuthMode(AuthModePassword))
+	require.NoError(t, err)
+	syncing := make(chan struct{}, 1)
+	k.newRequest = func(ctx context.Context, _, url string, _ *[]byte) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		if !strings.HasSuffix(url, "/login") {
+			// поток событий и синхронизация висят, пока блокировка не отменит запросы сессии.
+			if !strings.HasSuffix(url, "/events") {
+				select {
+				case syncing <- struct{}{}:
+				default:
+				}
+			}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		_, _ = w.WriteString(`{"status":true,"access_token":"token"}`)
+		return w.Result(), nil
+	}
+	assert.Nil(t, k.cancelSession)
+
+	require.NoError(t, k.EventAuthorization("user", "password"))
+	assert.NotNil(t, k.cancelSession)
+	k.requestSync()
+	<-syncing
+
+	// блокировка отменяет идущую синхронизацию, ждет остановки воркера и только потом закрывает хранилище.
+	require.NoError(t, k.EventLock())
+	assert.Nil(t, k.cancelSession)
+	_, err = s.NewData(0, 0, "title", models.TEXT, &[]byte{})
+	assert.ErrorIs(t, err, file.ErrNotOpen)
+
+	require.NoError(t, k.EventUnlock("password"))
+	assert.NotNil(t, k.cancelSession)
+	require.NoError(t, k.EventLogout())
+	assert.Nil(t, k.cancelSession)
+}
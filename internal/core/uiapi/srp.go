@@ -0,0 +1,181 @@
+package uiapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/pkg/srp"
+)
+
+// Значения AUTH_MODE.
+const (
+	// AuthModeSRP вход по SRP-6a, пароль не покидает клиента.
+	AuthModeSRP = "srp"
+	// AuthModePassword устаревший вход с передачей пароля серверу.
+	AuthModePassword = "password"
+)
+
+// newSecret новый пароль в том виде, в котором он уходит на сервер:
+// открытый текст в режиме password, соль и верификатор в режиме srp.
+type newSecret struct {
+	Password string
+	Salt     []byte
+	Verifier []byte
+}
+
+// currentSecret подтверждение текущего пароля: открытый текст или доказательство SRP.
+type currentSecret struct {
+	Password string
+	Session  string
+	Proof    []byte
+}
+
+// SetAuthMode способ входа на сервер, по умолчанию SRP.
+func SetAuthMode(mode string) option {
+	return func(kc *keepClient) {
+		if mode != "" {
+			kc.authMode = mode
+		}
+	}
+}
+
+func (k *keepClient) useSRP() bool {
+	return k.authMode != AuthModePassword
+}
+
+// newSecret данные нового пароля для сервера. В режиме srp сервер не видит пароль
+// и не может его проверить, поэтому проверка делается здесь.
+func (k *keepClient) newSecret(ctx context.Context, login, password string) (newSecret, error) {
+	if !k.useSRP() {
+		return newSecret{Password: password}, nil
+	}
+	if password == "" {
+		return newSecret{}, errors.New(errorMessagePasswordEmpty)
+	}
+	if err := k.checkPassword(ctx, login, password); err != nil {
+		return newSecret{}, err
+	}
+	salt, err := srp.NewSalt()
+	if err != nil {
+		return newSecret{}, fmt.Errorf("failed create srp salt: %w", err)
+	}
+	return newSecret{Salt: salt, Verifier: srp.Verifier(login, password, salt)}, nil
+}
+
+// currentSecret подтверждение текущего пароля вошедшего пользователя.
+// В режиме srp открывается сессия SRP, доказательство из нее сервер проверяет вместо пароля.
+func (k *keepClient) currentSecret(ctx context.Context, password string) (currentSecret, error) {
+	if !k.useSRP() {
+		return currentSecret{Password: password}, nil
+	}
+	client, challenge, err := k.srpStart(ctx, k.login, password)
+	if err != nil {
+		return currentSecret{}, err
+	}
+	proof, err := client.Proof(challenge.Salt, challenge.ServerPublic)
+	if err != nil {
+		return currentSecret{}, fmt.Errorf("failed srp proof: %w", err)
+	}
+	return currentSecret{Session: challenge.Session, Proof: proof}, nil
+}
+
+// srpStart первый шаг SRP: ключи клиента и ответ сервера.
+func (k *keepClient) srpStart(ctx context.Context, login, password string) (*srp.Client, *tSRPInitResponse, error) {
+	client, err := srp.NewClient(login, password)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed create srp client: %w", err)
+	}
+	srpInit := k.srpInit
+	if k.rpc != nil {
+		srpInit = k.rpcSRPInit
+	}
+	challenge, err := srpInit(ctx, login, client.PublicKey())
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, challenge, nil
+}
+
+// srpSignIn вход по SRP. Токен принимается только после проверки доказательства сервера:
+// иначе клиент мог бы говорить с сервером, не знающим его верификатор.
+func (k *keepClient) srpSignIn(ctx context.Context, login, password string) (string, error) {
+	client, challenge, err := k.srpStart(ctx, login, password)
+	if err != nil {
+		return "", err
+	}
+	proof, err := client.Proof(challenge.Salt, challenge.ServerPublic)
+	if err != nil {
+		return "", fmt.Errorf("failed srp proof: %w", err)
+	}
+
+	srpVerify := k.srpVerify
+	if k.rpc != nil {
+		srpVerify = k.rpcSRPVerify
+	}
+	result, err := srpVerify(ctx, challenge.Session, proof)
+	if err != nil {
+		return "", err
+	}
+	if err := client.VerifyServer(result.ServerProof); err != nil {
+		k.log.Error("srp server proof not valid", zap.Error(err))
+		return "", errors.New(errorMessageServerProof)
+	}
+	return result.AccessToken, nil
+}
+
+func (k *keepClient) srpInit(ctx context.Context, login string, clientPublic []byte) (*tSRPInitResponse, error) {
+	result := tSRPInitResponse{}
+	err := k.postJSON(ctx, "/api/v0/auth/srp/init", tSRPInitRequest{Login: login, ClientPublic: clientPublic}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (k *keepClient) srpVerify(ctx context.Context, session string, proof []byte) (*tSRPVerifyResponse, error) {
+	result := tSRPVerifyResponse{}
+	err := k.postJSON(ctx, "/api/v0/auth/srp/verify", tSRPVerifyRequest{Session: session, Proof: proof}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// postJSON POST запрос с телом req, ответ 200 разбирается в result.
+func (k *keepClient) postJSON(ctx context.Context, path string, req any, result any) error {
+	bReq, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed marshal request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	r, err := k.newRequest(ctx, http.MethodPost, k.apiURL+path, &bReq)
+	if err != nil {
+		k.log.Error("failed create request", zap.Error(err))
+		return fmt.Errorf("failed create request: %w", err)
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			k.log.Error(errMessageFailedCloseBody, zap.Error(err))
+		}
+	}()
+
+	res, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf(formatStringError, errMessageFailedReadBody, err)
+	}
+	if r.StatusCode != http.StatusOK {
+		return responseError(r.StatusCode, res)
+	}
+	if err := json.Unmarshal(res, result); err != nil {
+		return fmt.Errorf(formatStringError, errMessageFailedUnmarshal, err)
+	}
+	return nil
+}
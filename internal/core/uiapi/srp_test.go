@@ -0,0 +1,155 @@
+package uiapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/storage/file"
+	"github.com/playmixer/secret-keeper/pkg/srp"
+)
+
+// srpServer сервер SRP для тестов клиента, badProof подменяет доказательство сервера.
+func srpServer(t *testing.T, password string, badProof bool) keepRequest {
+	t.Helper()
+
+	salt, err := srp.NewSalt()
+	require.NoError(t, err)
+	verifier := srp.Verifier("user", password, salt)
+	var server *srp.Server
+
+	return func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		switch {
+		case strings.HasSuffix(url, "/api/v0/auth/srp/init"):
+			req := tSRPInitRequest{}
+			require.NoError(t, json.Unmarshal(*data, &req))
+			server, err = srp.NewServer(req.Login, salt, verifier, req.ClientPublic)
+			require.NoError(t, err)
+			require.NoError(t, json.NewEncoder(w).Encode(tSRPInitResponse{
+				Session:      "session",
+				Salt:         salt,
+				ServerPublic: server.PublicKey(),
+			}))
+		case strings.HasSuffix(url, "/api/v0/auth/srp/verify"):
+			req := tSRPVerifyRequest{}
+			require.NoError(t, json.Unmarshal(*data, &req))
+			m2, err := server.Verify(req.Proof)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.WriteString(`{"code":"invalid_credentials","status":401}`)
+				return w.Result(), nil
+			}
+			if badProof {
+				m2[0] ^= 1
+			}
+			require.NoError(t, json.NewEncoder(w).Encode(tSRPVerifyResponse{AccessToken: "token", ServerProof: m2}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+		return w.Result(), nil
+	}
+}
+
+func Test_keepClient_srpAuthorization(t *testing.T) {
+	tests := []struct {
+		name      string
+		password  string
+		badProof  bool
+		wantToken string
+		wantErr   string
+	}{
+		{name: "ok", password: "password", wantToken: "token"},
+		{name: "wrong password", password: "wrong", wantErr: "неверные данные авторизации"},
+		{name: "server proof not valid", password: "password", badProof: true, wantErr: errorMessageServerProof},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := file.Init(file.SetPath(t.TempDir()))
+			assert.NoError(t, err)
+			k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
+			assert.NoError(t, err)
+			k.newRequest = srpServer(t, tt.password, tt.badProof)
+
+			err = k.EventAuthorization("user", "password")
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantToken, k.token)
+		})
+	}
+}
+
+func Test_keepClient_srpRegistration(t *testing.T) {
+	s, err := file.Init(file.SetPath(t.TempDir()))
+	assert.NoError(t, err)
+	k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
+	assert.NoError(t, err)
+
+	req := tRegistrationRequest{}
+	k.newRequest = func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		if strings.HasSuffix(url, "/api/v0/auth/policy") {
+			_, _ = w.WriteString(`{"registration_mode":"invite","password_min_length":8,"password_min_strength":2}`)
+			return w.Result(), nil
+		}
+		assert.NoError(t, json.Unmarshal(*data, &req))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.WriteString(`{"status":true}`)
+		return w.Result(), nil
+	}
+
+	_, err = k.EventRegistration("user", "kqzmvbtr-31", "kqzmvbtr-31", false, "ABCD-EFGH")
+	assert.NoError(t, err)
+	assert.Empty(t, req.Password)
+	assert.Equal(t, "ABCD-EFGH", req.Invite)
+	assert.True(t, srp.ValidVerifier(req.Salt, req.Verifier))
+	assert.Equal(t, srp.Verifier("user", "kqzmvbtr-31", req.Salt), req.Verifier)
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  string
+	}{
+		{name: "empty", password: "", wantErr: errorMessagePasswordEmpty},
+		{name: "short", password: "k#9zQ", wantErr: "пароль слишком короткий, нужно не меньше 8 символов"},
+		{name: "contains login", password: "my-user-pass", wantErr: "пароль не должен содержать логин"},
+		{name: "weak", password: "password", wantErr: "пароль слишком простой: добавьте слов, цифр или символов"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := k.EventRegistration("user", tt.password, tt.password, false, "")
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func Test_keepClient_srpRegistrationOldServer(t *testing.T) {
+	s, err := file.Init(file.SetPath(t.TempDir()))
+	assert.NoError(t, err)
+	k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
+	assert.NoError(t, err)
+
+	// сервер без правил регистрации: пароль проверяется только на пустоту.
+	k.newRequest = func(_ context.Context, method, url string, data *[]byte) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		if method == http.MethodGet {
+			w.WriteHeader(http.StatusNotFound)
+			return w.Result(), nil
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.WriteString(`{"status":true}`)
+		return w.Result(), nil
+	}
+	_, err = k.EventRegistration("user", "1", "1", false, "")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,93 @@
+package uiapi
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/pkg/sshkey"
+)
+
+// sshKey ключ SSH с открытым ключом и отпечатком, вычисленными из закрытого ключа.
+func sshKey(title, privateKey, comment, passphrase string) ([]byte, error) {
+	key, err := sshkey.Describe(privateKey, comment, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed read ssh key: %w", err)
+	}
+	data, err := json.Marshal(&models.SSHKey{
+		Title:       title,
+		PrivateKey:  key.PrivateKey,
+		PublicKey:   key.PublicKey,
+		Fingerprint: key.Fingerprint,
+		Comment:     comment,
+		Passphrase:  passphrase,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed marshal ssh key: %w", err)
+	}
+	return data, nil
+}
+
+// EventNewSSHKey новый ключ SSH, открытый ключ и отпечаток вычисляются из закрытого ключа.
+func (k *keepClient) EventNewSSHKey(eID uint, title, privateKey, comment, passphrase string) (
+	*models.FileMetaDataItem, error,
+) {
+	data, err := sshKey(title, privateKey, comment, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := k.store.NewData(eID, 0, title, models.SSH_KEY, &data)
+	if err != nil {
+		k.log.Error("failed create ssh key", zap.Error(err))
+		return nil, fmt.Errorf("failed create ssh key: %w", err)
+	}
+
+	if err := k.queueChange(models.BatchCreate, m.ID); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (k *keepClient) EventGetSSHKey(id int64) (*models.SSHKey, error) {
+	_, data, err := k.store.GetData(id)
+	if err != nil {
+		k.log.Error("failed get ssh key", zap.Error(err))
+		return nil, fmt.Errorf("failed get ssh key from store: %w", err)
+	}
+
+	key := &models.SSHKey{}
+	if err := json.Unmarshal(*data, key); err != nil {
+		return nil, fmt.Errorf("failed unmarshal data: %w", err)
+	}
+
+	return key, nil
+}
+
+func (k *keepClient) EventEditSSHKey(id int64, title, privateKey, comment, passphrase string) error {
+	data, err := sshKey(title, privateKey, comment, passphrase)
+	if err != nil {
+		return err
+	}
+
+	m, _, err := k.store.GetData(id)
+	if err != nil {
+		return fmt.Errorf("failed get data from store id=`%v`: %w", id, err)
+	}
+	m.Title = title
+	m.UpdateDT = k.store.UpdateDate()
+
+	if err := k.store.EditData(id, m, &data); err != nil {
+		k.log.Error("failed edit ssh key", zap.Error(err))
+		return fmt.Errorf("failed edit ssh key: %w", err)
+	}
+
+	return k.queueChange(models.BatchUpdate, id)
+}
+
+func (k *keepClient) EventDeleteSSHKey(id int64) error {
+	return k.eventDeleteLocal(id)
+}
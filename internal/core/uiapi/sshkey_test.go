@@ -0,0 +1,52 @@
+package uiapi
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/internal/adapter/storage/file"
+	"github.com/playmixer/secret-keeper/pkg/sshkey"
+)
+
+func Test_keepClient_EventNewSSHKey(t *testing.T) {
+	s, err := file.Init(file.SetPath("./test_sshkey"))
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll("./test_sshkey")
+	}()
+	require.NoError(t, s.Open("user"))
+	k, err := New(context.TODO(), s, zap.NewNop(), SetEnableWorker(false))
+	require.NoError(t, err)
+
+	key, err := sshkey.Generate(sshkey.Ed25519, 0, "", "secret")
+	require.NoError(t, err)
+
+	_, err = k.EventNewSSHKey(0, "bad", "not a key", "", "")
+	assert.Error(t, err)
+
+	m, err := k.EventNewSSHKey(0, "deploy", key.PrivateKey, "deploy@ci", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, models.SSH_KEY, m.DataType)
+	assert.Len(t, s.Operations(), 1)
+
+	got, err := k.EventGetSSHKey(m.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.Fingerprint, got.Fingerprint)
+	assert.Equal(t, key.PublicKey+" deploy@ci", got.PublicKey)
+	assert.Equal(t, "secret", got.Passphrase)
+
+	other, err := sshkey.Generate(sshkey.Ed25519, 0, "", "")
+	require.NoError(t, err)
+	require.NoError(t, k.EventEditSSHKey(m.ID, "deploy2", other.PrivateKey, "", ""))
+	got, err = k.EventGetSSHKey(m.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "deploy2", got.Title)
+	assert.Equal(t, other.Fingerprint, got.Fingerprint)
+	assert.Error(t, k.EventEditSSHKey(m.ID, "deploy2", key.PrivateKey, "", "wrong"))
+}
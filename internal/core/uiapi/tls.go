@@ -0,0 +1,94 @@
+package uiapi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/playmixer/secret-keeper/pkg/certpin"
+)
+
+var ErrServerCANotValid = errors.New("server ca file has no certificates")
+
+// loadTLS читает сертификат клиента, CA сервера и запомненные ключи серверов.
+func (k *keepClient) loadTLS() error {
+	if k.clientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(k.clientCertFile, k.clientKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed load client certificate: %w", err)
+		}
+		k.clientCert = &cert
+	}
+
+	if k.caFile != "" {
+		pem, err := os.ReadFile(k.caFile)
+		if err != nil {
+			return fmt.Errorf("failed read server ca: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return ErrServerCANotValid
+		}
+		k.rootCAs = pool
+	}
+
+	if k.pinFile != "" {
+		pins, err := certpin.Open(k.pinFile)
+		if err != nil {
+			return fmt.Errorf("failed open pin file: %w", err)
+		}
+		k.pins = pins
+	}
+
+	if k.insecure {
+		k.log.Warn("server certificate verification is disabled, the connection can be intercepted")
+	}
+	return nil
+}
+
+// tlsConfig настройки TLS подключения к серверу, server - адрес, под которым запоминается ключ сервера.
+// Ключ сверяется и без проверки сертификата (SetInsecure).
+func (k *keepClient) tlsConfig(server string) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    k.rootCAs,
+		// InsecureSkipVerify включается только явно, о нем предупреждают журнал и стартовая страница.
+		InsecureSkipVerify: k.insecure,
+	}
+	if k.clientCert != nil {
+		cfg.Certificates = []tls.Certificate{*k.clientCert}
+	}
+	if k.pins != nil {
+		cfg.VerifyConnection = k.pins.VerifyConnection(server)
+	}
+	return cfg
+}
+
+// Insecure проверка сертификата сервера отключена.
+func (k *keepClient) Insecure() bool {
+	return k.insecure
+}
+
+// EventPendingPin смена ключа сервера, которую должен подтвердить пользователь, nil - нет.
+func (k *keepClient) EventPendingPin() *certpin.Change {
+	if k.pins == nil {
+		return nil
+	}
+	return k.pins.Pending()
+}
+
+// EventTrustServer решение пользователя по смене ключа сервера: trust - доверять новому ключу.
+func (k *keepClient) EventTrustServer(trust bool) error {
+	if k.pins == nil {
+		return nil
+	}
+	if err := k.pins.Resolve(trust); err != nil {
+		return fmt.Errorf("failed save server pin: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package uiapi
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/pkg/certpin"
+)
+
+func Test_keepClient_tlsConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	srvURL, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "server.crt")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+	pinFile := filepath.Join(dir, "known_servers")
+	require.NoError(t, os.WriteFile(pinFile, []byte(srvURL.Host+" sha256/old=\n"), 0o600))
+
+	tests := []struct {
+		name    string
+		options []option
+		wantErr bool
+		pending bool
+	}{
+		{
+			name:    "untrusted certificate",
+			wantErr: true,
+		},
+		{
+			name:    "server ca",
+			options: []option{SetServerCA(caFile)},
+		},
+		{
+			name:    "insecure",
+			options: []option{SetInsecure(true)},
+		},
+		{
+			name:    "pin changed",
+			options: []option{SetServerCA(caFile), SetPinFile(pinFile)},
+			wantErr: true,
+			pending: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append([]option{SetEnableWorker(false), SetAPIHost(srv.URL)}, tt.options...)
+			k, err := New(context.Background(), nil, zap.NewNop(), options...)
+			require.NoError(t, err)
+
+			res, err := k.newRequest(context.Background(), http.MethodGet, srv.URL, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.NoError(t, res.Body.Close())
+			}
+			assert.Equal(t, tt.pending, k.EventPendingPin() != nil)
+		})
+	}
+}
+
+func Test_keepClient_EventTrustServer(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	srvURL, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	pinFile := filepath.Join(t.TempDir(), "known_servers")
+	require.NoError(t, os.WriteFile(pinFile, []byte(srvURL.Host+" sha256/old=\n"), 0o600))
+	k, err := New(context.Background(), nil, zap.NewNop(),
+		SetEnableWorker(false), SetAPIHost(srv.URL), SetInsecure(true), SetPinFile(pinFile))
+	require.NoError(t, err)
+	assert.True(t, k.Insecure())
+
+	// ключ сверяется и без проверки сертификата.
+	_, err = k.newRequest(context.Background(), http.MethodGet, srv.URL, nil)
+	require.Error(t, err)
+	change := k.EventPendingPin()
+	require.NotNil(t, change)
+	assert.Equal(t, certpin.SPKI(srv.Certificate()), change.Presented)
+
+	require.NoError(t, k.EventTrustServer(true))
+	assert.Nil(t, k.EventPendingPin())
+	res, err := k.newRequest(context.Background(), http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	assert.NoError(t, res.Body.Close())
+
+	data, err := os.ReadFile(pinFile)
+	require.NoError(t, err)
+	assert.Equal(t, srvURL.Host+" "+change.Presented+"\n", string(data))
+}
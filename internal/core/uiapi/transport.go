@@ -0,0 +1,160 @@
+package uiapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/secret-keeper/pkg/breaker"
+	"github.com/playmixer/secret-keeper/pkg/certpin"
+)
+
+var (
+	// requestTimeout время на запрос к REST серверу вместе с чтением ответа.
+	requestTimeout = 30 * time.Second
+	dialTimeout    = 10 * time.Second
+
+	retryAttempts  = 3
+	retryBaseDelay = 200 * time.Millisecond
+	retryMaxDelay  = 2 * time.Second
+
+	// после breakerThreshold сбоев подряд клиент считается без связи и не ходит на сервер breakerCooldown.
+	breakerThreshold = 5
+	breakerCooldown  = 15 * time.Second
+
+	ErrOffline = errors.New("нет связи с сервером, повторите позже")
+)
+
+const (
+	maxIdleConns    = 10
+	idleConnTimeout = 90 * time.Second
+	keepAlive       = 30 * time.Second
+)
+
+// newHTTPClient общий клиент REST сервера: соединения переиспользуются между запросами.
+// Общего таймаута нет, чтобы не обрывать поток событий, срок запроса задает контекст.
+func newHTTPClient(tlsConfig *tls.Config) *http.Client {
+	tr := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     tlsConfig,
+		DialContext:         (&net.Dialer{Timeout: dialTimeout, KeepAlive: keepAlive}).DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        maxIdleConns,
+		IdleConnTimeout:     idleConnTimeout,
+		TLSHandshakeTimeout: dialTimeout,
+	}
+	return &http.Client{Transport: tr}
+}
+
+// initTransport общий HTTP клиент и выключатель, ключ сервера запоминается под адресом из API_ADDRESS.
+func (k *keepClient) initTransport() error {
+	apiURL, err := url.Parse(k.apiURL)
+	if err != nil {
+		return fmt.Errorf("failed parse api address: %w", err)
+	}
+	k.httpClient = newHTTPClient(k.tlsConfig(apiURL.Host))
+	k.breaker = breaker.New(breakerThreshold, breakerCooldown)
+	return nil
+}
+
+// Offline последние запросы к серверу не прошли или вход был без сервера, клиент работает с локальными данными.
+func (k *keepClient) Offline() bool {
+	return k.unlockedOffline.Load() || k.breaker != nil && k.breaker.Open()
+}
+
+func newRequest(k *keepClient) keepRequest {
+	return func(ctx context.Context, method, url string, data *[]byte) (*http.Response, error) {
+		if !k.breaker.Allow() {
+			return nil, ErrOffline
+		}
+		if data == nil {
+			data = &[]byte{}
+		}
+
+		attempts := 1
+		if isIdempotent(method) {
+			attempts = retryAttempts
+		}
+		for attempt := 1; ; attempt++ {
+			res, err := k.doRequest(ctx, method, url, *data)
+			failed := serverFailed(ctx, res, err)
+			if failed {
+				k.breaker.Failure()
+			} else {
+				k.breaker.Success()
+			}
+			if !failed || attempt >= attempts || !k.breaker.Allow() {
+				if err != nil {
+					return nil, fmt.Errorf(formatStringError, errMessageFailedRequest, err)
+				}
+				return res, nil
+			}
+
+			if res != nil {
+				if err := res.Body.Close(); err != nil {
+					k.log.Debug(errMessageFailedCloseBody, zap.Error(err))
+				}
+			}
+			k.log.Debug("retry request", zap.String("url", url), zap.Int("attempt", attempt), zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf(formatStringError, errMessageFailedRequest, ctx.Err())
+			case <-time.After(retryDelay(attempt)):
+			}
+		}
+	}
+}
+
+func (k *keepClient) doRequest(ctx context.Context, method, url string, data []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed create http client: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+k.token)
+	res, err := k.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed do request: %w", err)
+	}
+	return res, nil
+}
+
+// isIdempotent повтор запроса не меняет результат, такие запросы повторяются при сбоях.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// serverFailed сервер недоступен или перегружен. Отмена запроса пользователем и отказ
+// в доверии сертификату сбоем не считаются: повтор их не исправит.
+func serverFailed(ctx context.Context, res *http.Response, err error) bool {
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		if errors.As(err, &certErr) || errors.Is(err, certpin.ErrPinChanged) {
+			return false
+		}
+		return !errors.Is(ctx.Err(), context.Canceled)
+	}
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryDelay экспоненциальная пауза со случайным разбросом перед повтором attempt.
+func retryDelay(attempt int) time.Duration {
+	delay := min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	return delay/2 + rand.N(delay/2+1)
+}
@@ -0,0 +1,129 @@
+package uiapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_newRequest(t *testing.T) {
+	base := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = base })
+
+	tests := []struct {
+		name     string
+		method   string
+		failures int32
+		status   int
+		hits     int32
+	}{
+		{
+			name:     "get retried",
+			method:   http.MethodGet,
+			failures: 2,
+			status:   http.StatusOK,
+			hits:     3,
+		},
+		{
+			name:     "get retries exhausted",
+			method:   http.MethodGet,
+			failures: 5,
+			status:   http.StatusServiceUnavailable,
+			hits:     3,
+		},
+		{
+			name:     "post not retried",
+			method:   http.MethodPost,
+			failures: 1,
+			status:   http.StatusServiceUnavailable,
+			hits:     1,
+		},
+		{
+			name:   "client error not retried",
+			method: http.MethodGet,
+			status: http.StatusNotFound,
+			hits:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if hits.Add(1) <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				if tt.status != http.StatusOK {
+					w.WriteHeader(tt.status)
+				}
+			}))
+			defer srv.Close()
+
+			k, err := New(context.Background(), nil, zap.NewNop(),
+				SetEnableWorker(false), SetAPIHost(srv.URL), SetInsecure(true))
+			require.NoError(t, err)
+
+			res, err := k.newRequest(context.Background(), tt.method, srv.URL, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, res.StatusCode)
+			assert.NoError(t, res.Body.Close())
+			assert.Equal(t, tt.hits, hits.Load())
+		})
+	}
+}
+
+func Test_keepClient_Offline(t *testing.T) {
+	base := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = base })
+
+	var hits atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	k, err := New(context.Background(), nil, zap.NewNop(),
+		SetEnableWorker(false), SetAPIHost(srv.URL), SetInsecure(true))
+	require.NoError(t, err)
+
+	for !k.Offline() {
+		res, err := k.newRequest(context.Background(), http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		assert.NoError(t, res.Body.Close())
+	}
+	assert.Equal(t, int32(breakerThreshold), hits.Load())
+
+	// без связи запрос не отправляется.
+	_, err = k.newRequest(context.Background(), http.MethodGet, srv.URL, nil)
+	assert.ErrorIs(t, err, ErrOffline)
+	assert.Equal(t, int32(breakerThreshold), hits.Load())
+}
+
+func Test_newRequest_deadline(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	k, err := New(context.Background(), nil, zap.NewNop(),
+		SetEnableWorker(false), SetAPIHost(srv.URL), SetInsecure(true))
+	require.NoError(t, err)
+
+	// зависший сервер не держит запрос дольше срока контекста.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = k.newRequest(ctx, http.MethodPost, srv.URL, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
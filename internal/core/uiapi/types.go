@@ -22,11 +22,72 @@ type tSignInResponse struct {
 
 type tRegistrationRequest struct {
 	Login    string `json:"login"`
-	Password string `json:"password"`
+	Password string `json:"password,omitempty"`
+	Salt     []byte `json:"salt,omitempty"`
+	Verifier []byte `json:"verifier,omitempty"`
+	Recovery bool   `json:"recovery"`
+	Invite   string `json:"invite,omitempty"`
+}
+
+type tPolicyResponse struct {
+	RegistrationMode    string `json:"registration_mode"`
+	PasswordMinLength   int    `json:"password_min_length"`
+	PasswordMinStrength int    `json:"password_min_strength"`
 }
 
 type tRegistrationResponse struct {
+	RecoveryKey string `json:"recovery_key"`
+	tResultResponse
+}
+
+type tRecoverRequest struct {
+	Login       string `json:"login"`
+	RecoveryKey string `json:"recovery_key"`
+	NewPassword string `json:"new_password,omitempty"`
+	Salt        []byte `json:"salt,omitempty"`
+	Verifier    []byte `json:"verifier,omitempty"`
+}
+
+type tRecoverResponse struct {
+	AccessToken string `json:"access_token"`
+	RecoveryKey string `json:"recovery_key"`
+	tResultResponse
+}
+
+type tDeleteAccountRequest struct {
+	Password   string `json:"password,omitempty"`
+	SRPSession string `json:"srp_session,omitempty"`
+	SRPProof   []byte `json:"srp_proof,omitempty"`
+}
+
+type tChangePasswordRequest struct {
+	OldPassword string `json:"old_password,omitempty"`
+	NewPassword string `json:"new_password,omitempty"`
+	SRPSession  string `json:"srp_session,omitempty"`
+	SRPProof    []byte `json:"srp_proof,omitempty"`
+	Salt        []byte `json:"salt,omitempty"`
+	Verifier    []byte `json:"verifier,omitempty"`
+}
+
+type tSRPInitRequest struct {
+	Login        string `json:"login"`
+	ClientPublic []byte `json:"a"`
+}
+
+type tSRPInitResponse struct {
+	Session      string `json:"session"`
+	Salt         []byte `json:"salt"`
+	ServerPublic []byte `json:"b"`
+}
+
+type tSRPVerifyRequest struct {
+	Session string `json:"session"`
+	Proof   []byte `json:"m1"`
+}
+
+type tSRPVerifyResponse struct {
 	AccessToken string `json:"access_token"`
+	ServerProof []byte `json:"m2"`
 	tResultResponse
 }
 
@@ -40,5 +101,10 @@ type tHandlerGetData struct {
 
 type tHalderGetDatasResponse struct {
 	tResultResponse
-	Data []tHandlerGetData `json:"data"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+	Data       []tHandlerGetData `json:"data"`
+}
+
+type tEventRevision struct {
+	Revision int64 `json:"revision"`
 }
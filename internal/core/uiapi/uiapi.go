@@ -2,12 +2,20 @@ package uiapi
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/playmixer/secret-keeper/internal/adapter/api/grpc/pb"
+	"github.com/playmixer/secret-keeper/internal/adapter/api/rest"
 	"github.com/playmixer/secret-keeper/internal/adapter/models"
+	"github.com/playmixer/secret-keeper/pkg/breaker"
+	"github.com/playmixer/secret-keeper/pkg/certpin"
 	"go.uber.org/zap"
 )
 
@@ -15,6 +23,7 @@ type store interface {
 	UpdateDate() int64
 	Open(name string) error
 	Close() error
+	Drop() error
 	Get(id int64) (*models.FileMetaDataItem, error)
 	UpdMeta(m *models.FileMetaDataItem) error
 	GetAll() (*[]models.FileMetaDataItem, error)
@@ -25,27 +34,73 @@ type store interface {
 	EditData(id int64, m *models.FileMetaDataItem, data *[]byte) error
 	DelData(id int64) error
 	UploadFileToPath(id int64, path string) error
+	Operations() []models.PendingOperation
+	AddOperation(op models.PendingOperation) error
+	RemoveOperation(key string) error
+	Session(name string) ([]byte, error)
+	SaveSession(name string, data []byte) error
+	RemoveSession(name string) error
+	SetLocked(name string, locked bool) error
+	Locked(name string) bool
 }
 
 const (
 	kilobyte = 1024
 	byte8    = 8
+
+	batchMaxOperations = 100
+	listPageSize       = 500
 )
 
 var (
 	periodTickWorker time.Duration = 10 * time.Second
+
+	ErrPendingChanges = errors.New("изменения не отправлены на сервер")
 )
 
-type keepRequest func(method string, url string, data *[]byte) (*http.Response, error)
+// pendingChange локальное изменение, ожидающее отправки на сервер.
+type pendingChange struct {
+	op      rest.TBatchOperation
+	localID int64
+}
+
+type keepRequest func(ctx context.Context, method string, url string, data *[]byte) (*http.Response, error)
 
 type keepClient struct {
-	store         store
-	log           *zap.Logger
-	newRequest    keepRequest
-	apiURL        string
-	token         string
-	fileMaxSize   int64
-	workerEnabled bool
+	ctx             context.Context
+	store           store
+	rpc             pb.KeeperClient
+	log             *zap.Logger
+	clientCert      *tls.Certificate
+	rootCAs         *x509.CertPool
+	pins            *certpin.Store
+	httpClient      *http.Client
+	breaker         *breaker.Breaker
+	clientCertFile  string
+	clientKeyFile   string
+	caFile          string
+	pinFile         string
+	newRequest      keepRequest
+	syncCh          chan struct{}
+	session         context.Context
+	cancelSession   context.CancelFunc
+	workerDone      sync.WaitGroup
+	apiURL          string
+	rpcAddress      string
+	token           string
+	login           string
+	lockedLogin     string
+	authMode        string
+	fileMaxSize     int64
+	revision        atomic.Int64
+	workerEnabled   bool
+	rpcTLS          bool
+	insecure        bool
+	offlineLogin    bool
+	eventsConnected atomic.Bool
+	// unlockedOffline вход без сервера, сбрасывается первой удачной синхронизацией.
+	unlockedOffline atomic.Bool
+	sessionExpired  atomic.Bool
 }
 
 type option func(*keepClient)
@@ -68,13 +123,85 @@ func SetEnableWorker(enable bool) option {
 	}
 }
 
+// SetGRPC клиент работает с сервером по gRPC вместо REST, при пустом адресе остается REST.
+func SetGRPC(address string, useTLS bool) option {
+	return func(kc *keepClient) {
+		kc.rpcAddress = address
+		kc.rpcTLS = useTLS
+	}
+}
+
+// SetClientCertificate файлы сертификата устройства для серверов, которые требуют сертификат клиента.
+// Токены такого сервера действуют только с этим сертификатом. Пустой certFile - без сертификата.
+func SetClientCertificate(certFile, keyFile string) option {
+	return func(kc *keepClient) {
+		kc.clientCertFile = certFile
+		kc.clientKeyFile = keyFile
+	}
+}
+
+// SetServerCA файл CA, которому доверяется сертификат сервера в дополнение к системным, например самоподписанный
+// сертификат сервера. Пустой - только системные CA.
+func SetServerCA(caFile string) option {
+	return func(kc *keepClient) {
+		kc.caFile = caFile
+	}
+}
+
+// SetPinFile файл запомненных ключей серверов, пустой - ключ сервера не запоминается.
+// При первом подключении ключ сервера запоминается, смену ключа подтверждает пользователь.
+func SetPinFile(path string) option {
+	return func(kc *keepClient) {
+		kc.pinFile = path
+	}
+}
+
+// SetInsecure отключает проверку сертификата сервера. Только для отладки: подключение можно перехватить.
+func SetInsecure(insecure bool) option {
+	return func(kc *keepClient) {
+		kc.insecure = insecure
+	}
+}
+
+// SetOfflineLogin сохранять сессию, зашифрованную паролем, чтобы входить и читать данные без связи с сервером.
+func SetOfflineLogin(enable bool) option {
+	return func(kc *keepClient) {
+		kc.offlineLogin = enable
+	}
+}
+
+// SetConfig настройки подключения к серверу из конфигурации клиента.
+func SetConfig(cfg *Config) option {
+	return func(kc *keepClient) {
+		grpcAddress := ""
+		if cfg.Transport == TransportGRPC {
+			grpcAddress = cfg.GRPCAddress
+		}
+		for _, opt := range []option{
+			SetAPIHost(cfg.APIAddress),
+			SetGRPC(grpcAddress, cfg.GRPCTLS),
+			SetAuthMode(cfg.AuthMode),
+			SetClientCertificate(cfg.ClientCertFile, cfg.ClientKeyFile),
+			SetServerCA(cfg.CAFile),
+			SetPinFile(cfg.PinFile),
+			SetInsecure(cfg.Insecure),
+			SetOfflineLogin(cfg.OfflineLogin),
+		} {
+			opt(kc)
+		}
+	}
+}
+
 func New(ctx context.Context, store store, lgr *zap.Logger, options ...option) (*keepClient, error) {
 	k := &keepClient{
+		ctx:           ctx,
 		store:         store,
 		log:           lgr,
 		fileMaxSize:   kilobyte * byte8,
 		workerEnabled: true,
 		apiURL:        "https://localhost:8443",
+		authMode:      AuthModeSRP,
+		syncCh:        make(chan struct{}, 1),
 	}
 
 	k.newRequest = newRequest(k)
@@ -83,23 +210,88 @@ func New(ctx context.Context, store store, lgr *zap.Logger, options ...option) (
 		opt(k)
 	}
 
-	if k.workerEnabled {
-		go k.worker(ctx)
+	if err := k.loadTLS(); err != nil {
+		return nil, err
+	}
+	if err := k.initTransport(); err != nil {
+		return nil, err
+	}
+
+	if k.rpcAddress != "" {
+		var tlsConfig *tls.Config
+		if k.rpcTLS {
+			tlsConfig = k.tlsConfig(k.rpcAddress)
+		}
+		rpc, err := newRPCClient(ctx, k.rpcAddress, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		k.rpc = rpc
 	}
 
 	return k, nil
 }
 
+// startSession открывает контекст запросов вошедшего пользователя и запускает фоновую синхронизацию.
+func (k *keepClient) startSession() {
+	k.endSession()
+	ctx, cancel := context.WithCancel(k.ctx)
+	k.session = ctx
+	k.cancelSession = cancel
+	if !k.workerEnabled {
+		return
+	}
+	k.workerDone.Add(1)
+	go func() {
+		defer k.workerDone.Done()
+		k.listenEvents(ctx)
+	}()
+	k.workerDone.Add(1)
+	go func() {
+		defer k.workerDone.Done()
+		k.worker(ctx)
+	}()
+}
+
+// endSession отменяет запросы сессии, в том числе идущую синхронизацию, и ждет остановки воркера,
+// после этого хранилище можно закрыть: воркер в него уже не пишет.
+func (k *keepClient) endSession() {
+	if k.cancelSession == nil {
+		return
+	}
+	k.cancelSession()
+	k.workerDone.Wait()
+	k.session = nil
+	k.cancelSession = nil
+}
+
+// sessionContext контекст запросов вошедшего пользователя, отменяется выходом и блокировкой.
+func (k *keepClient) sessionContext() context.Context {
+	if k.session == nil {
+		return k.ctx
+	}
+	return k.session
+}
+
+// worker синхронизирует данные по событиям сервера,
+// опрос по таймеру используется пока поток событий недоступен или очередь изменений не отправлена.
 func (k *keepClient) worker(ctx context.Context) {
 	ticker := time.NewTicker(periodTickWorker)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			k.log.Info("Worker stoped")
 			return
 
+		case <-k.syncCh:
+			if k.canSync() {
+				k.log.Debug("синхронизация данных по событию")
+				k.updateStore(ctx)
+			}
+
 		case <-ticker.C:
-			if k.token != "" {
+			if k.canSync() && (!k.eventsConnected.Load() || k.PendingChanges() > 0) {
 				k.log.Debug("синхронизация данных")
 				k.updateStore(ctx)
 			}
@@ -107,28 +299,74 @@ func (k *keepClient) worker(ctx context.Context) {
 	}
 }
 
+// canSync пользователь вошел и сервер не отклонил его сессию.
+func (k *keepClient) canSync() bool {
+	return k.token != "" && !k.sessionExpired.Load()
+}
+
+// EventSync синхронизирует данные сразу, для клиентов без фоновой синхронизации, например команд командной строки.
+// Ошибка, если сервер недоступен или отклонил сессию, либо изменения остались в очереди.
+func (k *keepClient) EventSync() error {
+	if !k.canSync() {
+		return codeError(rest.CodeUnauthorized)
+	}
+	if err := k.syncStore(k.sessionContext()); err != nil {
+		return err
+	}
+	if n := k.PendingChanges(); n > 0 {
+		return fmt.Errorf("%w: %d", ErrPendingChanges, n)
+	}
+	return nil
+}
+
 func (k *keepClient) updateStore(ctx context.Context) {
-	exData, err := k.eventGetExternalMetaDatas()
+	if err := k.syncStore(ctx); err != nil {
+		k.log.Error("failed sync data", zap.Error(err))
+	}
+}
+
+// syncStore отправляет очередь изменений и забирает изменения с сервера.
+func (k *keepClient) syncStore(ctx context.Context) error {
+	// сначала отправляем свои изменения, чтобы версия с сервера их не перезаписала.
+	k.flushQueue(ctx)
+
+	exData, err := k.eventGetExternalMetaDatas(ctx)
 	if err != nil {
-		k.log.Error("failed get external data", zap.Error(err))
-		return
+		if unauthorized(err) {
+			k.sessionExpired.Store(true)
+		}
+		return fmt.Errorf("failed get external data: %w", err)
 	}
+	k.unlockedOffline.Store(false)
 
 	lData, err := k.store.GetAll()
 	if err != nil {
-		k.log.Error("failed get data from store", zap.Error(err))
-		return
+		return fmt.Errorf("failed get data from store: %w", err)
 	}
 	k.log.Debug("local data", zap.String("data", fmt.Sprint(*lData)))
 
+	// записи с неотправленными изменениями не трогаем, их версия уйдет на сервер из очереди.
+	pending := k.pendingLocalIDs()
+	queued := false
+	enqueue := func(action models.BatchAction, localID int64) {
+		if err := k.enqueue(action, localID); err != nil {
+			k.log.Error("failed queue change", zap.Error(err), zap.Int64("local_id", localID))
+			return
+		}
+		queued = true
+	}
+
 loopExternal:
 	for _, e := range *exData {
 		for _, l := range *lData {
 			select {
 			case <-ctx.Done():
-				return
+				return fmt.Errorf("sync canceled: %w", ctx.Err())
 			default:
 				if e.ID == l.ExternalID {
+					if pending[l.ID] {
+						continue loopExternal
+					}
 					if l.IsDeleted || e.IsDeleted {
 						if !l.IsDeleted {
 							err = k.eventDeleteData(l.ID)
@@ -137,28 +375,22 @@ loopExternal:
 							}
 						}
 						if !e.IsDeleted {
-							err = k.eventDeleteExternalData(e.ID)
-							if err != nil {
-								k.log.Error("failed delete local data", zap.Error(err), zap.Uint("id", e.ID))
-							}
+							enqueue(models.BatchDelete, l.ID)
 						}
 						continue loopExternal
 					}
 					if l.UpdateDT < e.UpdatedDT {
 						k.log.Debug("remote newed", zap.Uint("external_id", e.ID), zap.Int64("local_id", l.ID))
-						err = k.updateLocalData(l.ID, e.ID)
+						err = k.updateLocalData(ctx, l.ID, e.ID)
 						if err != nil {
 							k.log.Error("failed update local data", zap.Error(err), zap.Uint("id", e.ID))
 							continue loopExternal
 						}
 					}
+					// изменение старой версии клиента, сделанное до появления очереди.
 					if l.UpdateDT > e.UpdatedDT {
 						k.log.Debug("local newed", zap.Uint("external_id", e.ID), zap.Int64("local_id", l.ID))
-						err = k.updateExternalData(l.ID, e.ID)
-						if err != nil {
-							k.log.Error("failed update external data", zap.Error(err), zap.Uint("id", e.ID))
-							continue loopExternal
-						}
+						enqueue(models.BatchUpdate, l.ID)
 					}
 					continue loopExternal
 				}
@@ -167,7 +399,7 @@ loopExternal:
 		// не нашли данные в локальном сторе, добавляем.
 		if !e.IsDeleted {
 			k.log.Debug("not found in local", zap.Uint("external_id", e.ID))
-			err := k.addLocalData(e.ID)
+			err := k.addLocalData(ctx, e.ID)
 			if err != nil {
 				k.log.Error("failed add local data", zap.Error(err), zap.Uint("id", e.ID))
 				continue loopExternal
@@ -180,7 +412,7 @@ loopLocal:
 		for _, e := range *exData {
 			select {
 			case <-ctx.Done():
-				return
+				return fmt.Errorf("sync canceled: %w", ctx.Err())
 			default:
 				if e.ID == l.ExternalID {
 					continue loopLocal
@@ -188,18 +420,19 @@ loopLocal:
 			}
 		}
 		// не нашли данные в удаленном сторе, добавляем.
-		if !l.IsDeleted {
-			err := k.addExternalData(l.ID)
-			if err != nil {
-				k.log.Error("failed add external data", zap.Error(err), zap.Int64("id", l.ID))
-				continue loopLocal
-			}
+		if !l.IsDeleted && !pending[l.ID] {
+			enqueue(models.BatchCreate, l.ID)
 		}
 	}
+
+	if queued {
+		k.flushQueue(ctx)
+	}
+	return nil
 }
 
-func (k *keepClient) updateLocalData(lID int64, eID uint) error {
-	exData, err := k.eventGetExternalData(eID)
+func (k *keepClient) updateLocalData(ctx context.Context, lID int64, eID uint) error {
+	exData, err := k.eventGetExternalData(ctx, eID)
 	if err != nil {
 		return fmt.Errorf("failed get external card: %w", err)
 	}
@@ -218,8 +451,8 @@ func (k *keepClient) updateLocalData(lID int64, eID uint) error {
 	return nil
 }
 
-func (k *keepClient) addLocalData(eID uint) error {
-	exData, err := k.eventGetExternalData(eID)
+func (k *keepClient) addLocalData(ctx context.Context, eID uint) error {
+	exData, err := k.eventGetExternalData(ctx, eID)
 	if err != nil {
 		return fmt.Errorf("failed get external card: %w", err)
 	}
@@ -232,35 +465,84 @@ func (k *keepClient) addLocalData(eID uint) error {
 	return nil
 }
 
-func (k *keepClient) updateExternalData(lID int64, eID uint) error {
+func (k *keepClient) localChange(action models.BatchAction, lID int64, eID uint) (*pendingChange, error) {
 	meta, data, err := k.store.GetData(lID)
 	if err != nil {
-		return fmt.Errorf("failed get local card: %w", err)
+		return nil, fmt.Errorf("failed get local data: %w", err)
 	}
 	k.log.Debug("meta", zap.String("data", fmt.Sprint(*meta)))
-	err = k.eventUpdExternalData(eID, meta.Title, data, meta.DataType, meta.UpdateDT)
-	if err != nil {
-		return errors.New("failed upd external data")
+	return &pendingChange{
+		localID: lID,
+		op: rest.TBatchOperation{
+			Action:   action,
+			ID:       eID,
+			Title:    meta.Title,
+			DataType: meta.DataType,
+			Data:     *data,
+			UpdateDT: meta.UpdateDT,
+		},
+	}, nil
+}
+
+// pushChanges отправляет накопленные локальные изменения пакетами. Обработанные сервером операции
+// убираются из очереди, в том числе отклоненные: повтор их не исправит.
+// Возвращает false, если сервер недоступен и операции остались в очереди.
+func (k *keepClient) pushChanges(ctx context.Context, changes []pendingChange) bool {
+	for start := 0; start < len(changes); start += batchMaxOperations {
+		select {
+		case <-ctx.Done():
+			return false
+		default:
+		}
+
+		chunk := changes[start:min(start+batchMaxOperations, len(changes))]
+		ops := make([]rest.TBatchOperation, 0, len(chunk))
+		for _, c := range chunk {
+			ops = append(ops, c.op)
+		}
+
+		results, err := k.eventBatchExternalData(ctx, &ops)
+		if err != nil {
+			k.log.Error("failed push batch", zap.Error(err), zap.Int("size", len(ops)))
+			return false
+		}
+		if len(*results) != len(chunk) {
+			k.log.Error("batch result size mismatch", zap.Int("want", len(chunk)), zap.Int("got", len(*results)))
+			return false
+		}
+
+		for i, r := range *results {
+			k.applyResult(chunk[i], r)
+			// операция убирается после записи ее результата: при сбое между ними повтор с тем же ключом
+			// не создаст второй секрет.
+			if chunk[i].op.Key == "" {
+				continue
+			}
+			if err := k.store.RemoveOperation(chunk[i].op.Key); err != nil {
+				k.log.Error("failed remove queued change", zap.Error(err), zap.Int64("local_id", chunk[i].localID))
+			}
+		}
 	}
-	return nil
+	return true
 }
 
-func (k *keepClient) addExternalData(lID int64) error {
-	meta, data, err := k.store.GetData(lID)
-	if err != nil {
-		return fmt.Errorf("failed get local card: %w", err)
+// applyResult сохраняет результат операции: созданной записи проставляется ID на сервере.
+func (k *keepClient) applyResult(change pendingChange, r rest.TBatchResult) {
+	if !r.Status {
+		k.log.Error("failed batch operation", zap.String("code", string(r.Code)), zap.String("error", r.Error),
+			zap.String("action", string(r.Action)), zap.Int64("local_id", change.localID))
+		return
 	}
-	k.log.Debug("meta", zap.String("data", fmt.Sprint(*meta)))
-	exData, err := k.eventAddExternalData(meta.Title, data, meta.DataType, meta.UpdateDT)
-	if err != nil {
-		return errors.New("failed upd external data")
+	if r.Action != models.BatchCreate {
+		return
 	}
-
-	meta.ExternalID = exData.ID
-	err = k.store.EditData(meta.ID, meta, data)
+	meta, err := k.store.Get(change.localID)
 	if err != nil {
-		return fmt.Errorf("failed create local data %w", err)
+		k.log.Error("failed get local data", zap.Error(err), zap.Int64("id", change.localID))
+		return
+	}
+	meta.ExternalID = r.ID
+	if err := k.store.UpdMeta(meta); err != nil {
+		k.log.Error("failed update local meta", zap.Error(err), zap.Int64("id", change.localID))
 	}
-
-	return nil
 }
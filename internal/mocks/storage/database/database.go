@@ -41,18 +41,76 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 	return m.recorder
 }
 
+// Batch mocks base method.
+func (m *MockStorage) Batch(ctx context.Context, userID uint, ops *[]models.BatchOperation) (*[]models.BatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Batch", ctx, userID, ops)
+	ret0, _ := ret[0].(*[]models.BatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Batch indicates an expected call of Batch.
+func (mr *MockStorageMockRecorder) Batch(ctx, userID, ops any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Batch", reflect.TypeOf((*MockStorage)(nil).Batch), ctx, userID, ops)
+}
+
+// ChangePassword mocks base method.
+func (m *MockStorage) ChangePassword(ctx context.Context, userID uint, old, next models.Credentials) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, userID, old, next)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockStorageMockRecorder) ChangePassword(ctx, userID, old, next any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockStorage)(nil).ChangePassword), ctx, userID, old, next)
+}
+
+// CreateInvites mocks base method.
+func (m *MockStorage) CreateInvites(ctx context.Context, invites []models.Invite) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateInvites", ctx, invites)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateInvites indicates an expected call of CreateInvites.
+func (mr *MockStorageMockRecorder) CreateInvites(ctx, invites any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInvites", reflect.TypeOf((*MockStorage)(nil).CreateInvites), ctx, invites)
+}
+
 // DelSecret mocks base method.
-func (m *MockStorage) DelSecret(ctx context.Context, id uint) error {
+func (m *MockStorage) DelSecret(ctx context.Context, id, userID uint) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DelSecret", ctx, id)
+	ret := m.ctrl.Call(m, "DelSecret", ctx, id, userID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DelSecret indicates an expected call of DelSecret.
-func (mr *MockStorageMockRecorder) DelSecret(ctx, id any) *gomock.Call {
+func (mr *MockStorageMockRecorder) DelSecret(ctx, id, userID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DelSecret", reflect.TypeOf((*MockStorage)(nil).DelSecret), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DelSecret", reflect.TypeOf((*MockStorage)(nil).DelSecret), ctx, id, userID)
+}
+
+// DeleteUser mocks base method.
+func (m *MockStorage) DeleteUser(ctx context.Context, userID uint, old models.Credentials) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, userID, old)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockStorageMockRecorder) DeleteUser(ctx, userID, old any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockStorage)(nil).DeleteUser), ctx, userID, old)
 }
 
 // GetMetaDatasByUserID mocks base method.
@@ -70,19 +128,64 @@ func (mr *MockStorageMockRecorder) GetMetaDatasByUserID(ctx, userID any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMetaDatasByUserID", reflect.TypeOf((*MockStorage)(nil).GetMetaDatasByUserID), ctx, userID)
 }
 
+// GetRevision mocks base method.
+func (m *MockStorage) GetRevision(ctx context.Context, userID uint) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRevision", ctx, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRevision indicates an expected call of GetRevision.
+func (mr *MockStorageMockRecorder) GetRevision(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevision", reflect.TypeOf((*MockStorage)(nil).GetRevision), ctx, userID)
+}
+
 // GetSecret mocks base method.
-func (m *MockStorage) GetSecret(ctx context.Context, id uint) (*models.Secret, error) {
+func (m *MockStorage) GetSecret(ctx context.Context, id, userID uint) (*models.Secret, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSecret", ctx, id)
+	ret := m.ctrl.Call(m, "GetSecret", ctx, id, userID)
 	ret0, _ := ret[0].(*models.Secret)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetSecret indicates an expected call of GetSecret.
-func (mr *MockStorageMockRecorder) GetSecret(ctx, id any) *gomock.Call {
+func (mr *MockStorageMockRecorder) GetSecret(ctx, id, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecret", reflect.TypeOf((*MockStorage)(nil).GetSecret), ctx, id, userID)
+}
+
+// GetSessionEpoch mocks base method.
+func (m *MockStorage) GetSessionEpoch(ctx context.Context, userID uint) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionEpoch", ctx, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessionEpoch indicates an expected call of GetSessionEpoch.
+func (mr *MockStorageMockRecorder) GetSessionEpoch(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionEpoch", reflect.TypeOf((*MockStorage)(nil).GetSessionEpoch), ctx, userID)
+}
+
+// GetUserByID mocks base method.
+func (m *MockStorage) GetUserByID(ctx context.Context, userID uint) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", ctx, userID)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockStorageMockRecorder) GetUserByID(ctx, userID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecret", reflect.TypeOf((*MockStorage)(nil).GetSecret), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockStorage)(nil).GetUserByID), ctx, userID)
 }
 
 // GetUserByLogin mocks base method.
@@ -100,6 +203,21 @@ func (mr *MockStorageMockRecorder) GetUserByLogin(ctx, login any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByLogin", reflect.TypeOf((*MockStorage)(nil).GetUserByLogin), ctx, login)
 }
 
+// ListSecrets mocks base method.
+func (m *MockStorage) ListSecrets(ctx context.Context, userID uint, filter models.SecretFilter) (*[]models.Secret, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSecrets", ctx, userID, filter)
+	ret0, _ := ret[0].(*[]models.Secret)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSecrets indicates an expected call of ListSecrets.
+func (mr *MockStorageMockRecorder) ListSecrets(ctx, userID, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSecrets", reflect.TypeOf((*MockStorage)(nil).ListSecrets), ctx, userID, filter)
+}
+
 // NewSecret mocks base method.
 func (m *MockStorage) NewSecret(ctx context.Context, secret *models.Secret) (*models.Secret, error) {
 	m.ctrl.T.Helper()
@@ -115,31 +233,74 @@ func (mr *MockStorageMockRecorder) NewSecret(ctx, secret any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewSecret", reflect.TypeOf((*MockStorage)(nil).NewSecret), ctx, secret)
 }
 
+// RecoverPassword mocks base method.
+func (m *MockStorage) RecoverPassword(ctx context.Context, userID uint, recoveryHash string, next models.Credentials, newRecoveryHash string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecoverPassword", ctx, userID, recoveryHash, next, newRecoveryHash)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecoverPassword indicates an expected call of RecoverPassword.
+func (mr *MockStorageMockRecorder) RecoverPassword(ctx, userID, recoveryHash, next, newRecoveryHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecoverPassword", reflect.TypeOf((*MockStorage)(nil).RecoverPassword), ctx, userID, recoveryHash, next, newRecoveryHash)
+}
+
 // Registration mocks base method.
-func (m *MockStorage) Registration(ctx context.Context, login, passwordHash string) error {
+func (m *MockStorage) Registration(ctx context.Context, login string, cred models.Credentials, recoveryHash, inviteHash string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Registration", ctx, login, passwordHash)
+	ret := m.ctrl.Call(m, "Registration", ctx, login, cred, recoveryHash, inviteHash)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Registration indicates an expected call of Registration.
-func (mr *MockStorageMockRecorder) Registration(ctx, login, passwordHash any) *gomock.Call {
+func (mr *MockStorageMockRecorder) Registration(ctx, login, cred, recoveryHash, inviteHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Registration", reflect.TypeOf((*MockStorage)(nil).Registration), ctx, login, cred, recoveryHash, inviteHash)
+}
+
+// SetVerifier mocks base method.
+func (m *MockStorage) SetVerifier(ctx context.Context, userID uint, salt, verifier []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetVerifier", ctx, userID, salt, verifier)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetVerifier indicates an expected call of SetVerifier.
+func (mr *MockStorageMockRecorder) SetVerifier(ctx, userID, salt, verifier any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Registration", reflect.TypeOf((*MockStorage)(nil).Registration), ctx, login, passwordHash)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVerifier", reflect.TypeOf((*MockStorage)(nil).SetVerifier), ctx, userID, salt, verifier)
 }
 
 // UpdSecret mocks base method.
-func (m *MockStorage) UpdSecret(ctx context.Context, secret *models.Secret) (*models.Secret, error) {
+func (m *MockStorage) UpdSecret(ctx context.Context, secret *models.Secret, setFolder bool) (*models.Secret, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdSecret", ctx, secret)
+	ret := m.ctrl.Call(m, "UpdSecret", ctx, secret, setFolder)
 	ret0, _ := ret[0].(*models.Secret)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdSecret indicates an expected call of UpdSecret.
-func (mr *MockStorageMockRecorder) UpdSecret(ctx, secret any) *gomock.Call {
+func (mr *MockStorageMockRecorder) UpdSecret(ctx, secret, setFolder any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdSecret", reflect.TypeOf((*MockStorage)(nil).UpdSecret), ctx, secret, setFolder)
+}
+
+// UpdatePasswordHash mocks base method.
+func (m *MockStorage) UpdatePasswordHash(ctx context.Context, userID uint, oldHash, newHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasswordHash", ctx, userID, oldHash, newHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasswordHash indicates an expected call of UpdatePasswordHash.
+func (mr *MockStorageMockRecorder) UpdatePasswordHash(ctx, userID, oldHash, newHash any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdSecret", reflect.TypeOf((*MockStorage)(nil).UpdSecret), ctx, secret)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordHash", reflect.TypeOf((*MockStorage)(nil).UpdatePasswordHash), ctx, userID, oldHash, newHash)
 }
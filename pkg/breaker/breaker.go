@@ -0,0 +1,82 @@
+// Package breaker автоматический выключатель: после серии сбоев подряд запросы к недоступному серверу
+// не выполняются, пока не пройдет пауза, затем пропускается один пробный запрос.
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+// Breaker закрыт - запросы идут, открыт - запросы отклоняются до конца паузы.
+type Breaker struct {
+	now       func() time.Time
+	openUntil time.Time
+	threshold int
+	failures  int
+	cooldown  time.Duration
+	probing   bool
+	mu        sync.Mutex
+}
+
+type option func(*Breaker)
+
+// SetClock источник времени, для тестов.
+func SetClock(now func() time.Time) option {
+	return func(b *Breaker) {
+		b.now = now
+	}
+}
+
+// New выключатель, который открывается после threshold сбоев подряд на cooldown.
+func New(threshold int, cooldown time.Duration, options ...option) *Breaker {
+	b := &Breaker{
+		now:       time.Now,
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+	}
+	for _, opt := range options {
+		opt(b)
+	}
+	return b
+}
+
+// Allow можно ли выполнить запрос. После паузы пропускается один пробный запрос,
+// остальные ждут его результата.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || b.now().Before(b.openUntil) {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// Success запрос прошел, выключатель закрывается.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure сбой запроса. Сбой пробного запроса снова открывает выключатель на паузу.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
+
+// Open выключатель открыт: последние запросы не прошли и сервер считается недоступным.
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold
+}
@@ -0,0 +1,41 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := New(3, time.Minute, SetClock(func() time.Time { return now }))
+
+	for range 2 {
+		assert.True(t, b.Allow())
+		b.Failure()
+	}
+	assert.False(t, b.Open())
+	// успех сбрасывает счетчик сбоев подряд.
+	b.Success()
+	for range 3 {
+		assert.True(t, b.Allow())
+		b.Failure()
+	}
+	assert.True(t, b.Open())
+	assert.False(t, b.Allow())
+
+	// после паузы пропускается один пробный запрос.
+	now = now.Add(time.Minute)
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+	b.Failure()
+	assert.False(t, b.Allow())
+
+	now = now.Add(time.Minute)
+	assert.True(t, b.Allow())
+	b.Success()
+	assert.False(t, b.Open())
+	assert.True(t, b.Allow())
+	assert.True(t, b.Allow())
+}
@@ -0,0 +1,165 @@
+// Package certpin привязка клиента к открытому ключу сервера (SPKI pinning) по схеме доверия при первом подключении:
+// ключ сервера запоминается при первом подключении, смена ключа требует подтверждения пользователя.
+package certpin
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const pinPrefix = "sha256/"
+
+var (
+	ErrPinChanged    = errors.New("server public key changed")
+	ErrNoCertificate = errors.New("server did not present a certificate")
+	ErrLineNotValid  = errors.New("pin file line is not valid")
+)
+
+// Change ключ сервера отличается от запомненного и ждет решения пользователя.
+type Change struct {
+	Server    string
+	Known     string
+	Presented string
+}
+
+// SPKI отпечаток открытого ключа сертификата: sha256/ и base64 от SHA-256 SubjectPublicKeyInfo.
+// Не меняется при перевыпуске сертификата с тем же ключом.
+func SPKI(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return pinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Store запомненные ключи серверов, хранятся в файле строками "адрес отпечаток".
+type Store struct {
+	pins    map[string]string
+	pending *Change
+	path    string
+	mu      sync.Mutex
+}
+
+// Open читает файл ключей, отсутствующий файл - ни одного сервера еще не запомнено.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		pins: make(map[string]string),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed read pin file: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !strings.HasPrefix(fields[1], pinPrefix) {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, ErrLineNotValid)
+		}
+		s.pins[fields[0]] = fields[1]
+	}
+	return s, nil
+}
+
+// Check сверяет ключ сервера с запомненным. Неизвестный сервер запоминается,
+// при несовпадении смена сохраняется до решения пользователя и возвращается ErrPinChanged.
+func (s *Store) Check(server string, cert *x509.Certificate) error {
+	pin := SPKI(cert)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	known, ok := s.pins[server]
+	if !ok {
+		s.pins[server] = pin
+		if err := s.save(); err != nil {
+			delete(s.pins, server)
+			return err
+		}
+		return nil
+	}
+	if known == pin {
+		return nil
+	}
+	s.pending = &Change{Server: server, Known: known, Presented: pin}
+	return fmt.Errorf("%s: %w", server, ErrPinChanged)
+}
+
+// VerifyConnection проверка для tls.Config, server - адрес, под которым запоминается ключ.
+func (s *Store) VerifyConnection(server string) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return ErrNoCertificate
+		}
+		return s.Check(server, state.PeerCertificates[0])
+	}
+}
+
+// Pending смена ключа, ожидающая решения пользователя, nil - нет.
+func (s *Store) Pending() *Change {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == nil {
+		return nil
+	}
+	change := *s.pending
+	return &change
+}
+
+// Resolve решение пользователя по ожидающей смене ключа: trust - запомнить новый ключ, иначе оставить прежний.
+func (s *Store) Resolve(trust bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change := s.pending
+	s.pending = nil
+	if change == nil || !trust {
+		return nil
+	}
+	s.pins[change.Server] = change.Presented
+	return s.save()
+}
+
+// save перезаписывает файл целиком через временный файл (права 0600), чтобы при сбое не потерять ключи.
+func (s *Store) save() error {
+	servers := make([]string, 0, len(s.pins))
+	for server := range s.pins {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+	var buf bytes.Buffer
+	for _, server := range servers {
+		fmt.Fprintf(&buf, "%s %s\n", server, s.pins[server])
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed create pin file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed write pin file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed close pin file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed save pin file: %w", err)
+	}
+	return nil
+}
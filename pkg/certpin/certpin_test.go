@@ -0,0 +1,81 @@
+package certpin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCert самоподписанный сертификат с новым ключом.
+func newCert(t *testing.T) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestStore_Check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_servers")
+	first, second := newCert(t), newCert(t)
+
+	store, err := Open(path)
+	require.NoError(t, err)
+
+	// первое подключение запоминает ключ.
+	require.NoError(t, store.Check("localhost:8443", first))
+	require.NoError(t, store.Check("localhost:8443", first))
+	assert.Nil(t, store.Pending())
+
+	err = store.Check("localhost:8443", second)
+	assert.ErrorIs(t, err, ErrPinChanged)
+	change := store.Pending()
+	require.NotNil(t, change)
+	assert.Equal(t, Change{Server: "localhost:8443", Known: SPKI(first), Presented: SPKI(second)}, *change)
+
+	// отказ оставляет прежний ключ.
+	require.NoError(t, store.Resolve(false))
+	assert.Nil(t, store.Pending())
+	assert.ErrorIs(t, store.Check("localhost:8443", second), ErrPinChanged)
+
+	require.NoError(t, store.Resolve(true))
+	require.NoError(t, store.Check("localhost:8443", second))
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, reopened.Check("localhost:8443", second))
+	assert.ErrorIs(t, reopened.Check("localhost:8443", first), ErrPinChanged)
+	// ключ запоминается для адреса, другой адрес - другой сервер.
+	require.NoError(t, reopened.Check("example.com:443", first))
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_servers")
+	require.NoError(t, os.WriteFile(path, []byte("# comment\n\nlocalhost:8443 sha256/abc=\n"), 0o600))
+	store, err := Open(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"localhost:8443": "sha256/abc="}, store.pins)
+
+	require.NoError(t, os.WriteFile(path, []byte("localhost:8443 abc\n"), 0o600))
+	_, err = Open(path)
+	assert.ErrorIs(t, err, ErrLineNotValid)
+}
@@ -0,0 +1,166 @@
+// Package clipboard копирование в буфер обмена системной утилитой, если она есть,
+// и escape последовательностью OSC 52, которую понимают многие терминалы, в том числе по SSH.
+package clipboard
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var ErrUnavailable = errors.New("clipboard is unavailable")
+
+// tool утилита буфера обмена: env - переменная окружения, без которой утилита не работает.
+type tool struct {
+	env   string
+	copy  []string
+	paste []string
+}
+
+var tools = []tool{
+	{
+		env:   "WAYLAND_DISPLAY",
+		copy:  []string{"wl-copy"},
+		paste: []string{"wl-paste", "--no-newline"},
+	},
+	{
+		env:   "DISPLAY",
+		copy:  []string{"xclip", "-selection", "clipboard"},
+		paste: []string{"xclip", "-selection", "clipboard", "-o"},
+	},
+	{
+		env:   "DISPLAY",
+		copy:  []string{"xsel", "--clipboard", "--input"},
+		paste: []string{"xsel", "--clipboard", "--output"},
+	},
+	{copy: []string{"pbcopy"}, paste: []string{"pbpaste"}},
+	{copy: []string{"clip.exe"}},
+}
+
+type Clipboard struct {
+	osc      io.Writer
+	tool     *tool
+	lookPath func(file string) (string, error)
+	getenv   func(key string) string
+	noTools  bool
+}
+
+type option func(*Clipboard)
+
+// SetOSC52 писать последовательность OSC 52 в терминал w, nil - не писать.
+func SetOSC52(w io.Writer) option {
+	return func(c *Clipboard) {
+		c.osc = w
+	}
+}
+
+// SetSystemTools использовать системные утилиты буфера обмена, если они установлены.
+func SetSystemTools(enable bool) option {
+	return func(c *Clipboard) {
+		c.noTools = !enable
+	}
+}
+
+// New буфер обмена, утилита выбирается один раз при создании.
+func New(options ...option) *Clipboard {
+	c := &Clipboard{
+		lookPath: exec.LookPath,
+		getenv:   os.Getenv,
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	if !c.noTools {
+		c.tool = c.findTool()
+	}
+	return c
+}
+
+func (c *Clipboard) findTool() *tool {
+	for i := range tools {
+		if tools[i].env != "" && c.getenv(tools[i].env) == "" {
+			continue
+		}
+		if _, err := c.lookPath(tools[i].copy[0]); err == nil {
+			return &tools[i]
+		}
+	}
+	return nil
+}
+
+// Available есть утилита или терминал для OSC 52.
+func (c *Clipboard) Available() bool {
+	return c.tool != nil || c.osc != nil
+}
+
+// Copy кладет text в буфер обмена всеми доступными способами. Ошибка, только если не сработал ни один.
+func (c *Clipboard) Copy(text string) error {
+	if !c.Available() {
+		return ErrUnavailable
+	}
+	var errs []error
+	copied := false
+	if c.tool != nil {
+		if err := c.run(c.tool.copy, text); err != nil {
+			errs = append(errs, err)
+		} else {
+			copied = true
+		}
+	}
+	if c.osc != nil {
+		if _, err := io.WriteString(c.osc, c.osc52(text)); err != nil {
+			errs = append(errs, fmt.Errorf("failed write osc 52: %w", err))
+		} else {
+			copied = true
+		}
+	}
+	if !copied {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// Clear очищает буфер обмена, если в нем все еще copied. Если утилита умеет читать буфер
+// и там уже другое содержимое, скопированное пользователем позже, буфер не трогается.
+func (c *Clipboard) Clear(copied string) error {
+	if c.tool != nil && len(c.tool.paste) > 0 {
+		current, err := c.output(c.tool.paste)
+		if err == nil && current != copied {
+			return nil
+		}
+	}
+	return c.Copy("")
+}
+
+// osc52 последовательность записи в буфер обмена, внутри tmux она передается терминалу как есть.
+func (c *Clipboard) osc52(text string) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if c.getenv("TMUX") != "" {
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
+
+func (c *Clipboard) run(args []string, input string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(input)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed run %s: %w", args[0], err)
+	}
+	return nil
+}
+
+func (c *Clipboard) output(args []string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed run %s: %w", args[0], err)
+	}
+	return out.String(), nil
+}
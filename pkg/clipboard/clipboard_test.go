@@ -0,0 +1,92 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClipboard_osc52(t *testing.T) {
+	tests := []struct {
+		name string
+		tmux string
+		want string
+	}{
+		{
+			name: "terminal",
+			want: "\x1b]52;c;c2VjcmV0\a",
+		},
+		{
+			name: "tmux",
+			tmux: "/tmp/tmux-1000/default,1,0",
+			want: "\x1bPtmux;\x1b\x1b]52;c;c2VjcmV0\a\x1b\\",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			c := New(SetOSC52(&out), SetSystemTools(false))
+			c.getenv = func(key string) string {
+				if key == "TMUX" {
+					return tt.tmux
+				}
+				return ""
+			}
+			assert.NoError(t, c.Copy("secret"))
+			assert.Equal(t, tt.want, out.String())
+
+			out.Reset()
+			assert.NoError(t, c.Clear("secret"))
+			assert.Contains(t, out.String(), "52;c;\a")
+		})
+	}
+}
+
+func TestClipboard_findTool(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		installed []string
+		want      string
+	}{
+		{name: "none"},
+		{name: "xclip without display", installed: []string{"xclip"}},
+		{name: "xclip", env: map[string]string{"DISPLAY": ":0"}, installed: []string{"xclip", "xsel"}, want: "xclip"},
+		{
+			name:      "wayland first",
+			env:       map[string]string{"DISPLAY": ":0", "WAYLAND_DISPLAY": "wayland-0"},
+			installed: []string{"xclip", "wl-copy"},
+			want:      "wl-copy",
+		},
+		{name: "macos", installed: []string{"pbcopy"}, want: "pbcopy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Clipboard{
+				getenv: func(key string) string { return tt.env[key] },
+				lookPath: func(file string) (string, error) {
+					for _, name := range tt.installed {
+						if name == file {
+							return "/usr/bin/" + file, nil
+						}
+					}
+					return "", errors.New("not found")
+				},
+			}
+			got := c.findTool()
+			if tt.want == "" {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tt.want, got.copy[0])
+		})
+	}
+}
+
+func TestClipboard_unavailable(t *testing.T) {
+	c := New(SetSystemTools(false))
+	assert.False(t, c.Available())
+	assert.ErrorIs(t, c.Copy("secret"), ErrUnavailable)
+}
@@ -0,0 +1,129 @@
+// Package export зашифрованный архив данных пользователя.
+// Формат: заголовок magic и версия, nonce, затем AES-256-GCM от gzip JSON документа.
+// Ключ архива случайный и печатается группами base32, как ключ восстановления.
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/playmixer/secret-keeper/pkg/recoverykey"
+)
+
+const (
+	version   = 1
+	keySize   = 32
+	nonceSize = 12
+)
+
+var (
+	magic = []byte("SKEXPORT")
+
+	ErrArchiveNotValid = errors.New("export archive is not valid")
+)
+
+// Document содержимое архива.
+type Document struct {
+	ExportedAt time.Time `json:"exported_at"`
+	Login      string    `json:"login"`
+	Secrets    []Secret  `json:"secrets"`
+	Revision   int64     `json:"revision"`
+}
+
+// Secret секрет в открытом виде, Data - JSON карты, текста, пароля или содержимое файла.
+type Secret struct {
+	Title    string `json:"title"`
+	Folder   string `json:"folder,omitempty"`
+	DataType string `json:"data_type"`
+	Data     []byte `json:"data"`
+	ID       uint   `json:"id"`
+	UpdateDT int64  `json:"update_dt"`
+}
+
+func header() []byte {
+	return append(append([]byte{}, magic...), version)
+}
+
+// Seal шифрует документ новым ключом, возвращает архив и печатный ключ.
+func Seal(doc *Document) ([]byte, string, error) {
+	var plain bytes.Buffer
+	zw := gzip.NewWriter(&plain)
+	if err := json.NewEncoder(zw).Encode(doc); err != nil {
+		return nil, "", fmt.Errorf("failed encode document: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed compress document: %w", err)
+	}
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, "", fmt.Errorf("failed generate key: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, "", err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", fmt.Errorf("failed generate nonce: %w", err)
+	}
+
+	head := header()
+	out := append(append(head, nonce...), gcm.Seal(nil, nonce, plain.Bytes(), head)...)
+	return out, recoverykey.Format(key), nil
+}
+
+// Open расшифровывает архив печатным ключом.
+func Open(archive []byte, key string) (*Document, error) {
+	head := header()
+	if len(archive) < len(head)+nonceSize || !bytes.Equal(archive[:len(head)], head) {
+		return nil, ErrArchiveNotValid
+	}
+	bKey, err := recoverykey.Parse(key)
+	if err != nil || len(bKey) != keySize {
+		return nil, fmt.Errorf("key: %w", ErrArchiveNotValid)
+	}
+	gcm, err := newGCM(bKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := archive[len(head) : len(head)+nonceSize]
+	plain, err := gcm.Open(nil, nonce, archive[len(head)+nonceSize:], head)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrArchiveNotValid, err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, fmt.Errorf("failed decompress document: %w", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed decompress document: %w", err)
+	}
+	doc := &Document{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("failed decode document: %w", err)
+	}
+	return doc, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed create gcm: %w", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,58 @@
+package export
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealOpen(t *testing.T) {
+	doc := &Document{
+		ExportedAt: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		Login:      "user",
+		Revision:   7,
+		Secrets: []Secret{
+			{ID: 1, Title: "card", DataType: "CARD", Data: []byte(`{"Number":"1234"}`), UpdateDT: 10},
+			{ID: 2, Title: "file", Folder: "docs", DataType: "BINARY", Data: []byte{0, 1, 2, 255}},
+		},
+	}
+
+	archive, key, err := Seal(doc)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(archive), "1234")
+
+	got, err := Open(archive, key)
+	assert.NoError(t, err)
+	assert.Equal(t, doc, got)
+
+	other, _, err := Seal(doc)
+	assert.NoError(t, err)
+	_, err = Open(other, key)
+	assert.ErrorIs(t, err, ErrArchiveNotValid)
+}
+
+func TestOpen_notValid(t *testing.T) {
+	archive, key, err := Seal(&Document{Login: "user"})
+	assert.NoError(t, err)
+
+	tampered := append([]byte{}, archive...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		archive []byte
+		key     string
+	}{
+		{name: "short", archive: archive[:5], key: key},
+		{name: "wrong magic", archive: append([]byte("X"), archive[1:]...), key: key},
+		{name: "tampered", archive: tampered, key: key},
+		{name: "bad key", archive: archive, key: "AAAA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Open(tt.archive, tt.key)
+			assert.ErrorIs(t, err, ErrArchiveNotValid)
+		})
+	}
+}
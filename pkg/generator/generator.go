@@ -0,0 +1,246 @@
+// Package generator генерация паролей и парольных фраз из crypto/rand с оценкой энтропии:
+// пароли из классов символов, произносимые пароли и фразы diceware по списку слов EFF
+// (https://www.eff.org/dice, CC BY 3.0).
+package generator
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// Классы символов пароля.
+const (
+	Lower   = "abcdefghijklmnopqrstuvwxyz"
+	Upper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Digits  = "0123456789"
+	Symbols = "!#$%&*+-=?@^_~.,:;"
+	// ambiguous символы, которые легко спутать при чтении с экрана.
+	ambiguous = "Il1O0o"
+
+	consonants = "bcdfghjklmnprstvz"
+	vowels     = "aeiou"
+
+	// пороги оценки Strength в битах энтропии.
+	entropyWeak   = 40
+	entropyMedium = 60
+	entropyStrong = 80
+
+	MinLength     = 4
+	MaxLength     = 128
+	MinWords      = 3
+	MaxWords      = 20
+	DefaultLength = 20
+	DefaultWords  = 6
+)
+
+var (
+	ErrLengthNotValid = errors.New("password length is not valid")
+	ErrNoClasses      = errors.New("no character classes selected")
+
+	//go:embed eff_large_wordlist.txt
+	effLargeWordlist string
+	wordlist         = sync.OnceValue(parseWordlist)
+)
+
+// Secret сгенерированный пароль и его энтропия в битах.
+type Secret struct {
+	Value   string
+	Entropy float64
+}
+
+// PasswordOptions классы символов пароля, из каждого выбранного класса в пароль попадает хотя бы один символ.
+type PasswordOptions struct {
+	Length           int
+	Lower            bool
+	Upper            bool
+	Digits           bool
+	Symbols          bool
+	ExcludeAmbiguous bool
+}
+
+// DefaultPasswordOptions все классы символов, длина DefaultLength.
+var DefaultPasswordOptions = PasswordOptions{
+	Length:  DefaultLength,
+	Lower:   true,
+	Upper:   true,
+	Digits:  true,
+	Symbols: true,
+}
+
+// Password пароль из выбранных классов символов.
+func Password(opts PasswordOptions) (*Secret, error) {
+	if opts.Length < MinLength || opts.Length > MaxLength {
+		return nil, ErrLengthNotValid
+	}
+	classes := opts.classes()
+	if len(classes) == 0 {
+		return nil, ErrNoClasses
+	}
+	alphabet := strings.Join(classes, "")
+
+	// сначала по символу из каждого класса, остальное из всего алфавита, затем перемешиваем.
+	b := make([]byte, 0, opts.Length)
+	for _, class := range classes {
+		c, err := pick(class)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, c)
+	}
+	for len(b) < opts.Length {
+		c, err := pick(alphabet)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, c)
+	}
+	if err := shuffle(b); err != nil {
+		return nil, err
+	}
+
+	// требование по символу из класса чуть уменьшает энтропию, оценка по всему алфавиту ее слегка завышает.
+	return &Secret{Value: string(b), Entropy: float64(opts.Length) * math.Log2(float64(len(alphabet)))}, nil
+}
+
+func (o PasswordOptions) classes() []string {
+	var classes []string
+	for _, c := range []struct {
+		chars string
+		on    bool
+	}{{Lower, o.Lower}, {Upper, o.Upper}, {Digits, o.Digits}, {Symbols, o.Symbols}} {
+		if !c.on {
+			continue
+		}
+		chars := c.chars
+		if o.ExcludeAmbiguous {
+			chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(ambiguous, r) {
+					return -1
+				}
+				return r
+			}, chars)
+		}
+		classes = append(classes, chars)
+	}
+	return classes
+}
+
+// Pronounceable произносимый пароль из чередующихся согласных и гласных, слоги разделены дефисом через каждые 6 букв.
+func Pronounceable(length int) (*Secret, error) {
+	if length < MinLength || length > MaxLength {
+		return nil, ErrLengthNotValid
+	}
+	const group = 6
+	var sb strings.Builder
+	entropy := 0.0
+	for i := range length {
+		if i > 0 && i%group == 0 {
+			sb.WriteByte('-')
+		}
+		chars := consonants
+		if i%2 == 1 {
+			chars = vowels
+		}
+		c, err := pick(chars)
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteByte(c)
+		entropy += math.Log2(float64(len(chars)))
+	}
+	return &Secret{Value: sb.String(), Entropy: entropy}, nil
+}
+
+// Passphrase фраза из words случайных слов списка EFF, разделенных separator.
+func Passphrase(words int, separator string) (*Secret, error) {
+	if words < MinWords || words > MaxWords {
+		return nil, fmt.Errorf("words: %w", ErrLengthNotValid)
+	}
+	list := wordlist()
+	chosen := make([]string, words)
+	for i := range chosen {
+		n, err := randInt(len(list))
+		if err != nil {
+			return nil, err
+		}
+		chosen[i] = list[n]
+	}
+	return &Secret{
+		Value:   strings.Join(chosen, separator),
+		Entropy: float64(words) * math.Log2(float64(len(list))),
+	}, nil
+}
+
+// Strength словесная оценка энтропии.
+func Strength(entropy float64) string {
+	switch {
+	case entropy < entropyWeak:
+		return "слабый"
+	case entropy < entropyMedium:
+		return "средний"
+	case entropy < entropyStrong:
+		return "сильный"
+	default:
+		return "очень сильный"
+	}
+}
+
+// String случайная строка длины n из символов alphabet.
+func String(n int, alphabet string) (string, error) {
+	b := make([]byte, n)
+	for i := range b {
+		c, err := pick(alphabet)
+		if err != nil {
+			return "", err
+		}
+		b[i] = c
+	}
+	return string(b), nil
+}
+
+func parseWordlist() []string {
+	words := make([]string, 0)
+	scanner := bufio.NewScanner(strings.NewReader(effLargeWordlist))
+	for scanner.Scan() {
+		// строка: номер по броскам кубика, табуляция, слово.
+		if _, word, ok := strings.Cut(scanner.Text(), "\t"); ok {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+func pick(chars string) (byte, error) {
+	n, err := randInt(len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[n], nil
+}
+
+// shuffle перемешивание Фишера - Йетса.
+func shuffle(b []byte) error {
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := randInt(i + 1)
+		if err != nil {
+			return err
+		}
+		b[i], b[j] = b[j], b[i]
+	}
+	return nil
+}
+
+func randInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed read random: %w", err)
+	}
+	return int(v.Int64()), nil
+}
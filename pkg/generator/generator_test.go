@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    PasswordOptions
+		classes []string
+		wantErr error
+	}{
+		{
+			name:    "all classes",
+			opts:    DefaultPasswordOptions,
+			classes: []string{Lower, Upper, Digits, Symbols},
+		},
+		{
+			name:    "digits only",
+			opts:    PasswordOptions{Length: 6, Digits: true},
+			classes: []string{Digits},
+		},
+		{
+			name:    "without ambiguous",
+			opts:    PasswordOptions{Length: 64, Lower: true, Upper: true, Digits: true, ExcludeAmbiguous: true},
+			classes: []string{Lower, Upper, Digits},
+		},
+		{name: "too short", opts: PasswordOptions{Length: 3, Lower: true}, wantErr: ErrLengthNotValid},
+		{name: "too long", opts: PasswordOptions{Length: MaxLength + 1, Lower: true}, wantErr: ErrLengthNotValid},
+		{name: "no classes", opts: PasswordOptions{Length: 10}, wantErr: ErrNoClasses},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Password(tt.opts)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, got.Value, tt.opts.Length)
+			for _, class := range tt.classes {
+				assert.True(t, strings.ContainsAny(got.Value, class), "class %q", class)
+			}
+			all := strings.Join(tt.classes, "")
+			for _, r := range got.Value {
+				assert.True(t, strings.ContainsRune(all, r), "unexpected %q", r)
+				if tt.opts.ExcludeAmbiguous {
+					assert.False(t, strings.ContainsRune(ambiguous, r), "ambiguous %q", r)
+				}
+			}
+			assert.Greater(t, got.Entropy, 0.0)
+		})
+	}
+}
+
+func TestPassword_entropy(t *testing.T) {
+	got, err := Password(PasswordOptions{Length: 10, Digits: true})
+	require.NoError(t, err)
+	assert.InDelta(t, 10*math.Log2(10), got.Entropy, 0.001)
+}
+
+func TestPronounceable(t *testing.T) {
+	got, err := Pronounceable(12)
+	require.NoError(t, err)
+	assert.Len(t, got.Value, 13)
+	assert.Equal(t, byte('-'), got.Value[6])
+	for i, r := range strings.ReplaceAll(got.Value, "-", "") {
+		chars := consonants
+		if i%2 == 1 {
+			chars = vowels
+		}
+		assert.True(t, strings.ContainsRune(chars, r))
+	}
+
+	_, err = Pronounceable(1)
+	assert.ErrorIs(t, err, ErrLengthNotValid)
+}
+
+func TestPassphrase(t *testing.T) {
+	assert.Len(t, wordlist(), 7776)
+
+	got, err := Passphrase(DefaultWords, "-")
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(got.Value, "-"), DefaultWords)
+	// 6 слов из 7776 - около 77.5 бит.
+	assert.InDelta(t, 77.5, got.Entropy, 0.1)
+	assert.Equal(t, "сильный", Strength(got.Entropy))
+
+	_, err = Passphrase(MaxWords+1, " ")
+	assert.ErrorIs(t, err, ErrLengthNotValid)
+}
@@ -0,0 +1,202 @@
+// Package passhash хеширование паролей. Хеши Argon2id записываются в формате PHC
+// ($argon2id$v=19$m=65536,t=3,p=2$соль$хеш): алгоритм и параметры хранятся вместе с хешем,
+// поэтому параметры можно усиливать без потери старых хешей. Хеши bcrypt проверяются для совместимости.
+package passhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Алгоритмы хеширования.
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+	// bcryptMaxPassword bcrypt учитывает только первые 72 байта пароля.
+	bcryptMaxPassword = 72
+)
+
+var (
+	ErrHashNotValid       = errors.New("password hash is not valid")
+	ErrParamsNotValid     = errors.New("password hash params is not valid")
+	ErrPasswordTooLong    = errors.New("password is too long for bcrypt")
+	ErrAlgorithmNotExists = errors.New("password hash algorithm not exists")
+)
+
+// Hasher алгоритм хеширования новых паролей.
+type Hasher interface {
+	// Hash хеш пароля в формате, который понимает Verify.
+	Hash(password string) (string, error)
+	// NeedsRehash хеш создан другим алгоритмом или с более слабыми параметрами, чем текущие.
+	NeedsRehash(encoded string) bool
+}
+
+// Verify проверяет пароль по хешу любого поддерживаемого алгоритма, независимо от текущего.
+func Verify(password, encoded string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$"+AlgorithmArgon2id+"$"):
+		params, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, err
+		}
+		got := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(got, key) == 1, nil
+	case isBcrypt(encoded):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("%w: %w", ErrHashNotValid, err)
+		}
+		return true, nil
+	}
+	return false, ErrHashNotValid
+}
+
+// New хешер по имени алгоритма.
+func New(algorithm string, params Argon2Params, bcryptCost int) (Hasher, error) {
+	switch algorithm {
+	case "", AlgorithmArgon2id:
+		return NewArgon2id(params)
+	case AlgorithmBcrypt:
+		return NewBcrypt(bcryptCost)
+	}
+	return nil, fmt.Errorf("%s: %w", algorithm, ErrAlgorithmNotExists)
+}
+
+// Argon2Params параметры Argon2id: Memory в КиБ, Time - число проходов, Threads - параллельность.
+type Argon2Params struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+// DefaultArgon2Params параметры по умолчанию: 64 МиБ, 3 прохода, 2 потока.
+var DefaultArgon2Params = Argon2Params{
+	Memory:  64 * 1024,
+	Time:    3,
+	Threads: 2,
+}
+
+// weaker хотя бы один параметр слабее policy.
+func (p Argon2Params) weaker(policy Argon2Params) bool {
+	return p.Memory < policy.Memory || p.Time < policy.Time || p.Threads < policy.Threads
+}
+
+// Argon2id хешер Argon2id.
+type Argon2id struct {
+	params Argon2Params
+}
+
+// NewArgon2id хешер Argon2id с параметрами params.
+func NewArgon2id(params Argon2Params) (*Argon2id, error) {
+	// Argon2 требует не меньше 8 КиБ памяти на поток.
+	if params.Time == 0 || params.Threads == 0 || params.Memory < 8*uint32(params.Threads) {
+		return nil, fmt.Errorf("argon2id %+v: %w", params, ErrParamsNotValid)
+	}
+	return &Argon2id{params: params}, nil
+}
+
+// Hash хеш пароля в формате PHC.
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, a.params.Time, a.params.Memory, a.params.Threads, argon2KeyLen)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		AlgorithmArgon2id, argon2.Version, a.params.Memory, a.params.Time, a.params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// NeedsRehash хеш не Argon2id, неизвестного формата или с более слабыми параметрами.
+func (a *Argon2id) NeedsRehash(encoded string) bool {
+	params, _, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.weaker(a.params) || len(key) < argon2KeyLen
+}
+
+func decodeArgon2id(encoded string) (Argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", соль, хеш.
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return Argon2Params{}, nil, nil, ErrHashNotValid
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, fmt.Errorf("argon2id version: %w", ErrHashNotValid)
+	}
+	params := Argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("argon2id params: %w", ErrHashNotValid)
+	}
+	if params.Time == 0 || params.Threads == 0 {
+		return Argon2Params{}, nil, nil, fmt.Errorf("argon2id params: %w", ErrHashNotValid)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("argon2id salt: %w", ErrHashNotValid)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2Params{}, nil, nil, fmt.Errorf("argon2id key: %w", ErrHashNotValid)
+	}
+	return params, salt, key, nil
+}
+
+// Bcrypt хешер bcrypt, оставлен для совместимости: пароли длиннее 72 байт он не принимает.
+type Bcrypt struct {
+	cost int
+}
+
+// NewBcrypt хешер bcrypt со стоимостью cost.
+func NewBcrypt(cost int) (*Bcrypt, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost %d: %w", cost, ErrParamsNotValid)
+	}
+	return &Bcrypt{cost: cost}, nil
+}
+
+// Hash хеш пароля bcrypt.
+func (b *Bcrypt) Hash(password string) (string, error) {
+	if len(password) > bcryptMaxPassword {
+		return "", ErrPasswordTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", fmt.Errorf("failed bcrypt: %w", err)
+	}
+	return string(hash), nil
+}
+
+// NeedsRehash хеш не bcrypt или со стоимостью меньше текущей.
+func (b *Bcrypt) NeedsRehash(encoded string) bool {
+	if !isBcrypt(encoded) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < b.cost
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
@@ -0,0 +1,138 @@
+package passhash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testParams слабые параметры, чтобы тесты шли быстро.
+var testParams = Argon2Params{Memory: 1024, Time: 1, Threads: 1}
+
+const (
+	// bcrypt хеш пароля "user" со стоимостью 14.
+	bcryptUser = "$2a$14$M2qLheAVBq/0yqT6NBUleewVIjlhOY4EqzCfEdgg3M0vBvKJA6Ct."
+)
+
+func TestArgon2id_HashVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+	}{
+		{name: "short", password: "user"},
+		{name: "longer than bcrypt limit", password: strings.Repeat("1", 100)},
+		{name: "unicode", password: "пароль"},
+	}
+	h, err := NewArgon2id(testParams)
+	require.NoError(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := h.Hash(tt.password)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+			ok, err := Verify(tt.password, hash)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = Verify(tt.password+"x", hash)
+			assert.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+		wantErr  error
+	}{
+		{name: "bcrypt", password: "user", hash: bcryptUser, want: true},
+		{name: "bcrypt wrong", password: "wrong", hash: bcryptUser},
+		{name: "unknown", password: "user", hash: "plain", wantErr: ErrHashNotValid},
+		{name: "argon2id bad params", hash: "$argon2id$v=19$m=1,t=0,p=1$c2FsdA$a2V5", wantErr: ErrHashNotValid},
+		{name: "argon2id bad version", hash: "$argon2id$v=16$m=1,t=1,p=1$c2FsdA$a2V5", wantErr: ErrHashNotValid},
+		{name: "argon2id bad salt", hash: "$argon2id$v=19$m=8,t=1,p=1$!$a2V5", wantErr: ErrHashNotValid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Verify(tt.password, tt.hash)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weak, err := NewArgon2id(testParams)
+	require.NoError(t, err)
+	weakHash, err := weak.Hash("user")
+	require.NoError(t, err)
+
+	strong, err := NewArgon2id(Argon2Params{Memory: 2048, Time: 1, Threads: 1})
+	require.NoError(t, err)
+	strongHash, err := strong.Hash("user")
+	require.NoError(t, err)
+
+	bcryptHasher, err := NewBcrypt(15)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		hasher Hasher
+		hash   string
+		want   bool
+	}{
+		{name: "same params", hasher: weak, hash: weakHash, want: false},
+		{name: "stronger stored", hasher: weak, hash: strongHash, want: false},
+		{name: "weaker stored", hasher: strong, hash: weakHash, want: true},
+		{name: "bcrypt to argon2id", hasher: strong, hash: bcryptUser, want: true},
+		{name: "unknown format", hasher: strong, hash: "plain", want: true},
+		{name: "bcrypt lower cost", hasher: bcryptHasher, hash: bcryptUser, want: true},
+		{name: "argon2id to bcrypt", hasher: bcryptHasher, hash: weakHash, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.hasher.NeedsRehash(tt.hash))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		params    Argon2Params
+		cost      int
+		wantErr   error
+	}{
+		{name: "default argon2id", params: DefaultArgon2Params},
+		{name: "bcrypt", algorithm: AlgorithmBcrypt, cost: 10},
+		{name: "bcrypt cost", algorithm: AlgorithmBcrypt, cost: 40, wantErr: ErrParamsNotValid},
+		{
+			name:      "argon2id no threads",
+			algorithm: AlgorithmArgon2id,
+			params:    Argon2Params{Memory: 64, Time: 1},
+			wantErr:   ErrParamsNotValid,
+		},
+		{name: "unknown", algorithm: "md5", wantErr: ErrAlgorithmNotExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.algorithm, tt.params, tt.cost)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestBcrypt_longPassword(t *testing.T) {
+	h, err := NewBcrypt(4)
+	require.NoError(t, err)
+	_, err = h.Hash(strings.Repeat("1", 73))
+	assert.ErrorIs(t, err, ErrPasswordTooLong)
+}
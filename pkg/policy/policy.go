@@ -0,0 +1,106 @@
+// Package policy правила для новых логинов и паролей: длина, допустимые символы логина,
+// оценка стойкости пароля в духе zxcvbn и список запрещенных паролей.
+package policy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrLoginLength     = errors.New("login length is not allowed")
+	ErrLoginCharset    = errors.New("login contains not allowed characters")
+	ErrPasswordShort   = errors.New("password is too short")
+	ErrPasswordWeak    = errors.New("password is too weak")
+	ErrPasswordDenied  = errors.New("password is in the list of common passwords")
+	ErrPasswordIsLogin = errors.New("password is similar to login")
+
+	// DefaultLoginPattern латинские буквы, цифры и . _ - @.
+	DefaultLoginPattern = regexp.MustCompile(`^[A-Za-z0-9._@-]+$`)
+)
+
+// Policy правила. Нулевые значения - без ограничения, кроме непустых логина и пароля.
+type Policy struct {
+	// LoginPattern допустимый логин, nil - любые символы.
+	LoginPattern   *regexp.Regexp
+	denylist       map[string]struct{}
+	LoginMinLength int
+	LoginMaxLength int
+	// PasswordMinLength минимальная длина пароля в символах.
+	PasswordMinLength int
+	// PasswordMinStrength минимальная оценка Strength от 0 до 4.
+	PasswordMinStrength int
+}
+
+// SetDenylist запрещенные пароли, сравнение без учета регистра.
+func (p *Policy) SetDenylist(passwords []string) {
+	p.denylist = make(map[string]struct{}, len(passwords))
+	for _, password := range passwords {
+		if password = strings.ToLower(strings.TrimSpace(password)); password != "" {
+			p.denylist[password] = struct{}{}
+		}
+	}
+}
+
+// LoadDenylist читает запрещенные пароли из файла: по одному в строке, строки с # пропускаются.
+func LoadDenylist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed open denylist: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var passwords []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords = append(passwords, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed read denylist: %w", err)
+	}
+	return passwords, nil
+}
+
+// CheckLogin проверка логина нового пользователя.
+func (p *Policy) CheckLogin(login string) error {
+	length := utf8.RuneCountInString(login)
+	if length == 0 || length < p.LoginMinLength || (p.LoginMaxLength > 0 && length > p.LoginMaxLength) {
+		return fmt.Errorf("%w: from %d to %d characters", ErrLoginLength, max(p.LoginMinLength, 1), p.LoginMaxLength)
+	}
+	if p.LoginPattern != nil && !p.LoginPattern.MatchString(login) {
+		return ErrLoginCharset
+	}
+	return nil
+}
+
+// Strict заданы правила сложнее длины пароля: оценка стойкости или список запрещенных паролей.
+func (p *Policy) Strict() bool {
+	return p.PasswordMinStrength > 0 || len(p.denylist) > 0
+}
+
+// CheckPassword проверка нового пароля пользователя login.
+func (p *Policy) CheckPassword(login, password string) error {
+	if password == "" || utf8.RuneCountInString(password) < p.PasswordMinLength {
+		return fmt.Errorf("%w: at least %d characters", ErrPasswordShort, max(p.PasswordMinLength, 1))
+	}
+	lower := strings.ToLower(password)
+	if _, ok := p.denylist[lower]; ok {
+		return ErrPasswordDenied
+	}
+	if p.PasswordMinStrength > 0 && login != "" && strings.Contains(lower, strings.ToLower(login)) {
+		return ErrPasswordIsLogin
+	}
+	if score := p.Strength(password, login); score < p.PasswordMinStrength {
+		return fmt.Errorf("%w: score %d, need %d", ErrPasswordWeak, score, p.PasswordMinStrength)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_CheckLogin(t *testing.T) {
+	p := Policy{LoginPattern: DefaultLoginPattern, LoginMinLength: 3, LoginMaxLength: 8}
+	tests := []struct {
+		name    string
+		login   string
+		wantErr error
+	}{
+		{name: "ok", login: "user.01"},
+		{name: "empty", login: "", wantErr: ErrLoginLength},
+		{name: "short", login: "ab", wantErr: ErrLoginLength},
+		{name: "long", login: "user_name", wantErr: ErrLoginLength},
+		{name: "space", login: "us er", wantErr: ErrLoginCharset},
+		{name: "cyrillic", login: "юзер", wantErr: ErrLoginCharset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, p.CheckLogin(tt.login), tt.wantErr)
+		})
+	}
+
+	assert.NoError(t, (&Policy{}).CheckLogin("любой логин"))
+}
+
+func TestPolicy_CheckPassword(t *testing.T) {
+	p := Policy{PasswordMinLength: 8, PasswordMinStrength: 3}
+	p.SetDenylist([]string{"Password", " qwerty123 "})
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{name: "ok", password: "kqzmvbtr-31"},
+		{name: "empty", password: "", wantErr: ErrPasswordShort},
+		{name: "short", password: "k#9zQ", wantErr: ErrPasswordShort},
+		{name: "denied", password: "PASSWORD", wantErr: ErrPasswordDenied},
+		{name: "denied trimmed", password: "qwerty123", wantErr: ErrPasswordDenied},
+		{name: "contains login", password: "my-alice-pass", wantErr: ErrPasswordIsLogin},
+		{name: "weak", password: "abcd12345", wantErr: ErrPasswordWeak},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, p.CheckPassword("alice", tt.password), tt.wantErr)
+		})
+	}
+
+	assert.NoError(t, (&Policy{}).CheckPassword("user", "user"))
+}
+
+func TestPolicy_Strength(t *testing.T) {
+	p := Policy{}
+	p.SetDenylist([]string{"password", "qwerty", "dragon"})
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{password: "password", want: 0},
+		{password: "p@ssw0rd", want: 0},
+		{password: "Password1", want: 0},
+		{password: "aaaaaaaaaaaa", want: 0},
+		{password: "abcdefghij", want: 0},
+		{password: "asdfghjkl", want: 0},
+		{password: "qwerty1990", want: 0},
+		{password: "alice2024", want: 1},
+		{password: "kqzmvbtr", want: 3},
+		{password: "correcthorsebatterystaple", want: maxScore},
+		{password: "xK9#mP2$vL", want: maxScore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			assert.Equal(t, tt.want, p.Strength(tt.password, "alice"))
+		})
+	}
+
+	// частые пароли узнаются и без списка запрещенных.
+	assert.Equal(t, 0, (&Policy{}).Strength("Iloveyou"))
+}
+
+func TestLoadDenylist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# common\npassword\n\n  letmein  \n"), 0o600))
+
+	got, err := LoadDenylist(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"password", "letmein"}, got)
+
+	_, err = LoadDenylist(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
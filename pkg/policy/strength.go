@@ -0,0 +1,241 @@
+package policy
+
+import (
+	_ "embed"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	minMatchLength = 3
+	// bruteforceGuesses перебор одного символа, как в zxcvbn.
+	bruteforceGuesses = 10
+	// userInputGuesses логин и другие данные пользователя подбираются первыми.
+	userInputGuesses = 10
+	keyboardGuesses  = 40
+	yearGuesses      = 120
+	maxScore         = 4
+)
+
+var (
+	//go:embed common.txt
+	commonList string
+	// common частые пароли, подбираются первыми даже без списка запрещенных.
+	common = parseCommon(commonList)
+
+	// scoreThresholds порядок числа попыток подбора для оценок 1..4.
+	scoreThresholds = [maxScore]float64{3, 6, 8, 10}
+
+	// leet частые замены букв в словах: p@ssw0rd.
+	leet = strings.NewReplacer("@", "a", "4", "a", "0", "o", "1", "i", "!", "i", "3", "e", "$", "s", "5", "s", "7", "t")
+
+	keyboardRows = []string{"qwertyuiop", "asdfghjkl", "zxcvbnm", "1234567890", "йцукенгшщзхъ", "фывапролджэ", "ячсмитьбю"}
+)
+
+// Strength оценка стойкости пароля от 0 до 4 в духе zxcvbn: пароль разбивается на части,
+// для каждой берется самый дешевый способ подбора (словарь, данные пользователя, повтор,
+// последовательность, ряд клавиатуры, год или перебор), оценка - порядок общего числа попыток.
+func (p *Policy) Strength(password string, userInputs ...string) int {
+	log10 := p.guessesLog10([]rune(password), userInputs)
+	score := 0
+	for _, threshold := range scoreThresholds {
+		if log10 >= threshold {
+			score++
+		}
+	}
+	return score
+}
+
+// guessesLog10 десятичный логарифм числа попыток подбора.
+func (p *Policy) guessesLog10(password []rune, userInputs []string) float64 {
+	lower := []rune(strings.ToLower(string(password)))
+	unleet := []rune(leet.Replace(string(lower)))
+	if len(unleet) != len(lower) {
+		unleet = lower
+	}
+	inputs := make([]string, 0, len(userInputs))
+	for _, input := range userInputs {
+		if input = strings.ToLower(input); len([]rune(input)) >= minMatchLength {
+			inputs = append(inputs, input)
+		}
+	}
+
+	total := 0.0
+	for i := 0; i < len(password); {
+		length, guesses := p.bestMatch(password, lower, unleet, i, inputs)
+		if length == 0 {
+			total += math.Log10(bruteforceGuesses)
+			i++
+			continue
+		}
+		total += math.Log10(guesses)
+		i += length
+	}
+	return total
+}
+
+// bestMatch самый дешевый в пересчете на символ шаблон, начинающийся с позиции i.
+// Если шаблон не дешевле перебора, длина 0.
+func (p *Policy) bestMatch(password, lower, unleet []rune, i int, inputs []string) (int, float64) {
+	bestLength, bestGuesses := 0, 0.0
+	bestCost := math.Log10(bruteforceGuesses)
+	try := func(length int, guesses float64) {
+		if length < minMatchLength {
+			return
+		}
+		if cost := math.Log10(guesses) / float64(length); cost < bestCost {
+			bestLength, bestGuesses, bestCost = length, guesses, cost
+		}
+	}
+
+	for j := len(lower); j-i >= minMatchLength; j-- {
+		variations := caseVariations(password[i:j])
+		for _, word := range []string{string(lower[i:j]), string(unleet[i:j])} {
+			if _, ok := p.denylist[word]; ok {
+				try(j-i, float64(max(len(p.denylist), 1))*variations)
+			}
+			if _, ok := common[word]; ok {
+				try(j-i, float64(len(common))*variations)
+			}
+			for _, input := range inputs {
+				if input == word {
+					try(j-i, userInputGuesses*variations)
+				}
+			}
+			// замены в слове удваивают число вариантов.
+			variations *= 2
+		}
+	}
+
+	length := repeatLength(lower, i)
+	try(length, bruteforceGuesses*float64(length))
+
+	length, ascending := sequenceLength(lower, i)
+	try(length, sequenceGuesses(lower[i], ascending)*float64(length))
+
+	length = keyboardLength(lower, i)
+	try(length, keyboardGuesses*float64(length))
+
+	if isYear(lower, i) {
+		try(4, yearGuesses)
+	}
+
+	return bestLength, bestGuesses
+}
+
+func parseCommon(list string) map[string]struct{} {
+	words := make(map[string]struct{})
+	for _, line := range strings.Split(list, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			words[line] = struct{}{}
+		}
+	}
+	return words
+}
+
+// caseVariations множитель за заглавные буквы в слове.
+func caseVariations(word []rune) float64 {
+	upper := 0
+	for _, r := range word {
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 1
+	case upper == len(word) || (upper == 1 && unicode.IsUpper(word[0])):
+		return 2
+	}
+	return math.Pow(2, float64(upper))
+}
+
+func repeatLength(s []rune, i int) int {
+	j := i + 1
+	for j < len(s) && s[j] == s[i] {
+		j++
+	}
+	return j - i
+}
+
+// sequenceLength длина последовательности с шагом 1 (abc, 987).
+func sequenceLength(s []rune, i int) (int, bool) {
+	if i+1 >= len(s) {
+		return 1, true
+	}
+	step := s[i+1] - s[i]
+	if step != 1 && step != -1 {
+		return 1, true
+	}
+	j := i + 1
+	for j < len(s) && s[j]-s[j-1] == step {
+		j++
+	}
+	return j - i, step == 1
+}
+
+// sequenceGuesses как в zxcvbn: очевидное начало дешевле, убывающая последовательность в два раза дороже.
+func sequenceGuesses(first rune, ascending bool) float64 {
+	guesses := 26.0
+	switch {
+	case strings.ContainsRune("az019", first):
+		guesses = 4
+	case unicode.IsDigit(first):
+		guesses = 10
+	}
+	if !ascending {
+		guesses *= 2
+	}
+	return guesses
+}
+
+// keyboardLength длина подряд идущих клавиш одного ряда в любом направлении.
+func keyboardLength(s []rune, i int) int {
+	best := 0
+	for _, row := range keyboardRows {
+		rowRunes := []rune(row)
+		for _, reversed := range []bool{false, true} {
+			line := rowRunes
+			if reversed {
+				line = reverse(rowRunes)
+			}
+			start := indexRune(line, s[i])
+			if start < 0 {
+				continue
+			}
+			length := 1
+			for i+length < len(s) && start+length < len(line) && s[i+length] == line[start+length] {
+				length++
+			}
+			best = max(best, length)
+		}
+	}
+	return best
+}
+
+func isYear(s []rune, i int) bool {
+	if i+4 > len(s) {
+		return false
+	}
+	year, err := strconv.Atoi(string(s[i : i+4]))
+	return err == nil && year >= 1900 && year <= 2039
+}
+
+func reverse(s []rune) []rune {
+	r := make([]rune, len(s))
+	for i, c := range s {
+		r[len(s)-1-i] = c
+	}
+	return r
+}
+
+func indexRune(s []rune, c rune) int {
+	for i, r := range s {
+		if r == c {
+			return i
+		}
+	}
+	return -1
+}
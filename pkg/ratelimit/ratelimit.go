@@ -0,0 +1,193 @@
+// Package ratelimit ограничение частоты запросов корзиной токенов и блокировка после неудачных попыток.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var (
+	sweepInterval = time.Minute
+)
+
+// Limit - корзина токенов: Rate токенов в секунду, Burst - емкость корзины.
+// Нулевые Rate или Burst - без ограничения.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute лимит n запросов в минуту, n запросов можно сделать сразу.
+func PerMinute(n int) Limit {
+	return Limit{
+		Rate:  float64(n) / float64(time.Minute/time.Second),
+		Burst: n,
+	}
+}
+
+// Lockout политика блокировки: после Threshold неудач подряд ключ блокируется на Base,
+// каждая следующая неудача удваивает время блокировки, но не больше Max.
+// Неудачи старше Window забываются.
+type Lockout struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+	Window    time.Duration
+}
+
+// duration время блокировки после count неудач подряд.
+func (l Lockout) duration(count int) time.Duration {
+	if l.Threshold <= 0 || count < l.Threshold {
+		return 0
+	}
+	d := l.Base
+	for i := l.Threshold; i < count && d < l.Max; i++ {
+		d *= 2
+	}
+	return min(d, l.Max)
+}
+
+// Backend хранилище состояния ограничителей.
+// Memory хранит состояние в памяти процесса, для нескольких реплик сервера нужен общий backend.
+type Backend interface {
+	// Take забирает токен из корзины key. Если токенов нет, возвращает время до появления следующего.
+	Take(ctx context.Context, key string, limit Limit) (time.Duration, error)
+	// LockedFor оставшееся время блокировки key, 0 - ключ не заблокирован.
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	// Fail учитывает неудачу key, возвращает число неудач подряд и время блокировки, если она наступила.
+	Fail(ctx context.Context, key string, policy Lockout) (int, time.Duration, error)
+	// Reset забывает неудачи key.
+	Reset(ctx context.Context, key string) error
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+type failures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+	forgetAt    time.Time
+}
+
+// Memory - Backend в памяти процесса.
+type Memory struct {
+	now       func() time.Time
+	buckets   map[string]*bucket
+	failures  map[string]*failures
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+// NewMemory создает Backend в памяти процесса.
+func NewMemory() *Memory {
+	return &Memory{
+		now:      time.Now,
+		buckets:  make(map[string]*bucket),
+		failures: make(map[string]*failures),
+	}
+}
+
+// Take забирает токен из корзины key.
+func (m *Memory) Take(_ context.Context, key string, limit Limit) (time.Duration, error) {
+	if limit.Burst <= 0 || limit.Rate <= 0 {
+		return 0, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		m.buckets[key] = b
+	}
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	var wait time.Duration
+	if b.tokens >= 1 {
+		b.tokens--
+	} else {
+		wait = seconds((1 - b.tokens) / limit.Rate)
+	}
+	b.full = now.Add(seconds((float64(limit.Burst) - b.tokens) / limit.Rate))
+
+	return wait, nil
+}
+
+func seconds(v float64) time.Duration {
+	return time.Duration(v * float64(time.Second))
+}
+
+// LockedFor оставшееся время блокировки key.
+func (m *Memory) LockedFor(_ context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, ok := m.failures[key]
+	if !ok {
+		return 0, nil
+	}
+	return max(f.lockedUntil.Sub(m.now()), 0), nil
+}
+
+// Fail учитывает неудачу key.
+func (m *Memory) Fail(_ context.Context, key string, policy Lockout) (int, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	f, ok := m.failures[key]
+	if !ok || (policy.Window > 0 && now.Sub(f.last) > policy.Window && now.After(f.lockedUntil)) {
+		f = &failures{}
+		m.failures[key] = f
+	}
+	f.count++
+	f.last = now
+
+	d := policy.duration(f.count)
+	if d > 0 {
+		f.lockedUntil = now.Add(d)
+	}
+	f.forgetAt = now.Add(max(policy.Window, d))
+
+	return f.count, d, nil
+}
+
+// Reset забывает неудачи key.
+func (m *Memory) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.failures, key)
+	return nil
+}
+
+// sweep удаляет полные корзины и забытые неудачи, чтобы память не росла от разовых ключей.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+	for key, f := range m.failures {
+		if !now.Before(f.forgetAt) {
+			delete(m.failures, key)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time {
+	return c.t
+}
+
+func newTestMemory() (*Memory, *testClock) {
+	clock := &testClock{t: time.Unix(1700000000, 0)}
+	m := NewMemory()
+	m.now = clock.now
+	return m, clock
+}
+
+func TestMemory_Take(t *testing.T) {
+	ctx := context.TODO()
+	m, clock := newTestMemory()
+	limit := PerMinute(3)
+
+	for range 3 {
+		wait, err := m.Take(ctx, "ip:1", limit)
+		assert.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+
+	wait, err := m.Take(ctx, "ip:1", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 20*time.Second, wait)
+
+	wait, err = m.Take(ctx, "ip:2", limit)
+	assert.NoError(t, err)
+	assert.Zero(t, wait, "other key has own bucket")
+
+	clock.t = clock.t.Add(20 * time.Second)
+	wait, err = m.Take(ctx, "ip:1", limit)
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+
+	wait, err = m.Take(ctx, "ip:1", Limit{})
+	assert.NoError(t, err)
+	assert.Zero(t, wait, "zero limit is unlimited")
+}
+
+func TestMemory_Fail(t *testing.T) {
+	ctx := context.TODO()
+	m, clock := newTestMemory()
+	policy := Lockout{Threshold: 3, Base: time.Minute, Max: 5 * time.Minute, Window: time.Hour}
+
+	tests := []struct {
+		name  string
+		count int
+		lock  time.Duration
+	}{
+		{name: "first", count: 1},
+		{name: "second", count: 2},
+		{name: "threshold", count: 3, lock: time.Minute},
+		{name: "double", count: 4, lock: 2 * time.Minute},
+		{name: "double again", count: 5, lock: 4 * time.Minute},
+		{name: "max", count: 6, lock: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, lock, err := m.Fail(ctx, "login:user", policy)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.count, count)
+			assert.Equal(t, tt.lock, lock)
+
+			locked, err := m.LockedFor(ctx, "login:user")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.lock, locked)
+		})
+	}
+
+	assert.NoError(t, m.Reset(ctx, "login:user"))
+	locked, err := m.LockedFor(ctx, "login:user")
+	assert.NoError(t, err)
+	assert.Zero(t, locked)
+
+	count, _, err := m.Fail(ctx, "login:user", policy)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	clock.t = clock.t.Add(2 * time.Hour)
+	count, _, err = m.Fail(ctx, "login:user", policy)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count, "old failures are forgotten")
+}
+
+func TestMemory_sweep(t *testing.T) {
+	ctx := context.TODO()
+	m, clock := newTestMemory()
+	policy := Lockout{Threshold: 1, Base: time.Minute, Max: time.Minute, Window: time.Minute}
+
+	_, err := m.Take(ctx, "ip:1", PerMinute(60))
+	assert.NoError(t, err)
+	_, _, err = m.Fail(ctx, "login:user", policy)
+	assert.NoError(t, err)
+
+	clock.t = clock.t.Add(2 * time.Minute)
+	_, err = m.Take(ctx, "ip:2", PerMinute(60))
+	assert.NoError(t, err)
+
+	assert.Len(t, m.buckets, 1)
+	assert.Empty(t, m.failures)
+}
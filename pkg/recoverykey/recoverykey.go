@@ -0,0 +1,66 @@
+// Package recoverykey печатный ключ восстановления доступа: base32 группами по 4 символа.
+package recoverykey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Size длина ключа в байтах, 160 бит случайных данных.
+	Size = 20
+
+	groupSize = 4
+	separator = "-"
+)
+
+var (
+	ErrKeyNotValid = errors.New("recovery key is not valid")
+
+	encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// Generate новый ключ восстановления.
+func Generate() ([]byte, error) {
+	key := make([]byte, Size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed generate recovery key: %w", err)
+	}
+	return key, nil
+}
+
+// Format печатный вид данных: base32 группами, например ABCD-EFGH-...
+func Format(data []byte) string {
+	s := encoding.EncodeToString(data)
+	groups := make([]string, 0, len(s)/groupSize+1)
+	for len(s) > groupSize {
+		groups = append(groups, s[:groupSize])
+		s = s[groupSize:]
+	}
+	groups = append(groups, s)
+	return strings.Join(groups, separator)
+}
+
+// Parse разбирает печатный вид, регистр, пробелы и разделители групп не важны.
+func Parse(s string) ([]byte, error) {
+	s = strings.ToUpper(strings.Join(strings.Fields(strings.ReplaceAll(s, separator, " ")), ""))
+	if s == "" {
+		return nil, ErrKeyNotValid
+	}
+	data, err := encoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrKeyNotValid, err)
+	}
+	return data, nil
+}
+
+// Hash отпечаток ключа для хранения на сервере. Ключ случайный и длинный, соль и медленный хеш не нужны.
+func Hash(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:])
+}
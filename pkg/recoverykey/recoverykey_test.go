@@ -0,0 +1,41 @@
+package recoverykey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatParse(t *testing.T) {
+	key, err := Generate()
+	assert.NoError(t, err)
+	assert.Len(t, key, Size)
+
+	formatted := Format(key)
+	assert.Len(t, formatted, 39, "8 groups of 4 with separators")
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "as is", input: formatted},
+		{name: "lower case", input: strings.ToLower(formatted)},
+		{name: "spaces", input: "  " + formatted[:10] + " \n " + formatted[10:] + " "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, key, got)
+			assert.Equal(t, Hash(key), Hash(got))
+		})
+	}
+}
+
+func TestParse_notValid(t *testing.T) {
+	for _, input := range []string{"", " - ", "ABC1-0000"} {
+		_, err := Parse(input)
+		assert.ErrorIs(t, err, ErrKeyNotValid, "input %q", input)
+	}
+}
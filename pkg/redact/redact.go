@@ -0,0 +1,87 @@
+// Package redact маскирует секреты в потоке вывода, в том числе секреты, разорванные между записями.
+package redact
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// DefaultMask замена секрета в выводе.
+const DefaultMask = "*****"
+
+// Writer заменяет секреты маской и пишет результат в w. Хвост, который может оказаться началом секрета,
+// придерживается до следующей записи или Flush.
+type Writer struct {
+	w       io.Writer
+	mask    []byte
+	secrets [][]byte
+	buf     []byte
+	mu      sync.Mutex
+}
+
+// NewWriter маскирующий writer, пустые секреты пропускаются.
+func NewWriter(w io.Writer, secrets []string, mask string) *Writer {
+	r := &Writer{w: w, mask: []byte(mask)}
+	for _, s := range secrets {
+		if s != "" {
+			r.secrets = append(r.secrets, []byte(s))
+		}
+	}
+	// длинные секреты первыми: секрет, содержащий другой секрет, маскируется целиком.
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+	return r
+}
+
+// Write всегда принимает p целиком, ошибка только от w.
+func (r *Writer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf = append(r.buf, p...)
+	if err := r.flush(false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush пишет придержанный хвост.
+func (r *Writer) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.flush(true)
+}
+
+func (r *Writer) flush(final bool) error {
+	out := make([]byte, 0, len(r.buf))
+	i := 0
+loop:
+	for i < len(r.buf) {
+		rest := r.buf[i:]
+		for _, s := range r.secrets {
+			if bytes.HasPrefix(rest, s) {
+				out = append(out, r.mask...)
+				i += len(s)
+				continue loop
+			}
+		}
+		if !final {
+			for _, s := range r.secrets {
+				if len(rest) < len(s) && bytes.HasPrefix(s, rest) {
+					break loop
+				}
+			}
+		}
+		out = append(out, r.buf[i])
+		i++
+	}
+	r.buf = append(r.buf[:0], r.buf[i:]...)
+	if len(out) == 0 {
+		return nil
+	}
+	if _, err := r.w.Write(out); err != nil {
+		return fmt.Errorf("failed write: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package redact
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []string
+		writes  []string
+		want    string
+	}{
+		{
+			name:    "masked",
+			secrets: []string{"s3cret"},
+			writes:  []string{"password=s3cret\n"},
+			want:    "password=*****\n",
+		},
+		{
+			name:    "split between writes",
+			secrets: []string{"s3cret"},
+			writes:  []string{"password=s3", "cr", "et and s3", "cret\n"},
+			want:    "password=***** and *****\n",
+		},
+		{
+			name:    "longest first",
+			secrets: []string{"abc", "abcdef"},
+			writes:  []string{"abcdef abc"},
+			want:    "***** *****",
+		},
+		{
+			name:    "prefix at end is flushed",
+			secrets: []string{"s3cret"},
+			writes:  []string{"s3cr"},
+			want:    "s3cr",
+		},
+		{
+			name:    "empty secret ignored",
+			secrets: []string{""},
+			writes:  []string{"text"},
+			want:    "text",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			w := NewWriter(out, tt.secrets, DefaultMask)
+			for _, s := range tt.writes {
+				n, err := w.Write([]byte(s))
+				assert.NoError(t, err)
+				assert.Equal(t, len(s), n)
+			}
+			assert.NoError(t, w.Flush())
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
+
+func TestWriter_holdsPrefix(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := NewWriter(out, []string{"s3cret"}, DefaultMask)
+	_, err := w.Write([]byte("token s3c"))
+	assert.NoError(t, err)
+	assert.Equal(t, "token ", out.String())
+}
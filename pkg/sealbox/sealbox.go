@@ -0,0 +1,123 @@
+// Package sealbox шифрование небольших данных паролем.
+// Формат: заголовок magic и версия, параметры Argon2id, соль, nonce, затем AES-256-GCM от данных.
+// Ключ выводится из пароля, поэтому успешная расшифровка заодно подтверждает пароль без сервера.
+package sealbox
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	version   = 1
+	keySize   = 32
+	saltSize  = 16
+	nonceSize = 12
+	// paramsSize память и проходы по 4 байта, потоки 1 байт.
+	paramsSize = 9
+	// maxMemory и maxTime ограничивают параметры из файла, чтобы подмененный файл не занял всю память.
+	maxMemory = 1024 * 1024
+	maxTime   = 16
+)
+
+var (
+	magic = []byte("SKSEAL")
+
+	ErrSealedNotValid   = errors.New("sealed data is not valid")
+	ErrPasswordNotValid = errors.New("password is not valid")
+)
+
+// Params параметры Argon2id, с которыми выводится ключ.
+type Params struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+// DefaultParams параметры по умолчанию: 64 МиБ, 3 прохода, 2 потока.
+var DefaultParams = Params{
+	Memory:  64 * 1024,
+	Time:    3,
+	Threads: 2,
+}
+
+func (p Params) valid() bool {
+	return p.Memory > 0 && p.Memory <= maxMemory && p.Time > 0 && p.Time <= maxTime && p.Threads > 0
+}
+
+// Seal шифрует data ключом из password с параметрами params.
+func Seal(password string, data []byte, params Params) ([]byte, error) {
+	if !params.valid() {
+		return nil, fmt.Errorf("params: %w", ErrSealedNotValid)
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed generate salt: %w", err)
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed generate nonce: %w", err)
+	}
+
+	head := append(append([]byte{}, magic...), version)
+	head = binary.BigEndian.AppendUint32(head, params.Memory)
+	head = binary.BigEndian.AppendUint32(head, params.Time)
+	head = append(append(head, params.Threads), salt...)
+
+	gcm, err := newGCM(password, salt, params)
+	if err != nil {
+		return nil, err
+	}
+	// заголовок с параметрами и солью подписан вместе с данными.
+	return append(append(head, nonce...), gcm.Seal(nil, nonce, data, head)...), nil
+}
+
+// Open расшифровывает sealed паролем password, неверный пароль - ErrPasswordNotValid.
+func Open(password string, sealed []byte) ([]byte, error) {
+	headSize := len(magic) + 1 + paramsSize + saltSize
+	if len(sealed) < headSize+nonceSize || !bytes.Equal(sealed[:len(magic)], magic) || sealed[len(magic)] != version {
+		return nil, ErrSealedNotValid
+	}
+	raw := sealed[len(magic)+1:]
+	params := Params{
+		Memory:  binary.BigEndian.Uint32(raw[0:4]),
+		Time:    binary.BigEndian.Uint32(raw[4:8]),
+		Threads: raw[8],
+	}
+	if !params.valid() {
+		return nil, fmt.Errorf("params: %w", ErrSealedNotValid)
+	}
+	salt := raw[paramsSize : paramsSize+saltSize]
+
+	gcm, err := newGCM(password, salt, params)
+	if err != nil {
+		return nil, err
+	}
+	head := sealed[:headSize]
+	nonce := sealed[headSize : headSize+nonceSize]
+	data, err := gcm.Open(nil, nonce, sealed[headSize+nonceSize:], head)
+	if err != nil {
+		return nil, ErrPasswordNotValid
+	}
+	return data, nil
+}
+
+func newGCM(password string, salt []byte, params Params) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, keySize)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed create gcm: %w", err)
+	}
+	return gcm, nil
+}
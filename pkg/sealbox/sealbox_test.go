@@ -0,0 +1,53 @@
+package sealbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testParams = Params{Memory: 64, Time: 1, Threads: 1}
+
+func TestSealOpen(t *testing.T) {
+	sealed, err := Seal("password", []byte("token"), testParams)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(sealed), "token")
+
+	got, err := Open("password", sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("token"), got)
+
+	_, err = Open("other", sealed)
+	assert.ErrorIs(t, err, ErrPasswordNotValid)
+
+	_, err = Seal("password", []byte("token"), Params{})
+	assert.ErrorIs(t, err, ErrSealedNotValid)
+}
+
+func TestOpen_notValid(t *testing.T) {
+	sealed, err := Seal("password", []byte("token"), testParams)
+	assert.NoError(t, err)
+
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-1] ^= 1
+	// память 4 ГиБ в заголовке.
+	hugeMemory := append([]byte{}, sealed...)
+	copy(hugeMemory[len(magic)+1:], []byte{0, 0x40, 0, 0})
+
+	tests := []struct {
+		name    string
+		sealed  []byte
+		wantErr error
+	}{
+		{name: "short", sealed: sealed[:5], wantErr: ErrSealedNotValid},
+		{name: "wrong magic", sealed: append([]byte("X"), sealed[1:]...), wantErr: ErrSealedNotValid},
+		{name: "huge memory", sealed: hugeMemory, wantErr: ErrSealedNotValid},
+		{name: "tampered", sealed: tampered, wantErr: ErrPasswordNotValid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Open("password", tt.sealed)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Package shamir разделение секрета на доли по схеме Шамира над GF(2^8).
+// Доля - значения многочленов в точке x, сама точка x хранится последним байтом доли.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+const (
+	maxShares = 255
+	minShares = 2
+)
+
+var (
+	ErrParamsNotValid = errors.New("shamir params is not valid")
+	ErrSharesNotValid = errors.New("shamir shares is not valid")
+)
+
+// Split делит secret на parts долей, любые threshold из них восстанавливают секрет.
+func Split(secret []byte, parts, threshold int) ([][]byte, error) {
+	if len(secret) == 0 ||
+		threshold < minShares || parts < threshold || parts > maxShares {
+		return nil, fmt.Errorf("parts %d threshold %d: %w", parts, threshold, ErrParamsNotValid)
+	}
+
+	// коэффициенты многочленов степени threshold-1, свободный член - байт секрета.
+	coeffs := make([]byte, len(secret)*(threshold-1))
+	if _, err := rand.Read(coeffs); err != nil {
+		return nil, fmt.Errorf("failed generate coefficients: %w", err)
+	}
+
+	shares := make([][]byte, parts)
+	for i := range shares {
+		x := byte(i + 1)
+		share := make([]byte, len(secret)+1)
+		for j, b := range secret {
+			share[j] = evaluate(b, coeffs[j*(threshold-1):(j+1)*(threshold-1)], x)
+		}
+		share[len(secret)] = x
+		shares[i] = share
+	}
+	return shares, nil
+}
+
+// Combine восстанавливает секрет из долей. Если долей меньше порога, результат будет неверным,
+// это не обнаруживается: проверять секрет нужно по его назначению.
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < minShares {
+		return nil, fmt.Errorf("need at least %d shares: %w", minShares, ErrSharesNotValid)
+	}
+	size := len(shares[0])
+	if size < minShares {
+		return nil, fmt.Errorf("share too short: %w", ErrSharesNotValid)
+	}
+
+	xs := make([]byte, len(shares))
+	seen := map[byte]bool{}
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, fmt.Errorf("shares of different length: %w", ErrSharesNotValid)
+		}
+		x := share[size-1]
+		if x == 0 || seen[x] {
+			return nil, fmt.Errorf("duplicate or zero share index: %w", ErrSharesNotValid)
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	secret := make([]byte, size-1)
+	ys := make([]byte, len(shares))
+	for j := range secret {
+		for i, share := range shares {
+			ys[i] = share[j]
+		}
+		secret[j] = interpolateZero(xs, ys)
+	}
+	return secret, nil
+}
+
+// evaluate значение многочлена со свободным членом c0 и коэффициентами coeffs в точке x.
+func evaluate(c0 byte, coeffs []byte, x byte) byte {
+	var res byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		res = add(mul(res, x), coeffs[i])
+	}
+	return add(mul(res, x), c0)
+}
+
+// interpolateZero значение в нуле многочлена Лагранжа по точкам (xs, ys).
+func interpolateZero(xs, ys []byte) byte {
+	var res byte
+	for i := range xs {
+		basis := byte(1)
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			// в нуле множитель базиса равен x_j / (x_j - x_i), вычитание в GF(2^8) - это xor.
+			basis = mul(basis, div(xs[j], add(xs[j], xs[i])))
+		}
+		res = add(res, mul(ys[i], basis))
+	}
+	return res
+}
+
+func add(a, b byte) byte {
+	return a ^ b
+}
+
+// mul умножение в GF(2^8) по модулю x^8 + x^4 + x^3 + x + 1.
+func mul(a, b byte) byte {
+	var res byte
+	for b > 0 {
+		if b&1 == 1 {
+			res ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return res
+}
+
+// inverse обратный элемент: a^254 = a^-1 в GF(2^8).
+func inverse(a byte) byte {
+	res := byte(1)
+	for range 254 {
+		res = mul(res, a)
+	}
+	return res
+}
+
+func div(a, b byte) byte {
+	return mul(a, inverse(b))
+}
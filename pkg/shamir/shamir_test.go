@@ -0,0 +1,86 @@
+package shamir
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("recovery key of the account")
+	tests := []struct {
+		name      string
+		parts     int
+		threshold int
+		use       []int
+		wantEqual bool
+	}{
+		{name: "threshold of five", parts: 5, threshold: 3, use: []int{0, 2, 4}, wantEqual: true},
+		{name: "all shares", parts: 5, threshold: 3, use: []int{4, 3, 2, 1, 0}, wantEqual: true},
+		{name: "two of two", parts: 2, threshold: 2, use: []int{1, 0}, wantEqual: true},
+		{name: "below threshold", parts: 5, threshold: 3, use: []int{1, 3}, wantEqual: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares, err := Split(secret, tt.parts, tt.threshold)
+			assert.NoError(t, err)
+			assert.Len(t, shares, tt.parts)
+
+			use := make([][]byte, 0, len(tt.use))
+			for _, i := range tt.use {
+				use = append(use, shares[i])
+			}
+			got, err := Combine(use)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantEqual, bytes.Equal(secret, got))
+		})
+	}
+}
+
+func TestSplit_params(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    []byte
+		parts     int
+		threshold int
+	}{
+		{name: "empty secret", secret: nil, parts: 3, threshold: 2},
+		{name: "threshold one", secret: []byte("s"), parts: 3, threshold: 1},
+		{name: "parts below threshold", secret: []byte("s"), parts: 2, threshold: 3},
+		{name: "too many parts", secret: []byte("s"), parts: 256, threshold: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Split(tt.secret, tt.parts, tt.threshold)
+			assert.ErrorIs(t, err, ErrParamsNotValid)
+		})
+	}
+}
+
+func TestCombine_notValid(t *testing.T) {
+	shares, err := Split([]byte("secret"), 3, 2)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		shares [][]byte
+	}{
+		{name: "one share", shares: shares[:1]},
+		{name: "duplicate", shares: [][]byte{shares[0], shares[0]}},
+		{name: "different length", shares: [][]byte{shares[0], shares[1][1:]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Combine(tt.shares)
+			assert.ErrorIs(t, err, ErrSharesNotValid)
+		})
+	}
+}
+
+func Test_mul(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		assert.Equal(t, byte(1), mul(byte(a), inverse(byte(a))), "a=%d", a)
+	}
+	assert.Equal(t, byte(0xc1), mul(0x57, 0x83))
+}
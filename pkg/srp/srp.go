@@ -0,0 +1,247 @@
+// Package srp протокол SRP-6a (RFC 5054, группа 2048 бит, SHA-256).
+// Сервер хранит только соль и верификатор пароля, пароль по сети не передается.
+// Обе стороны вычисляют общий ключ сессии и доказывают друг другу, что знают его.
+package srp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// SaltSize длина соли верификатора.
+	SaltSize = 16
+
+	secretSize = 32
+)
+
+var (
+	ErrParamsNotValid = errors.New("srp params is not valid")
+	ErrProofNotValid  = errors.New("srp proof is not valid")
+)
+
+// группа 2048 бит из RFC 5054, приложение A.
+var (
+	n, _ = new(big.Int).SetString(""+
+		"AC6BDB41324A9A9BF166DE5E1389582FAF72B6651987EE07FC3192943DB56050"+
+		"A37329CBB4A099ED8193E0757767A13DD52312AB4B03310DCD7F48A9DA04FD50"+
+		"E8083969EDB767B0CF6095179A163AB3661A05FBD5FAAAE82918A9962F0B93B8"+
+		"55F97993EC975EEAA80D740ADBF4FF747359D041D5C33EA71D281E446B14773B"+
+		"CA97B43A23FB801676BD207A436C6481F1D2B9078717461A5B9D32E688F87748"+
+		"544523B524B0D57D5EA77A2775D2ECFA032CFBDBF52FB3786160279004E57AE6"+
+		"AF874E7303CE53299CCC041C7BC308D82A5698F3A8D0C38271AE35F8E9DBFBB6"+
+		"94B5C803D89F7AE435DE236D525F54759B65E372FCD68EF20FA7111F9E4AFF73", 16)
+	g = big.NewInt(2)
+	k = hashInt(pad(n), pad(g))
+)
+
+// pad число в байтах длины модуля.
+func pad(v *big.Int) []byte {
+	return v.FillBytes(make([]byte, len(n.Bytes())))
+}
+
+func hash(parts ...[]byte) []byte {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+func hashInt(parts ...[]byte) *big.Int {
+	return new(big.Int).SetBytes(hash(parts...))
+}
+
+func randomInt() (*big.Int, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed generate secret: %w", err)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// privateKey x = H(salt | H(login ":" password)).
+func privateKey(login, password string, salt []byte) *big.Int {
+	return hashInt(salt, hash([]byte(login+":"+password)))
+}
+
+// NewSalt случайная соль верификатора.
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed generate salt: %w", err)
+	}
+	return salt, nil
+}
+
+// Verifier верификатор пароля v = g^x mod N, его хранит сервер.
+func Verifier(login, password string, salt []byte) []byte {
+	return pad(new(big.Int).Exp(g, privateKey(login, password, salt), n))
+}
+
+// ValidVerifier проверяет, что верификатор - элемент группы, а не произвольные данные.
+func ValidVerifier(salt, verifier []byte) bool {
+	v := new(big.Int).SetBytes(verifier)
+	return len(salt) >= SaltSize && v.Sign() > 0 && v.Cmp(n) < 0
+}
+
+// publicValid открытый ключ стороны - ненулевой элемент группы, A mod N = 0 раскрыл бы ключ сессии.
+func publicValid(v *big.Int) bool {
+	return v.Sign() > 0 && v.Cmp(n) < 0
+}
+
+func scramble(a, b *big.Int) *big.Int {
+	return hashInt(pad(a), pad(b))
+}
+
+// clientProof M1 = H(H(N) xor H(g) | H(login) | salt | A | B | K).
+func clientProof(login string, salt []byte, a, b *big.Int, key []byte) []byte {
+	hn := hash(pad(n))
+	hg := hash(pad(g))
+	for i := range hn {
+		hn[i] ^= hg[i]
+	}
+	return hash(hn, hash([]byte(login)), salt, pad(a), pad(b), key)
+}
+
+// serverProof M2 = H(A | M1 | K).
+func serverProof(a *big.Int, m1, key []byte) []byte {
+	return hash(pad(a), m1, key)
+}
+
+// Client сторона клиента, знает пароль.
+type Client struct {
+	a        *big.Int
+	pubA     *big.Int
+	login    string
+	password string
+	key      []byte
+	m2       []byte
+}
+
+// NewClient начинает обмен ключами: открытый ключ клиента отправляется серверу вместе с логином.
+func NewClient(login, password string) (*Client, error) {
+	a, err := randomInt()
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		login:    login,
+		password: password,
+		a:        a,
+		pubA:     new(big.Int).Exp(g, a, n),
+	}, nil
+}
+
+// PublicKey открытый ключ клиента A.
+func (c *Client) PublicKey() []byte {
+	return pad(c.pubA)
+}
+
+// Proof по соли и открытому ключу сервера вычисляет ключ сессии и доказательство клиента M1.
+func (c *Client) Proof(salt, serverPublic []byte) ([]byte, error) {
+	pubB := new(big.Int).SetBytes(serverPublic)
+	if !publicValid(pubB) {
+		return nil, fmt.Errorf("server public key: %w", ErrParamsNotValid)
+	}
+	u := scramble(c.pubA, pubB)
+	if u.Sign() == 0 {
+		return nil, fmt.Errorf("scrambler: %w", ErrParamsNotValid)
+	}
+
+	// S = (B - k * g^x) ^ (a + u * x) mod N
+	x := privateKey(c.login, c.password, salt)
+	base := new(big.Int).Sub(pubB, new(big.Int).Mul(k, new(big.Int).Exp(g, x, n)))
+	base.Mod(base, n)
+	exp := new(big.Int).Add(c.a, new(big.Int).Mul(u, x))
+	s := new(big.Int).Exp(base, exp, n)
+
+	c.key = hash(pad(s))
+	m1 := clientProof(c.login, salt, c.pubA, pubB, c.key)
+	c.m2 = serverProof(c.pubA, m1, c.key)
+	return m1, nil
+}
+
+// VerifyServer проверяет доказательство сервера M2: сервер знает верификатор, а не подставной.
+func (c *Client) VerifyServer(m2 []byte) error {
+	if c.m2 == nil || !hmac.Equal(c.m2, m2) {
+		return ErrProofNotValid
+	}
+	return nil
+}
+
+// Key общий ключ сессии, доступен после Proof.
+func (c *Client) Key() []byte {
+	return c.key
+}
+
+// Server сторона сервера, знает соль и верификатор.
+type Server struct {
+	pubA  *big.Int
+	pubB  *big.Int
+	login string
+	salt  []byte
+	key   []byte
+	m1    []byte
+}
+
+// NewServer отвечает на начало обмена: вычисляет свой открытый ключ и ключ сессии.
+func NewServer(login string, salt, verifier, clientPublic []byte) (*Server, error) {
+	pubA := new(big.Int).SetBytes(clientPublic)
+	if !publicValid(pubA) {
+		return nil, fmt.Errorf("client public key: %w", ErrParamsNotValid)
+	}
+	v := new(big.Int).SetBytes(verifier)
+
+	var b, pubB, u *big.Int
+	for {
+		var err error
+		b, err = randomInt()
+		if err != nil {
+			return nil, err
+		}
+		// B = k * v + g^b mod N
+		pubB = new(big.Int).Add(new(big.Int).Mul(k, v), new(big.Int).Exp(g, b, n))
+		pubB.Mod(pubB, n)
+		u = scramble(pubA, pubB)
+		if publicValid(pubB) && u.Sign() != 0 {
+			break
+		}
+	}
+
+	// S = (A * v^u) ^ b mod N
+	base := new(big.Int).Mul(pubA, new(big.Int).Exp(v, u, n))
+	s := new(big.Int).Exp(base.Mod(base, n), b, n)
+
+	key := hash(pad(s))
+	return &Server{
+		login: login,
+		salt:  salt,
+		pubA:  pubA,
+		pubB:  pubB,
+		key:   key,
+		m1:    clientProof(login, salt, pubA, pubB, key),
+	}, nil
+}
+
+// PublicKey открытый ключ сервера B.
+func (s *Server) PublicKey() []byte {
+	return pad(s.pubB)
+}
+
+// Verify проверяет доказательство клиента M1 и возвращает доказательство сервера M2.
+func (s *Server) Verify(m1 []byte) ([]byte, error) {
+	if !hmac.Equal(s.m1, m1) {
+		return nil, ErrProofNotValid
+	}
+	return serverProof(s.pubA, s.m1, s.key), nil
+}
+
+// Key общий ключ сессии, использовать только после успешного Verify.
+func (s *Server) Key() []byte {
+	return s.key
+}
@@ -0,0 +1,83 @@
+package srp
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandshake(t *testing.T) {
+	salt, err := NewSalt()
+	require.NoError(t, err)
+	verifier := Verifier("user", "password", salt)
+	assert.True(t, ValidVerifier(salt, verifier))
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{name: "ok", password: "password"},
+		{name: "wrong password", password: "other", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient("user", tt.password)
+			require.NoError(t, err)
+			server, err := NewServer("user", salt, verifier, client.PublicKey())
+			require.NoError(t, err)
+
+			m1, err := client.Proof(salt, server.PublicKey())
+			require.NoError(t, err)
+			m2, err := server.Verify(m1)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrProofNotValid)
+				assert.NotEqual(t, client.Key(), server.Key())
+				return
+			}
+			require.NoError(t, err)
+			assert.NoError(t, client.VerifyServer(m2))
+			assert.Equal(t, client.Key(), server.Key())
+		})
+	}
+}
+
+func TestHandshake_otherVerifier(t *testing.T) {
+	salt, err := NewSalt()
+	require.NoError(t, err)
+	client, err := NewClient("user", "password")
+	require.NoError(t, err)
+	// сервер без верификатора пароля не может доказать клиенту знание ключа.
+	server, err := NewServer("user", salt, Verifier("user", "guess", salt), client.PublicKey())
+	require.NoError(t, err)
+
+	_, err = client.Proof(salt, server.PublicKey())
+	require.NoError(t, err)
+	assert.ErrorIs(t, client.VerifyServer([]byte("forged")), ErrProofNotValid)
+}
+
+func TestPublicKeyNotValid(t *testing.T) {
+	salt, err := NewSalt()
+	require.NoError(t, err)
+	verifier := Verifier("user", "password", salt)
+
+	for _, pub := range [][]byte{{0}, pad(n), new(big.Int).Add(n, big.NewInt(1)).Bytes()} {
+		_, err = NewServer("user", salt, verifier, pub)
+		assert.ErrorIs(t, err, ErrParamsNotValid)
+
+		client, err := NewClient("user", "password")
+		require.NoError(t, err)
+		_, err = client.Proof(salt, pub)
+		assert.ErrorIs(t, err, ErrParamsNotValid)
+	}
+}
+
+func TestValidVerifier(t *testing.T) {
+	salt, err := NewSalt()
+	require.NoError(t, err)
+	assert.False(t, ValidVerifier(salt[:4], Verifier("user", "password", salt)))
+	assert.False(t, ValidVerifier(salt, nil))
+	assert.False(t, ValidVerifier(salt, pad(n)))
+}
@@ -0,0 +1,148 @@
+// Package sshkey генерация и разбор ключей SSH: закрытый ключ в формате OpenSSH, открытый ключ
+// в формате authorized_keys и отпечаток SHA256, как их показывает ssh-keygen.
+package sshkey
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Типы генерируемых ключей.
+const (
+	Ed25519 = "ed25519"
+	RSA     = "rsa"
+)
+
+// Размеры ключа RSA в битах.
+const (
+	MinRSABits     = 2048
+	MaxRSABits     = 8192
+	DefaultRSABits = 3072
+)
+
+var (
+	ErrUnknownKind     = errors.New("unknown key type")
+	ErrBitsNotValid    = errors.New("rsa key size is not valid")
+	ErrPassphrase      = errors.New("key is encrypted, passphrase required")
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+)
+
+// Key ключ SSH: закрытый ключ PEM, открытый ключ строкой authorized_keys с комментарием и отпечаток.
+type Key struct {
+	PrivateKey  string
+	PublicKey   string
+	Fingerprint string
+}
+
+// Generate новый ключ kind, bits - размер ключа RSA, 0 - по умолчанию. Непустой passphrase шифрует закрытый ключ.
+func Generate(kind string, bits int, comment, passphrase string) (*Key, error) {
+	var signer crypto.Signer
+	switch kind {
+	case Ed25519:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed generate ed25519 key: %w", err)
+		}
+		signer = private
+	case RSA:
+		if bits == 0 {
+			bits = DefaultRSABits
+		}
+		if bits < MinRSABits || bits > MaxRSABits {
+			return nil, fmt.Errorf("%w: %d", ErrBitsNotValid, bits)
+		}
+		private, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, fmt.Errorf("failed generate rsa key: %w", err)
+		}
+		signer = private
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+
+	var block *pem.Block
+	var err error
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(signer, comment)
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(signer, comment, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed marshal private key: %w", err)
+	}
+	public, err := ssh.NewPublicKey(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed create public key: %w", err)
+	}
+	return &Key{
+		PrivateKey:  string(pem.EncodeToMemory(block)),
+		PublicKey:   authorizedKey(public, comment),
+		Fingerprint: ssh.FingerprintSHA256(public),
+	}, nil
+}
+
+// Describe открытый ключ и отпечаток закрытого ключа. Открытый ключ зашифрованного ключа OpenSSH
+// читается и без passphrase, для ключей PEM других форматов passphrase нужен.
+func Describe(privateKey, comment, passphrase string) (*Key, error) {
+	public, err := publicKey(privateKey, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		PrivateKey:  privateKey,
+		PublicKey:   authorizedKey(public, comment),
+		Fingerprint: ssh.FingerprintSHA256(public),
+	}, nil
+}
+
+func publicKey(privateKey, passphrase string) (ssh.PublicKey, error) {
+	signer, err := Signer(privateKey, passphrase)
+	if err == nil {
+		return signer.PublicKey(), nil
+	}
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) && missing.PublicKey != nil {
+		return missing.PublicKey, nil
+	}
+	return nil, err
+}
+
+// Signer ключ для подписи, зашифрованный ключ расшифровывается passphrase.
+func Signer(privateKey, passphrase string) (ssh.Signer, error) {
+	if passphrase == "" {
+		signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+		if err != nil {
+			var missing *ssh.PassphraseMissingError
+			if errors.As(err, &missing) {
+				return nil, fmt.Errorf("%w: %w", ErrPassphrase, err)
+			}
+			return nil, fmt.Errorf("failed parse private key: %w", err)
+		}
+		return signer, nil
+	}
+	signer, err := ssh.ParsePrivateKeyWithPassphrase([]byte(privateKey), []byte(passphrase))
+	if err != nil {
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, fmt.Errorf("%w: %w", ErrWrongPassphrase, err)
+		}
+		return nil, fmt.Errorf("failed parse private key: %w", err)
+	}
+	return signer, nil
+}
+
+func authorizedKey(public ssh.PublicKey, comment string) string {
+	line := strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(public)), "\n")
+	if comment != "" {
+		line += " " + comment
+	}
+	return line
+}
@@ -0,0 +1,63 @@
+package sshkey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		wantErr    error
+		name       string
+		kind       string
+		passphrase string
+		wantType   string
+		bits       int
+	}{
+		{name: "ed25519", kind: Ed25519, wantType: ssh.KeyAlgoED25519},
+		{name: "ed25519 passphrase", kind: Ed25519, passphrase: "secret", wantType: ssh.KeyAlgoED25519},
+		{name: "rsa", kind: RSA, bits: MinRSABits, wantType: ssh.KeyAlgoRSA},
+		{name: "rsa small", kind: RSA, bits: 1024, wantErr: ErrBitsNotValid},
+		{name: "unknown", kind: "dsa", wantErr: ErrUnknownKind},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := Generate(tt.kind, tt.bits, "user@host", tt.passphrase)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(key.PublicKey, tt.wantType+" "))
+			assert.True(t, strings.HasSuffix(key.PublicKey, " user@host"))
+			assert.True(t, strings.HasPrefix(key.Fingerprint, "SHA256:"))
+
+			signer, err := Signer(key.PrivateKey, tt.passphrase)
+			require.NoError(t, err)
+			assert.Equal(t, key.Fingerprint, ssh.FingerprintSHA256(signer.PublicKey()))
+
+			described, err := Describe(key.PrivateKey, "user@host", "")
+			require.NoError(t, err)
+			assert.Equal(t, key, described)
+		})
+	}
+}
+
+func TestSigner(t *testing.T) {
+	key, err := Generate(Ed25519, 0, "", "secret")
+	require.NoError(t, err)
+
+	_, err = Signer(key.PrivateKey, "")
+	assert.ErrorIs(t, err, ErrPassphrase)
+	_, err = Signer(key.PrivateKey, "wrong")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+	_, err = Signer("not a key", "")
+	assert.Error(t, err)
+
+	_, err = Describe(key.PrivateKey, "", "wrong")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+}
@@ -0,0 +1,247 @@
+// Package tlsconf настройки TLS сервера: минимальная версия, наборы шифров, проверка клиентских
+// сертификатов по CA и перечитывание сертификата и CA при изменении файлов без перезапуска.
+package tlsconf
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrVersionNotExists  = errors.New("tls version not exists")
+	ErrCipherNotExists   = errors.New("tls cipher suite not exists")
+	ErrClientCANotValid  = errors.New("client ca bundle has no certificates")
+	ErrCertFilesNotGiven = errors.New("tls certificate and key files are required")
+)
+
+// DefaultReloadInterval как часто проверяются файлы сертификата.
+const DefaultReloadInterval = 30 * time.Second
+
+var versions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Options файлы и параметры TLS сервера.
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile CA клиентских сертификатов, пустой - клиентский сертификат не запрашивается.
+	ClientCAFile string
+	// CipherSuites наборы шифров для TLS 1.2, пустой - наборы Go по умолчанию. TLS 1.3 их не настраивает.
+	CipherSuites []uint16
+	MinVersion   uint16
+}
+
+// ParseVersion версия TLS по имени: 1.2 или 1.3, пустое имя - 1.2.
+func ParseVersion(name string) (uint16, error) {
+	if name == "" {
+		return tls.VersionTLS12, nil
+	}
+	version, ok := versions[name]
+	if !ok {
+		return 0, fmt.Errorf("%s: %w", name, ErrVersionNotExists)
+	}
+	return version, nil
+}
+
+// ParseCipherSuites наборы шифров по именам Go, например TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+// Небезопасные наборы не принимаются.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", name, ErrCipherNotExists)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Fingerprint отпечаток сертификата: SHA-256 от DER в hex.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// PeerFingerprint отпечаток проверенного сертификата клиента, пустой - клиент сертификат не предъявил.
+func PeerFingerprint(state *tls.ConnectionState) string {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return ""
+	}
+	return Fingerprint(state.PeerCertificates[0])
+}
+
+// Reloader сертификат сервера и CA клиентов, которые перечитываются при изменении файлов.
+type Reloader struct {
+	log      *zap.Logger
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	// stamp время изменения и размер файлов при последней загрузке.
+	stamp string
+	opts  Options
+	mu    sync.RWMutex
+}
+
+type option func(*Reloader)
+
+// SetLogger журнал перезагрузок сертификата.
+func SetLogger(log *zap.Logger) option {
+	return func(r *Reloader) {
+		r.log = log
+	}
+}
+
+// New читает сертификат, ключ и CA клиентов, ошибка чтения сразу возвращается.
+func New(opts Options, options ...option) (*Reloader, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, ErrCertFilesNotGiven
+	}
+	r := &Reloader{
+		log:  zap.NewNop(),
+		opts: opts,
+	}
+	for _, opt := range options {
+		opt(r)
+	}
+
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Config настройки TLS сервера. Сертификат и CA берутся на каждое подключение,
+// поэтому после перезагрузки новые подключения получают новые файлы.
+func (r *Reloader) Config() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     r.opts.MinVersion,
+		CipherSuites:   r.opts.CipherSuites,
+		GetCertificate: r.getCertificate,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	if r.opts.ClientCAFile == "" {
+		return cfg
+	}
+
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		clientCfg := cfg.Clone()
+		clientCfg.GetConfigForClient = nil
+		clientCfg.ClientCAs = r.clientCA
+		return clientCfg, nil
+	}
+	return cfg
+}
+
+// ClientAuth требуется ли сертификат клиента.
+func (r *Reloader) ClientAuth() bool {
+	return r.opts.ClientCAFile != ""
+}
+
+func (r *Reloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Reload перечитывает файлы, если какой-то из них изменился. Возвращает true, если сертификат заменен.
+// При ошибке остаются прежние сертификат и CA.
+func (r *Reloader) Reload() (bool, error) {
+	stamp, err := r.filesStamp()
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	unchanged := r.cert != nil && stamp == r.stamp
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed load certificate: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return false, fmt.Errorf("failed read client ca: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return false, ErrClientCANotValid
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCA = pool
+	r.stamp = stamp
+	r.mu.Unlock()
+	return true, nil
+}
+
+// Watch проверяет файлы каждые interval, пока не отменен ctx.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				r.log.Error("failed reload tls certificate", zap.Error(err))
+				continue
+			}
+			if reloaded {
+				r.log.Info("tls certificate reloaded", zap.String("cert", r.opts.CertFile))
+			}
+		}
+	}
+}
+
+// filesStamp время изменения и размер файлов сертификата, ключа и CA. Сравнивается на равенство,
+// поэтому замена файла более старым (cp -p, переключение симлинка) тоже заметна.
+func (r *Reloader) filesStamp() (string, error) {
+	var stamp strings.Builder
+	for _, path := range []string{r.opts.CertFile, r.opts.KeyFile, r.opts.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("failed stat `%s`: %w", path, err)
+		}
+		fmt.Fprintf(&stamp, "%d:%d;", info.ModTime().UnixNano(), info.Size())
+	}
+	return stamp.String(), nil
+}
@@ -3,9 +3,9 @@ package tools
 import (
 	"crypto/md5"
 	"encoding/hex"
-	"time"
+	"fmt"
 
-	"golang.org/x/exp/rand"
+	"github.com/playmixer/secret-keeper/pkg/generator"
 )
 
 const (
@@ -14,18 +14,13 @@ const (
 	Mode0600 = 0o600
 )
 
-func init() {
-	rand.Seed(uint64(time.Now().UnixNano()))
-}
-
-// RandomString генерирует строку заданой длины.
-func RandomString(n uint) string {
-	var letterRunes = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890")
-	b := make([]byte, n)
-	for i := range b {
-		b[i] = letterRunes[rand.Intn(len(letterRunes))]
+// RandomString генерирует строку заданой длины из букв и цифр.
+func RandomString(n uint) (string, error) {
+	s, err := generator.String(int(n), generator.Lower+generator.Upper+generator.Digits)
+	if err != nil {
+		return "", fmt.Errorf("failed generate random string: %w", err)
 	}
-	return string(b)
+	return s, nil
 }
 
 // GetMD5Hash вернет md5 хеш  текста.
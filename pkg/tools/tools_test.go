@@ -26,7 +26,10 @@ func TestRandomString(t *testing.T) {
 	tests := []int{5, 10, 15}
 
 	for _, test := range tests {
-		actual := RandomString(uint(test))
+		actual, err := RandomString(uint(test))
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
 		if len(actual) != test {
 			t.Errorf("Expected %d len, but got %d", test, len(actual))
 		}
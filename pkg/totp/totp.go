@@ -0,0 +1,132 @@
+// Package totp одноразовые коды по времени (RFC 6238) для второго фактора сайтов.
+// Ключ задается URI otpauth://totp/... из QR-кода или секретом base32.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDigits = 6
+	defaultPeriod = 30 * time.Second
+	minDigits     = 6
+	maxDigits     = 8
+	decimal       = 10
+	// counterSize размер счетчика интервалов в байтах по RFC 4226.
+	counterSize = 8
+)
+
+var ErrKeyInvalid = errors.New("ключ OTP не распознан")
+
+// Key параметры генерации кодов.
+type Key struct {
+	secret []byte
+	algo   func() hash.Hash
+	digits int
+	period time.Duration
+}
+
+// Parse разбирает URI otpauth://totp/... или секрет base32, пробелы и регистр секрета не важны.
+func Parse(key string) (*Key, error) {
+	key = strings.TrimSpace(key)
+	if strings.HasPrefix(strings.ToLower(key), "otpauth://") {
+		return parseURI(key)
+	}
+	secret, err := decodeSecret(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{secret: secret, algo: sha1.New, digits: defaultDigits, period: defaultPeriod}, nil
+}
+
+func parseURI(key string) (*Key, error) {
+	u, err := url.Parse(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrKeyInvalid, err)
+	}
+	if !strings.EqualFold(u.Host, "totp") {
+		return nil, fmt.Errorf("%w: поддерживаются только коды по времени (totp)", ErrKeyInvalid)
+	}
+	q := u.Query()
+	secret, err := decodeSecret(q.Get("secret"))
+	if err != nil {
+		return nil, err
+	}
+	k := &Key{secret: secret, algo: sha1.New, digits: defaultDigits, period: defaultPeriod}
+
+	switch strings.ToUpper(q.Get("algorithm")) {
+	case "", "SHA1":
+	case "SHA256":
+		k.algo = sha256.New
+	case "SHA512":
+		k.algo = sha512.New
+	default:
+		return nil, fmt.Errorf("%w: алгоритм %s не поддерживается", ErrKeyInvalid, q.Get("algorithm"))
+	}
+	if v := q.Get("digits"); v != "" {
+		digits, err := strconv.Atoi(v)
+		if err != nil || digits < minDigits || digits > maxDigits {
+			return nil, fmt.Errorf("%w: число цифр %s", ErrKeyInvalid, v)
+		}
+		k.digits = digits
+	}
+	if v := q.Get("period"); v != "" {
+		period, err := strconv.Atoi(v)
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("%w: период %s", ErrKeyInvalid, v)
+		}
+		k.period = time.Duration(period) * time.Second
+	}
+	return k, nil
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	if secret == "" {
+		return nil, fmt.Errorf("%w: пустой секрет", ErrKeyInvalid)
+	}
+	b, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: секрет не в base32", ErrKeyInvalid)
+	}
+	return b, nil
+}
+
+// Code код на момент now.
+func (k *Key) Code(now time.Time) string {
+	counter := uint64(now.Unix() / int64(k.period/time.Second))
+	msg := make([]byte, counterSize)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(k.algo, k.secret)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// динамическое усечение RFC 4226.
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	mod := uint32(1)
+	for range k.digits {
+		mod *= decimal
+	}
+	return fmt.Sprintf("%0*d", k.digits, value%mod)
+}
+
+// Remaining сколько еще действует код, выданный на момент now.
+func (k *Key) Remaining(now time.Time) time.Duration {
+	elapsed := time.Duration(now.Unix()%int64(k.period/time.Second)) * time.Second
+	return k.period - elapsed
+}
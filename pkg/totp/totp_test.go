@@ -0,0 +1,64 @@
+package totp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// секреты из приложения B RFC 6238 в base32.
+const (
+	secretSHA1   = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	secretSHA256 = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA"
+	secretSHA512 = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" +
+		"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNA"
+)
+
+func TestKey_Code(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		unix int64
+		want string
+	}{
+		{name: "sha1", key: "otpauth://totp/x?secret=" + secretSHA1 + "&digits=8", unix: 59, want: "94287082"},
+		{name: "sha1 later", key: "otpauth://totp/x?secret=" + secretSHA1 + "&digits=8", unix: 1111111109,
+			want: "07081804"},
+		{name: "sha256", key: "otpauth://totp/x?secret=" + secretSHA256 + "&digits=8&algorithm=SHA256", unix: 59,
+			want: "46119246"},
+		{name: "sha512", key: "otpauth://totp/x?secret=" + secretSHA512 + "&digits=8&algorithm=SHA512", unix: 59,
+			want: "90693936"},
+		{name: "base32 secret", key: "gezd gnbv gy3t qojq gezd gnbv gy3t qojq", unix: 59, want: "287082"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := Parse(tt.key)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, k.Code(time.Unix(tt.unix, 0)))
+		})
+	}
+}
+
+func TestKey_Remaining(t *testing.T) {
+	k, err := Parse("otpauth://totp/x?secret=" + secretSHA1 + "&period=60")
+	require.NoError(t, err)
+	assert.Equal(t, 60*time.Second, k.Remaining(time.Unix(120, 0)))
+	assert.Equal(t, time.Second, k.Remaining(time.Unix(179, 0)))
+}
+
+func TestParse_invalid(t *testing.T) {
+	for _, key := range []string{
+		"",
+		"not base32!",
+		"otpauth://hotp/x?secret=" + secretSHA1,
+		"otpauth://totp/x",
+		"otpauth://totp/x?secret=" + secretSHA1 + "&algorithm=MD5",
+		"otpauth://totp/x?secret=" + secretSHA1 + "&digits=4",
+		"otpauth://totp/x?secret=" + secretSHA1 + "&period=0",
+	} {
+		_, err := Parse(key)
+		assert.ErrorIs(t, err, ErrKeyInvalid, key)
+	}
+}
@@ -2,4 +2,4 @@
 mkdir ./cert
 openssl genrsa -out ./cert/gophkeeper.key 2048
 openssl ecparam -genkey -name secp384r1 -out ./cert/gophkeeper.key
-openssl req -new -x509 -sha256 -key ./cert/gophkeeper.key -out ./cert/gophkeeper.crt -days 3650
\ No newline at end of file
+openssl req -new -x509 -sha256 -key ./cert/gophkeeper.key -out ./cert/gophkeeper.crt -days 3650 -subj "/CN=localhost" -addext "subjectAltName=DNS:localhost,IP:127.0.0.1"
\ No newline at end of file
@@ -0,0 +1 @@
+protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative .\internal\adapter\api\grpc\pb\keeper.proto